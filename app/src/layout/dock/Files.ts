@@ -28,6 +28,7 @@ import {ipcRenderer} from "electron";
 /// #endif
 import {hideTooltip, showTooltip} from "../../dialog/tooltip";
 import {selectOpenTab} from "./util";
+import {getIconByType} from "../../editor/getIcon";
 
 export class Files extends Model {
     public element: HTMLElement;
@@ -35,6 +36,8 @@ export class Files extends Model {
     public closeElement: HTMLElement;
     public lastSelectedElement: Element = null;
     private actionsElement: HTMLElement;
+    private smartFoldersElement: HTMLElement;
+    private smartFoldersTimeout: number;
 
     constructor(options: { tab: Tab, app: App }) {
         super({
@@ -106,6 +109,13 @@ export class Files extends Model {
                         case "rename":
                             this.onRename(data.data);
                             break;
+                        case "reloadSmartFolders":
+                            // 索引提交较频繁，合并短时间内的多次刷新
+                            clearTimeout(this.smartFoldersTimeout);
+                            this.smartFoldersTimeout = window.setTimeout(() => {
+                                this.initSmartFolders();
+                            }, 1000);
+                            break;
                     }
                 }
             },
@@ -129,6 +139,7 @@ export class Files extends Model {
     <span data-type="min" class="block__icon b3-tooltips b3-tooltips__sw" aria-label="${window.siyuan.languages.min}${updateHotkeyAfterTip(window.siyuan.config.keymap.general.closeTab.custom)}"><svg><use xlink:href='#iconMin'></use></svg></span>
 </div>
 <div class="fn__flex-1" style="padding-top: 2px;"></div>
+<ul class="b3-list b3-list--background fn__none" data-type="smart-folders" style="max-height: 40%;overflow: auto;flex-shrink: 0"></ul>
 <ul class="b3-list fn__flex-column" style="min-height: auto;height:30px;transition: height  .2s cubic-bezier(0, 0, .2, 1) 0ms">
     <li class="b3-list-item" data-type="toggle">
         <span class="b3-list-item__toggle">
@@ -141,7 +152,34 @@ export class Files extends Model {
 </ul>`;
         this.actionsElement = options.tab.panelElement.firstElementChild as HTMLElement;
         this.element = this.actionsElement.nextElementSibling as HTMLElement;
+        this.smartFoldersElement = this.element.nextElementSibling as HTMLElement;
         this.closeElement = options.tab.panelElement.lastElementChild as HTMLElement;
+        // 智能文件夹是已保存搜索的只读虚拟节点，展开后显示当前命中的块
+        this.smartFoldersElement.addEventListener("click", (event) => {
+            setPanelFocus(this.element.parentElement);
+            let target = event.target as HTMLElement;
+            while (target && !target.isEqualNode(this.smartFoldersElement)) {
+                const type = target.getAttribute("data-type");
+                if (type === "smart-folder") {
+                    this.toggleSmartFolder(target);
+                    window.siyuan.menus.menu.remove();
+                    event.stopPropagation();
+                    event.preventDefault();
+                    break;
+                } else if (type === "smart-folder-block") {
+                    openFileById({
+                        app: options.app,
+                        id: target.getAttribute("data-node-id"),
+                        action: [Constants.CB_GET_FOCUS, Constants.CB_GET_HL]
+                    });
+                    window.siyuan.menus.menu.remove();
+                    event.stopPropagation();
+                    event.preventDefault();
+                    break;
+                }
+                target = target.parentElement;
+            }
+        });
         this.closeElement.addEventListener("click", (event) => {
             setPanelFocus(this.element.parentElement);
             let target = event.target as HTMLElement;
@@ -901,6 +939,7 @@ data-type="navigation-root" data-path="/">
         } else {
             this.closeElement.classList.add("fn__none");
         }
+        this.initSmartFolders();
         window.siyuan.storage[Constants.LOCAL_FILESPATHS].forEach((item: IFilesPath) => {
             item.openPaths.forEach((openPath) => {
                 this.selectItem(item.notebookId, openPath, undefined, false, false);
@@ -921,6 +960,66 @@ data-type="navigation-root" data-path="/">
         }
     }
 
+    private initSmartFolders() {
+        fetchPost("/api/search/listSmartFolders", {}, (response) => {
+            // 刷新后保持已经展开的智能文件夹
+            const openNames: string[] = [];
+            this.smartFoldersElement.querySelectorAll(".b3-list-item__arrow--open").forEach((item) => {
+                openNames.push(item.parentElement.parentElement.getAttribute("data-name"));
+            });
+            let html = "";
+            response.data.forEach((item: { name: string, count: number }) => {
+                html += `<li class="b3-list-item b3-list-item--hide-action" data-type="smart-folder" data-name="${Lute.EscapeHTMLStr(item.name)}">
+    <span class="b3-list-item__toggle b3-list-item__toggle--hl">
+        <svg class="b3-list-item__arrow"><use xlink:href="#iconRight"></use></svg>
+    </span>
+    <svg class="b3-list-item__graphic"><use xlink:href="#iconSearch"></use></svg>
+    <span class="b3-list-item__text">${escapeHtml(item.name)}</span>
+    <span class="counter">${item.count}</span>
+</li>`;
+            });
+            this.smartFoldersElement.innerHTML = html;
+            if (html === "") {
+                this.smartFoldersElement.classList.add("fn__none");
+            } else {
+                this.smartFoldersElement.classList.remove("fn__none");
+            }
+            this.smartFoldersElement.querySelectorAll('li[data-type="smart-folder"]').forEach((item: HTMLElement) => {
+                if (openNames.includes(item.getAttribute("data-name"))) {
+                    this.toggleSmartFolder(item);
+                }
+            });
+        });
+    }
+
+    private toggleSmartFolder(liElement: HTMLElement) {
+        const arrowElement = liElement.querySelector(".b3-list-item__arrow");
+        if (arrowElement.classList.contains("b3-list-item__arrow--open")) {
+            arrowElement.classList.remove("b3-list-item__arrow--open");
+            if (liElement.nextElementSibling?.tagName === "UL") {
+                liElement.nextElementSibling.remove();
+            }
+            return;
+        }
+        fetchPost("/api/search/runCriterion", {
+            name: liElement.getAttribute("data-name"),
+            pageSize: 64,
+        }, (response) => {
+            let html = "";
+            response.data.blocks.forEach((item: IBlock) => {
+                // 按文档分组时显示文档下命中的块
+                (item.children || [item]).forEach((block) => {
+                    html += `<li class="b3-list-item" data-type="smart-folder-block" data-node-id="${block.id}" style="padding-left: 22px">
+    <svg class="b3-list-item__graphic"><use xlink:href="#${getIconByType(block.type)}"></use></svg>
+    <span class="b3-list-item__text">${block.content}</span>
+</li>`;
+                });
+            });
+            arrowElement.classList.add("b3-list-item__arrow--open");
+            liElement.insertAdjacentHTML("afterend", `<ul>${html}</ul>`);
+        });
+    }
+
     private onRemove(data: IWebSocketData) {
         // "doc2heading" 后删除文件或挂载帮助文档前的 unmount
         if (data.cmd === "unmount") {
@@ -303,6 +303,7 @@ export const setFilter = async (options: {
 <option ${"Does not contains" === options.filter.operator ? "selected" : ""} value="Does not contains">${window.siyuan.languages.filterOperatorDoesNotContain}</option>
 <option ${"Starts with" === options.filter.operator ? "selected" : ""} value="Starts with">${window.siyuan.languages.filterOperatorStartsWith}</option>
 <option ${"Ends with" === options.filter.operator ? "selected" : ""} value="Ends with">${window.siyuan.languages.filterOperatorEndsWith}</option>
+<option ${"Matches regex" === options.filter.operator ? "selected" : ""} value="Matches regex">${window.siyuan.languages.filterOperatorMatchesRegex}</option>
 <option ${"Is empty" === options.filter.operator ? "selected" : ""} value="Is empty">${window.siyuan.languages.filterOperatorIsEmpty}</option>
 <option ${"Is not empty" === options.filter.operator ? "selected" : ""} value="Is not empty">${window.siyuan.languages.filterOperatorIsNotEmpty}</option>`;
             break;
@@ -313,6 +314,7 @@ export const setFilter = async (options: {
 <option ${"Does not contains" === options.filter.operator ? "selected" : ""} value="Does not contains">${window.siyuan.languages.filterOperatorDoesNotContain}</option>
 <option ${"Starts with" === options.filter.operator ? "selected" : ""} value="Starts with">${window.siyuan.languages.filterOperatorStartsWith}</option>
 <option ${"Ends with" === options.filter.operator ? "selected" : ""} value="Ends with">${window.siyuan.languages.filterOperatorEndsWith}</option>
+<option ${"Matches regex" === options.filter.operator ? "selected" : ""} value="Matches regex">${window.siyuan.languages.filterOperatorMatchesRegex}</option>
 <option ${"Is empty" === options.filter.operator ? "selected" : ""} value="Is empty">${window.siyuan.languages.filterOperatorIsEmpty}</option>
 <option ${"Is not empty" === options.filter.operator ? "selected" : ""} value="Is not empty">${window.siyuan.languages.filterOperatorIsNotEmpty}</option>
 <option ${">" === options.filter.operator ? "selected" : ""} value=">">&gt;</option>
@@ -329,6 +331,8 @@ export const setFilter = async (options: {
 <option ${">=" === options.filter.operator ? "selected" : ""} value=">=">${window.siyuan.languages.filterOperatorIsOnOrAfter}</option>
 <option ${"<=" === options.filter.operator ? "selected" : ""} value="<=">${window.siyuan.languages.filterOperatorIsOnOrBefore}</option>
 <option ${"Is between" === options.filter.operator ? "selected" : ""} value="Is between">${window.siyuan.languages.filterOperatorIsBetween}</option>
+<option ${"Is within last" === options.filter.operator ? "selected" : ""} value="Is within last">${window.siyuan.languages.filterOperatorIsWithinLast}</option>
+<option ${"Is within next" === options.filter.operator ? "selected" : ""} value="Is within next">${window.siyuan.languages.filterOperatorIsWithinNext}</option>
 <option ${"Is empty" === options.filter.operator ? "selected" : ""} value="Is empty">${window.siyuan.languages.filterOperatorIsEmpty}</option>
 <option ${"Is not empty" === options.filter.operator ? "selected" : ""} value="Is not empty">${window.siyuan.languages.filterOperatorIsNotEmpty}</option>`;
             break;
@@ -346,12 +350,17 @@ export const setFilter = async (options: {
         case "relation":
             selectHTML = `<option ${"Contains" === options.filter.operator ? "selected" : ""} value="Contains">${window.siyuan.languages.filterOperatorContains}</option>
 <option ${"Does not contains" === options.filter.operator ? "selected" : ""} value="Does not contains">${window.siyuan.languages.filterOperatorDoesNotContain}</option>
+<option ${"Is any of" === options.filter.operator ? "selected" : ""} value="Is any of">${window.siyuan.languages.filterOperatorIsAnyOf}</option>
+<option ${"Is none of" === options.filter.operator ? "selected" : ""} value="Is none of">${window.siyuan.languages.filterOperatorIsNoneOf}</option>
+<option ${"Has all of" === options.filter.operator ? "selected" : ""} value="Has all of">${window.siyuan.languages.filterOperatorHasAllOf}</option>
 <option ${"Is empty" === options.filter.operator ? "selected" : ""} value="Is empty">${window.siyuan.languages.filterOperatorIsEmpty}</option>
 <option ${"Is not empty" === options.filter.operator ? "selected" : ""} value="Is not empty">${window.siyuan.languages.filterOperatorIsNotEmpty}</option>`;
             break;
         case "select":
             selectHTML = `<option ${"=" === options.filter.operator ? "selected" : ""} value="=">${window.siyuan.languages.filterOperatorIs}</option>
 <option ${"!=" === options.filter.operator ? "selected" : ""} value="!=">${window.siyuan.languages.filterOperatorIsNot}</option>
+<option ${"Is any of" === options.filter.operator ? "selected" : ""} value="Is any of">${window.siyuan.languages.filterOperatorIsAnyOf}</option>
+<option ${"Is none of" === options.filter.operator ? "selected" : ""} value="Is none of">${window.siyuan.languages.filterOperatorIsNoneOf}</option>
 <option ${"Is empty" === options.filter.operator ? "selected" : ""} value="Is empty">${window.siyuan.languages.filterOperatorIsEmpty}</option>
 <option ${"Is not empty" === options.filter.operator ? "selected" : ""} value="Is not empty">${window.siyuan.languages.filterOperatorIsNotEmpty}</option>`;
             break;
@@ -860,6 +869,10 @@ export const getFiltersHTML = (data: IAV) => {
         return filterHTML;
     };
     data.view.filters.forEach((item: IAVFilter) => {
+        if (item.conjunction) {
+            // 过滤组目前只能通过 API 设置，菜单中不显示，修改其他过滤条件时保留
+            return;
+        }
         const filterHTML = genFilterItem(item);
         if (filterHTML) {
             html += `<button class="b3-menu__item" draggable="true" data-id="${item.column}" data-filter-type="${item.value.type}">
@@ -878,7 +891,7 @@ export const getFiltersHTML = (data: IAV) => {
 </button>
 <button class="b3-menu__separator"></button>
 ${html}
-<button class="b3-menu__item${data.view.filters.length === fields.length ? " fn__none" : ""}" data-type="addFilter">
+<button class="b3-menu__item${data.view.filters.filter(item => !item.conjunction).length === fields.length ? " fn__none" : ""}" data-type="addFilter">
     <svg class="b3-menu__icon"><use xlink:href="#iconAdd"></use></svg>
     <span class="b3-menu__label">${window.siyuan.languages.addFilter}</span>
 </button>
@@ -58,7 +58,7 @@ export const genTabHeaderHTML = (data: IAV, showSearch: boolean, editable: boole
     getFieldsByData(data).forEach((item) => {
         if (!hasFilter) {
             data.view.filters.find(filterItem => {
-                if (filterItem.value.type === item.type && item.id === filterItem.column) {
+                if (filterItem.conjunction || (item.id === filterItem.column && filterItem.value.type === item.type)) {
                     hasFilter = true;
                     return true;
                 }
@@ -21,6 +21,7 @@ type TOperation =
     | "updateAttrViewCell"
     | "updateAttrViewCol"
     | "updateAttrViewColTemplate"
+    | "updateAttrViewColFormula"
     | "sortAttrViewRow"
     | "sortAttrViewCol"
     | "sortAttrViewKey"
@@ -55,6 +56,12 @@ type TOperation =
     | "setAttrViewCardAspectRatio"
     | "setAttrViewCoverFrom"
     | "setAttrViewCoverFromAssetKeyID"
+    | "setAttrViewCalendarDateField"
+    | "setAttrViewCalendarMode"
+    | "setAttrViewCalendarStartWeekday"
+    | "setAttrViewCalendarAnchor"
+    | "setAttrViewTimelineDependencyField"
+    | "setAttrViewTimelineScale"
     | "setAttrViewFitImage"
     | "setAttrViewShowIcon"
     | "setAttrViewWrapField"
@@ -91,13 +98,14 @@ type TEventBus = "ws-main" | "sync-start" | "sync-end" | "sync-fail" |
     "lock-screen" |
     "mobile-keyboard-show" | "mobile-keyboard-hide" |
     "code-language-update" | "code-language-change"
-type TAVView = "table" | "gallery" | "kanban"
+type TAVView = "table" | "gallery" | "kanban" | "calendar" | "timeline"
 type TAVCol =
     "text"
     | "date"
     | "number"
     | "relation"
     | "rollup"
+    | "formula"
     | "select"
     | "block"
     | "mSelect"
@@ -127,6 +135,12 @@ type TAVFilterOperator =
     | "Is relative to today"
     | "Is true"
     | "Is false"
+    | "Matches regex"
+    | "Is any of"
+    | "Is none of"
+    | "Has all of"
+    | "Is within last"
+    | "Is within next"
 
 type TRecentDocsSort = "viewedAt" | "closedAt" | "openAt" | "updated"
 
@@ -965,6 +979,9 @@ interface IAVFilter {
     value: IAVCellValue,
     relativeDate?: IAVRelativeDate
     relativeDate2?: IAVRelativeDate
+    conjunction?: "and" | "or", // 不为空时表示过滤组
+    filters?: IAVFilter[],
+    not?: boolean
 }
 
 interface IAVRelativeDate {
@@ -605,10 +605,11 @@ func getAttributeViewFilterSort(c *gin.Context) {
 	avID := arg["id"].(string)
 	blockID := arg["blockID"].(string)
 
-	filters, sorts := model.GetAttributeViewFilterSort(avID, blockID)
+	filters, leafFilters, sorts := model.GetAttributeViewFilterSort(avID, blockID)
 	ret.Data = map[string]interface{}{
-		"filters": filters,
-		"sorts":   sorts,
+		"filters":     filters,
+		"leafFilters": leafFilters,
+		"sorts":       sorts,
 	}
 }
 
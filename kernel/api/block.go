@@ -300,7 +300,11 @@ func setBlockReminder(c *gin.Context) {
 
 	id := arg["id"].(string)
 	timed := arg["timed"].(string) // yyyyMMddHHmmss
-	err := model.SetBlockReminder(id, timed)
+	var repeat string              // daily、weekly、monthly、yearly 或者 RRULE，为空时不重复
+	if repeatArg := arg["repeat"]; nil != repeatArg {
+		repeat = repeatArg.(string)
+	}
+	err := model.SetBlockReminder(id, timed, repeat)
 	if err != nil {
 		ret.Code = -1
 		ret.Msg = err.Error()
@@ -309,6 +313,25 @@ func setBlockReminder(c *gin.Context) {
 	}
 }
 
+func snoozeBlockReminder(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	id := arg["id"].(string)
+	minutes := int(arg["minutes"].(float64))
+	err := model.SnoozeBlockReminder(id, minutes)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+}
+
 func getUnfoldedParentID(c *gin.Context) {
 	ret := gulu.Ret.NewResult()
 	defer c.JSON(http.StatusOK, ret)
@@ -1043,7 +1043,16 @@ func searchDocs(c *gin.Context) {
 	}
 
 	k := arg["k"].(string)
-	ret.Data = model.SearchDocs(k, flashcard, excludeIDs)
+	var docs []map[string]string
+	for _, doc := range model.SearchDocs(k, flashcard, excludeIDs) {
+		if model.IsNotebookAllowedContext(c, doc["box"]) {
+			docs = append(docs, doc)
+		}
+	}
+	if nil == docs {
+		docs = []map[string]string{}
+	}
+	ret.Data = docs
 }
 
 func listDocsByPath(c *gin.Context) {
@@ -53,7 +53,12 @@ func searchHistory(c *gin.Context) {
 	if nil != arg["op"] {
 		op = arg["op"].(string)
 	}
-	histories, pageCount, totalCount := model.FullTextSearchHistory(query, notebook, op, typ, page)
+	histories, pageCount, totalCount, err := model.FullTextSearchHistory(query, notebook, op, typ, page, historySearchFilter(arg))
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
 	ret.Data = map[string]interface{}{
 		"histories":  histories,
 		"pageCount":  pageCount,
@@ -86,12 +91,28 @@ func getHistoryItems(c *gin.Context) {
 	if nil != arg["op"] {
 		op = arg["op"].(string)
 	}
-	histories := model.FullTextSearchHistoryItems(created, query, notebook, op, typ)
+	histories, err := model.FullTextSearchHistoryItems(created, query, notebook, op, typ, historySearchFilter(arg))
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
 	ret.Data = map[string]interface{}{
 		"items": histories,
 	}
 }
 
+func historySearchFilter(arg map[string]interface{}) (ret *model.HistorySearchFilter) {
+	ret = &model.HistorySearchFilter{}
+	if nil != arg["startDate"] {
+		ret.StartDate = arg["startDate"].(string)
+	}
+	if nil != arg["endDate"] {
+		ret.EndDate = arg["endDate"].(string)
+	}
+	return
+}
+
 func reindexHistory(c *gin.Context) {
 	ret := gulu.Ret.NewResult()
 	defer c.JSON(http.StatusOK, ret)
@@ -17,7 +17,9 @@
 package api
 
 import (
+	"errors"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -179,7 +181,32 @@ func importStdMd(c *gin.Context) {
 	notebook := arg["notebook"].(string)
 	localPath := arg["localPath"].(string)
 	toPath := arg["toPath"].(string)
-	err := model.ImportFromLocalPath(notebook, localPath, toPath)
+	var err error
+	if obsidianArg := arg["obsidian"]; nil != obsidianArg && obsidianArg.(bool) {
+		err = model.ImportObsidianVault(notebook, localPath, toPath)
+	} else {
+		err = model.ImportFromLocalPath(notebook, localPath, toPath)
+	}
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+}
+
+func importLogseq(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	notebook := arg["notebook"].(string)
+	localPath := arg["localPath"].(string)
+	toPath := arg["toPath"].(string)
+	err := model.ImportLogseqGraph(notebook, localPath, toPath)
 	if err != nil {
 		ret.Code = -1
 		ret.Msg = err.Error()
@@ -270,3 +297,157 @@ func importZipMd(c *gin.Context) {
 		return
 	}
 }
+
+func importAttributeView(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	util.PushEndlessProgress(model.Conf.Language(73))
+	defer util.ClearPushProgress(100)
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		logging.LogErrorf("parse import table file failed: %s", err)
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	writePath, err := saveImportFormFile(form)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	defer os.RemoveAll(writePath)
+
+	opts := &model.AttributeViewImport{
+		AvID:          importFormValue(form, "avID"),
+		Notebook:      importFormValue(form, "notebook"),
+		ToPath:        importFormValue(form, "toPath"),
+		PrimaryColumn: importFormValue(form, "primaryColumn"),
+		Upsert:        "true" == importFormValue(form, "upsert"),
+		BindDocs:      "true" == importFormValue(form, "bindDocs"),
+	}
+	if mapping := importFormValue(form, "mapping"); "" != mapping {
+		if err = gulu.JSON.UnmarshalJSON([]byte(mapping), &opts.Mapping); err != nil {
+			ret.Code = -1
+			ret.Msg = err.Error()
+			return
+		}
+	}
+
+	result, err := model.ImportAttributeView(writePath, opts)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	ret.Data = result
+}
+
+func importNotion(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	util.PushEndlessProgress(model.Conf.Language(73))
+	defer util.ClearPushProgress(100)
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		logging.LogErrorf("parse import notion export failed: %s", err)
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	writePath, err := saveImportFormFile(form)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	defer os.RemoveAll(writePath)
+
+	toPath := importFormValue(form, "toPath")
+	if "" == toPath {
+		toPath = "/"
+	}
+	if err = model.ImportNotion(writePath, importFormValue(form, "notebook"), toPath); err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+}
+
+func importENEX(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		logging.LogErrorf("parse import enex failed: %s", err)
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	writePath, err := saveImportFormFile(form)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	defer os.RemoveAll(writePath)
+
+	toPath := importFormValue(form, "toPath")
+	if "" == toPath {
+		toPath = "/"
+	}
+	if err = model.ImportENEX(writePath, importFormValue(form, "notebook"), toPath); err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+}
+
+// saveImportFormFile 将上传的文件保存到临时导入目录，调用方负责删除。
+func saveImportFormFile(form *multipart.Form) (writePath string, err error) {
+	files := form.File["file"]
+	if 1 > len(files) {
+		logging.LogErrorf("parse import file failed, no file found")
+		err = errors.New("no file found")
+		return
+	}
+	file := files[0]
+	reader, err := file.Open()
+	if err != nil {
+		logging.LogErrorf("read import file failed: %s", err)
+		return
+	}
+	defer reader.Close()
+
+	importDir := filepath.Join(util.TempDir, "import")
+	if err = os.MkdirAll(importDir, 0755); err != nil {
+		logging.LogErrorf("make import dir [%s] failed: %s", importDir, err)
+		return
+	}
+	writePath = filepath.Join(importDir, filepath.Base(file.Filename))
+	writer, err := os.OpenFile(writePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		logging.LogErrorf("open import file [%s] failed: %s", writePath, err)
+		return
+	}
+	defer writer.Close()
+	if _, err = io.Copy(writer, reader); err != nil {
+		logging.LogErrorf("write import file [%s] failed: %s", writePath, err)
+	}
+	return
+}
+
+func importFormValue(form *multipart.Form, name string) string {
+	if values := form.Value[name]; 0 < len(values) {
+		return values[0]
+	}
+	return ""
+}
@@ -421,7 +421,17 @@ func lsNotebooks(c *gin.Context) {
 		}
 	}
 
+	var allowedNotebooks []*model.Box
+	for _, notebook := range notebooks {
+		if model.IsNotebookAllowedContext(c, notebook.ID) {
+			allowedNotebooks = append(allowedNotebooks, notebook)
+		}
+	}
+	if nil == allowedNotebooks {
+		allowedNotebooks = []*model.Box{}
+	}
+
 	ret.Data = map[string]interface{}{
-		"notebooks": notebooks,
+		"notebooks": allowedNotebooks,
 	}
 }
@@ -119,6 +119,29 @@ func openRepoSnapshotDoc(c *gin.Context) {
 	}
 }
 
+func searchRepoSnapshotDocs(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	id := arg["id"].(string)
+	query := arg["query"].(string)
+	files, err := model.SearchRepoSnapshotDocs(id, query, historySearchFilter(arg))
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	ret.Data = map[string]interface{}{
+		"files": files,
+	}
+}
+
 func diffRepoSnapshots(c *gin.Context) {
 	ret := gulu.Ret.NewResult()
 	defer c.JSON(http.StatusOK, ret)
@@ -18,7 +18,6 @@ package api
 
 import (
 	"net/http"
-	"strings"
 
 	"github.com/88250/gulu"
 	"github.com/gin-gonic/gin"
@@ -60,6 +59,13 @@ func listInvalidBlockRefs(c *gin.Context) {
 	}
 }
 
+func reindexBlockVectors(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	go model.ReindexBlockVectors()
+}
+
 func getAssetContent(c *gin.Context) {
 	ret := gulu.Ret.NewResult()
 	defer c.JSON(http.StatusOK, ret)
@@ -126,16 +132,45 @@ func findReplace(c *gin.Context) {
 		}
 	}
 
-	err := model.FindReplace(k, r, replaceTypes, ids, paths, boxes, types, method, orderBy, groupBy)
+	dryRun := false
+	if dryRunArg := arg["dryRun"]; nil != dryRunArg {
+		dryRun = dryRunArg.(bool)
+	}
+
+	withDiffs := false
+	if withDiffsArg := arg["withDiffs"]; nil != withDiffsArg {
+		withDiffs = withDiffsArg.(bool)
+	}
+
+	result, err := model.FindReplace(k, r, replaceTypes, ids, paths, boxes, types, method, orderBy, groupBy, dryRun, withDiffs)
 	if err != nil {
 		ret.Code = 1
 		ret.Msg = err.Error()
 		ret.Data = map[string]interface{}{"closeTimeout": 5000}
 		return
 	}
+	ret.Data = result
 	return
 }
 
+func rollbackFindReplace(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	historyDir := arg["historyDir"].(string)
+	if failedIDs, err := model.RollbackReplaceHistory(historyDir); err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		ret.Data = map[string]interface{}{"failedIDs": failedIDs}
+		return
+	}
+}
+
 func searchAsset(c *gin.Context) {
 	ret := gulu.Ret.NewResult()
 	defer c.JSON(http.StatusOK, ret)
@@ -313,7 +348,8 @@ func searchEmbedBlock(c *gin.Context) {
 		breadcrumb = breadcrumbArg.(bool)
 	}
 
-	blocks := model.SearchEmbedBlock(embedBlockID, stmt, excludeIDs, headingMode, breadcrumb)
+	notebooks, _ := model.GetGinContextNotebooks(c)
+	blocks := model.SearchEmbedBlock(embedBlockID, stmt, excludeIDs, headingMode, breadcrumb, notebooks)
 	ret.Data = map[string]interface{}{
 		"blocks": blocks,
 	}
@@ -349,6 +385,7 @@ func searchRefBlock(c *gin.Context) {
 	keyword := arg["k"].(string)
 	beforeLen := int(arg["beforeLen"].(float64))
 	blocks, newDoc := model.SearchRefBlock(id, rootID, keyword, beforeLen, isSquareBrackets, isDatabase)
+	blocks = model.FilterBlocksByContextNotebooks(c, blocks)
 	ret.Data = map[string]interface{}{
 		"blocks": blocks,
 		"newDoc": newDoc,
@@ -357,6 +394,35 @@ func searchRefBlock(c *gin.Context) {
 	}
 }
 
+func runCriterion(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	name := arg["name"].(string)
+	page, pageSize, _, _, _, _, _, _, _ := parseSearchBlockArgs(arg)
+	notebooks, _ := model.GetGinContextNotebooks(c)
+	result, err := model.RunCriterion(name, notebooks, page, pageSize)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	ret.Data = result
+}
+
+func listSmartFolders(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	notebooks, _ := model.GetGinContextNotebooks(c)
+	ret.Data = model.ListSmartFolders(notebooks)
+}
+
 func fullTextSearchBlock(c *gin.Context) {
 	ret := gulu.Ret.NewResult()
 	defer c.JSON(http.StatusOK, ret)
@@ -367,14 +433,61 @@ func fullTextSearchBlock(c *gin.Context) {
 	}
 
 	page, pageSize, query, paths, boxes, types, method, orderBy, groupBy := parseSearchBlockArgs(arg)
-	blocks, matchedBlockCount, matchedRootCount, pageCount, docMode := model.FullTextSearchBlock(query, boxes, paths, types, method, orderBy, groupBy, page, pageSize)
-	ret.Data = map[string]interface{}{
+	if 5 == method {
+		if queryErr := model.CheckSearchQuery(query); nil != queryErr {
+			ret.Code = -1
+			ret.Msg = queryErr.Error()
+			ret.Data = map[string]interface{}{"msg": queryErr.Msg, "pos": queryErr.Pos, "reqId": arg["reqId"]}
+			return
+		}
+	}
+	if notebooks, scoped := model.GetGinContextNotebooks(c); scoped {
+		// 限制了笔记本时只搜索允许访问的笔记本，SQL 搜索无法限制笔记本所以不允许使用
+		if 2 == method {
+			ret.Code = -1
+			ret.Msg = "Access denied: SQL search is not allowed for notebook-scoped requests"
+			return
+		}
+
+		if 1 > len(boxes) {
+			boxes = notebooks
+		} else {
+			var allowedBoxes []string
+			for _, box := range boxes {
+				if model.IsNotebookAllowedContext(c, box) {
+					allowedBoxes = append(allowedBoxes, box)
+				}
+			}
+			if 1 > len(allowedBoxes) {
+				ret.Data = map[string]interface{}{
+					"blocks":            []*model.Block{},
+					"matchedBlockCount": 0,
+					"matchedRootCount":  0,
+					"pageCount":         0,
+					"docMode":           false,
+				}
+				return
+			}
+			boxes = allowedBoxes
+		}
+	}
+	withFacets := false
+	if withFacetsArg := arg["facets"]; nil != withFacetsArg {
+		withFacets = withFacetsArg.(bool)
+	}
+	blocks, matchedBlockCount, matchedRootCount, pageCount, docMode, facets := model.FullTextSearchBlock(query, boxes, paths, types, method, orderBy, groupBy, page, pageSize, withFacets)
+	blocks = model.FilterBlocksByContextNotebooks(c, blocks)
+	data := map[string]interface{}{
 		"blocks":            blocks,
 		"matchedBlockCount": matchedBlockCount,
 		"matchedRootCount":  matchedRootCount,
 		"pageCount":         pageCount,
 		"docMode":           docMode,
 	}
+	if withFacets {
+		data["facets"] = facets
+	}
+	ret.Data = data
 }
 
 func parseSearchBlockArgs(arg map[string]interface{}) (page, pageSize int, query string, paths, boxes []string, types map[string]bool, method, orderBy, groupBy int) {
@@ -401,19 +514,11 @@ func parseSearchBlockArgs(arg map[string]interface{}) (page, pageSize int, query
 
 	pathsArg := arg["paths"]
 	if nil != pathsArg {
+		var idPaths []string
 		for _, p := range pathsArg.([]interface{}) {
-			path := p.(string)
-			box := strings.TrimSpace(strings.Split(path, "/")[0])
-			if "" != box {
-				boxes = append(boxes, box)
-			}
-			path = strings.TrimSpace(strings.TrimPrefix(path, box))
-			if "" != path {
-				paths = append(paths, path)
-			}
+			idPaths = append(idPaths, p.(string))
 		}
-		paths = gulu.Str.RemoveDuplicatedElem(paths)
-		boxes = gulu.Str.RemoveDuplicatedElem(boxes)
+		paths, boxes = model.SplitSearchPaths(idPaths)
 	}
 
 	if nil != arg["types"] {
@@ -424,7 +529,7 @@ func parseSearchBlockArgs(arg map[string]interface{}) (page, pageSize int, query
 		}
 	}
 
-	// method：0：关键字，1：查询语法，2：SQL，3：正则表达式
+	// method：0：关键字，1：查询语法，2：SQL，3：正则表达式，4：语义，5：结构化查询
 	methodArg := arg["method"]
 	if nil != methodArg {
 		method = int(methodArg.(float64))
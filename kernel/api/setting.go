@@ -19,9 +19,11 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/88250/gulu"
+	"github.com/88250/lute/ast"
 	"github.com/gin-gonic/gin"
 	"github.com/siyuan-note/siyuan/kernel/bazaar"
 	"github.com/siyuan-note/siyuan/kernel/conf"
@@ -248,6 +250,108 @@ func setFlashcard(c *gin.Context) {
 	ret.Data = flashcard
 }
 
+func setCalDAV(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	param, err := gulu.JSON.MarshalJSON(arg)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	calDAV := &conf.CalDAV{}
+	if err = gulu.JSON.UnmarshalJSON(param, calDAV); err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	var avIDs []string
+	for _, avID := range calDAV.AttributeViews {
+		if ast.IsNodeIDPattern(avID) && !gulu.Str.Contains(avID, avIDs) {
+			avIDs = append(avIDs, avID)
+		}
+	}
+	if nil == avIDs {
+		avIDs = []string{}
+	}
+	calDAV.AttributeViews = avIDs
+
+	var boxIDs []string
+	for _, boxID := range calDAV.TaskNotebooks {
+		if ast.IsNodeIDPattern(boxID) && !gulu.Str.Contains(boxID, boxIDs) {
+			boxIDs = append(boxIDs, boxID)
+		}
+	}
+	if nil == boxIDs {
+		boxIDs = []string{}
+	}
+	calDAV.TaskNotebooks = boxIDs
+
+	tags := []string{}
+	for _, tag := range calDAV.TaskTags {
+		if tag = strings.Trim(strings.TrimSpace(tag), "#"); "" != tag && !gulu.Str.Contains(tag, tags) {
+			tags = append(tags, tag)
+		}
+	}
+	calDAV.TaskTags = tags
+
+	calDAV.TaskDueAttr = strings.TrimSpace(calDAV.TaskDueAttr)
+	if "" == calDAV.TaskDueAttr {
+		calDAV.TaskDueAttr = "custom-due"
+	}
+
+	model.Conf.CalDAV = calDAV
+	model.Conf.Save()
+
+	ret.Data = calDAV
+}
+
+func setReminder(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	param, err := gulu.JSON.MarshalJSON(arg)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	reminder := &conf.Reminder{}
+	if err = gulu.JSON.UnmarshalJSON(param, reminder); err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	reminder.Webhook = strings.TrimSpace(reminder.Webhook)
+	if "" != reminder.Webhook {
+		if u, parseErr := url.Parse(reminder.Webhook); nil != parseErr || ("http" != u.Scheme && "https" != u.Scheme) {
+			ret.Code = -1
+			ret.Msg = "invalid webhook URL [" + reminder.Webhook + "]"
+			return
+		}
+	}
+
+	model.Conf.Reminder = reminder
+	model.Conf.Save()
+
+	ret.Data = reminder
+}
+
 func setAccount(c *gin.Context) {
 	ret := gulu.Ret.NewResult()
 	defer c.JSON(http.StatusOK, ret)
@@ -467,8 +571,18 @@ func setSearch(c *gin.Context) {
 		s.Limit = 32
 	}
 
+	if 0 >= s.SemanticMinScore || 1 < s.SemanticMinScore {
+		s.SemanticMinScore = 0.3
+	}
+	s.FixRank()
+	if !sql.IsValidFTSTokenizer(s.Tokenizer) {
+		s.Tokenizer = sql.FTSTokenizerSiYuan
+	}
+
 	oldCaseSensitive := model.Conf.Search.CaseSensitive
+	oldTokenizer := model.Conf.Search.Tokenizer
 	oldIndexAssetPath := model.Conf.Search.IndexAssetPath
+	oldSemantic := model.Conf.Search.Semantic
 
 	oldVirtualRefName := model.Conf.Search.VirtualRefName
 	oldVirtualRefAlias := model.Conf.Search.VirtualRefAlias
@@ -480,10 +594,19 @@ func setSearch(c *gin.Context) {
 
 	sql.SetCaseSensitive(s.CaseSensitive)
 	sql.SetIndexAssetPath(s.IndexAssetPath)
+	sql.SetIndexBlockVector(s.Semantic)
+	sql.SetFTSTokenizer(s.Tokenizer)
 
-	if needFullReindex := s.CaseSensitive != oldCaseSensitive || s.IndexAssetPath != oldIndexAssetPath; needFullReindex {
+	if needFullReindex := s.CaseSensitive != oldCaseSensitive || s.IndexAssetPath != oldIndexAssetPath || s.Tokenizer != oldTokenizer; needFullReindex {
 		model.FullReindex()
 	}
+	if s.Tokenizer != oldTokenizer {
+		model.ReindexAssetContent()
+	}
+
+	if s.Semantic && !oldSemantic {
+		go model.ReindexBlockVectors()
+	}
 
 	if oldVirtualRefName != s.VirtualRefName ||
 		oldVirtualRefAlias != s.VirtualRefAlias ||
@@ -18,6 +18,7 @@ package api
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/88250/gulu"
 	"github.com/gin-gonic/gin"
@@ -45,7 +46,23 @@ func SQL(c *gin.Context) {
 	}
 
 	stmt := arg["stmt"].(string)
-	result, err := sql.Query(stmt, model.Conf.Search.Limit)
+	var timeout time.Duration
+	if timeoutArg := arg["timeout"]; nil != timeoutArg {
+		timeout = time.Duration(timeoutArg.(float64)) * time.Millisecond
+	}
+
+	if explainArg := arg["explain"]; nil != explainArg && explainArg.(bool) {
+		plan, err := sql.ExplainQueryPlan(stmt, timeout)
+		if err != nil {
+			ret.Code = 1
+			ret.Msg = err.Error()
+			return
+		}
+		ret.Data = plan
+		return
+	}
+
+	result, err := sql.Query(stmt, model.Conf.Search.Limit, timeout)
 	if err != nil {
 		ret.Code = 1
 		ret.Msg = err.Error()
@@ -330,6 +330,7 @@ func exportConf(c *gin.Context) {
 	clonedConf.UserData = ""
 	clonedConf.Account = nil
 	clonedConf.AccessAuthCode = ""
+	clonedConf.Users = nil
 	if nil != clonedConf.System {
 		clonedConf.System.ID = ""
 		clonedConf.System.Name = ""
@@ -599,6 +600,167 @@ func setAPIToken(c *gin.Context) {
 	model.Conf.Save()
 }
 
+func getAPITokens(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	ret.Data = model.GetAPITokens()
+}
+
+func createAPIToken(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	name, role, notebooks, paths, expired := parseAPITokenArg(arg)
+	token, secret, err := model.CreateAPIToken(name, role, notebooks, paths, expired)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	ret.Data = map[string]interface{}{
+		"token":  token,
+		"secret": secret,
+	}
+}
+
+func updateAPIToken(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	id := arg["id"].(string)
+	name, role, notebooks, paths, expired := parseAPITokenArg(arg)
+	if err := model.UpdateAPIToken(id, name, role, notebooks, paths, expired); err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+}
+
+func revokeAPIToken(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	id := arg["id"].(string)
+	if err := model.RevokeAPIToken(id); err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+}
+
+func getWorkspaceUsers(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	ret.Data = model.GetWorkspaceUsers()
+}
+
+func createWorkspaceUser(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	name, password, role, notebooks, _ := parseWorkspaceUserArg(arg)
+	user, err := model.CreateWorkspaceUser(name, password, role, notebooks)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	ret.Data = user
+}
+
+func updateWorkspaceUser(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	id := arg["id"].(string)
+	name, password, role, notebooks, disabled := parseWorkspaceUserArg(arg)
+	if err := model.UpdateWorkspaceUser(id, name, password, role, notebooks, disabled); err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+}
+
+func removeWorkspaceUser(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	id := arg["id"].(string)
+	if err := model.RemoveWorkspaceUser(id); err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+}
+
+func parseWorkspaceUserArg(arg map[string]interface{}) (name, password string, role conf.AccessRole, notebooks map[string]conf.AccessRole, disabled bool) {
+	name, _ = arg["name"].(string)
+	password, _ = arg["password"].(string)
+	roleArg, _ := arg["role"].(string)
+	role = conf.AccessRole(roleArg)
+	notebooks = map[string]conf.AccessRole{}
+	if notebooksArg, ok := arg["notebooks"].(map[string]interface{}); ok {
+		for notebook, notebookRole := range notebooksArg {
+			notebooks[notebook] = conf.AccessRole(notebookRole.(string))
+		}
+	}
+	disabled, _ = arg["disabled"].(bool)
+	return
+}
+
+func parseAPITokenArg(arg map[string]interface{}) (name string, role conf.AccessRole, notebooks, paths []string, expired int64) {
+	name, _ = arg["name"].(string)
+	roleArg, _ := arg["role"].(string)
+	role = conf.AccessRole(roleArg)
+	if notebooksArg, ok := arg["notebooks"].([]interface{}); ok {
+		for _, notebook := range notebooksArg {
+			notebooks = append(notebooks, notebook.(string))
+		}
+	}
+	if pathsArg, ok := arg["paths"].([]interface{}); ok {
+		for _, path := range pathsArg {
+			paths = append(paths, path.(string))
+		}
+	}
+	if expiredArg, ok := arg["expired"].(float64); ok {
+		expired = int64(expiredArg)
+	}
+	return
+}
+
 func setAccessAuthCode(c *gin.Context) {
 	ret := gulu.Ret.NewResult()
 	defer c.JSON(http.StatusOK, ret)
@@ -118,6 +118,7 @@ const (
 	KeyTypeRelation   KeyType = "relation"   // 关联
 	KeyTypeRollup     KeyType = "rollup"     // 汇总
 	KeyTypeLineNumber KeyType = "lineNumber" // 行号
+	KeyTypeFormula    KeyType = "formula"    // 公式
 )
 
 // Key 描述了属性视图属性字段的基础结构。
@@ -145,6 +146,9 @@ type Key struct {
 	// 汇总
 	Rollup *Rollup `json:"rollup,omitempty"` // 汇总信息
 
+	// 公式
+	Formula *Formula `json:"formula,omitempty"` // 公式信息
+
 	// 日期
 	Date *Date `json:"date,omitempty"` // 日期设置
 
@@ -198,6 +202,10 @@ type RollupCalc struct {
 	Result   *Value       `json:"result"`
 }
 
+type Formula struct {
+	Expression string `json:"expression"` // 公式表达式，通过 prop("字段名") 引用其他字段
+}
+
 type Relation struct {
 	AvID      string `json:"avID"`      // 关联的属性视图 ID
 	IsTwoWay  bool   `json:"isTwoWay"`  // 是否双向关联
@@ -212,19 +220,21 @@ type SelectOption struct {
 
 // View 描述了视图的结构。
 type View struct {
-	ID               string         `json:"id"`                // 视图 ID
-	Icon             string         `json:"icon"`              // 视图图标
-	Name             string         `json:"name"`              // 视图名称
-	HideAttrViewName bool           `json:"hideAttrViewName"`  // 是否隐藏属性视图名称
-	Desc             string         `json:"desc"`              // 视图描述
-	Filters          []*ViewFilter  `json:"filters,omitempty"` // 过滤规则
-	Sorts            []*ViewSort    `json:"sorts,omitempty"`   // 排序规则
-	PageSize         int            `json:"pageSize"`          // 每页条目数
-	LayoutType       LayoutType     `json:"type"`              // 当前布局类型
-	Table            *LayoutTable   `json:"table,omitempty"`   // 表格布局
-	Gallery          *LayoutGallery `json:"gallery,omitempty"` // 卡片布局
-	Kanban           *LayoutKanban  `json:"kanban,omitempty"`  // 看板布局
-	ItemIDs          []string       `json:"itemIds,omitempty"` // 项目 ID 列表，用于维护所有项目
+	ID               string          `json:"id"`                 // 视图 ID
+	Icon             string          `json:"icon"`               // 视图图标
+	Name             string          `json:"name"`               // 视图名称
+	HideAttrViewName bool            `json:"hideAttrViewName"`   // 是否隐藏属性视图名称
+	Desc             string          `json:"desc"`               // 视图描述
+	Filters          []*ViewFilter   `json:"filters,omitempty"`  // 过滤规则
+	Sorts            []*ViewSort     `json:"sorts,omitempty"`    // 排序规则
+	PageSize         int             `json:"pageSize"`           // 每页条目数
+	LayoutType       LayoutType      `json:"type"`               // 当前布局类型
+	Table            *LayoutTable    `json:"table,omitempty"`    // 表格布局
+	Gallery          *LayoutGallery  `json:"gallery,omitempty"`  // 卡片布局
+	Kanban           *LayoutKanban   `json:"kanban,omitempty"`   // 看板布局
+	Calendar         *LayoutCalendar `json:"calendar,omitempty"` // 日历布局
+	Timeline         *LayoutTimeline `json:"timeline,omitempty"` // 时间线布局
+	ItemIDs          []string        `json:"itemIds,omitempty"`  // 项目 ID 列表，用于维护所有项目
 
 	Group        *ViewGroup `json:"group,omitempty"`     // 分组规则
 	GroupCreated int64      `json:"groupCreated"`        // 分组生成时间戳
@@ -314,9 +324,11 @@ type GroupCalc struct {
 type LayoutType string
 
 const (
-	LayoutTypeTable   LayoutType = "table"   // 属性视图类型 - 表格
-	LayoutTypeGallery LayoutType = "gallery" // 属性视图类型 - 卡片
-	LayoutTypeKanban  LayoutType = "kanban"  // 属性视图类型 - 看板
+	LayoutTypeTable    LayoutType = "table"    // 属性视图类型 - 表格
+	LayoutTypeGallery  LayoutType = "gallery"  // 属性视图类型 - 卡片
+	LayoutTypeKanban   LayoutType = "kanban"   // 属性视图类型 - 看板
+	LayoutTypeCalendar LayoutType = "calendar" // 属性视图类型 - 日历
+	LayoutTypeTimeline LayoutType = "timeline" // 属性视图类型 - 时间线
 )
 
 const (
@@ -378,6 +390,30 @@ func NewKanbanView() (ret *View) {
 	}
 }
 
+func NewCalendarView() (ret *View) {
+	return &View{
+		ID:         ast.NewNodeID(),
+		Name:       GetAttributeViewI18n("calendar"),
+		Filters:    []*ViewFilter{},
+		Sorts:      []*ViewSort{},
+		PageSize:   ViewDefaultPageSize,
+		LayoutType: LayoutTypeCalendar,
+		Calendar:   NewLayoutCalendar(),
+	}
+}
+
+func NewTimelineView() (ret *View) {
+	return &View{
+		ID:         ast.NewNodeID(),
+		Name:       GetAttributeViewI18n("timeline"),
+		Filters:    []*ViewFilter{},
+		Sorts:      []*ViewSort{},
+		PageSize:   ViewDefaultPageSize,
+		LayoutType: LayoutTypeTimeline,
+		Timeline:   NewLayoutTimeline(),
+	}
+}
+
 // Viewable 描述了视图的接口。
 type Viewable interface {
 
@@ -820,7 +856,7 @@ func (av *AttributeView) Clone() (ret *AttributeView) {
 	for _, view := range ret.Views {
 		view.ID = ast.NewNodeID()
 
-		for _, f := range view.Filters {
+		for _, f := range GetLeafFilters(view.Filters) {
 			f.Column = keyIDMap[f.Column]
 		}
 		for _, s := range view.Sorts {
@@ -847,6 +883,19 @@ func (av *AttributeView) Clone() (ret *AttributeView) {
 			for _, field := range view.Kanban.Fields {
 				field.ID = keyIDMap[field.ID]
 			}
+		case LayoutTypeCalendar:
+			view.Calendar.ID = ast.NewNodeID()
+			view.Calendar.DateFieldID = keyIDMap[view.Calendar.DateFieldID]
+			for _, field := range view.Calendar.Fields {
+				field.ID = keyIDMap[field.ID]
+			}
+		case LayoutTypeTimeline:
+			view.Timeline.ID = ast.NewNodeID()
+			view.Timeline.DateFieldID = keyIDMap[view.Timeline.DateFieldID]
+			view.Timeline.DependencyFieldID = "" // 关联已经断开，依赖字段不再有效
+			for _, field := range view.Timeline.Fields {
+				field.ID = keyIDMap[field.ID]
+			}
 		}
 		view.ItemIDs = []string{}
 	}
@@ -881,6 +930,8 @@ var (
 	ErrViewNotFound          = errors.New("view not found")
 	ErrKeyNotFound           = errors.New("key not found")
 	ErrWrongLayoutType       = errors.New("wrong layout type")
+	ErrWrongKeyType          = errors.New("wrong key type")
+	ErrFormulaCycle          = errors.New("formula circular reference")
 )
 
 const (
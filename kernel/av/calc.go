@@ -128,9 +128,65 @@ func calcField(collection Collection, field Field, fieldIndex int, attrView *Att
 		calcFieldRelation(collection, field, fieldIndex)
 	case KeyTypeRollup:
 		calcFieldRollup(collection, field, fieldIndex)
+	case KeyTypeFormula:
+		calcFieldFormula(collection, field, fieldIndex)
 	}
 }
 
+func calcFieldFormula(collection Collection, field Field, fieldIndex int) {
+	// 使用公式计算结果替换字段值后，按计算结果的类型复用对应字段类型的计算逻辑
+	resultCollection := &formulaResultCollection{Collection: collection}
+	var resultType KeyType
+	for _, item := range collection.GetItems() {
+		resultItem := &formulaResultItem{Item: item, fieldIndex: fieldIndex}
+		resultCollection.items = append(resultCollection.items, resultItem)
+		if "" == resultType {
+			if value := item.GetValues()[fieldIndex]; nil != value && nil != value.Formula && !value.Formula.IsEmpty() {
+				resultType = value.Formula.Type
+			}
+		}
+	}
+
+	switch resultType {
+	case KeyTypeNumber:
+		calcFieldNumber(resultCollection, field, fieldIndex)
+	case KeyTypeDate:
+		calcFieldDate(resultCollection, field, fieldIndex)
+	case KeyTypeCheckbox:
+		calcFieldCheckbox(resultCollection, field, fieldIndex)
+	default:
+		calcFieldText(resultCollection, field, fieldIndex)
+	}
+}
+
+type formulaResultCollection struct {
+	Collection
+	items []Item
+}
+
+func (collection *formulaResultCollection) GetItems() []Item {
+	return collection.items
+}
+
+func (collection *formulaResultCollection) CountItems() int {
+	return len(collection.items)
+}
+
+type formulaResultItem struct {
+	Item
+	fieldIndex int
+}
+
+func (item *formulaResultItem) GetValues() (ret []*Value) {
+	values := item.Item.GetValues()
+	ret = make([]*Value, len(values))
+	copy(ret, values)
+	if value := ret[item.fieldIndex]; nil != value && nil != value.Formula && "" == value.Formula.Err {
+		ret[item.fieldIndex] = value.Formula.GetResultValue()
+	}
+	return
+}
+
 func calcFieldTemplate(collection Collection, field Field, fieldIndex int) {
 	calc := field.GetCalc()
 	switch calc.Operator {
@@ -17,8 +17,11 @@
 package av
 
 import (
+	"container/list"
 	"reflect"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/88250/lute/ast"
@@ -26,6 +29,9 @@ import (
 )
 
 // ViewFilter 描述了视图过滤规则的结构。
+//
+// 过滤规则是一棵树：Conjunction 不为空时该规则是一个过滤组，组内的 Filters 按 Conjunction 进行且/或组合，组可以嵌套；
+// 否则该规则是一个针对字段的过滤条件。视图顶层的过滤规则之间是且的关系。
 type ViewFilter struct {
 	Column        string           `json:"column"`                  // 字段（列）ID
 	Qualifier     FilterQuantifier `json:"quantifier,omitempty"`    // 量词
@@ -33,8 +39,19 @@ type ViewFilter struct {
 	Value         *Value           `json:"value"`                   // 过滤值
 	RelativeDate  *RelativeDate    `json:"relativeDate,omitempty"`  // 相对时间
 	RelativeDate2 *RelativeDate    `json:"relativeDate2,omitempty"` // 第二个相对时间，用于某些操作符，比如 FilterOperatorIsBetween
+
+	Conjunction FilterConjunction `json:"conjunction,omitempty"` // 过滤组的连接方式，不为空时表示该规则是一个过滤组
+	Filters     []*ViewFilter     `json:"filters,omitempty"`     // 过滤组中的过滤规则
+	Not         bool              `json:"not,omitempty"`         // 是否对过滤结果取反
 }
 
+type FilterConjunction string
+
+const (
+	FilterConjunctionAnd FilterConjunction = "and" // 且
+	FilterConjunctionOr  FilterConjunction = "or"  // 或
+)
+
 type RelativeDateUnit int
 
 const (
@@ -76,8 +93,19 @@ const (
 	FilterOperatorIsBetween        FilterOperator = "Is between"
 	FilterOperatorIsTrue           FilterOperator = "Is true"
 	FilterOperatorIsFalse          FilterOperator = "Is false"
+	FilterOperatorMatchesRegex     FilterOperator = "Matches regex"
+	FilterOperatorIsAnyOf          FilterOperator = "Is any of"
+	FilterOperatorIsNoneOf         FilterOperator = "Is none of"
+	FilterOperatorHasAllOf         FilterOperator = "Has all of"
+	FilterOperatorIsWithinLast     FilterOperator = "Is within last" // 使用 RelativeDate 的数量和单位，比如最近 7 天
+	FilterOperatorIsWithinNext     FilterOperator = "Is within next" // 使用 RelativeDate 的数量和单位，比如未来 2 周
 )
 
+// isSetFilterOperator 判断是否是集合操作符，集合操作符将字段值和过滤值都视为集合进行比较。
+func isSetFilterOperator(operator FilterOperator) bool {
+	return FilterOperatorIsAnyOf == operator || FilterOperatorIsNoneOf == operator || FilterOperatorHasAllOf == operator
+}
+
 type FilterQuantifier string
 
 const (
@@ -94,47 +122,138 @@ func Filter(viewable Viewable, attrView *AttributeView, rollupFurtherCollections
 		return
 	}
 
-	var colIndexes []int
-	for _, f := range filters {
-		for i, c := range collection.GetFields() {
-			if c.GetID() == f.Column {
-				colIndexes = append(colIndexes, i)
-				break
-			}
-		}
+	fieldIndexes := map[string]int{}
+	for i, field := range collection.GetFields() {
+		fieldIndexes[field.GetID()] = i
 	}
 
+	root := &ViewFilter{Conjunction: FilterConjunctionAnd, Filters: filters}
 	var items []Item
 	for _, item := range collection.GetItems() {
-		pass := true
-		values := item.GetValues()
-		for j, index := range colIndexes {
-			operator := filters[j].Operator
+		if pass, _ := root.match(item, fieldIndexes, attrView, rollupFurtherCollections, cachedAttrViews); pass {
+			items = append(items, item)
+		}
+	}
+	collection.SetItems(items)
+}
 
-			if nil == values[index] {
-				if FilterOperatorIsNotEmpty == operator {
-					pass = false
-				} else if FilterOperatorIsEmpty == operator {
-					pass = true
-					break
-				}
+// match 判断项目是否满足过滤规则，effective 为 false 时表示该规则不生效（比如字段已经不存在或者是空的过滤组）。
+func (filter *ViewFilter) match(item Item, fieldIndexes map[string]int, attrView *AttributeView, rollupFurtherCollections map[string]Collection, cachedAttrViews map[string]*AttributeView) (pass, effective bool) {
+	if filter.IsGroup() {
+		isOr := FilterConjunctionOr == filter.Conjunction
+		pass = !isOr
+		for _, f := range filter.Filters {
+			childPass, childEffective := f.match(item, fieldIndexes, attrView, rollupFurtherCollections, cachedAttrViews)
+			if !childEffective {
+				continue
+			}
 
-				if KeyTypeText != values[index].Type {
-					pass = false
-				}
+			effective = true
+			if isOr && childPass {
+				pass = true
 				break
 			}
-
-			if !values[index].Filter(filters[j], attrView, item.GetID(), rollupFurtherCollections, cachedAttrViews) {
+			if !isOr && !childPass {
 				pass = false
 				break
 			}
 		}
-		if pass {
-			items = append(items, item)
+	} else {
+		index, ok := fieldIndexes[filter.Column]
+		if !ok {
+			return
+		}
+
+		effective = true
+		value := item.GetValues()[index]
+		if nil == value {
+			pass = FilterOperatorIsEmpty == filter.Operator || FilterOperatorIsNoneOf == filter.Operator
+		} else {
+			pass = value.Filter(filter, attrView, item.GetID(), rollupFurtherCollections, cachedAttrViews)
 		}
 	}
-	collection.SetItems(items)
+
+	if effective && filter.Not {
+		pass = !pass
+	}
+	return
+}
+
+// IsGroup 判断过滤规则是否是过滤组。
+func (filter *ViewFilter) IsGroup() bool {
+	return "" != filter.Conjunction
+}
+
+// Clone 深拷贝过滤规则，过滤值仍然是共享的。
+func (filter *ViewFilter) Clone() (ret *ViewFilter) {
+	ret = &ViewFilter{
+		Column:        filter.Column,
+		Qualifier:     filter.Qualifier,
+		Operator:      filter.Operator,
+		Value:         filter.Value,
+		RelativeDate:  filter.RelativeDate,
+		RelativeDate2: filter.RelativeDate2,
+		Conjunction:   filter.Conjunction,
+		Not:           filter.Not,
+	}
+	for _, f := range filter.Filters {
+		ret.Filters = append(ret.Filters, f.Clone())
+	}
+	return
+}
+
+// GetLeafFilters 返回过滤规则树中所有针对字段的过滤条件。
+func GetLeafFilters(filters []*ViewFilter) (ret []*ViewFilter) {
+	for _, f := range filters {
+		if f.IsGroup() {
+			ret = append(ret, GetLeafFilters(f.Filters)...)
+			continue
+		}
+		ret = append(ret, f)
+	}
+	return
+}
+
+// GetMandatoryFilters 返回项目必须满足的过滤条件，即从顶层开始只经过且组合、并且没有取反的过滤条件。
+// 新增项目时使用这些条件来设置默认值，或组合和取反的条件无法确定默认值。
+func GetMandatoryFilters(filters []*ViewFilter) (ret []*ViewFilter) {
+	for _, f := range filters {
+		if f.Not {
+			continue
+		}
+
+		if f.IsGroup() {
+			if FilterConjunctionAnd == f.Conjunction || 1 == len(f.Filters) {
+				ret = append(ret, GetMandatoryFilters(f.Filters)...)
+			}
+			continue
+		}
+		ret = append(ret, f)
+	}
+	return
+}
+
+// RemoveFilters 从过滤规则树中移除满足 remove 的过滤条件，移除后变为空的过滤组也会被移除。
+func RemoveFilters(filters []*ViewFilter, remove func(filter *ViewFilter) bool) (ret []*ViewFilter) {
+	ret = []*ViewFilter{}
+	for _, f := range filters {
+		if f.IsGroup() {
+			if 0 < len(f.Filters) {
+				f.Filters = RemoveFilters(f.Filters, remove)
+				if 1 > len(f.Filters) {
+					continue
+				}
+			}
+			ret = append(ret, f)
+			continue
+		}
+
+		if remove(f) {
+			continue
+		}
+		ret = append(ret, f)
+	}
+	return
 }
 
 func (value *Value) Filter(filter *ViewFilter, attrView *AttributeView, itemID string, rollupFurtherCollections map[string]Collection, cachedAttrViews map[string]*AttributeView) bool {
@@ -329,6 +448,10 @@ func (value *Value) Filter(filter *ViewFilter, attrView *AttributeView, itemID s
 
 	// 单独处理关联
 	if nil != value.Relation && KeyTypeRelation == value.Type && nil != filter.Value && KeyTypeRelation == filter.Value.Type && nil != filter.Value.Relation {
+		if isSetFilterOperator(filter.Operator) {
+			return value.filter(filter.Value, filter.RelativeDate, filter.RelativeDate2, filter.Operator)
+		}
+
 		if 1 > len(filter.Value.Relation.BlockIDs) {
 			return true
 		}
@@ -370,6 +493,10 @@ func (value *Value) Filter(filter *ViewFilter, attrView *AttributeView, itemID s
 			return false
 		}
 
+		if isSetFilterOperator(filter.Operator) {
+			return value.filter(filter.Value, filter.RelativeDate, filter.RelativeDate2, filter.Operator)
+		}
+
 		var filterContent string
 		if 1 <= len(filter.Value.MAsset) {
 			filterContent = filter.Value.MAsset[0].Content
@@ -517,6 +644,11 @@ func (value *Value) filter(other *Value, relativeDate, relativeDate2 *RelativeDa
 		return value.IsEmpty()
 	case FilterOperatorIsNotEmpty:
 		return !value.IsEmpty()
+	case FilterOperatorIsNoneOf:
+		if value.IsEmpty() {
+			// 空值不包含任何候选值
+			return true
+		}
 	}
 
 	switch value.Type {
@@ -561,6 +693,10 @@ func (value *Value) filter(other *Value, relativeDate, relativeDate2 *RelativeDa
 				return false
 			}
 
+			if FilterOperatorIsWithinLast == operator || FilterOperatorIsWithinNext == operator {
+				return filterWithinTime(value.Date.Content, value.Date.IsNotEmpty, operator, relativeDate)
+			}
+
 			if nil != relativeDate { // 使用相对时间比较
 				relativeTimeStart, relativeTimeEnd := calcRelativeTimeRegion(relativeDate.Count, relativeDate.Unit, relativeDate.Direction)
 				relativeTimeStart2, relativeTimeEnd2 := calcRelativeTimeRegion(relativeDate2.Count, relativeDate2.Unit, relativeDate2.Direction)
@@ -574,6 +710,10 @@ func (value *Value) filter(other *Value, relativeDate, relativeDate2 *RelativeDa
 		}
 	case KeyTypeCreated:
 		if nil != value.Created {
+			if FilterOperatorIsWithinLast == operator || FilterOperatorIsWithinNext == operator {
+				return filterWithinTime(value.Created.Content, true, operator, relativeDate)
+			}
+
 			if nil != relativeDate { // 使用相对时间比较
 				relativeTimeStart, relativeTimeEnd := calcRelativeTimeRegion(relativeDate.Count, relativeDate.Unit, relativeDate.Direction)
 				relativeTimeStart2, relativeTimeEnd2 := calcRelativeTimeRegion(relativeDate2.Count, relativeDate2.Unit, relativeDate2.Direction)
@@ -587,6 +727,10 @@ func (value *Value) filter(other *Value, relativeDate, relativeDate2 *RelativeDa
 		}
 	case KeyTypeUpdated:
 		if nil != value.Updated {
+			if FilterOperatorIsWithinLast == operator || FilterOperatorIsWithinNext == operator {
+				return filterWithinTime(value.Updated.Content, true, operator, relativeDate)
+			}
+
 			if nil != relativeDate { // 使用相对时间比较
 				relativeTimeStart, relativeTimeEnd := calcRelativeTimeRegion(relativeDate.Count, relativeDate.Unit, relativeDate.Direction)
 				relativeTimeStart2, relativeTimeEnd2 := calcRelativeTimeRegion(relativeDate2.Count, relativeDate2.Unit, relativeDate2.Direction)
@@ -628,6 +772,22 @@ func (value *Value) filter(other *Value, relativeDate, relativeDate2 *RelativeDa
 					}
 				}
 				return !contains
+			case FilterOperatorIsAnyOf, FilterOperatorIsNoneOf, FilterOperatorHasAllOf:
+				var contents, otherContents []string
+				for _, v := range value.MSelect {
+					contents = append(contents, v.Content)
+				}
+				for _, v := range other.MSelect {
+					otherContents = append(otherContents, v.Content)
+				}
+				return filterSetContent(operator, contents, otherContents)
+			case FilterOperatorMatchesRegex:
+				for _, v := range value.MSelect {
+					if filterTextContent(operator, v.Content, other.MSelect[0].Content) {
+						return true
+					}
+				}
+				return false
 			}
 		}
 	case KeyTypeURL:
@@ -643,6 +803,17 @@ func (value *Value) filter(other *Value, relativeDate, relativeDate2 *RelativeDa
 			return filterTextContent(operator, value.Phone.Content, other.Phone.Content)
 		}
 	case KeyTypeMAsset:
+		if nil != value.MAsset && nil != other && isSetFilterOperator(operator) {
+			var contents, otherContents []string
+			for _, v := range value.MAsset {
+				contents = append(contents, v.Content)
+			}
+			for _, v := range other.MAsset {
+				otherContents = append(otherContents, v.Content)
+			}
+			return filterSetContent(operator, contents, otherContents)
+		}
+
 		if nil != value.MAsset && nil != other && nil != other.MAsset && 0 < len(value.MAsset) && 0 < len(other.MAsset) {
 			switch operator {
 			case FilterOperatorIsEqual, FilterOperatorContains:
@@ -722,8 +893,19 @@ func (value *Value) filter(other *Value, relativeDate, relativeDate2 *RelativeDa
 					return true
 				}
 				return strings.HasSuffix(value.Template.Content, other.Template.Content)
+			case FilterOperatorMatchesRegex, FilterOperatorIsAnyOf, FilterOperatorIsNoneOf:
+				return filterTextContent(operator, value.Template.Content, other.Template.Content)
 			}
 		}
+	case KeyTypeFormula:
+		if nil != value.Formula && nil != other && nil != other.Formula {
+			// 计算结果和过滤值类型相同时按该类型过滤，否则按文本过滤
+			result, otherResult := value.Formula.GetResultValue(), other.Formula.GetResultValue()
+			if "" != result.Type && result.Type == otherResult.Type {
+				return result.filter(otherResult, relativeDate, relativeDate2, operator)
+			}
+			return filterTextContent(operator, value.Formula.String(false), other.Formula.String(false))
+		}
 	case KeyTypeCheckbox:
 		if nil != value.Checkbox {
 			switch operator {
@@ -733,7 +915,11 @@ func (value *Value) filter(other *Value, relativeDate, relativeDate2 *RelativeDa
 				return !value.Checkbox.Checked
 			}
 		}
-	case KeyTypeRelation: // 过滤汇总字段，并且汇总目标是关联字段时才会进入该分支
+	case KeyTypeRelation: // 过滤汇总字段，并且汇总目标是关联字段时才会进入该分支；集合操作符过滤关联字段时也会进入该分支
+		if nil != value.Relation && nil != other && nil != other.Relation && isSetFilterOperator(operator) {
+			return filterSetContent(operator, value.Relation.BlockIDs, other.Relation.BlockIDs)
+		}
+
 		if nil != value.Relation && 0 < len(value.Relation.Contents) && nil != value.Relation.Contents[0].Block &&
 			nil != other && nil != other.Relation && 0 < len(other.Relation.BlockIDs) {
 			filterValue := &Value{Type: KeyTypeBlock, Block: &ValueBlock{Content: other.Relation.BlockIDs[0]}}
@@ -793,6 +979,33 @@ func filterTextContent(operator FilterOperator, valueContent, otherValueContent
 			return strings.HasSuffix(valueContent, otherValueContent)
 		}
 		return strings.HasSuffix(strings.ToLower(valueContent), strings.ToLower(otherValueContent))
+	case FilterOperatorMatchesRegex:
+		if "" == strings.TrimSpace(otherValueContent) {
+			return true
+		}
+		re := getFilterRegexp(otherValueContent)
+		if nil == re {
+			return false
+		}
+		return re.MatchString(valueContent)
+	case FilterOperatorIsAnyOf, FilterOperatorIsNoneOf:
+		// 文本类过滤值中的每一行是一个候选值
+		var candidates []string
+		for _, line := range strings.Split(otherValueContent, "\n") {
+			if line = strings.TrimSpace(line); "" != line {
+				candidates = append(candidates, line)
+			}
+		}
+		if 1 > len(candidates) {
+			return true
+		}
+
+		for _, candidate := range candidates {
+			if (util.SearchCaseSensitive && valueContent == candidate) || (!util.SearchCaseSensitive && strings.EqualFold(valueContent, candidate)) {
+				return FilterOperatorIsAnyOf == operator
+			}
+		}
+		return FilterOperatorIsNoneOf == operator
 	case FilterOperatorIsEmpty:
 		return "" == strings.TrimSpace(valueContent)
 	case FilterOperatorIsNotEmpty:
@@ -801,6 +1014,135 @@ func filterTextContent(operator FilterOperator, valueContent, otherValueContent
 	return false
 }
 
+// filterRegexps 缓存过滤使用的正则表达式，表达式来自用户输入，所以只保留最近使用的 filterRegexpsCapacity 个。
+var filterRegexps = &filterRegexpCache{entries: map[string]*list.Element{}, order: list.New()}
+
+const filterRegexpsCapacity = 64
+
+type filterRegexpCache struct {
+	entries map[string]*list.Element
+	order   *list.List // 最近使用的在前面
+	lock    sync.Mutex
+}
+
+type filterRegexpEntry struct {
+	pattern string
+	re      *regexp.Regexp // 表达式不合法时为 nil
+}
+
+func (cache *filterRegexpCache) get(pattern string) (ret *regexp.Regexp, ok bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	element := cache.entries[pattern]
+	if nil == element {
+		return
+	}
+	cache.order.MoveToFront(element)
+	return element.Value.(*filterRegexpEntry).re, true
+}
+
+func (cache *filterRegexpCache) put(pattern string, re *regexp.Regexp) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if element := cache.entries[pattern]; nil != element {
+		element.Value.(*filterRegexpEntry).re = re
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	cache.entries[pattern] = cache.order.PushFront(&filterRegexpEntry{pattern: pattern, re: re})
+	for filterRegexpsCapacity < cache.order.Len() {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*filterRegexpEntry).pattern)
+	}
+}
+
+// getFilterRegexp 返回过滤使用的正则表达式，表达式不合法时返回 nil。
+func getFilterRegexp(pattern string) (ret *regexp.Regexp) {
+	if !util.SearchCaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+
+	if cached, ok := filterRegexps.get(pattern); ok {
+		return cached
+	}
+
+	ret, err := regexp.Compile(pattern)
+	if nil != err {
+		ret = nil
+	}
+	filterRegexps.put(pattern, ret)
+	return
+}
+
+// filterSetContent 使用集合操作符过滤，values 为字段值中的元素，candidates 为过滤值中的元素。
+func filterSetContent(operator FilterOperator, values, candidates []string) bool {
+	if 1 > len(candidates) {
+		return true
+	}
+
+	matched := 0
+	for _, candidate := range candidates {
+		for _, v := range values {
+			if v == candidate {
+				matched++
+				break
+			}
+		}
+	}
+
+	switch operator {
+	case FilterOperatorIsAnyOf:
+		return 0 < matched
+	case FilterOperatorIsNoneOf:
+		return 0 == matched
+	case FilterOperatorHasAllOf:
+		return len(candidates) == matched
+	}
+	return false
+}
+
+// filterWithinTime 判断时间是否在最近或者未来的 N 个单位内，按天对齐，比如最近 7 天包含今天和之前的 7 天。
+func filterWithinTime(valueMills int64, valueIsNotEmpty bool, operator FilterOperator, relativeDate *RelativeDate) bool {
+	if !valueIsNotEmpty {
+		return false
+	}
+	if nil == relativeDate {
+		return true
+	}
+
+	now := time.Now()
+	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	todayEnd := todayStart.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	count := relativeDate.Count
+	if FilterOperatorIsWithinLast == operator {
+		count = -count
+	}
+
+	var edge time.Time
+	switch relativeDate.Unit {
+	case RelativeDateUnitDay:
+		edge = todayStart.AddDate(0, 0, count)
+	case RelativeDateUnitWeek:
+		edge = todayStart.AddDate(0, 0, count*7)
+	case RelativeDateUnitMonth:
+		edge = todayStart.AddDate(0, count, 0)
+	case RelativeDateUnitYear:
+		edge = todayStart.AddDate(count, 0, 0)
+	}
+
+	start, end := edge, todayEnd
+	if FilterOperatorIsWithinNext == operator {
+		start, end = todayStart, edge.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	valueTime := time.UnixMilli(valueMills)
+	return !valueTime.Before(start) && !valueTime.After(end)
+}
+
 func filterRelativeTime(valueMills int64, valueIsNotEmpty bool, operator FilterOperator, otherValueStart, otherValueEnd time.Time, direction RelativeDateDirection, otherValueStart2, otherValueEnd2 time.Time, direction2 RelativeDateDirection) bool {
 	valueTime := time.UnixMilli(valueMills)
 
@@ -1038,7 +1380,7 @@ func (filter *ViewFilter) IsValid() bool {
 
 func (filter *ViewFilter) GetAffectValue(key *Key, addingBlockID string) (ret *Value) {
 	if nil != filter.Value {
-		if KeyTypeRelation == filter.Value.Type || KeyTypeTemplate == filter.Value.Type || KeyTypeRollup == filter.Value.Type || KeyTypeUpdated == filter.Value.Type || KeyTypeCreated == filter.Value.Type {
+		if KeyTypeRelation == filter.Value.Type || KeyTypeTemplate == filter.Value.Type || KeyTypeRollup == filter.Value.Type || KeyTypeFormula == filter.Value.Type || KeyTypeUpdated == filter.Value.Type || KeyTypeCreated == filter.Value.Type {
 			// 所有生成的数据都不设置默认值
 			return nil
 		}
@@ -1055,6 +1397,16 @@ func (filter *ViewFilter) GetAffectValue(key *Key, addingBlockID string) (ret *V
 		}
 	}
 
+	switch filter.Operator {
+	case FilterOperatorMatchesRegex, FilterOperatorIsNoneOf:
+		// 正则表达式和排除集合无法确定默认值
+		return nil
+	case FilterOperatorIsAnyOf:
+		if KeyTypeSelect != filter.Value.Type && KeyTypeMSelect != filter.Value.Type && KeyTypeMAsset != filter.Value.Type {
+			return nil
+		}
+	}
+
 	ret = filter.Value.Clone()
 	ret.ID = ast.NewNodeID()
 	ret.KeyID = key.ID
@@ -1170,6 +1522,8 @@ func (filter *ViewFilter) GetAffectValue(key *Key, addingBlockID string) (ret *V
 				return
 			}
 			ret.Date = &ValueDate{Content: start.UnixMilli(), IsNotEmpty: true}
+		case FilterOperatorIsWithinLast, FilterOperatorIsWithinNext:
+			ret.Date = &ValueDate{Content: util.CurrentTimeMillis(), IsNotEmpty: true}
 		case FilterOperatorIsEmpty:
 			ret.Date = &ValueDate{Content: 0, IsNotEmpty: false}
 		case FilterOperatorIsNotEmpty:
@@ -1195,6 +1549,15 @@ func (filter *ViewFilter) GetAffectValue(key *Key, addingBlockID string) (ret *V
 			}
 		case FilterOperatorDoesNotContain:
 			return nil
+		case FilterOperatorIsAnyOf:
+			if 0 < len(filter.Value.MSelect) {
+				ret.MSelect = []*ValueSelect{{Content: filter.Value.MSelect[0].Content, Color: filter.Value.MSelect[0].Color}}
+			}
+		case FilterOperatorHasAllOf:
+			if KeyTypeSelect == filter.Value.Type && 1 < len(filter.Value.MSelect) {
+				// 单选无法同时包含多个选项
+				return nil
+			}
 		case FilterOperatorIsEmpty:
 			ret.MSelect = []*ValueSelect{}
 		case FilterOperatorIsNotEmpty:
@@ -1263,7 +1626,7 @@ func (filter *ViewFilter) GetAffectValue(key *Key, addingBlockID string) (ret *V
 		}
 	case KeyTypeMAsset:
 		switch filter.Operator {
-		case FilterOperatorIsEqual, FilterOperatorContains:
+		case FilterOperatorIsEqual, FilterOperatorContains, FilterOperatorIsAnyOf:
 			if 0 < len(filter.Value.MAsset) {
 				ret.MAsset = []*ValueAsset{{Type: filter.Value.MAsset[0].Type, Name: filter.Value.MAsset[0].Name, Content: filter.Value.MAsset[0].Content}}
 			}
@@ -0,0 +1,178 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	filterTestStatusKeyID = "20240101000000-status0"
+	filterTestOwnerKeyID  = "20240101000000-owner00"
+)
+
+// newFilterTestTable 返回包含状态（单选）和负责人（文本）两列的表格，行 ID 为 r1、r2 ...
+func newFilterTestTable(rows [][2]string) (attrView *AttributeView, table *Table) {
+	attrView = &AttributeView{ID: "20240101000000-avtest1"}
+	attrView.KeyValues = []*KeyValues{
+		{Key: NewKey(filterTestStatusKeyID, "status", "", KeyTypeSelect)},
+		{Key: NewKey(filterTestOwnerKeyID, "owner", "", KeyTypeText)},
+	}
+
+	table = &Table{BaseInstance: &BaseInstance{}}
+	for _, kv := range attrView.KeyValues {
+		table.Columns = append(table.Columns, &TableColumn{BaseInstanceField: &BaseInstanceField{ID: kv.Key.ID, Name: kv.Key.Name, Type: kv.Key.Type}})
+	}
+	for i, row := range rows {
+		rowID := "r" + string(rune('1'+i))
+		status := &Value{KeyID: filterTestStatusKeyID, BlockID: rowID, Type: KeyTypeSelect}
+		if "" != row[0] {
+			status.MSelect = []*ValueSelect{{Content: row[0]}}
+		}
+		owner := &Value{KeyID: filterTestOwnerKeyID, BlockID: rowID, Type: KeyTypeText, Text: &ValueText{Content: row[1]}}
+		table.Rows = append(table.Rows, &TableRow{ID: rowID, Cells: []*TableCell{
+			{BaseValue: &BaseValue{Value: status, ValueType: KeyTypeSelect}},
+			{BaseValue: &BaseValue{Value: owner, ValueType: KeyTypeText}},
+		}})
+	}
+	return
+}
+
+func newStatusFilter(operator FilterOperator, options ...string) *ViewFilter {
+	value := &Value{Type: KeyTypeSelect}
+	for _, option := range options {
+		value.MSelect = append(value.MSelect, &ValueSelect{Content: option})
+	}
+	return &ViewFilter{Column: filterTestStatusKeyID, Operator: operator, Value: value}
+}
+
+func newOwnerFilter(operator FilterOperator, content string) *ViewFilter {
+	return &ViewFilter{Column: filterTestOwnerKeyID, Operator: operator, Value: &Value{Type: KeyTypeText, Text: &ValueText{Content: content}}}
+}
+
+func filterTestRowIDs(table *Table) string {
+	var ids []string
+	for _, row := range table.Rows {
+		ids = append(ids, row.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestFilterGroups(t *testing.T) {
+	rows := [][2]string{{"Doing", "a"}, {"Todo", ""}, {"Done", ""}, {"Todo", "b"}}
+
+	tests := []struct {
+		name    string
+		filters []*ViewFilter
+		want    string
+	}{
+		{
+			name:    "flat filters are ANDed",
+			filters: []*ViewFilter{newStatusFilter(FilterOperatorIsEqual, "Todo"), newOwnerFilter(FilterOperatorIsEmpty, "")},
+			want:    "r2",
+		},
+		{
+			name: "status = Doing OR (owner is empty AND NOT status = Done)",
+			filters: []*ViewFilter{{Conjunction: FilterConjunctionOr, Filters: []*ViewFilter{
+				newStatusFilter(FilterOperatorIsEqual, "Doing"),
+				{Conjunction: FilterConjunctionAnd, Filters: []*ViewFilter{
+					newOwnerFilter(FilterOperatorIsEmpty, ""),
+					{Conjunction: FilterConjunctionAnd, Not: true, Filters: []*ViewFilter{newStatusFilter(FilterOperatorIsEqual, "Done")}},
+				}},
+			}}},
+			want: "r1,r2",
+		},
+		{
+			name:    "negated leaf",
+			filters: []*ViewFilter{{Column: filterTestOwnerKeyID, Operator: FilterOperatorIsEmpty, Value: &Value{Type: KeyTypeText, Text: &ValueText{}}, Not: true}},
+			want:    "r1,r4",
+		},
+		{
+			name:    "empty group and missing field are ignored",
+			filters: []*ViewFilter{{Conjunction: FilterConjunctionOr}, {Column: "20240101000000-missing", Operator: FilterOperatorIsNotEmpty}, newStatusFilter(FilterOperatorIsEqual, "Todo")},
+			want:    "r2,r4",
+		},
+	}
+
+	for _, test := range tests {
+		attrView, table := newFilterTestTable(rows)
+		table.Filters = test.filters
+		Filter(table, attrView, nil, nil)
+		if got := filterTestRowIDs(table); test.want != got {
+			t.Errorf("[%s] expected rows [%s], got [%s]", test.name, test.want, got)
+		}
+	}
+}
+
+func TestRemoveFilters(t *testing.T) {
+	filters := []*ViewFilter{
+		newStatusFilter(FilterOperatorIsEqual, "Doing"),
+		{Conjunction: FilterConjunctionOr, Filters: []*ViewFilter{newOwnerFilter(FilterOperatorIsEmpty, "")}},
+	}
+
+	filters = RemoveFilters(filters, func(filter *ViewFilter) bool { return filterTestOwnerKeyID == filter.Column })
+	if 1 != len(filters) || filters[0].IsGroup() {
+		t.Fatalf("expected the emptied group to be removed, got %d filters", len(filters))
+	}
+	if 1 != len(GetLeafFilters(filters)) {
+		t.Errorf("expected 1 leaf filter, got %d", len(GetLeafFilters(filters)))
+	}
+}
+
+func TestFilterSetAndRegexOperators(t *testing.T) {
+	rows := [][2]string{{"Doing", "alice"}, {"", "bob"}, {"Done", ""}, {"Todo", "Carol"}}
+
+	tests := []struct {
+		name   string
+		filter *ViewFilter
+		want   string
+	}{
+		{name: "select is any of", filter: newStatusFilter(FilterOperatorIsAnyOf, "Doing", "Done"), want: "r1,r3"},
+		{name: "select is none of matches empty values", filter: newStatusFilter(FilterOperatorIsNoneOf, "Doing", "Done"), want: "r2,r4"},
+		{name: "text is none of matches empty values", filter: newOwnerFilter(FilterOperatorIsNoneOf, "alice\nbob"), want: "r3,r4"},
+		{name: "text is any of ignores case", filter: newOwnerFilter(FilterOperatorIsAnyOf, "carol\n\nBOB"), want: "r2,r4"},
+		{name: "text matches regex", filter: newOwnerFilter(FilterOperatorMatchesRegex, "^(a|c)"), want: "r1,r4"},
+		{name: "invalid regex matches nothing", filter: newOwnerFilter(FilterOperatorMatchesRegex, "("), want: ""},
+	}
+
+	for _, test := range tests {
+		attrView, table := newFilterTestTable(rows)
+		table.Filters = []*ViewFilter{test.filter}
+		Filter(table, attrView, nil, nil)
+		if got := filterTestRowIDs(table); test.want != got {
+			t.Errorf("[%s] expected rows [%s], got [%s]", test.name, test.want, got)
+		}
+	}
+}
+
+func TestFilterRegexpCacheIsBounded(t *testing.T) {
+	for i := 0; i < filterRegexpsCapacity*3; i++ {
+		getFilterRegexp(strings.Repeat("a", i+1))
+	}
+	if filterRegexpsCapacity != filterRegexps.order.Len() || filterRegexpsCapacity != len(filterRegexps.entries) {
+		t.Fatalf("expected %d cached regexps, got %d", filterRegexpsCapacity, filterRegexps.order.Len())
+	}
+
+	// 最近使用的表达式仍然在缓存中
+	if _, ok := filterRegexps.get("(?i)" + strings.Repeat("a", filterRegexpsCapacity*3)); !ok {
+		t.Errorf("expected the most recently used regexp to be cached")
+	}
+	if _, ok := filterRegexps.get("(?i)a"); ok {
+		t.Errorf("expected the least recently used regexp to be evicted")
+	}
+}
@@ -0,0 +1,1485 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 公式表达式语法：
+//
+//	字面量：123、1.5、"text"、'text'、true、false
+//	字段引用：prop("字段名")
+//	运算符（优先级从低到高）：||、&&、== !=、< <= > >=、+ -、* / %、一元 ! -
+//	函数调用：if(条件, 值1, 值2)、dateAdd(prop("日期"), 1, "days") 等，见 formulaFuncs
+//
+// 计算结果类型为数字、日期、文本或布尔（复选框），列表类型的结果会转换为逗号分隔的文本。
+
+// FormulaExpr 描述了解析后的公式表达式。
+type FormulaExpr struct {
+	root formulaNode
+}
+
+// ParseFormula 解析公式表达式，语法错误、未知函数和参数个数错误都会在这里返回。
+func ParseFormula(expression string) (ret *FormulaExpr, err error) {
+	p := &formulaParser{lexer: &formulaLexer{src: expression}}
+	if err = p.next(); nil != err {
+		return
+	}
+
+	if formulaTokenEOF == p.tok.typ {
+		err = errors.New("formula is empty")
+		return
+	}
+
+	root, err := p.parseExpr(0)
+	if nil != err {
+		return
+	}
+	if formulaTokenEOF != p.tok.typ {
+		err = fmt.Errorf("unexpected [%s] at %d", p.tok.text, p.tok.pos)
+		return
+	}
+	ret = &FormulaExpr{root: root}
+	return
+}
+
+// GetRefKeyNames 返回表达式中通过 prop() 引用的字段名。
+func (expr *FormulaExpr) GetRefKeyNames() (ret []string) {
+	walkFormulaNode(expr.root, func(node formulaNode) {
+		if call, ok := node.(*formulaCall); ok && "prop" == call.name {
+			if lit, ok := call.args[0].(*formulaLiteral); ok && formulaValueText == lit.val.typ {
+				ret = append(ret, lit.val.str)
+			}
+		}
+	})
+	return
+}
+
+// Eval 对项目计算公式，numberFormat 用于格式化数字类型的结果。
+func (expr *FormulaExpr) Eval(attrView *AttributeView, item Item, numberFormat NumberFormat, now time.Time) (ret *ValueFormula) {
+	ctx := &formulaContext{attrView: attrView, item: item, now: now}
+	val, err := expr.root.eval(ctx)
+	if nil != err {
+		ret = &ValueFormula{Err: err.Error()}
+		return
+	}
+	ret = val.toValueFormula(numberFormat)
+	return
+}
+
+// GetFormulaKeysByResolutionOrder 按依赖关系返回公式字段的计算顺序，被依赖的公式字段排在前面。
+// 公式字段之间（包括通过汇总其他数据库的公式字段、跨数据库的汇总字段）存在循环引用时，涉及的公式字段通过 cycleKeys 返回。
+func GetFormulaKeysByResolutionOrder(attrView *AttributeView) (ret, cycleKeys []*Key) {
+	ret, cycleKeys = []*Key{}, []*Key{}
+	g := &formulaDepGraph{attrViews: map[string]*AttributeView{attrView.ID: attrView}, deps: map[string][]*formulaDepNode{}}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := map[string]int{}
+	inCycle := map[string]bool{}
+	broken := map[string]bool{} // 位于循环引用中或者依赖了循环引用的字段
+	var visit func(node *formulaDepNode, path []*formulaDepNode)
+	visit = func(node *formulaDepNode, path []*formulaDepNode) {
+		id := node.id()
+		switch states[id] {
+		case visiting:
+			for i := len(path) - 1; 0 <= i; i-- {
+				inCycle[path[i].id()] = true
+				if path[i].id() == id {
+					break
+				}
+			}
+			return
+		case visited:
+			return
+		}
+
+		states[id] = visiting
+		path = append(path, node)
+		deps := g.getDependencies(node)
+		for _, dep := range deps {
+			visit(dep, path)
+		}
+		states[id] = visited
+
+		// 依赖的字段在这里都已经访问完毕（或者构成循环引用），依赖了循环引用公式的公式也无法计算
+		broken[id] = inCycle[id]
+		for _, dep := range deps {
+			if broken[dep.id()] || inCycle[dep.id()] {
+				broken[id] = true
+			}
+		}
+		if node.attrView == attrView && KeyTypeFormula == node.key.Type {
+			ret = append(ret, node.key)
+		}
+	}
+
+	for _, kv := range attrView.KeyValues {
+		if KeyTypeFormula != kv.Key.Type || nil == kv.Key.Formula {
+			continue
+		}
+		visit(&formulaDepNode{attrView: attrView, key: kv.Key}, nil)
+	}
+
+	var resolved []*Key
+	for _, key := range ret {
+		if broken[attrView.ID+"/"+key.ID] {
+			cycleKeys = append(cycleKeys, key)
+			continue
+		}
+		resolved = append(resolved, key)
+	}
+	ret = resolved
+	if nil == ret {
+		ret = []*Key{}
+	}
+	return
+}
+
+// formulaDepNode 描述公式依赖图中的节点，节点是某个数据库的公式字段或者汇总字段。
+type formulaDepNode struct {
+	attrView *AttributeView
+	key      *Key
+}
+
+func (node *formulaDepNode) id() string {
+	return node.attrView.ID + "/" + node.key.ID
+}
+
+// formulaDepGraph 描述公式字段的依赖图，汇总字段会跨越数据库，其他数据库按需加载。
+type formulaDepGraph struct {
+	attrViews map[string]*AttributeView
+	deps      map[string][]*formulaDepNode
+}
+
+func (g *formulaDepGraph) getAttrView(avID string) *AttributeView {
+	if ret, ok := g.attrViews[avID]; ok {
+		return ret
+	}
+
+	ret, _ := ParseAttributeView(avID)
+	g.attrViews[avID] = ret
+	return ret
+}
+
+// getDependencies 返回节点依赖的公式字段和汇总字段：公式字段依赖其引用的公式和汇总字段，汇总字段依赖其汇总的公式或者汇总字段。
+func (g *formulaDepGraph) getDependencies(node *formulaDepNode) (ret []*formulaDepNode) {
+	if deps, ok := g.deps[node.id()]; ok {
+		return deps
+	}
+
+	switch node.key.Type {
+	case KeyTypeFormula:
+		for _, refKey := range GetFormulaRelevantKeys(node.attrView, node.key) {
+			if KeyTypeFormula == refKey.Type || KeyTypeRollup == refKey.Type {
+				ret = append(ret, &formulaDepNode{attrView: node.attrView, key: refKey})
+			}
+		}
+	case KeyTypeRollup:
+		if nil == node.key.Rollup {
+			break
+		}
+
+		relKey, _ := node.attrView.GetKey(node.key.Rollup.RelationKeyID)
+		if nil == relKey || nil == relKey.Relation {
+			break
+		}
+
+		destAv := g.getAttrView(relKey.Relation.AvID)
+		if nil == destAv {
+			break
+		}
+
+		if destKey, _ := destAv.GetKey(node.key.Rollup.KeyID); nil != destKey && (KeyTypeFormula == destKey.Type || KeyTypeRollup == destKey.Type) {
+			ret = append(ret, &formulaDepNode{attrView: destAv, key: destKey})
+		}
+	}
+	g.deps[node.id()] = ret
+	return
+}
+
+// CheckFormula 检查公式字段的表达式是否合法，包括语法、引用的字段是否存在以及是否存在循环引用。
+func CheckFormula(attrView *AttributeView, formulaKey *Key) (err error) {
+	if nil == formulaKey.Formula {
+		return
+	}
+
+	expr, err := ParseFormula(formulaKey.Formula.Expression)
+	if nil != err {
+		return
+	}
+
+	for _, name := range expr.GetRefKeyNames() {
+		if nil == getKeyByName(attrView, name) {
+			err = fmt.Errorf("field [%s] not found", name)
+			return
+		}
+	}
+
+	_, cycleKeys := GetFormulaKeysByResolutionOrder(attrView)
+	for _, cycleKey := range cycleKeys {
+		if cycleKey.ID == formulaKey.ID {
+			err = fmt.Errorf("%w: [%s]", ErrFormulaCycle, formulaKey.Name)
+			return
+		}
+	}
+	return
+}
+
+// GetFormulaRelevantKeys 返回公式字段引用的字段。
+func GetFormulaRelevantKeys(attrView *AttributeView, formulaKey *Key) (ret []*Key) {
+	ret = []*Key{}
+	if nil == formulaKey.Formula {
+		return
+	}
+
+	expr, err := ParseFormula(formulaKey.Formula.Expression)
+	if nil != err {
+		return
+	}
+
+	for _, name := range expr.GetRefKeyNames() {
+		if key := getKeyByName(attrView, name); nil != key {
+			ret = append(ret, key)
+		}
+	}
+	return
+}
+
+func getKeyByName(attrView *AttributeView, name string) *Key {
+	for _, kv := range attrView.KeyValues {
+		if kv.Key.Name == name {
+			return kv.Key
+		}
+	}
+	return nil
+}
+
+type formulaContext struct {
+	attrView *AttributeView
+	item     Item
+	now      time.Time
+}
+
+func (ctx *formulaContext) prop(name string) (ret *formulaValue, err error) {
+	key := getKeyByName(ctx.attrView, name)
+	if nil == key {
+		err = fmt.Errorf("field [%s] not found", name)
+		return
+	}
+
+	value := ctx.item.GetValue(key.ID)
+	if nil == value {
+		value = ctx.attrView.GetValue(key.ID, ctx.item.GetID())
+	}
+	ret = newFormulaValue(value, key)
+	return
+}
+
+type formulaValueType int
+
+const (
+	formulaValueEmpty formulaValueType = iota
+	formulaValueNumber
+	formulaValueText
+	formulaValueDate
+	formulaValueBool
+	formulaValueList
+)
+
+var formulaValueTypeNames = map[formulaValueType]string{
+	formulaValueEmpty:  "empty",
+	formulaValueNumber: "number",
+	formulaValueText:   "text",
+	formulaValueDate:   "date",
+	formulaValueBool:   "boolean",
+	formulaValueList:   "list",
+}
+
+type formulaValue struct {
+	typ       formulaValueType
+	num       float64
+	str       string
+	date      int64 // 毫秒时间戳
+	date2     int64 // 结束时间，没有结束时间时为 0
+	isNotTime bool
+	b         bool
+	list      []*formulaValue
+}
+
+var formulaEmpty = &formulaValue{typ: formulaValueEmpty}
+
+func newFormulaNumber(n float64) *formulaValue { return &formulaValue{typ: formulaValueNumber, num: n} }
+func newFormulaText(s string) *formulaValue    { return &formulaValue{typ: formulaValueText, str: s} }
+func newFormulaBool(b bool) *formulaValue      { return &formulaValue{typ: formulaValueBool, b: b} }
+func newFormulaDate(t time.Time, isNotTime bool) *formulaValue {
+	return &formulaValue{typ: formulaValueDate, date: t.UnixMilli(), isNotTime: isNotTime}
+}
+
+func newFormulaValue(value *Value, key *Key) (ret *formulaValue) {
+	ret = formulaEmpty
+	if nil == value {
+		return
+	}
+
+	switch value.Type {
+	case KeyTypeNumber:
+		if nil != value.Number && value.Number.IsNotEmpty {
+			ret = newFormulaNumber(value.Number.Content)
+		}
+	case KeyTypeDate, KeyTypeCreated, KeyTypeUpdated:
+		start, end, isNotTime, ok := GetDateRange(value)
+		if !ok {
+			return
+		}
+		ret = &formulaValue{typ: formulaValueDate, date: start, isNotTime: isNotTime}
+		if end != start {
+			ret.date2 = end
+		}
+	case KeyTypeCheckbox:
+		ret = newFormulaBool(nil != value.Checkbox && value.Checkbox.Checked)
+	case KeyTypeMSelect:
+		ret = &formulaValue{typ: formulaValueList}
+		for _, opt := range value.MSelect {
+			ret.list = append(ret.list, newFormulaText(opt.Content))
+		}
+	case KeyTypeMAsset:
+		ret = &formulaValue{typ: formulaValueList}
+		for _, asset := range value.MAsset {
+			ret.list = append(ret.list, newFormulaText(asset.Content))
+		}
+	case KeyTypeRelation:
+		ret = &formulaValue{typ: formulaValueList}
+		if nil != value.Relation {
+			for _, content := range value.Relation.Contents {
+				ret.list = append(ret.list, newFormulaValue(content, nil))
+			}
+		}
+	case KeyTypeRollup:
+		if nil == value.Rollup {
+			return
+		}
+
+		var contents []*formulaValue
+		for _, content := range value.Rollup.Contents {
+			contents = append(contents, newFormulaValue(content, nil))
+		}
+		if nil != key && nil != key.Rollup && nil != key.Rollup.Calc && CalcOperatorNone != key.Rollup.Calc.Operator && CalcOperatorUniqueValues != key.Rollup.Calc.Operator {
+			// 使用了计算的汇总结果是单个值
+			if 0 < len(contents) {
+				ret = contents[0]
+			}
+			return
+		}
+		ret = &formulaValue{typ: formulaValueList, list: contents}
+	case KeyTypeFormula:
+		if nil == value.Formula || "" != value.Formula.Err {
+			return
+		}
+		ret = newFormulaValue(value.Formula.GetResultValue(), nil)
+	case KeyTypeLineNumber:
+		return
+	default:
+		ret = newFormulaText(value.String(false))
+	}
+	return
+}
+
+func (v *formulaValue) typeName() string {
+	return formulaValueTypeNames[v.typ]
+}
+
+func (v *formulaValue) String() string {
+	switch v.typ {
+	case formulaValueNumber:
+		return strconv.FormatFloat(v.num, 'f', -1, 64)
+	case formulaValueText:
+		return v.str
+	case formulaValueDate:
+		return NewFormattedValueDate(v.date, v.date2, DateFormatNone, v.isNotTime, 0 != v.date2).FormattedContent
+	case formulaValueBool:
+		return strconv.FormatBool(v.b)
+	case formulaValueList:
+		var items []string
+		for _, item := range v.list {
+			if s := item.String(); "" != s {
+				items = append(items, s)
+			}
+		}
+		return strings.Join(items, ", ")
+	}
+	return ""
+}
+
+func (v *formulaValue) isEmpty() bool {
+	switch v.typ {
+	case formulaValueEmpty:
+		return true
+	case formulaValueText:
+		return "" == v.str
+	case formulaValueList:
+		return 1 > len(v.list)
+	}
+	return false
+}
+
+func (v *formulaValue) truthy() bool {
+	switch v.typ {
+	case formulaValueNumber:
+		return 0 != v.num
+	case formulaValueText:
+		return "" != v.str
+	case formulaValueDate:
+		return true
+	case formulaValueBool:
+		return v.b
+	case formulaValueList:
+		return 0 < len(v.list)
+	}
+	return false
+}
+
+// scalar 对于只有一个元素的列表返回该元素，以便直接参与运算。
+func (v *formulaValue) scalar() *formulaValue {
+	if formulaValueList == v.typ && 1 == len(v.list) {
+		return v.list[0]
+	}
+	return v
+}
+
+func (v *formulaValue) toNumber() (float64, error) {
+	v = v.scalar()
+	switch v.typ {
+	case formulaValueEmpty:
+		return 0, nil
+	case formulaValueNumber:
+		return v.num, nil
+	case formulaValueBool:
+		if v.b {
+			return 1, nil
+		}
+		return 0, nil
+	case formulaValueText:
+		if "" == strings.TrimSpace(v.str) {
+			return 0, nil
+		}
+		if n, err := strconv.ParseFloat(strings.TrimSpace(v.str), 64); nil == err {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("cannot use %s [%s] as number", v.typeName(), v.String())
+}
+
+func (v *formulaValue) toDate() (*formulaValue, error) {
+	v = v.scalar()
+	switch v.typ {
+	case formulaValueDate:
+		return v, nil
+	case formulaValueText:
+		return parseFormulaDate(v.str)
+	}
+	return nil, fmt.Errorf("cannot use %s [%s] as date", v.typeName(), v.String())
+}
+
+func (v *formulaValue) toValueFormula(numberFormat NumberFormat) (ret *ValueFormula) {
+	v = v.scalar()
+	ret = &ValueFormula{}
+	switch v.typ {
+	case formulaValueNumber:
+		if math.IsNaN(v.num) || math.IsInf(v.num, 0) {
+			ret.Err = "invalid number"
+			return
+		}
+		ret.Type = KeyTypeNumber
+		ret.Number = NewFormattedValueNumber(v.num, numberFormat)
+	case formulaValueDate:
+		ret.Type = KeyTypeDate
+		ret.Date = NewFormattedValueDate(v.date, v.date2, DateFormatNone, v.isNotTime, 0 != v.date2)
+	case formulaValueBool:
+		ret.Type = KeyTypeCheckbox
+		ret.Checkbox = &ValueCheckbox{Checked: v.b}
+	case formulaValueText, formulaValueList:
+		ret.Type = KeyTypeText
+		ret.Text = &ValueText{Content: v.String()}
+	}
+	return
+}
+
+func parseFormulaDate(s string) (*formulaValue, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01-02", "2006/01/02", "20060102"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); nil == err {
+			return newFormulaDate(t, true), nil
+		}
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006/01/02 15:04", "20060102150405", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); nil == err {
+			return newFormulaDate(t, false), nil
+		}
+	}
+	return nil, fmt.Errorf("cannot parse [%s] as date", s)
+}
+
+func compareFormulaValues(x, y *formulaValue) (ret int, err error) {
+	x, y = x.scalar(), y.scalar()
+	if formulaValueEmpty == x.typ && formulaValueText == y.typ {
+		x = newFormulaText("")
+	}
+	if formulaValueEmpty == y.typ && formulaValueText == x.typ {
+		y = newFormulaText("")
+	}
+	if formulaValueEmpty == x.typ && formulaValueNumber == y.typ {
+		x = newFormulaNumber(0)
+	}
+	if formulaValueEmpty == y.typ && formulaValueNumber == x.typ {
+		y = newFormulaNumber(0)
+	}
+
+	if x.typ != y.typ {
+		err = fmt.Errorf("cannot compare %s with %s", x.typeName(), y.typeName())
+		return
+	}
+
+	switch x.typ {
+	case formulaValueEmpty:
+		return 0, nil
+	case formulaValueNumber:
+		return compareFloat(x.num, y.num), nil
+	case formulaValueText:
+		return strings.Compare(x.str, y.str), nil
+	case formulaValueDate:
+		return compareFloat(float64(x.date), float64(y.date)), nil
+	case formulaValueBool:
+		if x.b == y.b {
+			return 0, nil
+		}
+		if x.b {
+			return 1, nil
+		}
+		return -1, nil
+	case formulaValueList:
+		return strings.Compare(x.String(), y.String()), nil
+	}
+	return
+}
+
+func compareFloat(x, y float64) int {
+	if x < y {
+		return -1
+	}
+	if x > y {
+		return 1
+	}
+	return 0
+}
+
+type formulaNode interface {
+	eval(ctx *formulaContext) (*formulaValue, error)
+}
+
+type formulaLiteral struct {
+	val *formulaValue
+}
+
+func (n *formulaLiteral) eval(ctx *formulaContext) (*formulaValue, error) {
+	return n.val, nil
+}
+
+type formulaUnary struct {
+	op string
+	x  formulaNode
+}
+
+func (n *formulaUnary) eval(ctx *formulaContext) (*formulaValue, error) {
+	x, err := n.x.eval(ctx)
+	if nil != err {
+		return nil, err
+	}
+
+	switch n.op {
+	case "!":
+		return newFormulaBool(!x.scalar().truthy()), nil
+	case "-":
+		num, err := x.toNumber()
+		if nil != err {
+			return nil, err
+		}
+		return newFormulaNumber(-num), nil
+	}
+	return nil, fmt.Errorf("unknown operator [%s]", n.op)
+}
+
+type formulaBinary struct {
+	op   string
+	x, y formulaNode
+}
+
+func (n *formulaBinary) eval(ctx *formulaContext) (*formulaValue, error) {
+	x, err := n.x.eval(ctx)
+	if nil != err {
+		return nil, err
+	}
+
+	// 逻辑运算短路求值
+	switch n.op {
+	case "&&":
+		if !x.scalar().truthy() {
+			return newFormulaBool(false), nil
+		}
+		y, err := n.y.eval(ctx)
+		if nil != err {
+			return nil, err
+		}
+		return newFormulaBool(y.scalar().truthy()), nil
+	case "||":
+		if x.scalar().truthy() {
+			return newFormulaBool(true), nil
+		}
+		y, err := n.y.eval(ctx)
+		if nil != err {
+			return nil, err
+		}
+		return newFormulaBool(y.scalar().truthy()), nil
+	}
+
+	y, err := n.y.eval(ctx)
+	if nil != err {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==", "!=":
+		c, cmpErr := compareFormulaValues(x, y)
+		equal := nil == cmpErr && 0 == c
+		if "==" == n.op {
+			return newFormulaBool(equal), nil
+		}
+		return newFormulaBool(!equal), nil
+	case "<", "<=", ">", ">=":
+		c, err := compareFormulaValues(x, y)
+		if nil != err {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return newFormulaBool(0 > c), nil
+		case "<=":
+			return newFormulaBool(0 >= c), nil
+		case ">":
+			return newFormulaBool(0 < c), nil
+		default:
+			return newFormulaBool(0 <= c), nil
+		}
+	case "+":
+		sx, sy := x.scalar(), y.scalar()
+		if formulaValueText == sx.typ || formulaValueText == sy.typ || formulaValueList == sx.typ || formulaValueList == sy.typ {
+			return newFormulaText(sx.String() + sy.String()), nil
+		}
+		if formulaValueDate == sx.typ || formulaValueDate == sy.typ {
+			return nil, errors.New("use dateAdd() to add to a date")
+		}
+	case "-":
+		if formulaValueDate == x.scalar().typ || formulaValueDate == y.scalar().typ {
+			return nil, errors.New("use dateSubtract() or dateBetween() for date math")
+		}
+	}
+
+	nx, err := x.toNumber()
+	if nil != err {
+		return nil, err
+	}
+	ny, err := y.toNumber()
+	if nil != err {
+		return nil, err
+	}
+
+	switch n.op {
+	case "+":
+		return newFormulaNumber(nx + ny), nil
+	case "-":
+		return newFormulaNumber(nx - ny), nil
+	case "*":
+		return newFormulaNumber(nx * ny), nil
+	case "/":
+		if 0 == ny {
+			return nil, errors.New("division by zero")
+		}
+		return newFormulaNumber(nx / ny), nil
+	case "%":
+		if 0 == ny {
+			return nil, errors.New("division by zero")
+		}
+		return newFormulaNumber(math.Mod(nx, ny)), nil
+	}
+	return nil, fmt.Errorf("unknown operator [%s]", n.op)
+}
+
+type formulaCall struct {
+	name string
+	args []formulaNode
+}
+
+func (n *formulaCall) eval(ctx *formulaContext) (*formulaValue, error) {
+	switch n.name {
+	case "prop":
+		lit, ok := n.args[0].(*formulaLiteral)
+		if !ok || formulaValueText != lit.val.typ {
+			return nil, errors.New("prop() requires a field name string")
+		}
+		return ctx.prop(lit.val.str)
+	case "if":
+		// 条件分支只计算命中的分支
+		cond, err := n.args[0].eval(ctx)
+		if nil != err {
+			return nil, err
+		}
+		if cond.scalar().truthy() {
+			return n.args[1].eval(ctx)
+		}
+		if 3 > len(n.args) {
+			return formulaEmpty, nil
+		}
+		return n.args[2].eval(ctx)
+	}
+
+	var args []*formulaValue
+	for _, arg := range n.args {
+		v, err := arg.eval(ctx)
+		if nil != err {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	fn := formulaFuncs[n.name]
+	ret, err := fn.call(ctx, args)
+	if nil != err {
+		return nil, fmt.Errorf("%s(): %s", n.name, err)
+	}
+	return ret, nil
+}
+
+func walkFormulaNode(node formulaNode, fn func(node formulaNode)) {
+	fn(node)
+	switch n := node.(type) {
+	case *formulaUnary:
+		walkFormulaNode(n.x, fn)
+	case *formulaBinary:
+		walkFormulaNode(n.x, fn)
+		walkFormulaNode(n.y, fn)
+	case *formulaCall:
+		for _, arg := range n.args {
+			walkFormulaNode(arg, fn)
+		}
+	}
+}
+
+type formulaFunc struct {
+	minArgs, maxArgs int // maxArgs 为 -1 时表示不限参数个数
+	call             func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error)
+}
+
+var formulaFuncs map[string]*formulaFunc
+
+func init() {
+	formulaFuncs = map[string]*formulaFunc{
+		// 字段引用和条件，在 formulaCall.eval 中单独处理
+		"prop": {minArgs: 1, maxArgs: 1},
+		"if":   {minArgs: 2, maxArgs: 3},
+
+		"empty": {minArgs: 1, maxArgs: 1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			return newFormulaBool(args[0].isEmpty()), nil
+		}},
+
+		// 数学
+		"abs":   {minArgs: 1, maxArgs: 1, call: formulaMathFunc(math.Abs)},
+		"floor": {minArgs: 1, maxArgs: 1, call: formulaMathFunc(math.Floor)},
+		"ceil":  {minArgs: 1, maxArgs: 1, call: formulaMathFunc(math.Ceil)},
+		"sqrt":  {minArgs: 1, maxArgs: 1, call: formulaMathFunc(math.Sqrt)},
+		"round": {minArgs: 1, maxArgs: 2, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			x, err := args[0].toNumber()
+			if nil != err {
+				return nil, err
+			}
+			digits := 0.0
+			if 1 < len(args) {
+				if digits, err = args[1].toNumber(); nil != err {
+					return nil, err
+				}
+			}
+			pow := math.Pow10(int(digits))
+			return newFormulaNumber(math.Round(x*pow) / pow), nil
+		}},
+		"pow": {minArgs: 2, maxArgs: 2, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			x, err := args[0].toNumber()
+			if nil != err {
+				return nil, err
+			}
+			y, err := args[1].toNumber()
+			if nil != err {
+				return nil, err
+			}
+			return newFormulaNumber(math.Pow(x, y)), nil
+		}},
+		"sum": {minArgs: 1, maxArgs: -1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			nums, err := flattenFormulaNumbers(args)
+			if nil != err {
+				return nil, err
+			}
+			sum := 0.0
+			for _, n := range nums {
+				sum += n
+			}
+			return newFormulaNumber(sum), nil
+		}},
+		"average": {minArgs: 1, maxArgs: -1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			nums, err := flattenFormulaNumbers(args)
+			if nil != err {
+				return nil, err
+			}
+			if 1 > len(nums) {
+				return formulaEmpty, nil
+			}
+			sum := 0.0
+			for _, n := range nums {
+				sum += n
+			}
+			return newFormulaNumber(sum / float64(len(nums))), nil
+		}},
+		"min": {minArgs: 1, maxArgs: -1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			nums, err := flattenFormulaNumbers(args)
+			if nil != err || 1 > len(nums) {
+				return formulaEmpty, err
+			}
+			ret := nums[0]
+			for _, n := range nums[1:] {
+				ret = math.Min(ret, n)
+			}
+			return newFormulaNumber(ret), nil
+		}},
+		"max": {minArgs: 1, maxArgs: -1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			nums, err := flattenFormulaNumbers(args)
+			if nil != err || 1 > len(nums) {
+				return formulaEmpty, err
+			}
+			ret := nums[0]
+			for _, n := range nums[1:] {
+				ret = math.Max(ret, n)
+			}
+			return newFormulaNumber(ret), nil
+		}},
+		"toNumber": {minArgs: 1, maxArgs: 1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			if d := args[0].scalar(); formulaValueDate == d.typ {
+				return newFormulaNumber(float64(d.date)), nil
+			}
+			n, err := args[0].toNumber()
+			if nil != err {
+				return nil, err
+			}
+			return newFormulaNumber(n), nil
+		}},
+
+		// 文本
+		"format": {minArgs: 1, maxArgs: 1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			return newFormulaText(args[0].String()), nil
+		}},
+		"concat": {minArgs: 1, maxArgs: -1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			buf := strings.Builder{}
+			for _, arg := range args {
+				buf.WriteString(arg.String())
+			}
+			return newFormulaText(buf.String()), nil
+		}},
+		"length": {minArgs: 1, maxArgs: 1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			if formulaValueList == args[0].typ {
+				return newFormulaNumber(float64(len(args[0].list))), nil
+			}
+			return newFormulaNumber(float64(utf8.RuneCountInString(args[0].String()))), nil
+		}},
+		"lower": {minArgs: 1, maxArgs: 1, call: formulaTextFunc(strings.ToLower)},
+		"upper": {minArgs: 1, maxArgs: 1, call: formulaTextFunc(strings.ToUpper)},
+		"trim":  {minArgs: 1, maxArgs: 1, call: formulaTextFunc(strings.TrimSpace)},
+		"contains": {minArgs: 2, maxArgs: 2, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			if formulaValueList == args[0].typ {
+				for _, item := range args[0].list {
+					if item.String() == args[1].String() {
+						return newFormulaBool(true), nil
+					}
+				}
+				return newFormulaBool(false), nil
+			}
+			return newFormulaBool(strings.Contains(args[0].String(), args[1].String())), nil
+		}},
+		"startsWith": {minArgs: 2, maxArgs: 2, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			return newFormulaBool(strings.HasPrefix(args[0].String(), args[1].String())), nil
+		}},
+		"endsWith": {minArgs: 2, maxArgs: 2, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			return newFormulaBool(strings.HasSuffix(args[0].String(), args[1].String())), nil
+		}},
+		"replace": {minArgs: 3, maxArgs: 3, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			return newFormulaText(strings.Replace(args[0].String(), args[1].String(), args[2].String(), 1)), nil
+		}},
+		"replaceAll": {minArgs: 3, maxArgs: 3, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			return newFormulaText(strings.ReplaceAll(args[0].String(), args[1].String(), args[2].String())), nil
+		}},
+		"slice": {minArgs: 2, maxArgs: 3, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			runes := []rune(args[0].String())
+			start, err := args[1].toNumber()
+			if nil != err {
+				return nil, err
+			}
+			end := float64(len(runes))
+			if 2 < len(args) {
+				if end, err = args[2].toNumber(); nil != err {
+					return nil, err
+				}
+			}
+			s, e := clampFormulaIndex(int(start), len(runes)), clampFormulaIndex(int(end), len(runes))
+			if s >= e {
+				return newFormulaText(""), nil
+			}
+			return newFormulaText(string(runes[s:e])), nil
+		}},
+		"join": {minArgs: 2, maxArgs: 2, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			var items []string
+			for _, item := range flattenFormulaValues(args[:1]) {
+				items = append(items, item.String())
+			}
+			return newFormulaText(strings.Join(items, args[1].String())), nil
+		}},
+		"first": {minArgs: 1, maxArgs: 1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			items := flattenFormulaValues(args)
+			if 1 > len(items) {
+				return formulaEmpty, nil
+			}
+			return items[0], nil
+		}},
+		"last": {minArgs: 1, maxArgs: 1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			items := flattenFormulaValues(args)
+			if 1 > len(items) {
+				return formulaEmpty, nil
+			}
+			return items[len(items)-1], nil
+		}},
+
+		// 日期
+		"now": {minArgs: 0, maxArgs: 0, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			return newFormulaDate(ctx.now, false), nil
+		}},
+		"today": {minArgs: 0, maxArgs: 0, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			today := time.Date(ctx.now.Year(), ctx.now.Month(), ctx.now.Day(), 0, 0, 0, 0, time.Local)
+			return newFormulaDate(today, true), nil
+		}},
+		"date": {minArgs: 3, maxArgs: 3, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			nums, err := flattenFormulaNumbers(args)
+			if nil != err {
+				return nil, err
+			}
+			if 3 > len(nums) {
+				// 参数为空时会被跳过
+				return nil, errors.New("year, month and day are required")
+			}
+			return newFormulaDate(time.Date(int(nums[0]), time.Month(int(nums[1])), int(nums[2]), 0, 0, 0, 0, time.Local), true), nil
+		}},
+		"parseDate": {minArgs: 1, maxArgs: 1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			if args[0].isEmpty() {
+				return formulaEmpty, nil
+			}
+			return args[0].toDate()
+		}},
+		"fromTimestamp": {minArgs: 1, maxArgs: 1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			n, err := args[0].toNumber()
+			if nil != err {
+				return nil, err
+			}
+			return newFormulaDate(time.UnixMilli(int64(n)), false), nil
+		}},
+		"timestamp": {minArgs: 1, maxArgs: 1, call: formulaDateFunc(func(t time.Time) float64 { return float64(t.UnixMilli()) })},
+		"year":      {minArgs: 1, maxArgs: 1, call: formulaDateFunc(func(t time.Time) float64 { return float64(t.Year()) })},
+		"month":     {minArgs: 1, maxArgs: 1, call: formulaDateFunc(func(t time.Time) float64 { return float64(t.Month()) })},
+		"day":       {minArgs: 1, maxArgs: 1, call: formulaDateFunc(func(t time.Time) float64 { return float64(t.Day()) })},
+		"weekday":   {minArgs: 1, maxArgs: 1, call: formulaDateFunc(func(t time.Time) float64 { return float64(t.Weekday()) })},
+		"hour":      {minArgs: 1, maxArgs: 1, call: formulaDateFunc(func(t time.Time) float64 { return float64(t.Hour()) })},
+		"minute":    {minArgs: 1, maxArgs: 1, call: formulaDateFunc(func(t time.Time) float64 { return float64(t.Minute()) })},
+		"start": {minArgs: 1, maxArgs: 1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			if args[0].isEmpty() {
+				return formulaEmpty, nil
+			}
+			d, err := args[0].toDate()
+			if nil != err {
+				return nil, err
+			}
+			return &formulaValue{typ: formulaValueDate, date: d.date, isNotTime: d.isNotTime}, nil
+		}},
+		"end": {minArgs: 1, maxArgs: 1, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			if args[0].isEmpty() {
+				return formulaEmpty, nil
+			}
+			d, err := args[0].toDate()
+			if nil != err {
+				return nil, err
+			}
+			end := d.date
+			if 0 != d.date2 {
+				end = d.date2
+			}
+			return &formulaValue{typ: formulaValueDate, date: end, isNotTime: d.isNotTime}, nil
+		}},
+		"dateAdd": {minArgs: 3, maxArgs: 3, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			return addFormulaDate(args, 1)
+		}},
+		"dateSubtract": {minArgs: 3, maxArgs: 3, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			return addFormulaDate(args, -1)
+		}},
+		"dateBetween": {minArgs: 3, maxArgs: 3, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			if args[0].isEmpty() || args[1].isEmpty() {
+				return formulaEmpty, nil
+			}
+			d1, err := args[0].toDate()
+			if nil != err {
+				return nil, err
+			}
+			d2, err := args[1].toDate()
+			if nil != err {
+				return nil, err
+			}
+			return dateBetweenFormula(time.UnixMilli(d1.date), time.UnixMilli(d2.date), args[2].String())
+		}},
+		"formatDate": {minArgs: 2, maxArgs: 2, call: func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+			if args[0].isEmpty() {
+				return newFormulaText(""), nil
+			}
+			d, err := args[0].toDate()
+			if nil != err {
+				return nil, err
+			}
+			return newFormulaText(formatFormulaDate(time.UnixMilli(d.date), args[1].String())), nil
+		}},
+	}
+}
+
+func formulaMathFunc(fn func(float64) float64) func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+	return func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+		if args[0].isEmpty() {
+			return formulaEmpty, nil
+		}
+		x, err := args[0].toNumber()
+		if nil != err {
+			return nil, err
+		}
+		return newFormulaNumber(fn(x)), nil
+	}
+}
+
+func formulaTextFunc(fn func(string) string) func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+	return func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+		return newFormulaText(fn(args[0].String())), nil
+	}
+}
+
+func formulaDateFunc(fn func(time.Time) float64) func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+	return func(ctx *formulaContext, args []*formulaValue) (*formulaValue, error) {
+		if args[0].isEmpty() {
+			return formulaEmpty, nil
+		}
+		d, err := args[0].toDate()
+		if nil != err {
+			return nil, err
+		}
+		return newFormulaNumber(fn(time.UnixMilli(d.date))), nil
+	}
+}
+
+func flattenFormulaValues(args []*formulaValue) (ret []*formulaValue) {
+	for _, arg := range args {
+		if formulaValueList == arg.typ {
+			ret = append(ret, flattenFormulaValues(arg.list)...)
+			continue
+		}
+		ret = append(ret, arg)
+	}
+	return
+}
+
+func flattenFormulaNumbers(args []*formulaValue) (ret []float64, err error) {
+	for _, arg := range flattenFormulaValues(args) {
+		if arg.isEmpty() {
+			continue
+		}
+
+		n, toErr := arg.toNumber()
+		if nil != toErr {
+			err = toErr
+			return
+		}
+		ret = append(ret, n)
+	}
+	return
+}
+
+func clampFormulaIndex(i, length int) int {
+	if 0 > i {
+		i += length
+	}
+	if 0 > i {
+		return 0
+	}
+	if i > length {
+		return length
+	}
+	return i
+}
+
+func addFormulaDate(args []*formulaValue, sign int) (*formulaValue, error) {
+	if args[0].isEmpty() {
+		return formulaEmpty, nil
+	}
+	d, err := args[0].toDate()
+	if nil != err {
+		return nil, err
+	}
+	n, err := args[1].toNumber()
+	if nil != err {
+		return nil, err
+	}
+
+	amount := int(n) * sign
+	t := time.UnixMilli(d.date)
+	switch strings.TrimSuffix(strings.ToLower(args[2].String()), "s") {
+	case "year":
+		t = t.AddDate(amount, 0, 0)
+	case "quarter":
+		t = t.AddDate(0, amount*3, 0)
+	case "month":
+		t = t.AddDate(0, amount, 0)
+	case "week":
+		t = t.AddDate(0, 0, amount*7)
+	case "day":
+		t = t.AddDate(0, 0, amount)
+	case "hour":
+		t = t.Add(time.Duration(amount) * time.Hour)
+	case "minute":
+		t = t.Add(time.Duration(amount) * time.Minute)
+	case "second":
+		t = t.Add(time.Duration(amount) * time.Second)
+	default:
+		return nil, fmt.Errorf("unknown unit [%s]", args[2].String())
+	}
+	return newFormulaDate(t, d.isNotTime), nil
+}
+
+func dateBetweenFormula(t1, t2 time.Time, unit string) (*formulaValue, error) {
+	d := t1.Sub(t2)
+	switch strings.TrimSuffix(strings.ToLower(unit), "s") {
+	case "year":
+		return newFormulaNumber(float64(monthsBetween(t1, t2) / 12)), nil
+	case "quarter":
+		return newFormulaNumber(float64(monthsBetween(t1, t2) / 3)), nil
+	case "month":
+		return newFormulaNumber(float64(monthsBetween(t1, t2))), nil
+	case "week":
+		return newFormulaNumber(math.Trunc(d.Hours() / 24 / 7)), nil
+	case "day":
+		return newFormulaNumber(math.Trunc(d.Hours() / 24)), nil
+	case "hour":
+		return newFormulaNumber(math.Trunc(d.Hours())), nil
+	case "minute":
+		return newFormulaNumber(math.Trunc(d.Minutes())), nil
+	case "second":
+		return newFormulaNumber(math.Trunc(d.Seconds())), nil
+	}
+	return nil, fmt.Errorf("unknown unit [%s]", unit)
+}
+
+func monthsBetween(t1, t2 time.Time) int {
+	sign := 1
+	if t1.Before(t2) {
+		t1, t2 = t2, t1
+		sign = -1
+	}
+
+	months := (t1.Year()-t2.Year())*12 + int(t1.Month()-t2.Month())
+	if t2.AddDate(0, months, 0).After(t1) {
+		months--
+	}
+	return months * sign
+}
+
+// formatFormulaDate 使用 YYYY、MM、DD、HH、mm、ss 格式化日期。
+func formatFormulaDate(t time.Time, layout string) string {
+	replacer := strings.NewReplacer("YYYY", "2006", "MM", "01", "DD", "02", "HH", "15", "mm", "04", "ss", "05")
+	return t.Format(replacer.Replace(layout))
+}
+
+type formulaTokenType int
+
+const (
+	formulaTokenEOF formulaTokenType = iota
+	formulaTokenNumber
+	formulaTokenString
+	formulaTokenIdent
+	formulaTokenOp
+)
+
+type formulaToken struct {
+	typ  formulaTokenType
+	text string
+	pos  int
+}
+
+type formulaLexer struct {
+	src string
+	pos int
+}
+
+func (l *formulaLexer) next() (ret formulaToken, err error) {
+	for l.pos < len(l.src) {
+		r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		l.pos += size
+	}
+	if l.pos >= len(l.src) {
+		ret = formulaToken{typ: formulaTokenEOF, pos: l.pos}
+		return
+	}
+
+	// 按字符解码，这样字段名和标识符可以使用中文等非 ASCII 字符
+	start := l.pos
+	c, size := utf8.DecodeRuneInString(l.src[l.pos:])
+	switch {
+	case '0' <= c && '9' >= c || '.' == c:
+		for l.pos < len(l.src) && ('0' <= l.src[l.pos] && '9' >= l.src[l.pos] || '.' == l.src[l.pos]) {
+			l.pos++
+		}
+		ret = formulaToken{typ: formulaTokenNumber, text: l.src[start:l.pos], pos: start}
+	case '"' == c || '\'' == c:
+		l.pos += size
+		buf := strings.Builder{}
+		for {
+			if l.pos >= len(l.src) {
+				err = fmt.Errorf("unterminated string at %d", start)
+				return
+			}
+			ch, chSize := utf8.DecodeRuneInString(l.src[l.pos:])
+			l.pos += chSize
+			if '\\' == ch && l.pos < len(l.src) {
+				escaped, escapedSize := utf8.DecodeRuneInString(l.src[l.pos:])
+				buf.WriteRune(escaped)
+				l.pos += escapedSize
+				continue
+			}
+			if ch == c {
+				break
+			}
+			buf.WriteRune(ch)
+		}
+		ret = formulaToken{typ: formulaTokenString, text: buf.String(), pos: start}
+	case '_' == c || unicode.IsLetter(c):
+		for l.pos < len(l.src) {
+			r, rSize := utf8.DecodeRuneInString(l.src[l.pos:])
+			if '_' != r && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				break
+			}
+			l.pos += rSize
+		}
+		ret = formulaToken{typ: formulaTokenIdent, text: l.src[start:l.pos], pos: start}
+	default:
+		for _, op := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!", "(", ")", ","} {
+			if strings.HasPrefix(l.src[l.pos:], op) {
+				l.pos += len(op)
+				ret = formulaToken{typ: formulaTokenOp, text: op, pos: start}
+				return
+			}
+		}
+		err = fmt.Errorf("unexpected character [%c] at %d", c, start)
+	}
+	return
+}
+
+type formulaParser struct {
+	lexer *formulaLexer
+	tok   formulaToken
+}
+
+func (p *formulaParser) next() (err error) {
+	p.tok, err = p.lexer.next()
+	return
+}
+
+func (p *formulaParser) expect(op string) (err error) {
+	if formulaTokenOp != p.tok.typ || op != p.tok.text {
+		if formulaTokenEOF == p.tok.typ {
+			return fmt.Errorf("expected [%s] at end of formula", op)
+		}
+		return fmt.Errorf("expected [%s] but got [%s] at %d", op, p.tok.text, p.tok.pos)
+	}
+	return p.next()
+}
+
+var formulaBinaryPrecedences = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+func (p *formulaParser) parseExpr(minPrecedence int) (ret formulaNode, err error) {
+	ret, err = p.parseUnary()
+	if nil != err {
+		return
+	}
+
+	for formulaTokenOp == p.tok.typ {
+		op := p.tok.text
+		precedence, ok := formulaBinaryPrecedences[op]
+		if !ok || precedence <= minPrecedence {
+			break
+		}
+		if err = p.next(); nil != err {
+			return
+		}
+
+		var y formulaNode
+		if y, err = p.parseExpr(precedence); nil != err {
+			return
+		}
+		ret = &formulaBinary{op: op, x: ret, y: y}
+	}
+	return
+}
+
+func (p *formulaParser) parseUnary() (ret formulaNode, err error) {
+	if formulaTokenOp == p.tok.typ && ("!" == p.tok.text || "-" == p.tok.text) {
+		op := p.tok.text
+		if err = p.next(); nil != err {
+			return
+		}
+
+		var x formulaNode
+		if x, err = p.parseUnary(); nil != err {
+			return
+		}
+		ret = &formulaUnary{op: op, x: x}
+		return
+	}
+	return p.parsePrimary()
+}
+
+func (p *formulaParser) parsePrimary() (ret formulaNode, err error) {
+	tok := p.tok
+	switch tok.typ {
+	case formulaTokenNumber:
+		n, parseErr := strconv.ParseFloat(tok.text, 64)
+		if nil != parseErr {
+			err = fmt.Errorf("invalid number [%s] at %d", tok.text, tok.pos)
+			return
+		}
+		ret = &formulaLiteral{val: newFormulaNumber(n)}
+		err = p.next()
+	case formulaTokenString:
+		ret = &formulaLiteral{val: newFormulaText(tok.text)}
+		err = p.next()
+	case formulaTokenIdent:
+		if err = p.next(); nil != err {
+			return
+		}
+
+		switch tok.text {
+		case "true":
+			ret = &formulaLiteral{val: newFormulaBool(true)}
+			return
+		case "false":
+			ret = &formulaLiteral{val: newFormulaBool(false)}
+			return
+		}
+		ret, err = p.parseCall(tok)
+	case formulaTokenOp:
+		if "(" != tok.text {
+			err = fmt.Errorf("unexpected [%s] at %d", tok.text, tok.pos)
+			return
+		}
+		if err = p.next(); nil != err {
+			return
+		}
+		if ret, err = p.parseExpr(0); nil != err {
+			return
+		}
+		err = p.expect(")")
+	default:
+		err = errors.New("unexpected end of formula")
+	}
+	return
+}
+
+func (p *formulaParser) parseCall(name formulaToken) (ret formulaNode, err error) {
+	fn := formulaFuncs[name.text]
+	if nil == fn {
+		err = fmt.Errorf("unknown function [%s] at %d", name.text, name.pos)
+		return
+	}
+
+	if err = p.expect("("); nil != err {
+		return
+	}
+
+	call := &formulaCall{name: name.text}
+	for formulaTokenOp != p.tok.typ || ")" != p.tok.text {
+		if 0 < len(call.args) {
+			if err = p.expect(","); nil != err {
+				return
+			}
+		}
+
+		var arg formulaNode
+		if arg, err = p.parseExpr(0); nil != err {
+			return
+		}
+		call.args = append(call.args, arg)
+	}
+	if err = p.next(); nil != err {
+		return
+	}
+
+	if len(call.args) < fn.minArgs || (-1 != fn.maxArgs && len(call.args) > fn.maxArgs) {
+		err = fmt.Errorf("wrong number of arguments for %s() at %d", name.text, name.pos)
+		return
+	}
+
+	if "prop" == call.name {
+		if lit, ok := call.args[0].(*formulaLiteral); !ok || formulaValueText != lit.val.typ {
+			err = fmt.Errorf("prop() requires a field name string at %d", name.pos)
+			return
+		}
+	}
+	ret = call
+	return
+}
@@ -0,0 +1,226 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+func newFormulaTestAttrView() (attrView *AttributeView, item Item) {
+	itemID := "20240101000000-item001"
+	attrView = &AttributeView{ID: "20240101000000-avtest1"}
+	row := &TableRow{ID: itemID}
+	addKey := func(key *Key, value *Value) {
+		kv := &KeyValues{Key: key}
+		if nil != value {
+			value.KeyID, value.BlockID, value.Type = key.ID, itemID, key.Type
+			kv.Values = append(kv.Values, value)
+			row.Cells = append(row.Cells, &TableCell{BaseValue: &BaseValue{ID: value.ID, Value: value, ValueType: key.Type}})
+		}
+		attrView.KeyValues = append(attrView.KeyValues, kv)
+	}
+
+	addKey(NewKey("20240101000000-keyprc", "价格", "", KeyTypeNumber), &Value{ID: "20240101000000-valprc", Number: &ValueNumber{Content: 12, IsNotEmpty: true}})
+	addKey(NewKey("20240101000000-keyqty", "数量", "", KeyTypeNumber), &Value{ID: "20240101000000-valqty", Number: &ValueNumber{}})
+	addKey(NewKey("20240101000000-keynam", "name", "", KeyTypeText), &Value{ID: "20240101000000-valnam", Text: &ValueText{Content: "SiYuan"}})
+	addKey(NewKey("20240101000000-keyyer", "year", "", KeyTypeNumber), &Value{ID: "20240101000000-valyer", Number: &ValueNumber{Content: 2024, IsNotEmpty: true}})
+	item = row
+	return
+}
+
+func TestFormulaEval(t *testing.T) {
+	attrView, item := newFormulaTestAttrView()
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		expr    string
+		typ     KeyType
+		want    string // 数字、文本和复选框结果的字符串形式，日期结果为 2006-01-02
+		wantErr string // 计算错误包含的内容
+	}{
+		{expr: `1 + 2 * 3`, typ: KeyTypeNumber, want: "7"},
+		{expr: `(1 + 2) * 3 % 4`, typ: KeyTypeNumber, want: "1"},
+		{expr: `prop("价格") * 2`, typ: KeyTypeNumber, want: "24"},
+		{expr: `if(prop("价格") > 10, "贵", "便宜")`, typ: KeyTypeText, want: "贵"},
+		{expr: `concat(prop("name"), "-", "笔记")`, typ: KeyTypeText, want: "SiYuan-笔记"},
+		{expr: `length("思源笔记")`, typ: KeyTypeNumber, want: "4"},
+		{expr: `upper(prop("name"))`, typ: KeyTypeText, want: "SIYUAN"},
+		{expr: `contains(prop("name"), "Yu") && !empty(prop("name"))`, typ: KeyTypeCheckbox, want: "true"},
+		{expr: `empty(prop("数量"))`, typ: KeyTypeCheckbox, want: "true"},
+		{expr: `sum(1, 2, 3) + max(4, 9) - min(5, 2)`, typ: KeyTypeNumber, want: "13"},
+		{expr: `formatDate(date(prop("year"), 2, 29), "2006-01-02")`, typ: KeyTypeText, want: "2024-02-29"},
+		{expr: `dateAdd(date(2024, 1, 31), 1, "days")`, typ: KeyTypeDate, want: "2024-02-01"},
+		{expr: `dateBetween(today(), date(2024, 3, 1), "days")`, typ: KeyTypeNumber, want: "14"},
+		{expr: `date(prop("year"), prop("数量"), 1)`, wantErr: "year, month and day are required"},
+		{expr: `1 / 0`, wantErr: "division by zero"},
+		{expr: `prop("missing")`, wantErr: "not found"},
+		{expr: `"a" < 1`, wantErr: "cannot compare"},
+	}
+
+	for _, test := range tests {
+		expr, err := ParseFormula(test.expr)
+		if nil != err {
+			t.Fatalf("parse formula [%s] failed: %s", test.expr, err)
+		}
+
+		result := expr.Eval(attrView, item, NumberFormatNone, now)
+		if "" != test.wantErr {
+			if !strings.Contains(result.Err, test.wantErr) {
+				t.Errorf("formula [%s] error expected to contain [%s], got [%s]", test.expr, test.wantErr, result.Err)
+			}
+			continue
+		}
+		if "" != result.Err {
+			t.Errorf("formula [%s] failed: %s", test.expr, result.Err)
+			continue
+		}
+		if test.typ != result.Type {
+			t.Errorf("formula [%s] result type expected [%s], got [%s]", test.expr, test.typ, result.Type)
+			continue
+		}
+
+		var got string
+		switch result.Type {
+		case KeyTypeNumber:
+			got = strconv.FormatFloat(result.Number.Content, 'f', -1, 64)
+		case KeyTypeText:
+			got = result.Text.Content
+		case KeyTypeCheckbox:
+			got = "false"
+			if result.Checkbox.Checked {
+				got = "true"
+			}
+		case KeyTypeDate:
+			got = time.UnixMilli(result.Date.Content).Format("2006-01-02")
+		}
+		if test.want != got {
+			t.Errorf("formula [%s] expected [%s], got [%s]", test.expr, test.want, got)
+		}
+	}
+}
+
+func TestParseFormulaErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{expr: ``, wantErr: "formula is empty"},
+		{expr: `1 +`, wantErr: "unexpected end of formula"},
+		{expr: `(1 + 2`, wantErr: "expected [)]"},
+		{expr: `"abc`, wantErr: "unterminated string at 0"},
+		{expr: `foo(1)`, wantErr: "unknown function [foo] at 0"},
+		{expr: `date(1, 2)`, wantErr: "wrong number of arguments for date()"},
+		{expr: `1 @ 2`, wantErr: "unexpected character [@] at 2"},
+		{expr: `价格 + 1`, wantErr: "unknown function [价格]"},
+		{expr: `prop("价格") # 1`, wantErr: "unexpected character [#] at 15"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseFormula(test.expr)
+		if nil == err {
+			t.Errorf("formula [%s] expected error [%s]", test.expr, test.wantErr)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("formula [%s] error expected to contain [%s], got [%s]", test.expr, test.wantErr, err)
+		}
+	}
+}
+
+func TestParseFormulaRefKeyNames(t *testing.T) {
+	expr, err := ParseFormula(`prop("价格") * prop('数量') + length(prop("名称\"引号"))`)
+	if nil != err {
+		t.Fatalf("parse formula failed: %s", err)
+	}
+
+	names := expr.GetRefKeyNames()
+	want := []string{"价格", "数量", "名称\"引号"}
+	if len(want) != len(names) {
+		t.Fatalf("ref key names expected %v, got %v", want, names)
+	}
+	for i := range want {
+		if want[i] != names[i] {
+			t.Errorf("ref key name expected [%s], got [%s]", want[i], names[i])
+		}
+	}
+}
+
+func TestGetFormulaKeysByResolutionOrder(t *testing.T) {
+	util.DataDir = t.TempDir()
+
+	newFormulaKey := func(id, name, expression string) *Key {
+		key := NewKey(id, name, "", KeyTypeFormula)
+		key.Formula = &Formula{Expression: expression}
+		return key
+	}
+
+	// 当前数据库：a 依赖 b，c 和 d 互相依赖，e 依赖 c，f 汇总另一个数据库的公式 g，g 又汇总当前数据库的 h，h 依赖 f
+	otherAvID := "20240101000000-avtest2"
+	attrView := &AttributeView{ID: "20240101000000-avtest1"}
+	relKey := NewKey("20240101000000-keyrel", "rel", "", KeyTypeRelation)
+	relKey.Relation = &Relation{AvID: otherAvID}
+	rollupKey := NewKey("20240101000000-keyrlp", "rollup", "", KeyTypeRollup)
+	rollupKey.Rollup = &Rollup{RelationKeyID: relKey.ID, KeyID: "20240101000000-keyg00"}
+	for _, key := range []*Key{
+		newFormulaKey("20240101000000-keya00", "a", `prop("b") + 1`),
+		newFormulaKey("20240101000000-keyb00", "b", `1`),
+		newFormulaKey("20240101000000-keyc00", "c", `prop("d")`),
+		newFormulaKey("20240101000000-keyd00", "d", `prop("c")`),
+		newFormulaKey("20240101000000-keye00", "e", `prop("c") * 2`),
+		relKey,
+		rollupKey,
+		newFormulaKey("20240101000000-keyf00", "f", `prop("rollup")`),
+		newFormulaKey("20240101000000-keyh00", "h", `prop("f")`),
+	} {
+		attrView.KeyValues = append(attrView.KeyValues, &KeyValues{Key: key})
+	}
+
+	otherAv := &AttributeView{ID: otherAvID}
+	otherRelKey := NewKey("20240101000000-keyore", "rel", "", KeyTypeRelation)
+	otherRelKey.Relation = &Relation{AvID: attrView.ID}
+	otherRollupKey := NewKey("20240101000000-keyorl", "rollup", "", KeyTypeRollup)
+	otherRollupKey.Rollup = &Rollup{RelationKeyID: otherRelKey.ID, KeyID: "20240101000000-keyh00"}
+	for _, key := range []*Key{NewKey("20240101000000-keyblk", "block", "", KeyTypeBlock), otherRelKey, otherRollupKey, newFormulaKey("20240101000000-keyg00", "g", `prop("rollup")`)} {
+		otherAv.KeyValues = append(otherAv.KeyValues, &KeyValues{Key: key})
+	}
+	if err := SaveAttributeView(otherAv); nil != err {
+		t.Fatalf("save attribute view failed: %s", err)
+	}
+
+	ret, cycleKeys := GetFormulaKeysByResolutionOrder(attrView)
+	var order, cycles []string
+	for _, key := range ret {
+		order = append(order, key.Name)
+	}
+	for _, key := range cycleKeys {
+		cycles = append(cycles, key.Name)
+	}
+
+	if "b,a" != strings.Join(order, ",") {
+		t.Errorf("resolution order expected [b,a], got %v", order)
+	}
+	sort.Strings(cycles)
+	if "c,d,e,f,h" != strings.Join(cycles, ",") {
+		t.Errorf("cycle keys expected [c,d,e,f,h], got %v", cycles)
+	}
+}
@@ -84,6 +84,12 @@ func NewViewBaseInstance(view *View) *BaseInstance {
 	case LayoutTypeKanban:
 		showIcon = view.Kanban.ShowIcon
 		wrapField = view.Kanban.WrapField
+	case LayoutTypeCalendar:
+		showIcon = view.Calendar.ShowIcon
+		wrapField = view.Calendar.WrapField
+	case LayoutTypeTimeline:
+		showIcon = view.Timeline.ShowIcon
+		wrapField = view.Timeline.WrapField
 	}
 	return &BaseInstance{
 		ID:               view.ID,
@@ -158,6 +164,7 @@ type BaseInstanceField struct {
 	Template     string          `json:"template"`           // 模板字段内容
 	Relation     *Relation       `json:"relation,omitempty"` // 关联字段
 	Rollup       *Rollup         `json:"rollup,omitempty"`   // 汇总字段
+	Formula      *Formula        `json:"formula,omitempty"`  // 公式字段
 	Date         *Date           `json:"date,omitempty"`     // 日期设置
 	Created      *Created        `json:"created,omitempty"`  // 创建时间设置
 	Updated      *Updated        `json:"updated,omitempty"`  // 更新时间设置
@@ -0,0 +1,274 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+import (
+	"sort"
+	"time"
+
+	"github.com/88250/lute/ast"
+)
+
+// LayoutCalendar 描述了日历布局的结构。
+type LayoutCalendar struct {
+	*BaseLayout
+
+	DateFieldID      string       `json:"dateFieldID"`      // 日期字段 ID，支持日期、创建时间和更新时间字段
+	Mode             CalendarMode `json:"mode"`             // 日历模式，0：月，1：周
+	StartWeekday     int          `json:"startWeekday"`     // 每周起始日，0：周日，1：周一
+	Anchor           int64        `json:"anchor"`           // 当前显示的日期（毫秒时间戳），为 0 时显示今天所在的月或周
+	DisplayFieldName bool         `json:"displayFieldName"` // 是否显示字段名称
+
+	Fields []*ViewCalendarField `json:"fields"` // 字段
+}
+
+func NewLayoutCalendar() *LayoutCalendar {
+	return &LayoutCalendar{
+		BaseLayout: &BaseLayout{
+			Spec:     0,
+			ID:       ast.NewNodeID(),
+			ShowIcon: true,
+		},
+		Mode:         CalendarModeMonth,
+		StartWeekday: 1,
+	}
+}
+
+// CalendarMode 描述了日历模式。
+type CalendarMode int
+
+const (
+	CalendarModeMonth CalendarMode = iota // 月
+	CalendarModeWeek                      // 周
+)
+
+// ViewCalendarField 描述了日历字段的结构。
+type ViewCalendarField struct {
+	*BaseField
+}
+
+// Calendar 描述了日历视图实例的结构。
+type Calendar struct {
+	*BaseInstance
+
+	DateFieldID      string           `json:"dateFieldID"`      // 日期字段 ID
+	Mode             CalendarMode     `json:"mode"`             // 日历模式
+	StartWeekday     int              `json:"startWeekday"`     // 每周起始日
+	Anchor           int64            `json:"anchor"`           // 当前显示的日期
+	DisplayFieldName bool             `json:"displayFieldName"` // 是否显示字段名称
+	Fields           []*CalendarField `json:"fields"`           // 事项字段
+	Events           []*CalendarEvent `json:"events"`           // 事项
+	EventCount       int              `json:"eventCount"`       // 总事项数
+	Days             []*CalendarDay   `json:"days"`             // 日历格子
+}
+
+// CalendarEvent 描述了日历实例事项的结构。
+type CalendarEvent struct {
+	ID     string                `json:"id"`     // 事项 ID
+	Values []*CalendarFieldValue `json:"values"` // 事项字段值
+
+	Start     int64 `json:"start"`     // 开始时间（毫秒时间戳）
+	End       int64 `json:"end"`       // 结束时间（毫秒时间戳），没有结束时间时和开始时间相同
+	IsNotTime bool  `json:"isNotTime"` // 是否为全天事项
+}
+
+// CalendarDay 描述了日历格子的结构。
+type CalendarDay struct {
+	Date     string   `json:"date"`     // 日期，格式为 2006-01-02
+	InPeriod bool     `json:"inPeriod"` // 是否在当前显示的月份内（月模式下前后补齐的日期为 false）
+	EventIDs []string `json:"eventIds"` // 当天的事项 ID
+}
+
+// CalendarField 描述了日历实例字段的结构。
+type CalendarField struct {
+	*BaseInstanceField
+}
+
+// CalendarFieldValue 描述了事项字段实例值的结构。
+type CalendarFieldValue struct {
+	*BaseValue
+}
+
+func (event *CalendarEvent) GetID() string {
+	return event.ID
+}
+
+func (event *CalendarEvent) GetBlockValue() (ret *Value) {
+	for _, v := range event.Values {
+		if KeyTypeBlock == v.ValueType {
+			ret = v.Value
+			break
+		}
+	}
+	return
+}
+
+func (event *CalendarEvent) GetValues() (ret []*Value) {
+	ret = []*Value{}
+	for _, v := range event.Values {
+		ret = append(ret, v.Value)
+	}
+	return
+}
+
+func (event *CalendarEvent) GetValue(keyID string) (ret *Value) {
+	for _, value := range event.Values {
+		if nil != value.Value && keyID == value.Value.KeyID {
+			ret = value.Value
+			break
+		}
+	}
+	return
+}
+
+// SetTime 根据日期字段值设置事项的开始和结束时间，返回 false 表示该值没有日期。
+func (event *CalendarEvent) SetTime(dateValue *Value) (ok bool) {
+	event.Start, event.End, event.IsNotTime, ok = GetDateRange(dateValue)
+	return
+}
+
+func (calendar *Calendar) GetItems() (ret []Item) {
+	ret = []Item{}
+	for _, event := range calendar.Events {
+		ret = append(ret, event)
+	}
+	return
+}
+
+func (calendar *Calendar) SetItems(items []Item) {
+	calendar.Events = []*CalendarEvent{}
+	for _, item := range items {
+		calendar.Events = append(calendar.Events, item.(*CalendarEvent))
+	}
+}
+
+func (calendar *Calendar) CountItems() int {
+	return len(calendar.Events)
+}
+
+func (calendar *Calendar) GetFields() (ret []Field) {
+	ret = []Field{}
+	for _, field := range calendar.Fields {
+		ret = append(ret, field)
+	}
+	return ret
+}
+
+func (calendar *Calendar) GetField(id string) (ret Field, fieldIndex int) {
+	for i, field := range calendar.Fields {
+		if field.ID == id {
+			return field, i
+		}
+	}
+	return nil, -1
+}
+
+func (calendar *Calendar) GetValue(itemID, keyID string) (ret *Value) {
+	for _, event := range calendar.Events {
+		if event.ID == itemID {
+			return event.GetValue(keyID)
+		}
+	}
+	return nil
+}
+
+func (calendar *Calendar) GetType() LayoutType {
+	return LayoutTypeCalendar
+}
+
+// GetPeriod 返回当前显示的日期范围 [start, end)，月模式下会按周补齐首尾。
+func (calendar *Calendar) GetPeriod() (start, end time.Time, month time.Month) {
+	anchor := time.Now()
+	if 0 < calendar.Anchor {
+		anchor = time.UnixMilli(calendar.Anchor)
+	}
+	anchor = time.Date(anchor.Year(), anchor.Month(), anchor.Day(), 0, 0, 0, 0, time.Local)
+	month = anchor.Month()
+
+	weekStart := func(t time.Time) time.Time {
+		offset := (int(t.Weekday()) - calendar.StartWeekday + 7) % 7
+		return t.AddDate(0, 0, -offset)
+	}
+
+	switch calendar.Mode {
+	case CalendarModeWeek:
+		start = weekStart(anchor)
+		end = start.AddDate(0, 0, 7)
+	default:
+		firstDay := time.Date(anchor.Year(), anchor.Month(), 1, 0, 0, 0, 0, time.Local)
+		lastDay := firstDay.AddDate(0, 1, -1)
+		start = weekStart(firstDay)
+		end = weekStart(lastDay).AddDate(0, 0, 7)
+	}
+	return
+}
+
+// BuildDays 生成日历格子并将事项放入其覆盖的每一天，不在当前显示范围内的事项会被移除。
+func (calendar *Calendar) BuildDays() {
+	start, end, month := calendar.GetPeriod()
+	startMills, endMills := start.UnixMilli(), end.UnixMilli()
+
+	var events []*CalendarEvent
+	for _, event := range calendar.Events {
+		if 0 == event.Start && 0 == event.End {
+			continue
+		}
+		if event.End < startMills || event.Start >= endMills {
+			continue
+		}
+		events = append(events, event)
+	}
+	if nil == events {
+		events = []*CalendarEvent{}
+	}
+	calendar.Events = events
+
+	calendar.Days = []*CalendarDay{}
+	dayIndexes := map[string]int{}
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		dayIndexes[date] = len(calendar.Days)
+		inPeriod := CalendarModeWeek == calendar.Mode || d.Month() == month
+		calendar.Days = append(calendar.Days, &CalendarDay{Date: date, InPeriod: inPeriod, EventIDs: []string{}})
+	}
+
+	// 同一天内的事项保持排序后的顺序，跨天事项排在前面
+	sorted := make([]*CalendarEvent, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		iMulti, jMulti := isMultiDayEvent(sorted[i]), isMultiDayEvent(sorted[j])
+		return iMulti && !jMulti
+	})
+
+	for _, event := range sorted {
+		eventStart := time.UnixMilli(event.Start)
+		eventStart = time.Date(eventStart.Year(), eventStart.Month(), eventStart.Day(), 0, 0, 0, 0, time.Local)
+		if eventStart.Before(start) {
+			eventStart = start
+		}
+		eventEnd := time.UnixMilli(event.End)
+		for d := eventStart; !d.After(eventEnd) && d.Before(end); d = d.AddDate(0, 0, 1) {
+			if i, ok := dayIndexes[d.Format("2006-01-02")]; ok {
+				calendar.Days[i].EventIDs = append(calendar.Days[i].EventIDs, event.ID)
+			}
+		}
+	}
+}
+
+func isMultiDayEvent(event *CalendarEvent) bool {
+	return time.UnixMilli(event.Start).Format("2006-01-02") != time.UnixMilli(event.End).Format("2006-01-02")
+}
@@ -0,0 +1,200 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+import (
+	"github.com/88250/lute/ast"
+)
+
+// LayoutTimeline 描述了时间线（甘特图）布局的结构。
+type LayoutTimeline struct {
+	*BaseLayout
+
+	DateFieldID       string        `json:"dateFieldID"`                 // 日期字段 ID，使用日期的开始和结束时间作为时间条的起止
+	DependencyFieldID string        `json:"dependencyFieldID,omitempty"` // 依赖字段 ID，必须是关联到当前数据库的关联字段
+	Scale             TimelineScale `json:"scale"`                       // 时间刻度，0：日，1：周，2：月，3：季度，4：年
+	DisplayFieldName  bool          `json:"displayFieldName"`            // 是否显示字段名称
+
+	Fields []*ViewTimelineField `json:"fields"` // 字段
+}
+
+func NewLayoutTimeline() *LayoutTimeline {
+	return &LayoutTimeline{
+		BaseLayout: &BaseLayout{
+			Spec:     0,
+			ID:       ast.NewNodeID(),
+			ShowIcon: true,
+		},
+		Scale: TimelineScaleWeek,
+	}
+}
+
+// TimelineScale 描述了时间线的时间刻度。
+type TimelineScale int
+
+const (
+	TimelineScaleDay     TimelineScale = iota // 日
+	TimelineScaleWeek                         // 周
+	TimelineScaleMonth                        // 月
+	TimelineScaleQuarter                      // 季度
+	TimelineScaleYear                         // 年
+)
+
+// ViewTimelineField 描述了时间线字段的结构。
+type ViewTimelineField struct {
+	*BaseField
+}
+
+// Timeline 描述了时间线视图实例的结构。
+type Timeline struct {
+	*BaseInstance
+
+	DateFieldID       string           `json:"dateFieldID"`                 // 日期字段 ID
+	DependencyFieldID string           `json:"dependencyFieldID,omitempty"` // 依赖字段 ID
+	Scale             TimelineScale    `json:"scale"`                       // 时间刻度
+	DisplayFieldName  bool             `json:"displayFieldName"`            // 是否显示字段名称
+	Fields            []*TimelineField `json:"fields"`                      // 条目字段
+	Items             []*TimelineItem  `json:"items"`                       // 条目
+	ItemCount         int              `json:"itemCount"`                   // 总条目数
+	Start             int64            `json:"start"`                       // 当前页条目的最早开始时间（毫秒时间戳），没有条目时为 0
+	End               int64            `json:"end"`                         // 当前页条目的最晚结束时间（毫秒时间戳），没有条目时为 0
+}
+
+// TimelineItem 描述了时间线实例条目的结构。
+type TimelineItem struct {
+	ID     string                `json:"id"`     // 条目 ID
+	Values []*TimelineFieldValue `json:"values"` // 条目字段值
+
+	Start        int64    `json:"start"`        // 开始时间（毫秒时间戳），没有日期时为 0
+	End          int64    `json:"end"`          // 结束时间（毫秒时间戳），没有结束时间时和开始时间相同
+	IsNotTime    bool     `json:"isNotTime"`    // 是否只有日期没有时间
+	Dependencies []string `json:"dependencies"` // 依赖的条目 ID，即该条目需要在这些条目之后进行
+}
+
+// TimelineField 描述了时间线实例字段的结构。
+type TimelineField struct {
+	*BaseInstanceField
+}
+
+// TimelineFieldValue 描述了条目字段实例值的结构。
+type TimelineFieldValue struct {
+	*BaseValue
+}
+
+func (item *TimelineItem) GetID() string {
+	return item.ID
+}
+
+func (item *TimelineItem) GetBlockValue() (ret *Value) {
+	for _, v := range item.Values {
+		if KeyTypeBlock == v.ValueType {
+			ret = v.Value
+			break
+		}
+	}
+	return
+}
+
+func (item *TimelineItem) GetValues() (ret []*Value) {
+	ret = []*Value{}
+	for _, v := range item.Values {
+		ret = append(ret, v.Value)
+	}
+	return
+}
+
+func (item *TimelineItem) GetValue(keyID string) (ret *Value) {
+	for _, value := range item.Values {
+		if nil != value.Value && keyID == value.Value.KeyID {
+			ret = value.Value
+			break
+		}
+	}
+	return
+}
+
+// SetTime 根据日期字段值设置条目的开始和结束时间，返回 false 表示该值没有日期。
+func (item *TimelineItem) SetTime(dateValue *Value) (ok bool) {
+	item.Start, item.End, item.IsNotTime, ok = GetDateRange(dateValue)
+	return
+}
+
+func (timeline *Timeline) GetItems() (ret []Item) {
+	ret = []Item{}
+	for _, item := range timeline.Items {
+		ret = append(ret, item)
+	}
+	return
+}
+
+func (timeline *Timeline) SetItems(items []Item) {
+	timeline.Items = []*TimelineItem{}
+	for _, item := range items {
+		timeline.Items = append(timeline.Items, item.(*TimelineItem))
+	}
+}
+
+func (timeline *Timeline) CountItems() int {
+	return len(timeline.Items)
+}
+
+func (timeline *Timeline) GetFields() (ret []Field) {
+	ret = []Field{}
+	for _, field := range timeline.Fields {
+		ret = append(ret, field)
+	}
+	return ret
+}
+
+func (timeline *Timeline) GetField(id string) (ret Field, fieldIndex int) {
+	for i, field := range timeline.Fields {
+		if field.ID == id {
+			return field, i
+		}
+	}
+	return nil, -1
+}
+
+func (timeline *Timeline) GetValue(itemID, keyID string) (ret *Value) {
+	for _, item := range timeline.Items {
+		if item.ID == itemID {
+			return item.GetValue(keyID)
+		}
+	}
+	return nil
+}
+
+func (timeline *Timeline) GetType() LayoutType {
+	return LayoutTypeTimeline
+}
+
+// CalcRange 计算当前条目的最早开始时间和最晚结束时间，没有日期的条目不参与计算。
+func (timeline *Timeline) CalcRange() {
+	timeline.Start, timeline.End = 0, 0
+	for _, item := range timeline.Items {
+		if 0 == item.Start && 0 == item.End {
+			continue
+		}
+
+		if 0 == timeline.Start || item.Start < timeline.Start {
+			timeline.Start = item.Start
+		}
+		if item.End > timeline.End {
+			timeline.End = item.End
+		}
+	}
+}
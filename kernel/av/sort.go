@@ -466,6 +466,23 @@ func (value *Value) Compare(other *Value, attrView *AttributeView) int {
 				return 0
 			}
 
+			if util.EmojiPinYinCompare(vContent, oContent) {
+				return -1
+			}
+			return 1
+		}
+	case KeyTypeFormula:
+		if nil != value.Formula && nil != other.Formula {
+			v1, v2 := value.Formula.GetResultValue(), other.Formula.GetResultValue()
+			if nil != v1 && nil != v2 && v1.Type == v2.Type {
+				return v1.Compare(v2, attrView)
+			}
+
+			vContent, oContent := value.Formula.String(true), other.Formula.String(true)
+			if 0 == strings.Compare(vContent, oContent) {
+				return 0
+			}
+
 			if util.EmojiPinYinCompare(vContent, oContent) {
 				return -1
 			}
@@ -57,6 +57,7 @@ type Value struct {
 	Checkbox *ValueCheckbox `json:"checkbox,omitempty"`
 	Relation *ValueRelation `json:"relation,omitempty"`
 	Rollup   *ValueRollup   `json:"rollup,omitempty"`
+	Formula  *ValueFormula  `json:"formula,omitempty"`
 
 	IsRenderAutoFill bool `json:"-"` // 标识是否是渲染阶段自动填充的值，保存数据的时候要删掉
 }
@@ -179,6 +180,11 @@ func (value *Value) String(format bool) string {
 			ret = append(ret, v.String(format))
 		}
 		return strings.TrimSpace(strings.Join(ret, ", "))
+	case KeyTypeFormula:
+		if nil == value.Formula {
+			return ""
+		}
+		return value.Formula.String(format)
 	default:
 		return ""
 	}
@@ -299,6 +305,11 @@ func (value *Value) IsBlank() bool {
 		return 1 > len(value.Relation.Contents)
 	case KeyTypeRollup:
 		return 1 > len(value.Rollup.Contents)
+	case KeyTypeFormula:
+		if nil == value.Formula {
+			return true
+		}
+		return value.Formula.IsEmpty()
 	}
 	return false
 }
@@ -377,6 +388,11 @@ func (value *Value) IsEmpty() bool {
 		return 1 > len(value.Relation.Contents)
 	case KeyTypeRollup:
 		return 1 > len(value.Rollup.Contents)
+	case KeyTypeFormula:
+		if nil == value.Formula {
+			return true
+		}
+		return value.Formula.IsEmpty()
 	}
 	return false
 }
@@ -415,6 +431,8 @@ func (value *Value) SetValByType(typ KeyType, val interface{}) {
 		value.Relation = val.(*ValueRelation)
 	case KeyTypeRollup:
 		value.Rollup = val.(*ValueRollup)
+	case KeyTypeFormula:
+		value.Formula = val.(*ValueFormula)
 	}
 }
 
@@ -460,6 +478,8 @@ func (value *Value) GetValByType(typ KeyType) (ret interface{}) {
 		return value.Relation
 	case KeyTypeRollup:
 		return value.Rollup
+	case KeyTypeFormula:
+		return value.Formula
 	}
 	return
 }
@@ -620,6 +640,39 @@ type ValueDate struct {
 	FormattedContent string `json:"formattedContent"`
 }
 
+// GetDateRange 获取日期、创建时间或更新时间字段值的起止时间（毫秒时间戳），没有结束时间时 end 和 start 相同。
+func GetDateRange(value *Value) (start, end int64, isNotTime, ok bool) {
+	if nil == value {
+		return
+	}
+
+	switch value.Type {
+	case KeyTypeDate:
+		if nil == value.Date || !value.Date.IsNotEmpty {
+			return
+		}
+		start, end = value.Date.Content, value.Date.Content
+		if value.Date.HasEndDate && value.Date.IsNotEmpty2 && value.Date.Content2 > value.Date.Content {
+			end = value.Date.Content2
+		}
+		isNotTime = value.Date.IsNotTime
+	case KeyTypeCreated:
+		if nil == value.Created || !value.Created.IsNotEmpty {
+			return
+		}
+		start, end = value.Created.Content, value.Created.Content
+	case KeyTypeUpdated:
+		if nil == value.Updated || !value.Updated.IsNotEmpty {
+			return
+		}
+		start, end = value.Updated.Content, value.Updated.Content
+	default:
+		return
+	}
+	ok = true
+	return
+}
+
 type DateFormat string
 
 const (
@@ -745,6 +798,36 @@ type ValueTemplate struct {
 	Content string `json:"content"`
 }
 
+// ValueFormula 描述了公式字段值的结构，计算结果的类型可能是数字、日期、文本或复选框。
+type ValueFormula struct {
+	Type     KeyType        `json:"type"` // 计算结果类型，为空时表示没有结果
+	Number   *ValueNumber   `json:"number,omitempty"`
+	Date     *ValueDate     `json:"date,omitempty"`
+	Text     *ValueText     `json:"text,omitempty"`
+	Checkbox *ValueCheckbox `json:"checkbox,omitempty"`
+	Err      string         `json:"err,omitempty"` // 计算出错时的错误信息
+}
+
+// GetResultValue 将计算结果转换为对应类型的字段值，以便复用该类型的排序、过滤和计算逻辑。
+func (f *ValueFormula) GetResultValue() (ret *Value) {
+	ret = &Value{Type: f.Type, Number: f.Number, Date: f.Date, Text: f.Text, Checkbox: f.Checkbox}
+	return
+}
+
+func (f *ValueFormula) String(format bool) string {
+	if "" != f.Err {
+		return ""
+	}
+	return f.GetResultValue().String(format)
+}
+
+func (f *ValueFormula) IsEmpty() bool {
+	if "" != f.Err || "" == f.Type {
+		return true
+	}
+	return f.GetResultValue().IsEmpty()
+}
+
 type ValueCreated struct {
 	Content          int64  `json:"content"`
 	IsNotEmpty       bool   `json:"isNotEmpty"`
@@ -844,7 +927,7 @@ func (r *ValueRollup) BuildContents(keyValues []*KeyValues, destKey *Key, relati
 	r.Contents = nil
 	for _, blockID := range relationVal.Relation.BlockIDs {
 		destVal := GetValue(keyValues, destKey.ID, blockID)
-		if nil != furtherCollection && (KeyTypeTemplate == destKey.Type || KeyTypeUpdated == destKey.Type || KeyTypeCreated == destKey.Type) {
+		if nil != furtherCollection && (KeyTypeTemplate == destKey.Type || KeyTypeFormula == destKey.Type || KeyTypeUpdated == destKey.Type || KeyTypeCreated == destKey.Type) {
 			destVal = furtherCollection.GetValue(blockID, destKey.ID)
 		}
 
@@ -1361,6 +1444,8 @@ func GetAttributeViewDefaultValue(valueID, keyID, blockID string, typ KeyType, k
 		ret.Relation = &ValueRelation{}
 	case KeyTypeRollup:
 		ret.Rollup = &ValueRollup{}
+	case KeyTypeFormula:
+		ret.Formula = &ValueFormula{}
 	}
 	return
 }
@@ -0,0 +1,42 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package conf
+
+// AccessRole 描述了 API token 和工作空间用户的访问角色。
+type AccessRole string
+
+const (
+	AccessRoleAdmin  AccessRole = "admin"  // 管理员
+	AccessRoleEditor AccessRole = "editor" // 编辑者
+	AccessRoleReader AccessRole = "reader" // 读者
+)
+
+func (role AccessRole) IsValid() bool {
+	return AccessRoleAdmin == role || AccessRoleEditor == role || AccessRoleReader == role
+}
+
+// WorkspaceUser 描述了伺服时登录工作空间的用户。
+type WorkspaceUser struct {
+	ID        string                `json:"id"`        // ID
+	Name      string                `json:"name"`      // 用户名，登录时使用
+	Password  string                `json:"password"`  // 密码哈希值，格式为 pbkdf2-sha256$迭代次数$盐$哈希值
+	Role      AccessRole            `json:"role"`      // 默认角色，用于不涉及笔记本的接口
+	Notebooks map[string]AccessRole `json:"notebooks"` // 笔记本 ID 到角色的映射，为空时可以按默认角色访问所有笔记本，不为空时只能访问其中的笔记本
+	Disabled  bool                  `json:"disabled"`  // 是否禁用
+	Created   int64                 `json:"created"`   // 创建时间（毫秒时间戳）
+	Updated   int64                 `json:"updated"`   // 更新时间（毫秒时间戳）
+}
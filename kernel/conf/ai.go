@@ -41,6 +41,8 @@ type OpenAI struct {
 	APIUserAgent   string  `json:"apiUserAgent"`
 	APIProvider    string  `json:"apiProvider"` // OpenAI, Azure
 	APIVersion     string  `json:"apiVersion"`  // Azure API version
+
+	APIEmbeddingModel string `json:"apiEmbeddingModel"` // 语义搜索使用的嵌入模型
 }
 
 func NewAI() *AI {
@@ -52,6 +54,8 @@ func NewAI() *AI {
 		APIBaseURL:     "https://api.openai.com/v1",
 		APIUserAgent:   util.UserAgent,
 		APIProvider:    "OpenAI",
+
+		APIEmbeddingModel: string(openai.SmallEmbedding3),
 	}
 
 	openAI.APIKey = os.Getenv("SIYUAN_OPENAI_API_KEY")
@@ -95,5 +99,9 @@ func NewAI() *AI {
 	if userAgent := os.Getenv("SIYUAN_OPENAI_API_USER_AGENT"); "" != userAgent {
 		openAI.APIUserAgent = userAgent
 	}
+
+	if embeddingModel := os.Getenv("SIYUAN_OPENAI_API_EMBEDDING_MODEL"); "" != embeddingModel {
+		openAI.APIEmbeddingModel = embeddingModel
+	}
 	return &AI{OpenAI: openAI}
 }
@@ -19,11 +19,26 @@ package conf
 import "github.com/88250/gulu"
 
 type API struct {
-	Token string `json:"token"`
+	Token  string      `json:"token"`  // 管理员 token，拥有所有权限
+	Tokens []*APIToken `json:"tokens"` // 具名 token，可以限制角色、笔记本和接口路径
 }
 
 func NewAPI() *API {
 	return &API{
-		Token: gulu.Rand.String(16),
+		Token:  gulu.Rand.String(16),
+		Tokens: []*APIToken{},
 	}
 }
+
+// APIToken 描述了具名 API token 的结构。
+type APIToken struct {
+	ID        string     `json:"id"`        // ID
+	Name      string     `json:"name"`      // 名称，用于区分不同的脚本或集成
+	Hash      string     `json:"hash"`      // token 的 SHA-256 哈希值，token 本身只在创建时返回一次
+	Role      AccessRole `json:"role"`      // 角色
+	Notebooks []string   `json:"notebooks"` // 允许访问的笔记本 ID，为空时不限制
+	Paths     []string   `json:"paths"`     // 允许访问的接口路径前缀，比如 /api/block/，为空时不限制
+	Expired   int64      `json:"expired"`   // 过期时间（毫秒时间戳），为 0 时不过期
+	Created   int64      `json:"created"`   // 创建时间（毫秒时间戳）
+	LastUsed  int64      `json:"lastUsed"`  // 最后使用时间（毫秒时间戳）
+}
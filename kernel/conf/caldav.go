@@ -0,0 +1,37 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package conf
+
+type CalDAV struct {
+	Reminders      bool     `json:"reminders"`      // 是否通过 CalDAV 提供块提醒日历
+	AttributeViews []string `json:"attributeViews"` // 通过 CalDAV 提供日期字段日历的属性视图 ID 列表
+	Tasks          bool     `json:"tasks"`          // 是否通过 CalDAV 提供任务列表项待办
+	TaskNotebooks  []string `json:"taskNotebooks"`  // 待办只包含这些笔记本中的任务列表项，为空时不限制
+	TaskTags       []string `json:"taskTags"`       // 待办只包含带有这些标签的任务列表项，为空时不限制
+	TaskDueAttr    string   `json:"taskDueAttr"`    // 待办截止时间使用的块属性名
+}
+
+func NewCalDAV() *CalDAV {
+	return &CalDAV{
+		Reminders:      true,
+		AttributeViews: []string{},
+		Tasks:          true,
+		TaskNotebooks:  []string{},
+		TaskTags:       []string{},
+		TaskDueAttr:    "custom-due",
+	}
+}
@@ -0,0 +1,28 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package conf
+
+type Reminder struct {
+	Local   bool   `json:"local"`   // 是否启用本地提醒调度，启用后不需要订阅也可以使用块提醒，订阅用户的首次提醒仍由云端通知
+	Webhook string `json:"webhook"` // 提醒到期时 POST 通知的 Webhook 地址，为空时不调用
+}
+
+func NewReminder() *Reminder {
+	return &Reminder{
+		Local: true,
+	}
+}
@@ -54,6 +54,24 @@ type Search struct {
 
 	IndexAssetPath bool `json:"indexAssetPath"`
 
+	Fuzzy     bool   `json:"fuzzy"`     // 关键字搜索时是否同时搜索拼写相近的词
+	Tokenizer string `json:"tokenizer"` // 全文索引分词器：siyuan（默认，中日韩单字分词）、porter（英文词干提取）、dict（基于词典的中日韩分词）、trigram（n-gram），修改后需要重建索引
+
+	// 按相关度排序时各字段的 BM25 权重，权重越大该字段命中时越靠前
+	RankContent float64 `json:"rankContent"`
+	RankName    float64 `json:"rankName"`
+	RankAlias   float64 `json:"rankAlias"`
+	RankMemo    float64 `json:"rankMemo"`
+	RankTag     float64 `json:"rankTag"`
+	RankHPath   float64 `json:"rankHPath"`
+
+	RankRecency         float64 `json:"rankRecency"`         // 按相关度排序时最近更新的加权系数，0 为不加权
+	RankRecencyHalfLife int     `json:"rankRecencyHalfLife"` // 最近更新加权衰减一半所需的天数
+	RankDocBoost        float64 `json:"rankDocBoost"`        // 按相关度排序时文档块和标题块的加权倍数，1 为不加权
+
+	Semantic         bool    `json:"semantic"`         // 是否为块内容生成嵌入向量以支持语义搜索
+	SemanticMinScore float64 `json:"semanticMinScore"` // 语义搜索结果的最低相似度
+
 	BacklinkMentionName          bool `json:"backlinkMentionName"`
 	BacklinkMentionAlias         bool `json:"backlinkMentionAlias"`
 	BacklinkMentionAnchor        bool `json:"backlinkMentionAnchor"`
@@ -97,6 +115,23 @@ func NewSearch() *Search {
 
 		IndexAssetPath: true,
 
+		Fuzzy:     false,
+		Tokenizer: "siyuan",
+
+		RankContent: 1,
+		RankName:    1,
+		RankAlias:   1,
+		RankMemo:    1,
+		RankTag:     1,
+		RankHPath:   1,
+
+		RankRecency:         0,
+		RankRecencyHalfLife: 30,
+		RankDocBoost:        1,
+
+		Semantic:         false,
+		SemanticMinScore: 0.3,
+
 		BacklinkMentionName:          true,
 		BacklinkMentionAlias:         false,
 		BacklinkMentionAnchor:        true,
@@ -110,6 +145,23 @@ func NewSearch() *Search {
 	}
 }
 
+// FixRank 修正相关度排序参数，字段权重不能为负数，为 0 时表示不参与相关度计算。
+func (s *Search) FixRank() {
+	s.RankContent = max(s.RankContent, 0)
+	s.RankName = max(s.RankName, 0)
+	s.RankAlias = max(s.RankAlias, 0)
+	s.RankMemo = max(s.RankMemo, 0)
+	s.RankTag = max(s.RankTag, 0)
+	s.RankHPath = max(s.RankHPath, 0)
+	s.RankRecency = max(s.RankRecency, 0)
+	if 1 > s.RankRecencyHalfLife {
+		s.RankRecencyHalfLife = 30
+	}
+	if 1 > s.RankDocBoost {
+		s.RankDocBoost = 1
+	}
+}
+
 func (s *Search) NAMFilter(keyword string) string {
 	keyword = strings.TrimSpace(keyword)
 	buf := bytes.Buffer{}
@@ -52,11 +52,14 @@ func StartKernel(container, appDir, workspaceBaseDir, timezoneID, localIPs, lang
 	go server.Serve(false, model.Conf.CookieKey)
 	go func() {
 		model.InitAppearance()
+		sql.SetFTSTokenizer(model.Conf.Search.Tokenizer)
 		sql.InitDatabase(false)
 		sql.InitHistoryDatabase(false)
 		sql.InitAssetContentDatabase(false)
+		sql.InitBlockVectorDatabase(false)
 		sql.SetCaseSensitive(model.Conf.Search.CaseSensitive)
 		sql.SetIndexAssetPath(model.Conf.Search.IndexAssetPath)
+		sql.SetIndexBlockVector(model.Conf.Search.Semantic)
 
 		model.BootSyncData()
 		model.InitBoxes()
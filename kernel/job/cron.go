@@ -47,6 +47,8 @@ func StartCron() {
 	go every(24*time.Hour, model.AutoPurgeRepoJob)
 	go every(30*time.Minute, model.AutoCheckMicrosoftDefenderJob)
 	go every(24*time.Hour, model.ClearOutdatedHistoryDirJob)
+	go every(30*time.Second, model.ReminderJob)
+	go every(30*time.Second, model.IndexBlockVectorsJob)
 
 	// TODO: 移除旧方案 https://github.com/siyuan-note/siyuan/issues/14414 实现新的刷新机制
 	//go every(3*time.Second, model.WatchLocalShorthands)
@@ -33,11 +33,14 @@ func main() {
 	model.InitConf()
 	go server.Serve(false, model.Conf.CookieKey)
 	model.InitAppearance()
+	sql.SetFTSTokenizer(model.Conf.Search.Tokenizer)
 	sql.InitDatabase(false)
 	sql.InitHistoryDatabase(false)
 	sql.InitAssetContentDatabase(false)
+	sql.InitBlockVectorDatabase(false)
 	sql.SetCaseSensitive(model.Conf.Search.CaseSensitive)
 	sql.SetIndexAssetPath(model.Conf.Search.IndexAssetPath)
+	sql.SetIndexBlockVector(model.Conf.Search.Semantic)
 
 	model.BootSyncData()
 	model.InitBoxes()
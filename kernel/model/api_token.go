@@ -0,0 +1,437 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute/ast"
+	"github.com/gin-gonic/gin"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/conf"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+var (
+	ErrAPITokenNotFound    = errors.New("API token not found")
+	ErrAPITokenInvalidRole = errors.New("invalid API token role")
+)
+
+var apiTokenLock = sync.Mutex{}
+
+// GetAPITokens 返回所有具名 API token，返回的 token 中不包含哈希值。
+func GetAPITokens() (ret []*conf.APIToken) {
+	apiTokenLock.Lock()
+	defer apiTokenLock.Unlock()
+
+	ret = []*conf.APIToken{}
+	for _, token := range Conf.Api.Tokens {
+		cloned := *token
+		cloned.Hash = ""
+		ret = append(ret, &cloned)
+	}
+	return
+}
+
+// CreateAPIToken 创建具名 API token，secret 只在这里返回一次，之后只保存哈希值。
+func CreateAPIToken(name string, role conf.AccessRole, notebooks, paths []string, expired int64) (ret *conf.APIToken, secret string, err error) {
+	if !role.IsValid() {
+		err = ErrAPITokenInvalidRole
+		return
+	}
+
+	apiTokenLock.Lock()
+	defer apiTokenLock.Unlock()
+
+	secret = gulu.Rand.String(32)
+	token := &conf.APIToken{
+		ID:        ast.NewNodeID(),
+		Name:      strings.TrimSpace(name),
+		Hash:      hashAPIToken(secret),
+		Role:      role,
+		Notebooks: normalizeAPITokenScopes(notebooks),
+		Paths:     normalizeAPITokenScopes(paths),
+		Expired:   expired,
+		Created:   util.CurrentTimeMillis(),
+	}
+	Conf.Api.Tokens = append(Conf.Api.Tokens, token)
+	Conf.Save()
+
+	cloned := *token
+	cloned.Hash = ""
+	ret = &cloned
+	return
+}
+
+// UpdateAPIToken 更新具名 API token 的名称、角色、笔记本、接口路径和过期时间。
+func UpdateAPIToken(id, name string, role conf.AccessRole, notebooks, paths []string, expired int64) (err error) {
+	if !role.IsValid() {
+		err = ErrAPITokenInvalidRole
+		return
+	}
+
+	apiTokenLock.Lock()
+	defer apiTokenLock.Unlock()
+
+	for _, token := range Conf.Api.Tokens {
+		if token.ID == id {
+			token.Name = strings.TrimSpace(name)
+			token.Role = role
+			token.Notebooks = normalizeAPITokenScopes(notebooks)
+			token.Paths = normalizeAPITokenScopes(paths)
+			token.Expired = expired
+			Conf.Save()
+			return
+		}
+	}
+	err = ErrAPITokenNotFound
+	return
+}
+
+// RevokeAPIToken 吊销具名 API token，吊销后立即失效。
+func RevokeAPIToken(id string) (err error) {
+	apiTokenLock.Lock()
+	defer apiTokenLock.Unlock()
+
+	for i, token := range Conf.Api.Tokens {
+		if token.ID == id {
+			Conf.Api.Tokens = append(Conf.Api.Tokens[:i], Conf.Api.Tokens[i+1:]...)
+			Conf.Save()
+			logging.LogInfof("revoked API token [%s, %s]", token.ID, token.Name)
+			return
+		}
+	}
+	err = ErrAPITokenNotFound
+	return
+}
+
+// checkAPIToken 校验请求中的 token，通过时设置角色并返回 true，失败时已经写入响应。
+func checkAPIToken(c *gin.Context, token, source string) bool {
+	if 1 == subtle.ConstantTimeCompare([]byte(Conf.Api.Token), []byte(token)) {
+		c.Set(RoleContextKey, RoleAdministrator)
+		return true
+	}
+
+	apiToken := getAPITokenBySecret(token)
+	if nil == apiToken {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"code": -1, "msg": "Auth failed [" + source + "]"})
+		c.Abort()
+		return false
+	}
+
+	now := util.CurrentTimeMillis()
+	if 0 < apiToken.Expired && apiToken.Expired < now {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"code": -1, "msg": "Auth failed [" + source + "]: token expired"})
+		c.Abort()
+		return false
+	}
+
+	if !isAPITokenPathAllowed(apiToken, c.Request.URL.Path) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{"code": -1, "msg": "Access denied: API path not allowed for token [" + apiToken.Name + "]"})
+		c.Abort()
+		return false
+	}
+
+	if !isRequestNotebooksAllowed(c, apiToken.Notebooks) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{"code": -1, "msg": "Access denied: notebook not allowed for token [" + apiToken.Name + "]"})
+		c.Abort()
+		return false
+	}
+
+	touchAPIToken(apiToken, now)
+
+	c.Set(RoleContextKey, getAccessRole(apiToken.Role))
+	return true
+}
+
+func getAPITokenBySecret(secret string) *conf.APIToken {
+	apiTokenLock.Lock()
+	defer apiTokenLock.Unlock()
+
+	hash := hashAPIToken(secret)
+	for _, token := range Conf.Api.Tokens {
+		if 1 == subtle.ConstantTimeCompare([]byte(token.Hash), []byte(hash)) {
+			return token
+		}
+	}
+	return nil
+}
+
+// touchAPIToken 更新最后使用时间，为了避免频繁写入配置文件，每分钟最多保存一次。
+func touchAPIToken(token *conf.APIToken, now int64) {
+	apiTokenLock.Lock()
+	needSave := 60*1000 < now-token.LastUsed
+	token.LastUsed = now
+	apiTokenLock.Unlock()
+
+	if needSave {
+		Conf.Save()
+	}
+}
+
+// isAPITokenPathAllowed 判断请求路径是否在 token 允许的接口路径中，按照路径段匹配，比如 /api/block 不匹配 /api/blockX。
+func isAPITokenPathAllowed(token *conf.APIToken, reqPath string) bool {
+	if 1 > len(token.Paths) {
+		return true
+	}
+
+	reqPath = path.Clean(reqPath)
+	for _, scope := range token.Paths {
+		scope = strings.TrimSuffix(path.Clean("/"+scope), "/")
+		if reqPath == scope || strings.HasPrefix(reqPath, scope+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// notebookScopedAPIs 是限制了笔记本的请求允许调用的接口，不在其中的接口一律拒绝。
+//
+// 值为 true 的接口会按照 NotebooksContextKey 过滤返回结果，参数中可以不指定笔记本和块；
+// 值为 false 的接口必须在参数中指定笔记本或者块，参数中涉及的笔记本和块都必须在允许的笔记本中。
+// 路由中只有这里覆盖的编辑接口才使用 CheckEditRole，其他编辑接口仍然只允许管理员调用。
+var notebookScopedAPIs = map[string]bool{
+	"/api/notebook/lsNotebooks":       true,
+	"/api/storage/getRecentDocs":      true,
+	"/api/filetree/searchDocs":        true,
+	"/api/search/fullTextSearchBlock": true,
+	"/api/search/searchRefBlock":      true,
+	"/api/search/runCriterion":        true,
+	"/api/search/listSmartFolders":    true,
+
+	"/api/notebook/getNotebookConf": false,
+	"/api/filetree/listDocsByPath":  false,
+	"/api/filetree/getDoc":          false,
+	"/api/filetree/createDocWithMd": false,
+	"/api/filetree/createDoc":       false,
+	"/api/filetree/renameDoc":       false,
+	"/api/filetree/renameDocByID":   false,
+	"/api/filetree/removeDoc":       false,
+	"/api/filetree/removeDocByID":   false,
+	"/api/filetree/getHPathByPath":  false,
+	"/api/filetree/getHPathByID":    false,
+	"/api/filetree/getPathByID":     false,
+	"/api/filetree/getIDsByHPath":   false,
+	"/api/block/getBlockKramdown":   false,
+	"/api/block/getChildBlocks":     false,
+	"/api/block/getBlockInfo":       false,
+	"/api/block/getDocInfo":         false,
+	"/api/block/insertBlock":        false,
+	"/api/block/prependBlock":       false,
+	"/api/block/appendBlock":        false,
+	"/api/block/updateBlock":        false,
+	"/api/block/deleteBlock":        false,
+	"/api/block/moveBlock":          false,
+	"/api/block/foldBlock":          false,
+	"/api/block/unfoldBlock":        false,
+	"/api/attr/getBlockAttrs":       false,
+	"/api/attr/batchGetBlockAttrs":  false,
+	"/api/attr/setBlockAttrs":       false,
+	"/api/attr/batchSetBlockAttrs":  false,
+	"/api/attr/resetBlockAttrs":     false,
+	"/api/transactions":             false,
+}
+
+// notebookScopedOperations 是限制了笔记本的请求在 /api/transactions 中允许执行的块操作，数据库等操作涉及的块无法按照笔记本校验。
+var notebookScopedOperations = []string{"insert", "update", "delete", "move", "append", "appendInsert", "prependInsert", "foldHeading", "unfoldHeading", "setAttrs", "doUpdateUpdated"}
+
+// requestBoxArgNames 和 requestBlockArgNames 是接口参数中用于指定笔记本和块的参数名。
+var (
+	requestBoxArgNames   = []string{"notebook", "notebooks", "box", "boxes", "boxID", "toNotebook", "fromNotebook"}
+	requestBlockArgNames = []string{"id", "ids", "parentID", "previousID", "nextID", "rootID", "startID", "endID", "blockID", "blockIDs"}
+)
+
+// isRequestNotebooksAllowed 检查请求的接口是否允许限制了笔记本的请求调用，以及参数中涉及的笔记本是否都在允许的笔记本中。
+func isRequestNotebooksAllowed(c *gin.Context, notebooks []string) bool {
+	if 1 > len(notebooks) {
+		return true
+	}
+
+	boxIDs, ok := getNotebookScopedRequestBoxIDs(c)
+	if !ok {
+		return false
+	}
+
+	for _, boxID := range boxIDs {
+		if !gulu.Str.Contains(boxID, notebooks) {
+			return false
+		}
+	}
+	c.Set(NotebooksContextKey, notebooks)
+	return true
+}
+
+// getNotebookScopedRequestBoxIDs 返回限制了笔记本的请求涉及的笔记本 ID，ok 为 false 时表示该请求不允许调用。
+func getNotebookScopedRequestBoxIDs(c *gin.Context) (ret []string, ok bool) {
+	filtered, allowed := notebookScopedAPIs[c.Request.URL.Path]
+	if !allowed || http.MethodPost != c.Request.Method {
+		return
+	}
+
+	if ret, ok = getRequestBoxIDs(c); !ok {
+		return
+	}
+	if !filtered && 1 > len(ret) {
+		// 不过滤结果的接口必须能够确定涉及的笔记本
+		ok = false
+	}
+	return
+}
+
+// getRequestBoxIDs 从请求参数中解析涉及的笔记本 ID，读取请求体后会恢复请求体以便后续处理。
+//
+// 参数中的块 ID 都通过块树解析为所在的笔记本，块树中不存在的块 ID（比如新建块的 ID）不涉及任何笔记本。
+// 请求体不是 JSON、无法解析或者包含不允许的事务操作时 ok 为 false。
+func getRequestBoxIDs(c *gin.Context) (ret []string, ok bool) {
+	if nil == c.Request.Body {
+		ok = true
+		return
+	}
+
+	if !strings.HasPrefix(c.ContentType(), "application/json") && "" != c.ContentType() {
+		return
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(data))
+	if nil != err {
+		return
+	}
+	if 1 > len(bytes.TrimSpace(data)) {
+		ok = true
+		return
+	}
+
+	arg := map[string]interface{}{}
+	if err = gulu.JSON.UnmarshalJSON(data, &arg); nil != err {
+		return
+	}
+
+	if "/api/transactions" == c.Request.URL.Path {
+		ret, ok = collectTransactionBoxIDs(arg)
+	} else {
+		ret, ok = collectRequestBoxIDs(arg, ret), true
+	}
+	ret = gulu.Str.RemoveDuplicatedElem(ret)
+	return
+}
+
+// collectTransactionBoxIDs 收集 /api/transactions 中所有操作涉及的笔记本 ID，每个操作都必须能够确定涉及的笔记本。
+func collectTransactionBoxIDs(arg map[string]interface{}) (ret []string, ok bool) {
+	transactions, _ := arg["transactions"].([]interface{})
+	for _, transaction := range transactions {
+		tx, _ := transaction.(map[string]interface{})
+		if nil == tx {
+			return
+		}
+
+		// 撤销操作中会删除新插入的块，这些块在块树中还不存在，所以只要求执行操作能够确定笔记本
+		for _, opsName := range []string{"doOperations", "undoOperations"} {
+			ops, _ := tx[opsName].([]interface{})
+			for _, op := range ops {
+				operation, _ := op.(map[string]interface{})
+				if nil == operation {
+					return
+				}
+
+				action, _ := operation["action"].(string)
+				if !gulu.Str.Contains(action, notebookScopedOperations) {
+					return
+				}
+
+				boxIDs := collectRequestBoxIDs(operation, nil)
+				if "doOperations" == opsName && 1 > len(boxIDs) {
+					return
+				}
+				ret = append(ret, boxIDs...)
+			}
+		}
+	}
+	ok = 0 < len(ret)
+	return
+}
+
+// collectRequestBoxIDs 递归收集参数中的笔记本 ID，比如 /api/attr/batchSetBlockAttrs 的块 ID 嵌套在 blockAttrs 中。
+func collectRequestBoxIDs(arg map[string]interface{}, ret []string) []string {
+	for _, name := range requestBoxArgNames {
+		ret = append(ret, getRequestStringArgs(arg[name])...)
+	}
+
+	for _, name := range requestBlockArgNames {
+		for _, id := range getRequestStringArgs(arg[name]) {
+			if bt := treenode.GetBlockTree(id); nil != bt {
+				ret = append(ret, bt.BoxID)
+			}
+		}
+	}
+
+	for _, v := range arg {
+		switch child := v.(type) {
+		case map[string]interface{}:
+			ret = collectRequestBoxIDs(child, ret)
+		case []interface{}:
+			for _, item := range child {
+				if m, ok := item.(map[string]interface{}); ok {
+					ret = collectRequestBoxIDs(m, ret)
+				}
+			}
+		}
+	}
+	return ret
+}
+
+func getRequestStringArgs(arg interface{}) (ret []string) {
+	switch v := arg.(type) {
+	case string:
+		if "" != v {
+			ret = append(ret, v)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && "" != s {
+				ret = append(ret, s)
+			}
+		}
+	}
+	return
+}
+
+func normalizeAPITokenScopes(scopes []string) (ret []string) {
+	ret = []string{}
+	for _, scope := range scopes {
+		if scope = strings.TrimSpace(scope); "" != scope && !gulu.Str.Contains(scope, ret) {
+			ret = append(ret, scope)
+		}
+	}
+	return
+}
+
+func hashAPIToken(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
@@ -21,6 +21,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
@@ -60,7 +61,7 @@ type AssetContent struct {
 func GetAssetContent(id, query string, queryMethod int) (ret *AssetContent) {
 	if "" != query && (0 == queryMethod || 1 == queryMethod) {
 		if 0 == queryMethod {
-			query = stringQuery(query)
+			query = expandStringQuery(query)
 		}
 	}
 	if !ast.IsNodeIDPattern(id) {
@@ -70,7 +71,7 @@ func GetAssetContent(id, query string, queryMethod int) (ret *AssetContent) {
 	table := "asset_contents_fts_case_insensitive"
 	filter := " id = '" + id + "'"
 	if "" != query {
-		filter += " AND `" + table + "` MATCH '" + buildAssetContentColumnFilter() + ":(" + query + ")'"
+		filter += " AND `" + table + "` MATCH '" + buildAssetContentColumnFilter() + ":(" + sql.SegmentFTSQuery(query) + ")'"
 	}
 
 	projections := "id, name, ext, path, size, updated, " +
@@ -122,7 +123,11 @@ func fullTextSearchAssetContentByQuerySyntax(query, typeFilter, orderBy string,
 
 func fullTextSearchAssetContentByKeyword(query, typeFilter string, orderBy string, beforeLen, page, pageSize int) (ret []*AssetContent, matchedAssetCount int) {
 	query = filterQueryInvisibleChars(query)
-	query = stringQuery(query)
+	if isShortNgramQuery(query) {
+		// n-gram 分词无法匹配少于 3 个字符的关键字，改为按正则表达式匹配字面值
+		return fullTextSearchAssetContentByRegexp("(?i)"+regexp.QuoteMeta(strings.TrimSpace(query)), typeFilter, orderBy, beforeLen, page, pageSize)
+	}
+	query = expandStringQuery(query)
 	return fullTextSearchAssetContentByFTS(query, typeFilter, orderBy, beforeLen, page, pageSize)
 }
 
@@ -143,6 +148,7 @@ func fullTextSearchAssetContentByRegexp(exp, typeFilter, orderBy string, beforeL
 }
 
 func assetContentFieldRegexp(exp string) string {
+	exp = strings.ReplaceAll(exp, "'", "''")
 	buf := bytes.Buffer{}
 	buf.WriteString("(name REGEXP '")
 	buf.WriteString(exp)
@@ -168,9 +174,9 @@ func fullTextSearchAssetContentByFTS(query, typeFilter, orderBy string, beforeLe
 	table := "asset_contents_fts_case_insensitive"
 	projections := "id, name, ext, path, size, updated, " +
 		"snippet(" + table + ", 6, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 64) AS content"
-	stmt := "SELECT " + projections + " FROM " + table + " WHERE (`" + table + "` MATCH '" + buildAssetContentColumnFilter() + ":(" + query + ")'"
+	stmt := "SELECT " + projections + " FROM " + table + " WHERE (`" + table + "` MATCH '" + buildAssetContentColumnFilter() + ":(" + sql.SegmentFTSQuery(query) + ")'"
 	stmt += ") AND ext IN " + typeFilter
-	stmt += " " + orderBy
+	stmt += " " + buildAssetContentFTSRankOrderBy(orderBy, table)
 	stmt += " LIMIT " + strconv.Itoa(pageSize) + " OFFSET " + strconv.Itoa((page-1)*pageSize)
 	assetContents := sql.SelectAssetContentsRawStmt(stmt, page, pageSize)
 	ret = fromSQLAssetContents(&assetContents, beforeLen)
@@ -208,7 +214,7 @@ func fullTextSearchAssetContentCount(query, typeFilter string) (matchedAssetCoun
 	query = filterQueryInvisibleChars(query)
 
 	table := "asset_contents_fts_case_insensitive"
-	stmt := "SELECT COUNT(path) AS `assets` FROM `" + table + "` WHERE (`" + table + "` MATCH '" + buildAssetContentColumnFilter() + ":(" + query + ")'"
+	stmt := "SELECT COUNT(path) AS `assets` FROM `" + table + "` WHERE (`" + table + "` MATCH '" + buildAssetContentColumnFilter() + ":(" + sql.SegmentFTSQuery(query) + ")'"
 	stmt += ") AND ext IN " + typeFilter
 	result, _ := sql.QueryAssetContentNoLimit(stmt)
 	if 1 > len(result) {
@@ -290,6 +296,14 @@ func buildAssetContentOrderBy(orderBy int) string {
 	}
 }
 
+func buildAssetContentFTSRankOrderBy(orderBy, table string) string {
+	s := Conf.Search
+	// id, name, ext, path, size, updated, content
+	columnWeights := []float64{0, s.RankName, 1, s.RankHPath, 0, 0, s.RankContent}
+	recencyAge := "(strftime('%s', 'now') - updated) / 86400.0"
+	return buildFTSRankOrderBy(orderBy, table, columnWeights, recencyAge, "")
+}
+
 var assetContentSearcher = NewAssetsSearcher()
 
 func removeIndexAssetContent(absPath string) {
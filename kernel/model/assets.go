@@ -1289,7 +1289,7 @@ func getQueryEmbedNodesAssetsLinkDests(node *ast.Node) (ret []string) {
 		stmt := n.TokensStr()
 		stmt = html.UnescapeString(stmt)
 		stmt = strings.ReplaceAll(stmt, editor.IALValEscNewLine, "\n")
-		sqlBlocks := sql.SelectBlocksRawStmt(stmt, 1, Conf.Search.Limit)
+		sqlBlocks := sql.SelectBlocksReadOnlyRawStmt(stmt, 1, Conf.Search.Limit)
 		for _, sqlBlock := range sqlBlocks {
 			subtree, _ := LoadTreeByBlockID(sqlBlock.ID)
 			if nil == subtree {
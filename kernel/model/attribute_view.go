@@ -389,7 +389,7 @@ func getAttrViewAddingBlockDefaultValues(attrView *av.AttributeView, view, group
 	}
 
 	filterKeyIDs := map[string]bool{}
-	for _, filter := range view.Filters {
+	for _, filter := range av.GetMandatoryFilters(view.Filters) {
 		filterKeyIDs[filter.Column] = true
 		keyValues, _ := attrView.GetKeyValues(filter.Column)
 		if nil == keyValues {
@@ -703,7 +703,7 @@ func syncAttrViewTableColWidth(operation *Operation) (err error) {
 				break
 			}
 		}
-	case av.LayoutTypeGallery, av.LayoutTypeKanban:
+	case av.LayoutTypeGallery, av.LayoutTypeKanban, av.LayoutTypeCalendar, av.LayoutTypeTimeline:
 		return
 	}
 
@@ -991,7 +991,7 @@ func ChangeAttrViewLayout(blockID, avID string, newLayout av.LayoutType) (err er
 
 	switch newLayout {
 	case av.LayoutTypeTable:
-		if view.Name == av.GetAttributeViewI18n("gallery") || view.Name == av.GetAttributeViewI18n("kanban") {
+		if view.Name == av.GetAttributeViewI18n("gallery") || view.Name == av.GetAttributeViewI18n("kanban") || view.Name == av.GetAttributeViewI18n("calendar") || view.Name == av.GetAttributeViewI18n("timeline") {
 			view.Name = av.GetAttributeViewI18n("table")
 		}
 
@@ -1009,9 +1009,17 @@ func ChangeAttrViewLayout(blockID, avID string, newLayout av.LayoutType) (err er
 			for _, field := range view.Kanban.Fields {
 				view.Table.Columns = append(view.Table.Columns, &av.ViewTableColumn{BaseField: &av.BaseField{ID: field.ID}})
 			}
+		case av.LayoutTypeCalendar:
+			for _, field := range view.Calendar.Fields {
+				view.Table.Columns = append(view.Table.Columns, &av.ViewTableColumn{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeTimeline:
+			for _, field := range view.Timeline.Fields {
+				view.Table.Columns = append(view.Table.Columns, &av.ViewTableColumn{BaseField: &av.BaseField{ID: field.ID}})
+			}
 		}
 	case av.LayoutTypeGallery:
-		if view.Name == av.GetAttributeViewI18n("table") || view.Name == av.GetAttributeViewI18n("kanban") {
+		if view.Name == av.GetAttributeViewI18n("table") || view.Name == av.GetAttributeViewI18n("kanban") || view.Name == av.GetAttributeViewI18n("calendar") || view.Name == av.GetAttributeViewI18n("timeline") {
 			view.Name = av.GetAttributeViewI18n("gallery")
 		}
 
@@ -1029,9 +1037,17 @@ func ChangeAttrViewLayout(blockID, avID string, newLayout av.LayoutType) (err er
 			for _, field := range view.Kanban.Fields {
 				view.Gallery.CardFields = append(view.Gallery.CardFields, &av.ViewGalleryCardField{BaseField: &av.BaseField{ID: field.ID}})
 			}
+		case av.LayoutTypeCalendar:
+			for _, field := range view.Calendar.Fields {
+				view.Gallery.CardFields = append(view.Gallery.CardFields, &av.ViewGalleryCardField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeTimeline:
+			for _, field := range view.Timeline.Fields {
+				view.Gallery.CardFields = append(view.Gallery.CardFields, &av.ViewGalleryCardField{BaseField: &av.BaseField{ID: field.ID}})
+			}
 		}
 	case av.LayoutTypeKanban:
-		if view.Name == av.GetAttributeViewI18n("table") || view.Name == av.GetAttributeViewI18n("gallery") {
+		if view.Name == av.GetAttributeViewI18n("table") || view.Name == av.GetAttributeViewI18n("gallery") || view.Name == av.GetAttributeViewI18n("calendar") || view.Name == av.GetAttributeViewI18n("timeline") {
 			view.Name = av.GetAttributeViewI18n("kanban")
 		}
 
@@ -1049,6 +1065,14 @@ func ChangeAttrViewLayout(blockID, avID string, newLayout av.LayoutType) (err er
 			for _, field := range view.Gallery.CardFields {
 				view.Kanban.Fields = append(view.Kanban.Fields, &av.ViewKanbanField{BaseField: &av.BaseField{ID: field.ID}})
 			}
+		case av.LayoutTypeCalendar:
+			for _, field := range view.Calendar.Fields {
+				view.Kanban.Fields = append(view.Kanban.Fields, &av.ViewKanbanField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeTimeline:
+			for _, field := range view.Timeline.Fields {
+				view.Kanban.Fields = append(view.Kanban.Fields, &av.ViewKanbanField{BaseField: &av.BaseField{ID: field.ID}})
+			}
 		}
 
 		if !view.IsGroupView() {
@@ -1056,6 +1080,64 @@ func ChangeAttrViewLayout(blockID, avID string, newLayout av.LayoutType) (err er
 			group := &av.ViewGroup{Field: preferredGroupKey.ID}
 			setAttributeViewGroup(attrView, view, group)
 		}
+	case av.LayoutTypeCalendar:
+		if view.Name == av.GetAttributeViewI18n("table") || view.Name == av.GetAttributeViewI18n("gallery") || view.Name == av.GetAttributeViewI18n("kanban") || view.Name == av.GetAttributeViewI18n("timeline") {
+			view.Name = av.GetAttributeViewI18n("calendar")
+		}
+
+		if nil != view.Calendar {
+			break
+		}
+
+		view.Calendar = av.NewLayoutCalendar()
+		switch oldLayout {
+		case av.LayoutTypeTable:
+			for _, col := range view.Table.Columns {
+				view.Calendar.Fields = append(view.Calendar.Fields, &av.ViewCalendarField{BaseField: &av.BaseField{ID: col.ID}})
+			}
+		case av.LayoutTypeGallery:
+			for _, field := range view.Gallery.CardFields {
+				view.Calendar.Fields = append(view.Calendar.Fields, &av.ViewCalendarField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeKanban:
+			for _, field := range view.Kanban.Fields {
+				view.Calendar.Fields = append(view.Calendar.Fields, &av.ViewCalendarField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeTimeline:
+			for _, field := range view.Timeline.Fields {
+				view.Calendar.Fields = append(view.Calendar.Fields, &av.ViewCalendarField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		}
+		view.Calendar.DateFieldID = getPreferredDateKey(attrView).ID
+	case av.LayoutTypeTimeline:
+		if view.Name == av.GetAttributeViewI18n("table") || view.Name == av.GetAttributeViewI18n("gallery") || view.Name == av.GetAttributeViewI18n("kanban") || view.Name == av.GetAttributeViewI18n("calendar") {
+			view.Name = av.GetAttributeViewI18n("timeline")
+		}
+
+		if nil != view.Timeline {
+			break
+		}
+
+		view.Timeline = av.NewLayoutTimeline()
+		switch oldLayout {
+		case av.LayoutTypeTable:
+			for _, col := range view.Table.Columns {
+				view.Timeline.Fields = append(view.Timeline.Fields, &av.ViewTimelineField{BaseField: &av.BaseField{ID: col.ID}})
+			}
+		case av.LayoutTypeGallery:
+			for _, field := range view.Gallery.CardFields {
+				view.Timeline.Fields = append(view.Timeline.Fields, &av.ViewTimelineField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeKanban:
+			for _, field := range view.Kanban.Fields {
+				view.Timeline.Fields = append(view.Timeline.Fields, &av.ViewTimelineField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeCalendar:
+			for _, field := range view.Calendar.Fields {
+				view.Timeline.Fields = append(view.Timeline.Fields, &av.ViewTimelineField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		}
+		view.Timeline.DateFieldID = getPreferredDateKey(attrView).ID
 	}
 
 	blockIDs := treenode.GetMirrorAttrViewBlockIDs(avID)
@@ -1137,6 +1219,16 @@ func setAttrViewWrapField(operation *Operation) (err error) {
 		for _, field := range view.Kanban.Fields {
 			field.Wrap = allFieldWrap
 		}
+	case av.LayoutTypeCalendar:
+		view.Calendar.WrapField = allFieldWrap
+		for _, field := range view.Calendar.Fields {
+			field.Wrap = allFieldWrap
+		}
+	case av.LayoutTypeTimeline:
+		view.Timeline.WrapField = allFieldWrap
+		for _, field := range view.Timeline.Fields {
+			field.Wrap = allFieldWrap
+		}
 	}
 
 	err = av.SaveAttributeView(attrView)
@@ -1169,6 +1261,10 @@ func setAttrViewShowIcon(operation *Operation) (err error) {
 		view.Gallery.ShowIcon = operation.Data.(bool)
 	case av.LayoutTypeKanban:
 		view.Kanban.ShowIcon = operation.Data.(bool)
+	case av.LayoutTypeCalendar:
+		view.Calendar.ShowIcon = operation.Data.(bool)
+	case av.LayoutTypeTimeline:
+		view.Timeline.ShowIcon = operation.Data.(bool)
 	}
 
 	err = av.SaveAttributeView(attrView)
@@ -1241,6 +1337,10 @@ func setAttrViewDisplayFieldName(operation *Operation) (err error) {
 		view.Gallery.DisplayFieldName = operation.Data.(bool)
 	case av.LayoutTypeKanban:
 		view.Kanban.DisplayFieldName = operation.Data.(bool)
+	case av.LayoutTypeCalendar:
+		view.Calendar.DisplayFieldName = operation.Data.(bool)
+	case av.LayoutTypeTimeline:
+		view.Timeline.DisplayFieldName = operation.Data.(bool)
 	}
 
 	err = av.SaveAttributeView(attrView)
@@ -1367,6 +1467,208 @@ func setAttrViewCoverFrom(operation *Operation) (err error) {
 	return
 }
 
+func (tx *Transaction) doSetAttrViewCalendarDateField(operation *Operation) (ret *TxErr) {
+	err := setAttrViewCalendarDateField(operation)
+	if err != nil {
+		return &TxErr{code: TxErrHandleAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+// setAttrViewCalendarDateField 设置日历视图的日期字段，时间线视图也使用该操作设置日期字段。
+func setAttrViewCalendarDateField(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	key, err := attrView.GetKey(operation.KeyID)
+	if err != nil {
+		return
+	}
+	if !isDateKey(key) {
+		err = av.ErrWrongKeyType
+		return
+	}
+
+	switch view.LayoutType {
+	case av.LayoutTypeCalendar:
+		view.Calendar.DateFieldID = key.ID
+	case av.LayoutTypeTimeline:
+		view.Timeline.DateFieldID = key.ID
+	default:
+		return
+	}
+
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doSetAttrViewCalendarMode(operation *Operation) (ret *TxErr) {
+	err := setAttrViewCalendarMode(operation)
+	if err != nil {
+		return &TxErr{code: TxErrHandleAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttrViewCalendarMode(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	if av.LayoutTypeCalendar != view.LayoutType {
+		return
+	}
+
+	view.Calendar.Mode = av.CalendarMode(operation.Data.(float64))
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doSetAttrViewCalendarStartWeekday(operation *Operation) (ret *TxErr) {
+	err := setAttrViewCalendarStartWeekday(operation)
+	if err != nil {
+		return &TxErr{code: TxErrHandleAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttrViewCalendarStartWeekday(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	if av.LayoutTypeCalendar != view.LayoutType {
+		return
+	}
+
+	view.Calendar.StartWeekday = int(operation.Data.(float64)) % 7
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doSetAttrViewCalendarAnchor(operation *Operation) (ret *TxErr) {
+	err := setAttrViewCalendarAnchor(operation)
+	if err != nil {
+		return &TxErr{code: TxErrHandleAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttrViewCalendarAnchor(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	if av.LayoutTypeCalendar != view.LayoutType {
+		return
+	}
+
+	view.Calendar.Anchor = int64(operation.Data.(float64))
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doSetAttrViewTimelineDependencyField(operation *Operation) (ret *TxErr) {
+	err := setAttrViewTimelineDependencyField(operation)
+	if err != nil {
+		return &TxErr{code: TxErrHandleAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttrViewTimelineDependencyField(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	if av.LayoutTypeTimeline != view.LayoutType {
+		return
+	}
+
+	if "" == operation.KeyID {
+		// 取消依赖字段
+		view.Timeline.DependencyFieldID = ""
+		err = av.SaveAttributeView(attrView)
+		return
+	}
+
+	key, err := attrView.GetKey(operation.KeyID)
+	if err != nil {
+		return
+	}
+	if !isDependencyKey(attrView, key) {
+		err = av.ErrWrongKeyType
+		return
+	}
+
+	view.Timeline.DependencyFieldID = key.ID
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doSetAttrViewTimelineScale(operation *Operation) (ret *TxErr) {
+	err := setAttrViewTimelineScale(operation)
+	if err != nil {
+		return &TxErr{code: TxErrHandleAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttrViewTimelineScale(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	if av.LayoutTypeTimeline != view.LayoutType {
+		return
+	}
+
+	scale := av.TimelineScale(operation.Data.(float64))
+	if av.TimelineScaleDay > scale || av.TimelineScaleYear < scale {
+		scale = av.TimelineScaleWeek
+	}
+	view.Timeline.Scale = scale
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
 func AppendAttributeViewDetachedBlocksWithValues(avID string, blocksValues [][]*av.Value) (err error) {
 	attrView, err := av.ParseAttributeView(avID)
 	if err != nil {
@@ -1596,7 +1898,11 @@ func GetAttributeViewPrimaryKeyValues(avID, keyword string, page, pageSize int)
 	return
 }
 
-func GetAttributeViewFilterSort(avID, blockID string) (filters []*av.ViewFilter, sorts []*av.ViewSort) {
+// GetAttributeViewFilterSort 获取视图的过滤和排序规则。
+//
+// filters 是过滤规则树，顶层规则之间是且的关系，Conjunction 不为空的规则是过滤组；leafFilters 是树中所有针对字段的过滤条件，
+// 用于不需要区分过滤组的调用方。字段已经被删除的过滤条件以及因此变为空的过滤组不会返回。
+func GetAttributeViewFilterSort(avID, blockID string) (filters, leafFilters []*av.ViewFilter, sorts []*av.ViewSort) {
 	waitForSyncingStorages()
 
 	attrView, err := av.ParseAttributeView(avID)
@@ -1614,10 +1920,18 @@ func GetAttributeViewFilterSort(avID, blockID string) (filters []*av.ViewFilter,
 		}
 	}
 
-	filters = view.Filters
+	var clonedFilters []*av.ViewFilter
+	for _, filter := range view.Filters {
+		clonedFilters = append(clonedFilters, filter.Clone())
+	}
+	filters = av.RemoveFilters(clonedFilters, func(filter *av.ViewFilter) bool {
+		key, _ := attrView.GetKey(filter.Column)
+		return nil == key
+	})
+	leafFilters = av.GetLeafFilters(filters)
 	sorts = view.Sorts
-	if 1 > len(filters) {
-		filters = []*av.ViewFilter{}
+	if 1 > len(leafFilters) {
+		leafFilters = []*av.ViewFilter{}
 	}
 	if 1 > len(sorts) {
 		sorts = []*av.ViewSort{}
@@ -2178,6 +2492,12 @@ func genAttrViewGroups(view *av.View, attrView *av.AttributeView) {
 		case av.LayoutTypeKanban:
 			v = av.NewKanbanView()
 			v.Kanban = av.NewLayoutKanban()
+		case av.LayoutTypeCalendar:
+			v = av.NewCalendarView()
+			v.Calendar = av.NewLayoutCalendar()
+		case av.LayoutTypeTimeline:
+			v = av.NewTimelineView()
+			v.Timeline = av.NewLayoutTimeline()
 		default:
 			logging.LogWarnf("unknown layout type [%s] for group view", view.LayoutType)
 			return
@@ -2535,13 +2855,9 @@ func updateAttributeViewColRollup(operation *Operation) (err error) {
 
 	// 如果存在该汇总字段的过滤条件，则移除该过滤条件 https://github.com/siyuan-note/siyuan/issues/15660
 	for _, view := range attrView.Views {
-		for i, filter := range view.Filters {
-			if filter.Column != rollUpKey.ID {
-				continue
-			}
-
-			view.Filters = append(view.Filters[:i], view.Filters[i+1:]...)
-		}
+		view.Filters = av.RemoveFilters(view.Filters, func(filter *av.ViewFilter) bool {
+			return filter.Column == rollUpKey.ID
+		})
 	}
 
 	err = av.SaveAttributeView(attrView)
@@ -2675,6 +2991,10 @@ func updateAttributeViewColRelation(operation *Operation) (err error) {
 				v.Gallery.CardFields = append(v.Gallery.CardFields, &av.ViewGalleryCardField{BaseField: &av.BaseField{ID: operation.BackRelationKeyID}})
 			case av.LayoutTypeKanban:
 				v.Kanban.Fields = append(v.Kanban.Fields, &av.ViewKanbanField{BaseField: &av.BaseField{ID: operation.BackRelationKeyID}})
+			case av.LayoutTypeCalendar:
+				v.Calendar.Fields = append(v.Calendar.Fields, &av.ViewCalendarField{BaseField: &av.BaseField{ID: operation.BackRelationKeyID}})
+			case av.LayoutTypeTimeline:
+				v.Timeline.Fields = append(v.Timeline.Fields, &av.ViewTimelineField{BaseField: &av.BaseField{ID: operation.BackRelationKeyID}})
 			}
 		}
 
@@ -2892,6 +3212,10 @@ func (tx *Transaction) doDuplicateAttrViewView(operation *Operation) (ret *TxErr
 		view = av.NewGalleryView()
 	case av.LayoutTypeKanban:
 		view = av.NewKanbanView()
+	case av.LayoutTypeCalendar:
+		view = av.NewCalendarView()
+	case av.LayoutTypeTimeline:
+		view = av.NewTimelineView()
 	}
 
 	view.ID = operation.ID
@@ -2906,14 +3230,7 @@ func (tx *Transaction) doDuplicateAttrViewView(operation *Operation) (ret *TxErr
 	view.PageSize = masterView.PageSize
 
 	for _, filter := range masterView.Filters {
-		view.Filters = append(view.Filters, &av.ViewFilter{
-			Column:        filter.Column,
-			Qualifier:     filter.Qualifier,
-			Operator:      filter.Operator,
-			Value:         filter.Value,
-			RelativeDate:  filter.RelativeDate,
-			RelativeDate2: filter.RelativeDate2,
-		})
+		view.Filters = append(view.Filters, filter.Clone())
 	}
 
 	for _, s := range masterView.Sorts {
@@ -2980,6 +3297,43 @@ func (tx *Transaction) doDuplicateAttrViewView(operation *Operation) (ret *TxErr
 		view.Kanban.FillColBackgroundColor = masterView.Kanban.FillColBackgroundColor
 		view.Kanban.ShowIcon = masterView.Kanban.ShowIcon
 		view.Kanban.WrapField = masterView.Kanban.WrapField
+	case av.LayoutTypeCalendar:
+		for _, field := range masterView.Calendar.Fields {
+			view.Calendar.Fields = append(view.Calendar.Fields, &av.ViewCalendarField{
+				BaseField: &av.BaseField{
+					ID:     field.ID,
+					Wrap:   field.Wrap,
+					Hidden: field.Hidden,
+					Desc:   field.Desc,
+				},
+			})
+		}
+
+		view.Calendar.DateFieldID = masterView.Calendar.DateFieldID
+		view.Calendar.Mode = masterView.Calendar.Mode
+		view.Calendar.StartWeekday = masterView.Calendar.StartWeekday
+		view.Calendar.Anchor = masterView.Calendar.Anchor
+		view.Calendar.DisplayFieldName = masterView.Calendar.DisplayFieldName
+		view.Calendar.ShowIcon = masterView.Calendar.ShowIcon
+		view.Calendar.WrapField = masterView.Calendar.WrapField
+	case av.LayoutTypeTimeline:
+		for _, field := range masterView.Timeline.Fields {
+			view.Timeline.Fields = append(view.Timeline.Fields, &av.ViewTimelineField{
+				BaseField: &av.BaseField{
+					ID:     field.ID,
+					Wrap:   field.Wrap,
+					Hidden: field.Hidden,
+					Desc:   field.Desc,
+				},
+			})
+		}
+
+		view.Timeline.DateFieldID = masterView.Timeline.DateFieldID
+		view.Timeline.DependencyFieldID = masterView.Timeline.DependencyFieldID
+		view.Timeline.Scale = masterView.Timeline.Scale
+		view.Timeline.DisplayFieldName = masterView.Timeline.DisplayFieldName
+		view.Timeline.ShowIcon = masterView.Timeline.ShowIcon
+		view.Timeline.WrapField = masterView.Timeline.WrapField
 	}
 
 	view.ItemIDs = masterView.ItemIDs
@@ -3049,6 +3403,14 @@ func addAttrViewView(avID, viewID, blockID string, layout av.LayoutType) (err er
 			for _, field := range firstView.Kanban.Fields {
 				view.Table.Columns = append(view.Table.Columns, &av.ViewTableColumn{BaseField: &av.BaseField{ID: field.ID}})
 			}
+		case av.LayoutTypeCalendar:
+			for _, field := range firstView.Calendar.Fields {
+				view.Table.Columns = append(view.Table.Columns, &av.ViewTableColumn{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeTimeline:
+			for _, field := range firstView.Timeline.Fields {
+				view.Table.Columns = append(view.Table.Columns, &av.ViewTableColumn{BaseField: &av.BaseField{ID: field.ID}})
+			}
 		}
 	case av.LayoutTypeGallery:
 		view = av.NewGalleryView()
@@ -3065,6 +3427,14 @@ func addAttrViewView(avID, viewID, blockID string, layout av.LayoutType) (err er
 			for _, field := range firstView.Kanban.Fields {
 				view.Gallery.CardFields = append(view.Gallery.CardFields, &av.ViewGalleryCardField{BaseField: &av.BaseField{ID: field.ID}})
 			}
+		case av.LayoutTypeCalendar:
+			for _, field := range firstView.Calendar.Fields {
+				view.Gallery.CardFields = append(view.Gallery.CardFields, &av.ViewGalleryCardField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeTimeline:
+			for _, field := range firstView.Timeline.Fields {
+				view.Gallery.CardFields = append(view.Gallery.CardFields, &av.ViewGalleryCardField{BaseField: &av.BaseField{ID: field.ID}})
+			}
 		}
 	case av.LayoutTypeKanban:
 		view = av.NewKanbanView()
@@ -3081,6 +3451,62 @@ func addAttrViewView(avID, viewID, blockID string, layout av.LayoutType) (err er
 			for _, field := range firstView.Kanban.Fields {
 				view.Kanban.Fields = append(view.Kanban.Fields, &av.ViewKanbanField{BaseField: &av.BaseField{ID: field.ID}})
 			}
+		case av.LayoutTypeCalendar:
+			for _, field := range firstView.Calendar.Fields {
+				view.Kanban.Fields = append(view.Kanban.Fields, &av.ViewKanbanField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeTimeline:
+			for _, field := range firstView.Timeline.Fields {
+				view.Kanban.Fields = append(view.Kanban.Fields, &av.ViewKanbanField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		}
+	case av.LayoutTypeCalendar:
+		view = av.NewCalendarView()
+		switch firstView.LayoutType {
+		case av.LayoutTypeTable:
+			for _, col := range firstView.Table.Columns {
+				view.Calendar.Fields = append(view.Calendar.Fields, &av.ViewCalendarField{BaseField: &av.BaseField{ID: col.ID}})
+			}
+		case av.LayoutTypeGallery:
+			for _, field := range firstView.Gallery.CardFields {
+				view.Calendar.Fields = append(view.Calendar.Fields, &av.ViewCalendarField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeKanban:
+			for _, field := range firstView.Kanban.Fields {
+				view.Calendar.Fields = append(view.Calendar.Fields, &av.ViewCalendarField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeCalendar:
+			for _, field := range firstView.Calendar.Fields {
+				view.Calendar.Fields = append(view.Calendar.Fields, &av.ViewCalendarField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeTimeline:
+			for _, field := range firstView.Timeline.Fields {
+				view.Calendar.Fields = append(view.Calendar.Fields, &av.ViewCalendarField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		}
+	case av.LayoutTypeTimeline:
+		view = av.NewTimelineView()
+		switch firstView.LayoutType {
+		case av.LayoutTypeTable:
+			for _, col := range firstView.Table.Columns {
+				view.Timeline.Fields = append(view.Timeline.Fields, &av.ViewTimelineField{BaseField: &av.BaseField{ID: col.ID}})
+			}
+		case av.LayoutTypeGallery:
+			for _, field := range firstView.Gallery.CardFields {
+				view.Timeline.Fields = append(view.Timeline.Fields, &av.ViewTimelineField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeKanban:
+			for _, field := range firstView.Kanban.Fields {
+				view.Timeline.Fields = append(view.Timeline.Fields, &av.ViewTimelineField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeCalendar:
+			for _, field := range firstView.Calendar.Fields {
+				view.Timeline.Fields = append(view.Timeline.Fields, &av.ViewTimelineField{BaseField: &av.BaseField{ID: field.ID}})
+			}
+		case av.LayoutTypeTimeline:
+			for _, field := range firstView.Timeline.Fields {
+				view.Timeline.Fields = append(view.Timeline.Fields, &av.ViewTimelineField{BaseField: &av.BaseField{ID: field.ID}})
+			}
 		}
 	default:
 		err = av.ErrWrongLayoutType
@@ -3099,6 +3525,14 @@ func addAttrViewView(avID, viewID, blockID string, layout av.LayoutType) (err er
 		setAttributeViewGroup(attrView, view, group)
 	}
 
+	if av.LayoutTypeCalendar == layout {
+		view.Calendar.DateFieldID = getPreferredDateKey(attrView).ID
+	}
+
+	if av.LayoutTypeTimeline == layout {
+		view.Timeline.DateFieldID = getPreferredDateKey(attrView).ID
+	}
+
 	node, tree, _ := getNodeByBlockID(nil, blockID)
 	if nil == node {
 		logging.LogErrorf("get node by block ID [%s] failed", blockID)
@@ -3149,11 +3583,85 @@ func getKanbanPreferredGroupKey(attrView *av.AttributeView) (ret *av.Key) {
 				newField.Wrap = view.Kanban.WrapField
 				view.Kanban.Fields = append(view.Kanban.Fields, &av.ViewKanbanField{BaseField: newField})
 			}
+
+			if nil != view.Calendar {
+				newField.Wrap = view.Calendar.WrapField
+				view.Calendar.Fields = append(view.Calendar.Fields, &av.ViewCalendarField{BaseField: newField})
+			}
+
+			if nil != view.Timeline {
+				newField.Wrap = view.Timeline.WrapField
+				view.Timeline.Fields = append(view.Timeline.Fields, &av.ViewTimelineField{BaseField: newField})
+			}
+		}
+	}
+	return
+}
+
+// findPreferredDateKey 查找日历和时间线视图默认使用的日期字段，依次查找日期、创建时间和更新时间字段，不会修改属性视图。
+func findPreferredDateKey(attrView *av.AttributeView) (ret *av.Key) {
+	for _, typ := range []av.KeyType{av.KeyTypeDate, av.KeyTypeCreated, av.KeyTypeUpdated} {
+		for _, kv := range attrView.KeyValues {
+			if typ == kv.Key.Type {
+				return kv.Key
+			}
+		}
+	}
+	return
+}
+
+// getPreferredDateKey 获取日历和时间线视图默认使用的日期字段，没有日期字段时新建一个。只能在写入属性视图时调用。
+func getPreferredDateKey(attrView *av.AttributeView) (ret *av.Key) {
+	for _, kv := range attrView.KeyValues {
+		if av.KeyTypeDate == kv.Key.Type {
+			ret = kv.Key
+			break
+		}
+	}
+
+	if nil == ret {
+		name := av.GetAttributeViewI18n("date")
+		ret = av.NewKey(ast.NewNodeID(), name, "", av.KeyTypeDate)
+		attrView.KeyValues = append(attrView.KeyValues, &av.KeyValues{Key: ret})
+		for _, view := range attrView.Views {
+			newField := &av.BaseField{ID: ret.ID}
+			if nil != view.Table {
+				newField.Wrap = view.Table.WrapField
+				view.Table.Columns = append(view.Table.Columns, &av.ViewTableColumn{BaseField: newField})
+			}
+
+			if nil != view.Gallery {
+				newField.Wrap = view.Gallery.WrapField
+				view.Gallery.CardFields = append(view.Gallery.CardFields, &av.ViewGalleryCardField{BaseField: newField})
+			}
+
+			if nil != view.Kanban {
+				newField.Wrap = view.Kanban.WrapField
+				view.Kanban.Fields = append(view.Kanban.Fields, &av.ViewKanbanField{BaseField: newField})
+			}
+
+			if nil != view.Calendar {
+				newField.Wrap = view.Calendar.WrapField
+				view.Calendar.Fields = append(view.Calendar.Fields, &av.ViewCalendarField{BaseField: newField})
+			}
+
+			if nil != view.Timeline {
+				newField.Wrap = view.Timeline.WrapField
+				view.Timeline.Fields = append(view.Timeline.Fields, &av.ViewTimelineField{BaseField: newField})
+			}
 		}
 	}
 	return
 }
 
+func isDateKey(key *av.Key) bool {
+	return av.KeyTypeDate == key.Type || av.KeyTypeCreated == key.Type || av.KeyTypeUpdated == key.Type
+}
+
+func isDependencyKey(attrView *av.AttributeView, key *av.Key) bool {
+	return av.KeyTypeRelation == key.Type && nil != key.Relation && key.Relation.AvID == attrView.ID
+}
+
 func (tx *Transaction) doSetAttrViewViewName(operation *Operation) (ret *TxErr) {
 	var err error
 	avID := operation.AvID
@@ -3448,7 +3956,7 @@ func setAttributeViewColumnCalc(operation *Operation) (err error) {
 				break
 			}
 		}
-	case av.LayoutTypeGallery, av.LayoutTypeKanban:
+	case av.LayoutTypeGallery, av.LayoutTypeKanban, av.LayoutTypeCalendar, av.LayoutTypeTimeline:
 		return
 	}
 
@@ -3964,6 +4472,38 @@ func duplicateAttributeViewKey(operation *Operation) (err error) {
 					break
 				}
 			}
+		case av.LayoutTypeCalendar:
+			for i, field := range view.Calendar.Fields {
+				if field.ID == key.ID {
+					view.Calendar.Fields = append(view.Calendar.Fields[:i+1], append([]*av.ViewCalendarField{
+						{
+							BaseField: &av.BaseField{
+								ID:     copyKey.ID,
+								Wrap:   field.Wrap,
+								Hidden: field.Hidden,
+								Desc:   field.Desc,
+							},
+						},
+					}, view.Calendar.Fields[i+1:]...)...)
+					break
+				}
+			}
+		case av.LayoutTypeTimeline:
+			for i, field := range view.Timeline.Fields {
+				if field.ID == key.ID {
+					view.Timeline.Fields = append(view.Timeline.Fields[:i+1], append([]*av.ViewTimelineField{
+						{
+							BaseField: &av.BaseField{
+								ID:     copyKey.ID,
+								Wrap:   field.Wrap,
+								Hidden: field.Hidden,
+								Desc:   field.Desc,
+							},
+						},
+					}, view.Timeline.Fields[i+1:]...)...)
+					break
+				}
+			}
 		}
 	}
 
@@ -3998,7 +4538,7 @@ func setAttributeViewColWidth(operation *Operation) (err error) {
 				break
 			}
 		}
-	case av.LayoutTypeGallery, av.LayoutTypeKanban:
+	case av.LayoutTypeGallery, av.LayoutTypeKanban, av.LayoutTypeCalendar, av.LayoutTypeTimeline:
 		return
 	}
 
@@ -4052,6 +4592,22 @@ func setAttributeViewColWrap(operation *Operation) (err error) {
 			allFieldWrap = allFieldWrap && field.Wrap
 		}
 		view.Kanban.WrapField = allFieldWrap
+	case av.LayoutTypeCalendar:
+		for _, field := range view.Calendar.Fields {
+			if field.ID == operation.ID {
+				field.Wrap = newWrap
+			}
+			allFieldWrap = allFieldWrap && field.Wrap
+		}
+		view.Calendar.WrapField = allFieldWrap
+	case av.LayoutTypeTimeline:
+		for _, field := range view.Timeline.Fields {
+			if field.ID == operation.ID {
+				field.Wrap = newWrap
+			}
+			allFieldWrap = allFieldWrap && field.Wrap
+		}
+		view.Timeline.WrapField = allFieldWrap
 	}
 
 	err = av.SaveAttributeView(attrView)
@@ -4099,6 +4655,20 @@ func setAttributeViewColHidden(operation *Operation) (err error) {
 				break
 			}
 		}
+	case av.LayoutTypeCalendar:
+		for _, field := range view.Calendar.Fields {
+			if field.ID == operation.ID {
+				field.Hidden = operation.Data.(bool)
+				break
+			}
+		}
+	case av.LayoutTypeTimeline:
+		for _, field := range view.Timeline.Fields {
+			if field.ID == operation.ID {
+				field.Hidden = operation.Data.(bool)
+				break
+			}
+		}
 	}
 
 	err = av.SaveAttributeView(attrView)
@@ -4132,7 +4702,7 @@ func setAttributeViewColPin(operation *Operation) (err error) {
 				break
 			}
 		}
-	case av.LayoutTypeGallery, av.LayoutTypeKanban:
+	case av.LayoutTypeGallery, av.LayoutTypeKanban, av.LayoutTypeCalendar, av.LayoutTypeTimeline:
 		return
 	}
 
@@ -4221,7 +4791,7 @@ func sortAttributeViewRow(operation *Operation) (err error) {
 		if groupView := view.GetGroupByID(operation.GroupID); nil != groupView {
 			groupKey := view.GetGroupKey(attrView)
 			isAcrossGroup := operation.GroupID != operation.TargetGroupID
-			if isAcrossGroup && (av.KeyTypeTemplate == groupKey.Type || av.KeyTypeCreated == groupKey.Type || av.KeyTypeUpdated == groupKey.Type) {
+			if isAcrossGroup && (av.KeyTypeTemplate == groupKey.Type || av.KeyTypeFormula == groupKey.Type || av.KeyTypeCreated == groupKey.Type || av.KeyTypeUpdated == groupKey.Type) {
 				// 这些字段类型不支持跨分组移动，因为它们的值是自动计算生成的
 				return
 			}
@@ -4395,6 +4965,48 @@ func SortAttributeViewViewKey(avID, blockID, keyID, previousKeyID string) (err e
 			}
 		}
 		view.Kanban.Fields = util.InsertElem(view.Kanban.Fields, previousIndex, field)
+	case av.LayoutTypeCalendar:
+		var field *av.ViewCalendarField
+		for i, calendarField := range view.Calendar.Fields {
+			if calendarField.ID == keyID {
+				field = calendarField
+				curIndex = i
+				break
+			}
+		}
+		if nil == field {
+			return
+		}
+
+		view.Calendar.Fields = append(view.Calendar.Fields[:curIndex], view.Calendar.Fields[curIndex+1:]...)
+		for i, calendarField := range view.Calendar.Fields {
+			if calendarField.ID == previousKeyID {
+				previousIndex = i + 1
+				break
+			}
+		}
+		view.Calendar.Fields = util.InsertElem(view.Calendar.Fields, previousIndex, field)
+	case av.LayoutTypeTimeline:
+		var field *av.ViewTimelineField
+		for i, timelineField := range view.Timeline.Fields {
+			if timelineField.ID == keyID {
+				field = timelineField
+				curIndex = i
+				break
+			}
+		}
+		if nil == field {
+			return
+		}
+
+		view.Timeline.Fields = append(view.Timeline.Fields[:curIndex], view.Timeline.Fields[curIndex+1:]...)
+		for i, timelineField := range view.Timeline.Fields {
+			if timelineField.ID == previousKeyID {
+				previousIndex = i + 1
+				break
+			}
+		}
+		view.Timeline.Fields = util.InsertElem(view.Timeline.Fields, previousIndex, field)
 	}
 
 	err = av.SaveAttributeView(attrView)
@@ -4503,12 +5115,15 @@ func AddAttributeViewKey(avID, keyID, keyName, keyType, keyIcon, previousKeyID s
 	switch keyTyp {
 	case av.KeyTypeText, av.KeyTypeNumber, av.KeyTypeDate, av.KeyTypeSelect, av.KeyTypeMSelect, av.KeyTypeURL, av.KeyTypeEmail,
 		av.KeyTypePhone, av.KeyTypeMAsset, av.KeyTypeTemplate, av.KeyTypeCreated, av.KeyTypeUpdated, av.KeyTypeCheckbox,
-		av.KeyTypeRelation, av.KeyTypeRollup, av.KeyTypeLineNumber:
+		av.KeyTypeRelation, av.KeyTypeRollup, av.KeyTypeLineNumber, av.KeyTypeFormula:
 
 		key := av.NewKey(keyID, keyName, keyIcon, keyTyp)
 		if av.KeyTypeRollup == keyTyp {
 			key.Rollup = &av.Rollup{Calc: &av.RollupCalc{Operator: av.CalcOperatorNone}}
 		}
+		if av.KeyTypeFormula == keyTyp {
+			key.Formula = &av.Formula{}
+		}
 
 		attrView.KeyValues = append(attrView.KeyValues, &av.KeyValues{Key: key})
 
@@ -4518,8 +5133,8 @@ func AddAttributeViewKey(avID, keyID, keyName, keyType, keyIcon, previousKeyID s
 				newField.Wrap = view.Table.WrapField
 
 				if "" == previousKeyID {
-					if av.LayoutTypeGallery == currentView.LayoutType || av.LayoutTypeKanban == currentView.LayoutType {
-						// 如果当前视图是卡片或看板视图则添加到最后
+					if av.LayoutTypeGallery == currentView.LayoutType || av.LayoutTypeKanban == currentView.LayoutType || av.LayoutTypeCalendar == currentView.LayoutType || av.LayoutTypeTimeline == currentView.LayoutType {
+						// 如果当前视图是卡片、看板、日历或时间线视图则添加到最后
 						view.Table.Columns = append(view.Table.Columns, &av.ViewTableColumn{BaseField: newField})
 					} else {
 						view.Table.Columns = append([]*av.ViewTableColumn{{BaseField: newField}}, view.Table.Columns...)
@@ -4578,6 +5193,46 @@ func AddAttributeViewKey(avID, keyID, keyName, keyType, keyIcon, previousKeyID s
 					}
 				}
 			}
+
+			if nil != view.Calendar {
+				newField.Wrap = view.Calendar.WrapField
+
+				if "" == previousKeyID {
+					view.Calendar.Fields = append(view.Calendar.Fields, &av.ViewCalendarField{BaseField: newField})
+				} else {
+					added := false
+					for i, field := range view.Calendar.Fields {
+						if field.ID == previousKeyID {
+							view.Calendar.Fields = append(view.Calendar.Fields[:i+1], append([]*av.ViewCalendarField{{BaseField: newField}}, view.Calendar.Fields[i+1:]...)...)
+							added = true
+							break
+						}
+					}
+					if !added {
+						view.Calendar.Fields = append(view.Calendar.Fields, &av.ViewCalendarField{BaseField: newField})
+					}
+				}
+			}
+
+			if nil != view.Timeline {
+				newField.Wrap = view.Timeline.WrapField
+
+				if "" == previousKeyID {
+					view.Timeline.Fields = append(view.Timeline.Fields, &av.ViewTimelineField{BaseField: newField})
+				} else {
+					added := false
+					for i, field := range view.Timeline.Fields {
+						if field.ID == previousKeyID {
+							view.Timeline.Fields = append(view.Timeline.Fields[:i+1], append([]*av.ViewTimelineField{{BaseField: newField}}, view.Timeline.Fields[i+1:]...)...)
+							added = true
+							break
+						}
+					}
+					if !added {
+						view.Timeline.Fields = append(view.Timeline.Fields, &av.ViewTimelineField{BaseField: newField})
+					}
+				}
+			}
 		}
 	}
 
@@ -4615,6 +5270,43 @@ func updateAttributeViewColTemplate(operation *Operation) (err error) {
 	return
 }
 
+func (tx *Transaction) doUpdateAttrViewColFormula(operation *Operation) (ret *TxErr) {
+	err := updateAttributeViewColFormula(operation)
+	if err != nil {
+		return &TxErr{code: TxErrHandleAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func updateAttributeViewColFormula(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	keyValues, err := attrView.GetKeyValues(operation.ID)
+	if nil != err {
+		return
+	}
+
+	if av.KeyTypeFormula != keyValues.Key.Type {
+		err = av.ErrWrongKeyType
+		return
+	}
+
+	// 表达式有语法错误、引用了不存在的字段或者存在循环引用时不保存
+	keyValues.Key.Formula = &av.Formula{Expression: strings.TrimSpace(operation.Data.(string))}
+	if "" != keyValues.Key.Formula.Expression {
+		if err = av.CheckFormula(attrView, keyValues.Key); nil != err {
+			return
+		}
+	}
+
+	regenAttrViewGroups(attrView)
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
 func (tx *Transaction) doUpdateAttrViewColNumberFormat(operation *Operation) (ret *TxErr) {
 	err := updateAttributeViewColNumberFormat(operation)
 	if err != nil {
@@ -4631,9 +5323,9 @@ func updateAttributeViewColNumberFormat(operation *Operation) (err error) {
 
 	colType := av.KeyType(operation.Typ)
 	switch colType {
-	case av.KeyTypeNumber:
+	case av.KeyTypeNumber, av.KeyTypeFormula: // 公式字段计算结果为数字时使用该格式
 		for _, keyValues := range attrView.KeyValues {
-			if keyValues.Key.ID == operation.ID && av.KeyTypeNumber == keyValues.Key.Type {
+			if keyValues.Key.ID == operation.ID && colType == keyValues.Key.Type {
 				keyValues.Key.NumberFormat = av.NumberFormat(operation.Format)
 				break
 			}
@@ -4663,13 +5355,16 @@ func updateAttributeViewColumn(operation *Operation) (err error) {
 	switch colType {
 	case av.KeyTypeBlock, av.KeyTypeText, av.KeyTypeNumber, av.KeyTypeDate, av.KeyTypeSelect, av.KeyTypeMSelect, av.KeyTypeURL, av.KeyTypeEmail,
 		av.KeyTypePhone, av.KeyTypeMAsset, av.KeyTypeTemplate, av.KeyTypeCreated, av.KeyTypeUpdated, av.KeyTypeCheckbox,
-		av.KeyTypeRelation, av.KeyTypeRollup, av.KeyTypeLineNumber:
+		av.KeyTypeRelation, av.KeyTypeRollup, av.KeyTypeLineNumber, av.KeyTypeFormula:
 		for _, keyValues := range attrView.KeyValues {
 			if keyValues.Key.ID == operation.ID {
 				keyValues.Key.Name = strings.TrimSpace(operation.Name)
 
 				changeType = keyValues.Key.Type != colType
 				keyValues.Key.Type = colType
+				if av.KeyTypeFormula == colType && nil == keyValues.Key.Formula {
+					keyValues.Key.Formula = &av.Formula{}
+				}
 
 				for _, value := range keyValues.Values {
 					value.Type = colType
@@ -4797,6 +5492,20 @@ func RemoveAttributeViewKey(avID, keyID string, removeRelationDest bool) (err er
 									break
 								}
 							}
+						case av.LayoutTypeCalendar:
+							for i, field := range view.Calendar.Fields {
+								if field.ID == removedKey.Relation.BackKeyID {
+									view.Calendar.Fields = append(view.Calendar.Fields[:i], view.Calendar.Fields[i+1:]...)
+									break
+								}
+							}
+						case av.LayoutTypeTimeline:
+							for i, field := range view.Timeline.Fields {
+								if field.ID == removedKey.Relation.BackKeyID {
+									view.Timeline.Fields = append(view.Timeline.Fields[:i], view.Timeline.Fields[i+1:]...)
+									break
+								}
+							}
 						}
 					}
 				}
@@ -4850,6 +5559,24 @@ func RemoveAttributeViewKey(avID, keyID string, removeRelationDest bool) (err er
 				}
 			}
 		}
+
+		if nil != view.Calendar {
+			for i, field := range view.Calendar.Fields {
+				if field.ID == keyID {
+					view.Calendar.Fields = append(view.Calendar.Fields[:i], view.Calendar.Fields[i+1:]...)
+					break
+				}
+			}
+		}
+
+		if nil != view.Timeline {
+			for i, field := range view.Timeline.Fields {
+				if field.ID == keyID {
+					view.Timeline.Fields = append(view.Timeline.Fields[:i], view.Timeline.Fields[i+1:]...)
+					break
+				}
+			}
+		}
 	}
 
 	for _, view := range attrView.Views {
@@ -5569,14 +6296,14 @@ func removeAttributeViewColumnOption(operation *Operation) (err error) {
 
 	// 如果存在选项对应的过滤条件，则删除过滤条件中设置的选项值 https://github.com/siyuan-note/siyuan/issues/15536
 	for _, view := range attrView.Views {
-		for _, filter := range view.Filters {
+		view.Filters = av.RemoveFilters(view.Filters, func(filter *av.ViewFilter) bool {
 			if filter.Column != operation.ID {
-				continue
+				return false
 			}
 
 			if nil != filter.Value && (av.KeyTypeSelect == filter.Value.Type || av.KeyTypeMSelect == filter.Value.Type) {
 				if av.FilterOperatorIsEmpty == filter.Operator || av.FilterOperatorIsNotEmpty == filter.Operator {
-					continue
+					return false
 				}
 
 				for i, opt := range filter.Value.MSelect {
@@ -5585,17 +6312,11 @@ func removeAttributeViewColumnOption(operation *Operation) (err error) {
 						break
 					}
 				}
-				if 1 > len(filter.Value.MSelect) {
-					// 如果删除后选项值为空，则删除过滤条件
-					for i, f := range view.Filters {
-						if f.Column == operation.ID && f.Value == filter.Value {
-							view.Filters = append(view.Filters[:i], view.Filters[i+1:]...)
-							break
-						}
-					}
-				}
+				// 如果删除后选项值为空，则删除过滤条件
+				return 1 > len(filter.Value.MSelect)
 			}
-		}
+			return false
+		})
 	}
 
 	regenAttrViewGroups(attrView)
@@ -5700,7 +6421,7 @@ func updateAttributeViewColumnOption(operation *Operation) (err error) {
 	// 如果存在选项对应的过滤条件，需要更新过滤条件中设置的选项值
 	// Database select field filters follow option editing changes https://github.com/siyuan-note/siyuan/issues/10881
 	for _, view := range attrView.Views {
-		for _, filter := range view.Filters {
+		for _, filter := range av.GetLeafFilters(view.Filters) {
 			if filter.Column != key.ID {
 				continue
 			}
@@ -31,14 +31,12 @@ import (
 
 func checkAttrView(attrView *av.AttributeView, view *av.View) {
 	// 字段删除以后需要删除设置的过滤和排序
-	tmpFilters := []*av.ViewFilter{}
-	for _, f := range view.Filters {
-		if k, _ := attrView.GetKey(f.Column); nil != k {
-			tmpFilters = append(tmpFilters, f)
-		}
-	}
-	changed := len(tmpFilters) != len(view.Filters)
-	view.Filters = tmpFilters
+	filterCount := len(av.GetLeafFilters(view.Filters))
+	view.Filters = av.RemoveFilters(view.Filters, func(f *av.ViewFilter) bool {
+		k, _ := attrView.GetKey(f.Column)
+		return nil == k
+	})
+	changed := filterCount != len(av.GetLeafFilters(view.Filters))
 
 	tmpSorts := []*av.ViewSort{}
 	for _, s := range view.Sorts {
@@ -58,6 +56,39 @@ func checkAttrView(attrView *av.AttributeView, view *av.View) {
 		}
 	}
 
+	// 日历和时间线视图的日期字段删除或者变更类型以后需要重新选择日期字段
+	if av.LayoutTypeCalendar == view.LayoutType && nil != view.Calendar {
+		if k, _ := attrView.GetKey(view.Calendar.DateFieldID); nil == k || !isDateKey(k) {
+			// 这里是渲染时的校验，没有日期字段时不新建字段，清空后由用户重新选择
+			dateFieldID := ""
+			if preferred := findPreferredDateKey(attrView); nil != preferred {
+				dateFieldID = preferred.ID
+			}
+			if dateFieldID != view.Calendar.DateFieldID {
+				view.Calendar.DateFieldID = dateFieldID
+				changed = true
+			}
+		}
+	}
+	if av.LayoutTypeTimeline == view.LayoutType && nil != view.Timeline {
+		if k, _ := attrView.GetKey(view.Timeline.DateFieldID); nil == k || !isDateKey(k) {
+			dateFieldID := ""
+			if preferred := findPreferredDateKey(attrView); nil != preferred {
+				dateFieldID = preferred.ID
+			}
+			if dateFieldID != view.Timeline.DateFieldID {
+				view.Timeline.DateFieldID = dateFieldID
+				changed = true
+			}
+		}
+		if "" != view.Timeline.DependencyFieldID {
+			if k, _ := attrView.GetKey(view.Timeline.DependencyFieldID); nil == k || !isDependencyKey(attrView, k) {
+				view.Timeline.DependencyFieldID = ""
+				changed = true
+			}
+		}
+	}
+
 	// 订正视图类型
 	for i, v := range attrView.Views {
 		if av.LayoutTypeGallery == v.LayoutType && nil == v.Gallery {
@@ -191,6 +191,10 @@ func renderAttributeViewGroups(viewable av.Viewable, attrView *av.AttributeView,
 			groupView.Gallery.CardFields = nil
 		case av.LayoutTypeKanban:
 			groupView.Kanban.Fields = nil
+		case av.LayoutTypeCalendar:
+			groupView.Calendar.Fields = nil
+		case av.LayoutTypeTimeline:
+			groupView.Timeline.Fields = nil
 		}
 	}
 	viewable.SetGroups(groups)
@@ -382,7 +386,7 @@ func isGroupByTemplate(attrView *av.AttributeView, view *av.View) bool {
 	if nil == groupKey {
 		return false
 	}
-	return av.KeyTypeTemplate == groupKey.Type
+	return av.KeyTypeTemplate == groupKey.Type || av.KeyTypeFormula == groupKey.Type
 }
 
 func renderViewableInstance(viewable av.Viewable, view *av.View, attrView *av.AttributeView, page, pageSize int) (err error) {
@@ -439,6 +443,26 @@ func renderViewableInstance(viewable av.Viewable, view *av.View, attrView *av.At
 			end = len(kanban.Cards)
 		}
 		kanban.Cards = kanban.Cards[start:end]
+	case av.LayoutTypeCalendar:
+		// 日历按显示的月或周限定事项范围，不分页
+		calendar := viewable.(*av.Calendar)
+		calendar.EventCount = len(calendar.Events)
+		calendar.PageSize = view.PageSize
+		calendar.BuildDays()
+	case av.LayoutTypeTimeline:
+		timeline := viewable.(*av.Timeline)
+		timeline.ItemCount = len(timeline.Items)
+		timeline.PageSize = view.PageSize
+		if 1 > pageSize {
+			pageSize = timeline.PageSize
+		}
+		start := (page - 1) * pageSize
+		end := start + pageSize
+		if len(timeline.Items) < end {
+			end = len(timeline.Items)
+		}
+		timeline.Items = timeline.Items[start:end]
+		timeline.CalcRange()
 	}
 	return
 }
@@ -821,12 +821,12 @@ func searchBackmention(mentionKeywords []string, keyword string, excludeBacklink
 		if Conf.Search.BacklinkMentionKeywordsLimit < i {
 			util.PushMsg(fmt.Sprintf(Conf.Language(38), len(mentionKeywords)), 5000)
 			mentionKeyword = strings.ReplaceAll(mentionKeyword, "\"", "\"\"")
-			buf.WriteString("\"" + mentionKeyword + "\"")
+			buf.WriteString("\"" + sql.SegmentFTSQuery(mentionKeyword) + "\"")
 			break
 		}
 
 		mentionKeyword = strings.ReplaceAll(mentionKeyword, "\"", "\"\"")
-		buf.WriteString("\"" + mentionKeyword + "\"")
+		buf.WriteString("\"" + sql.SegmentFTSQuery(mentionKeyword) + "\"")
 		if i < len(mentionKeywords)-1 {
 			buf.WriteString(" OR ")
 		}
@@ -920,7 +920,7 @@ func resolveEmbedContent(n *ast.Node, luteEngine *lute.Lute) {
 		stmt = strings.ReplaceAll(stmt, editor.IALValEscNewLine, "\n")
 
 		// 执行查询获取嵌入的块
-		sqlBlocks := sql.SelectBlocksRawStmt(stmt, 1, Conf.Search.Limit)
+		sqlBlocks := sql.SelectBlocksReadOnlyRawStmt(stmt, 1, Conf.Search.Limit)
 
 		// 收集所有嵌入块的内容 HTML
 		var embedContents []string
@@ -36,14 +36,21 @@ import (
 	"github.com/siyuan-note/siyuan/kernel/util"
 )
 
-func SetBlockReminder(id string, timed string) (err error) {
-	if !IsSubscriber() {
+func SetBlockReminder(id, timed, repeat string) (err error) {
+	// 启用本地提醒调度后不需要订阅也可以设置提醒
+	if !IsSubscriber() && !Conf.Reminder.Local {
 		if "ios" == util.Container {
 			return errors.New(Conf.Language(122))
 		}
 		return errors.New(Conf.Language(29))
 	}
 
+	if "0" != timed {
+		if repeat, err = normalizeReminderRepeat(repeat); err != nil {
+			return
+		}
+	}
+
 	var timedMills int64
 	if "0" != timed {
 		t, e := dateparse.ParseIn(timed, time.Now().Location())
@@ -72,23 +79,34 @@ func SetBlockReminder(id string, timed string) (err error) {
 	content := sql.NodeStaticContent(node, nil, false, false, false)
 	content = gulu.Str.SubStr(content, 128)
 	content = strings.ReplaceAll(content, editor.Zwsp, "")
-	err = SetCloudBlockReminder(id, content, timedMills)
-	if err != nil {
-		return
+	if IsSubscriber() {
+		err = SetCloudBlockReminder(id, content, timedMills)
+		if err != nil {
+			return
+		}
 	}
 
-	attrName := "custom-reminder-wechat"
+	attrName := reminderAttrName
 	if "0" == timed {
 		delete(attrs, attrName)
+		delete(attrs, reminderRepeatAttrName)
 		old := node.IALAttr(attrName)
 		oldTimedMills, e := dateparse.ParseIn(old, time.Now().Location())
 		if nil == e {
 			util.PushMsg(fmt.Sprintf(Conf.Language(109), oldTimedMills.Format("2006-01-02 15:04")), 3000)
 		}
 		node.RemoveIALAttr(attrName)
+		node.RemoveIALAttr(reminderRepeatAttrName)
 	} else {
 		attrs[attrName] = timed
 		node.SetIALAttr(attrName, timed)
+		if "" == repeat {
+			delete(attrs, reminderRepeatAttrName)
+			node.RemoveIALAttr(reminderRepeatAttrName)
+		} else {
+			attrs[reminderRepeatAttrName] = repeat
+			node.SetIALAttr(reminderRepeatAttrName, repeat)
+		}
 		util.PushMsg(fmt.Sprintf(Conf.Language(101), time.UnixMilli(timedMills).Format("2006-01-02 15:04")), 5000)
 	}
 	if err = indexWriteTreeUpsertQueue(tree); err != nil {
@@ -96,6 +114,7 @@ func SetBlockReminder(id string, timed string) (err error) {
 	}
 	IncSync()
 	cache.PutBlockIAL(id, attrs)
+	removeReminderState(id)
 	return
 }
 
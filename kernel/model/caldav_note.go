@@ -0,0 +1,476 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/88250/gulu"
+	"github.com/araddon/dateparse"
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav/caldav"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/av"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+)
+
+// 笔记日历是根据笔记数据实时生成的虚拟日历，不保存 *.ics 文件，CalDAV 客户端的修改会通过事务写回块属性或者属性视图。
+const (
+	CalDavRemindersCalendarPath = CalDavHomeSetPath + "/siyuan-reminders" // 块提醒日历
+	CalDavDatabasesCalendarPath = CalDavHomeSetPath + "/siyuan-databases" // 属性视图日期字段日历
+
+	reminderAttrName = "custom-reminder-wechat"
+
+	noteCalendarProductID = "-//SiYuan//SiYuan Note//EN"
+)
+
+var (
+	remindersCalendar = caldav.Calendar{
+		Path:                  CalDavRemindersCalendarPath,
+		Name:                  "SiYuan reminders",
+		Description:           "Block reminders",
+		MaxResourceSize:       calendarMaxResourceSize,
+		SupportedComponentSet: []string{ical.CompEvent},
+	}
+	databasesCalendar = caldav.Calendar{
+		Path:                  CalDavDatabasesCalendarPath,
+		Name:                  "SiYuan databases",
+		Description:           "Date fields of databases",
+		MaxResourceSize:       calendarMaxResourceSize,
+		SupportedComponentSet: []string{ical.CompEvent},
+	}
+
+	ErrorCalDavNoteCalendarReadOnly = errors.New("CalDAV: calendar is generated from notes and cannot be modified")
+	ErrorCalDavNoteCalendarNoEvent  = errors.New("CalDAV: calendar object has no event")
+)
+
+func isNoteCalendarPath(calendarPath string) bool {
+	return CalDavRemindersCalendarPath == calendarPath || CalDavDatabasesCalendarPath == calendarPath || CalDavTasksCalendarPath == calendarPath
+}
+
+func isNoteCalendarEnabled(calendarPath string) bool {
+	switch calendarPath {
+	case CalDavRemindersCalendarPath:
+		return Conf.CalDAV.Reminders
+	case CalDavDatabasesCalendarPath:
+		return 0 < len(Conf.CalDAV.AttributeViews)
+	case CalDavTasksCalendarPath:
+		return Conf.CalDAV.Tasks
+	}
+	return false
+}
+
+func listNoteCalendars() (ret []caldav.Calendar) {
+	if isNoteCalendarEnabled(CalDavRemindersCalendarPath) {
+		ret = append(ret, remindersCalendar)
+	}
+	if isNoteCalendarEnabled(CalDavDatabasesCalendarPath) {
+		ret = append(ret, databasesCalendar)
+	}
+	if isNoteCalendarEnabled(CalDavTasksCalendarPath) {
+		ret = append(ret, tasksCalendar)
+	}
+	return
+}
+
+func getNoteCalendar(calendarPath string) (ret *caldav.Calendar, err error) {
+	if !isNoteCalendarEnabled(calendarPath) {
+		err = ErrorCalDavCalendarNotFound
+		return
+	}
+
+	switch calendarPath {
+	case CalDavRemindersCalendarPath:
+		calendar := remindersCalendar
+		ret = &calendar
+	case CalDavDatabasesCalendarPath:
+		calendar := databasesCalendar
+		ret = &calendar
+	case CalDavTasksCalendarPath:
+		calendar := tasksCalendar
+		ret = &calendar
+	}
+	return
+}
+
+func listNoteCalendarObjects(calendarPath string) (ret []caldav.CalendarObject, err error) {
+	if !isNoteCalendarEnabled(calendarPath) {
+		err = ErrorCalDavCalendarNotFound
+		return
+	}
+
+	switch calendarPath {
+	case CalDavRemindersCalendarPath:
+		ret = listReminderCalendarObjects()
+	case CalDavDatabasesCalendarPath:
+		for _, avID := range Conf.CalDAV.AttributeViews {
+			ret = append(ret, listDatabaseCalendarObjects(avID)...)
+		}
+	case CalDavTasksCalendarPath:
+		ret = listTaskCalendarObjects()
+	}
+	return
+}
+
+func getNoteCalendarObject(objectPath string) (ret *caldav.CalendarObject, err error) {
+	calendarPath, objectID, err := parseNoteCalendarObjectPath(objectPath)
+	if err != nil {
+		return
+	}
+
+	switch calendarPath {
+	case CalDavRemindersCalendarPath:
+		ret = getReminderCalendarObject(objectID)
+	case CalDavDatabasesCalendarPath:
+		avID, keyID, itemID := parseDatabaseCalendarObjectID(objectID)
+		for _, object := range listDatabaseCalendarObjects(avID) {
+			if object.Path == getDatabaseCalendarObjectPath(avID, keyID, itemID) {
+				ret = &object
+				break
+			}
+		}
+	case CalDavTasksCalendarPath:
+		ret = getTaskCalendarObject(objectID)
+	}
+
+	if nil == ret {
+		err = ErrorCalDavCalendarObjectNotFound
+	}
+	return
+}
+
+func putNoteCalendarObject(objectPath string, calendarData *ical.Calendar) (ret *caldav.CalendarObject, err error) {
+	calendarPath, objectID, err := parseNoteCalendarObjectPath(objectPath)
+	if err != nil {
+		return
+	}
+
+	// 只能修改已有的事件，不能在笔记日历中新建事件
+	if _, err = getNoteCalendarObject(objectPath); err != nil {
+		err = ErrorCalDavNoteCalendarReadOnly
+		return
+	}
+
+	if CalDavTasksCalendarPath == calendarPath {
+		if err = putTaskCalendarObject(objectID, calendarData); err != nil {
+			return
+		}
+		ret, err = getNoteCalendarObject(objectPath)
+		return
+	}
+
+	events := calendarData.Events()
+	if 1 > len(events) {
+		err = ErrorCalDavNoteCalendarNoEvent
+		return
+	}
+	event := events[0]
+
+	start, err := event.DateTimeStart(time.Local)
+	if err != nil {
+		return
+	}
+
+	switch calendarPath {
+	case CalDavRemindersCalendarPath:
+		// 沿用块上已有的重复规则，并和界面上设置提醒一样同步云端提醒
+		repeat := sql.GetBlockAttrs(objectID)[reminderRepeatAttrName]
+		if err = SetBlockReminder(objectID, start.In(time.Local).Format("20060102150405"), repeat); err != nil {
+			return
+		}
+		if bt := treenode.GetBlockTree(objectID); nil != bt {
+			ReloadProtyle(bt.RootID)
+		}
+	case CalDavDatabasesCalendarPath:
+		end, endErr := event.DateTimeEnd(time.Local)
+		if endErr != nil {
+			err = endErr
+			return
+		}
+
+		isNotTime := ical.ValueDate == event.Props.Get(ical.PropDateTimeStart).ValueType()
+		if isNotTime {
+			// 全天事件的结束日期不包含在事件中
+			end = end.AddDate(0, 0, -1)
+		}
+		date := &av.ValueDate{Content: start.UnixMilli(), IsNotEmpty: true, IsNotTime: isNotTime}
+		if end.After(start) {
+			date.HasEndDate = true
+			date.Content2 = end.UnixMilli()
+			date.IsNotEmpty2 = true
+		}
+
+		avID, keyID, itemID := parseDatabaseCalendarObjectID(objectID)
+		performNoteCalendarTransaction(&Operation{Action: "updateAttrViewCell", AvID: avID, KeyID: keyID, RowID: itemID, Data: map[string]interface{}{"date": date}})
+		ReloadAttrView(avID)
+	}
+
+	ret, err = getNoteCalendarObject(objectPath)
+	return
+}
+
+func deleteNoteCalendarObject(objectPath string) (err error) {
+	calendarPath, objectID, err := parseNoteCalendarObjectPath(objectPath)
+	if err != nil {
+		return
+	}
+
+	if _, err = getNoteCalendarObject(objectPath); err != nil {
+		return
+	}
+
+	switch calendarPath {
+	case CalDavRemindersCalendarPath:
+		if err = SetBlockReminder(objectID, "0", ""); err != nil {
+			return
+		}
+		if bt := treenode.GetBlockTree(objectID); nil != bt {
+			ReloadProtyle(bt.RootID)
+		}
+	case CalDavDatabasesCalendarPath:
+		avID, keyID, itemID := parseDatabaseCalendarObjectID(objectID)
+		performNoteCalendarTransaction(&Operation{Action: "updateAttrViewCell", AvID: avID, KeyID: keyID, RowID: itemID, Data: map[string]interface{}{"date": &av.ValueDate{}}})
+		ReloadAttrView(avID)
+	case CalDavTasksCalendarPath:
+		deleteTaskCalendarObject(objectID)
+	}
+	return
+}
+
+func performNoteCalendarTransaction(operation *Operation) {
+	transaction := &Transaction{DoOperations: []*Operation{operation}}
+	PerformTransactions(&[]*Transaction{transaction})
+	FlushTxQueue()
+}
+
+func listReminderCalendarObjects() (ret []caldav.CalendarObject) {
+	rows, err := sql.QueryNoLimit("SELECT block_id, value FROM attributes WHERE name = '" + reminderAttrName + "'")
+	if err != nil {
+		logging.LogErrorf("query reminders failed: %s", err)
+		return
+	}
+
+	var ids []string
+	timeds := map[string]string{}
+	for _, row := range rows {
+		id := row["block_id"].(string)
+		ids = append(ids, id)
+		timeds[id] = row["value"].(string)
+	}
+
+	for _, block := range sql.GetBlocks(ids) {
+		if nil == block {
+			continue
+		}
+
+		if object := newReminderCalendarObject(block, timeds[block.ID]); nil != object {
+			ret = append(ret, *object)
+		}
+	}
+	return
+}
+
+func getReminderCalendarObject(id string) *caldav.CalendarObject {
+	block := sql.GetBlock(id)
+	if nil == block {
+		return nil
+	}
+
+	attrs := sql.GetBlockAttrs(id)
+	return newReminderCalendarObject(block, attrs[reminderAttrName])
+}
+
+func newReminderCalendarObject(block *sql.Block, timed string) *caldav.CalendarObject {
+	if "" == timed {
+		return nil
+	}
+
+	start, err := dateparse.ParseIn(timed, time.Now().Location())
+	if err != nil {
+		logging.LogWarnf("parse reminder [%s] of block [%s] failed: %s", timed, block.ID, err)
+		return nil
+	}
+
+	event := newNoteCalendarEvent(block.ID, block.Content, block.HPath, block.ID)
+	event.Props.SetDateTime(ical.PropDateTimeStart, start.UTC())
+
+	// 事件开始时提醒
+	alarm := ical.NewComponent(ical.CompAlarm)
+	alarm.Props.SetText(ical.PropAction, "DISPLAY")
+	alarm.Props.SetText(ical.PropDescription, event.Props.Get(ical.PropSummary).Value)
+	trigger := ical.NewProp(ical.PropTrigger)
+	trigger.SetDuration(0)
+	alarm.Props.Set(trigger)
+	event.Children = append(event.Children, alarm)
+
+	modTime := start
+	if updated, parseErr := time.ParseInLocation("20060102150405", block.Updated, time.Local); nil == parseErr {
+		modTime = updated
+	}
+	return newNoteCalendarObject(PathJoinWithSlash(CalDavRemindersCalendarPath, block.ID+ICalendarFileExt), event.Component, modTime)
+}
+
+func listDatabaseCalendarObjects(avID string) (ret []caldav.CalendarObject) {
+	if !gulu.Str.Contains(avID, Conf.CalDAV.AttributeViews) {
+		return
+	}
+
+	attrView, err := av.ParseAttributeView(avID)
+	if err != nil {
+		logging.LogWarnf("parse attribute view [%s] failed: %s", avID, err)
+		return
+	}
+
+	var linkBlockID string
+	if mirrorBlockIDs := treenode.GetMirrorAttrViewBlockIDs(avID); 0 < len(mirrorBlockIDs) {
+		linkBlockID = mirrorBlockIDs[0]
+	}
+
+	for _, keyValues := range attrView.KeyValues {
+		if av.KeyTypeDate != keyValues.Key.Type {
+			continue
+		}
+
+		for _, value := range keyValues.Values {
+			start, end, isNotTime, ok := av.GetDateRange(value)
+			if !ok {
+				continue
+			}
+
+			blockValue := attrView.GetBlockValue(value.BlockID)
+			if nil == blockValue || nil == blockValue.Block {
+				continue
+			}
+
+			summary := blockValue.Block.Content
+			if "" == strings.TrimSpace(summary) {
+				summary = attrView.Name
+			}
+			description := attrView.Name + " - " + keyValues.Key.Name
+			link := linkBlockID
+			if !blockValue.IsDetached && "" != blockValue.Block.ID {
+				link = blockValue.Block.ID
+			}
+
+			objectID := getDatabaseCalendarObjectID(avID, keyValues.Key.ID, value.BlockID)
+			event := newNoteCalendarEvent(objectID, summary, description, link)
+			startTime, endTime := time.UnixMilli(start), time.UnixMilli(end)
+			if isNotTime {
+				event.Props.SetDate(ical.PropDateTimeStart, startTime)
+				// 全天事件的结束日期不包含在事件中
+				event.Props.SetDate(ical.PropDateTimeEnd, endTime.AddDate(0, 0, 1))
+			} else {
+				event.Props.SetDateTime(ical.PropDateTimeStart, startTime.UTC())
+				if end > start {
+					event.Props.SetDateTime(ical.PropDateTimeEnd, endTime.UTC())
+				}
+			}
+
+			modTime := time.UnixMilli(value.UpdatedAt)
+			if 0 == value.UpdatedAt {
+				modTime = time.UnixMilli(value.CreatedAt)
+			}
+			objectPath := getDatabaseCalendarObjectPath(avID, keyValues.Key.ID, value.BlockID)
+			if object := newNoteCalendarObject(objectPath, event.Component, modTime); nil != object {
+				ret = append(ret, *object)
+			}
+		}
+	}
+	return
+}
+
+func getDatabaseCalendarObjectID(avID, keyID, itemID string) string {
+	return avID + "_" + keyID + "_" + itemID
+}
+
+func getDatabaseCalendarObjectPath(avID, keyID, itemID string) string {
+	return PathJoinWithSlash(CalDavDatabasesCalendarPath, getDatabaseCalendarObjectID(avID, keyID, itemID)+ICalendarFileExt)
+}
+
+func parseDatabaseCalendarObjectID(objectID string) (avID, keyID, itemID string) {
+	parts := strings.Split(objectID, "_")
+	if 3 != len(parts) {
+		return
+	}
+	return parts[0], parts[1], parts[2]
+}
+
+// parseNoteCalendarObjectPath 解析笔记日历对象路径，返回的 objectID 不包含 .ics 扩展名。
+func parseNoteCalendarObjectPath(objectPath string) (calendarPath, objectID string, err error) {
+	calendarPath, objectFileName, err := ParseCalendarObjectPath(objectPath)
+	if err != nil {
+		return
+	}
+
+	if !isNoteCalendarEnabled(calendarPath) {
+		err = ErrorCalDavCalendarNotFound
+		return
+	}
+
+	objectID = strings.TrimSuffix(objectFileName, ICalendarFileExt)
+	return
+}
+
+func newNoteCalendarEvent(uid, summary, description, blockID string) *ical.Event {
+	event := ical.NewEvent()
+	event.Props.SetText(ical.PropUID, uid)
+	event.Props.SetDateTime(ical.PropDateTimeStamp, time.Now().UTC())
+	event.Props.SetText(ical.PropSummary, summary)
+	if "" != description {
+		event.Props.SetText(ical.PropDescription, description)
+	}
+	if "" != blockID {
+		event.Props.SetURI(ical.PropURL, &url.URL{Scheme: "siyuan", Host: "blocks", Path: "/" + blockID})
+	}
+	return event
+}
+
+func newNoteCalendarObject(objectPath string, component *ical.Component, modTime time.Time) *caldav.CalendarObject {
+	calendar := ical.NewCalendar()
+	calendar.Props.SetText(ical.PropVersion, "2.0")
+	calendar.Props.SetText(ical.PropProductID, noteCalendarProductID)
+	calendar.Children = append(calendar.Children, component)
+
+	var data bytes.Buffer
+	if err := ical.NewEncoder(&data).Encode(calendar); err != nil {
+		logging.LogErrorf("encode iCalendar [%s] failed: %s", objectPath, err)
+		return nil
+	}
+
+	// DTSTAMP 每次都不同，计算 ETag 时去掉
+	etagData := bytes.Buffer{}
+	for _, line := range strings.Split(data.String(), "\n") {
+		if !strings.HasPrefix(line, ical.PropDateTimeStamp+":") {
+			etagData.WriteString(line)
+		}
+	}
+
+	return &caldav.CalendarObject{
+		Path:          objectPath,
+		ModTime:       modTime,
+		ContentLength: int64(data.Len()),
+		ETag:          fmt.Sprintf("%x", sha256.Sum256(etagData.Bytes()))[:32],
+		Data:          calendar,
+	}
+}
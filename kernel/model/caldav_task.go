@@ -0,0 +1,236 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute/ast"
+	"github.com/araddon/dateparse"
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav/caldav"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+// 任务待办是根据任务列表项实时生成的虚拟待办列表，CalDAV 客户端勾选待办或者修改截止时间后会更新任务列表项。
+const CalDavTasksCalendarPath = CalDavHomeSetPath + "/siyuan-tasks"
+
+var (
+	tasksCalendar = caldav.Calendar{
+		Path:                  CalDavTasksCalendarPath,
+		Name:                  "SiYuan tasks",
+		Description:           "Task list items",
+		MaxResourceSize:       calendarMaxResourceSize,
+		SupportedComponentSet: []string{ical.CompToDo},
+	}
+
+	ErrorCalDavTaskNotFound = errors.New("CalDAV: task list item not found")
+
+	// taskListItemMarkerRegexp 匹配任务列表项 Markdown 开头的任务标记，比如 `* [X] `、`1. [ ] `
+	taskListItemMarkerRegexp = regexp.MustCompile(`^\s*(?:[*+-]|\d+[.)])\s+\[([ xX])\]`)
+)
+
+func listTaskCalendarObjects() (ret []caldav.CalendarObject) {
+	// 按 ID 排序后分页查询，避免任务列表项数量超过单次查询上限时被截断
+	const pageSize = 512
+	for offset := 0; ; offset += pageSize {
+		stmt := taskBlocksStmt() + " ORDER BY id LIMIT " + strconv.Itoa(pageSize) + " OFFSET " + strconv.Itoa(offset)
+		blocks := sql.SelectBlocksRawStmtNoParse(stmt, pageSize)
+		ret = append(ret, newTaskCalendarObjects(blocks)...)
+		if pageSize > len(blocks) {
+			break
+		}
+	}
+	return
+}
+
+func getTaskCalendarObject(id string) *caldav.CalendarObject {
+	stmt := taskBlocksStmt() + " AND id = '" + strings.Join(escapeTaskSQLValues([]string{id}), "") + "' LIMIT 1"
+	objects := newTaskCalendarObjects(sql.SelectBlocksRawStmtNoParse(stmt, 1))
+	if 1 > len(objects) {
+		return nil
+	}
+	return &objects[0]
+}
+
+// taskBlocksStmt 返回查询同步范围内任务列表项的语句，笔记本和标签范围由 CalDAV 配置决定。
+func taskBlocksStmt() (ret string) {
+	ret = "SELECT * FROM blocks WHERE type = 'i' AND subtype = 't'"
+	if notebooks := Conf.CalDAV.TaskNotebooks; 0 < len(notebooks) {
+		ret += " AND box IN ('" + strings.Join(escapeTaskSQLValues(notebooks), "', '") + "')"
+	}
+	if tags := Conf.CalDAV.TaskTags; 0 < len(tags) {
+		// 标签一般位于列表项下的段落中
+		var conditions []string
+		for _, tag := range escapeTaskSQLValues(tags) {
+			like := "'%#" + escapeTaskSQLLike(tag) + "#%' ESCAPE '\\'"
+			conditions = append(conditions, "tag LIKE "+like, "id IN (SELECT parent_id FROM blocks WHERE type = 'p' AND tag LIKE "+like+")")
+		}
+		ret += " AND (" + strings.Join(conditions, " OR ") + ")"
+	}
+	return
+}
+
+func newTaskCalendarObjects(blocks []*sql.Block) (ret []caldav.CalendarObject) {
+	var ids []string
+	for _, block := range blocks {
+		ids = append(ids, block.ID)
+	}
+	attrs := sql.BatchGetBlockAttrs(ids)
+
+	for _, block := range blocks {
+		if object := newTaskCalendarObject(block, attrs[block.ID]); nil != object {
+			ret = append(ret, *object)
+		}
+	}
+	return
+}
+
+func newTaskCalendarObject(block *sql.Block, attrs map[string]string) *caldav.CalendarObject {
+	matches := taskListItemMarkerRegexp.FindStringSubmatch(block.Markdown)
+	if 2 > len(matches) {
+		return nil
+	}
+	completed := " " != matches[1]
+
+	// 待办和事件的基础属性相同，只是组件名称不同
+	todo := newNoteCalendarEvent(block.ID, block.Content, block.HPath, block.ID).Component
+	todo.Name = ical.CompToDo
+
+	updated, err := time.ParseInLocation("20060102150405", block.Updated, time.Local)
+	if err != nil {
+		updated = time.Now()
+	}
+	todo.Props.SetDateTime(ical.PropLastModified, updated.UTC())
+	if completed {
+		todo.Props.SetText(ical.PropStatus, "COMPLETED")
+		todo.Props.SetDateTime(ical.PropCompleted, updated.UTC())
+	} else {
+		todo.Props.SetText(ical.PropStatus, "NEEDS-ACTION")
+	}
+
+	if due := attrs[Conf.CalDAV.TaskDueAttr]; "" != due {
+		if dueTime, parseErr := dateparse.ParseIn(due, time.Local); nil == parseErr {
+			if isDateOnly(due) {
+				todo.Props.SetDate(ical.PropDue, dueTime)
+			} else {
+				todo.Props.SetDateTime(ical.PropDue, dueTime.UTC())
+			}
+		} else {
+			logging.LogWarnf("parse task due [%s] of block [%s] failed: %s", due, block.ID, parseErr)
+		}
+	}
+	return newNoteCalendarObject(PathJoinWithSlash(CalDavTasksCalendarPath, block.ID+ICalendarFileExt), todo, updated)
+}
+
+// putTaskCalendarObject 根据待办的状态和截止时间更新任务列表项。
+func putTaskCalendarObject(id string, calendarData *ical.Calendar) (err error) {
+	var todo *ical.Component
+	for _, child := range calendarData.Children {
+		if ical.CompToDo == child.Name {
+			todo = child
+			break
+		}
+	}
+	if nil == todo {
+		err = ErrorCalDavNoteCalendarNoEvent
+		return
+	}
+
+	status, _ := todo.Props.Text(ical.PropStatus)
+	completed := "COMPLETED" == strings.ToUpper(status) || nil != todo.Props.Get(ical.PropCompleted)
+
+	var due string
+	if dueProp := todo.Props.Get(ical.PropDue); nil != dueProp {
+		dueTime, dueErr := dueProp.DateTime(time.Local)
+		if dueErr != nil {
+			err = dueErr
+			return
+		}
+		if ical.ValueDate == dueProp.ValueType() {
+			due = dueTime.Format("2006-01-02")
+		} else {
+			due = dueTime.In(time.Local).Format("2006-01-02 15:04:05")
+		}
+	}
+
+	FlushTxQueue()
+
+	tree, err := LoadTreeByBlockID(id)
+	if err != nil {
+		return
+	}
+
+	node := treenode.GetNodeInTree(tree, id)
+	if nil == node || ast.NodeListItem != node.Type || nil == node.ListData || 3 != node.ListData.Typ {
+		err = ErrorCalDavTaskNotFound
+		return
+	}
+
+	node.ListData.Checked = completed
+	if nil != node.FirstChild && ast.NodeTaskListItemMarker == node.FirstChild.Type {
+		node.FirstChild.TaskChecked = completed
+	}
+	if "" == due {
+		node.RemoveIALAttr(Conf.CalDAV.TaskDueAttr)
+	} else {
+		node.SetIALAttr(Conf.CalDAV.TaskDueAttr, due)
+	}
+
+	luteEngine := util.NewLute()
+	transaction := &Transaction{DoOperations: []*Operation{{Action: "update", ID: id, Data: luteEngine.RenderNodeBlockDOM(node)}}}
+	PerformTransactions(&[]*Transaction{transaction})
+	FlushTxQueue()
+	ReloadProtyle(tree.ID)
+	return
+}
+
+// deleteTaskCalendarObject 删除待办时只移除截止时间，不删除任务列表项。
+func deleteTaskCalendarObject(id string) {
+	attrs, _ := gulu.JSON.MarshalJSON(map[string]string{Conf.CalDAV.TaskDueAttr: ""})
+	performNoteCalendarTransaction(&Operation{Action: "setAttrs", ID: id, Data: string(attrs)})
+	if bt := treenode.GetBlockTree(id); nil != bt {
+		ReloadProtyle(bt.RootID)
+	}
+}
+
+func isDateOnly(value string) bool {
+	value = strings.TrimSpace(value)
+	return 8 == len(value) || 10 == len(value)
+}
+
+func escapeTaskSQLValues(values []string) (ret []string) {
+	for _, value := range values {
+		ret = append(ret, strings.ReplaceAll(value, "'", "''"))
+	}
+	return
+}
+
+// escapeTaskSQLLike 转义 LIKE 模式中的通配符，配合 ESCAPE '\' 使用。
+func escapeTaskSQLLike(value string) string {
+	value = strings.ReplaceAll(value, "\\", "\\\\")
+	value = strings.ReplaceAll(value, "%", "\\%")
+	return strings.ReplaceAll(value, "_", "\\_")
+}
@@ -50,39 +50,42 @@ var Conf *AppConf
 
 // AppConf 维护应用元数据，保存在 ~/.siyuan/conf.json。
 type AppConf struct {
-	LogLevel       string           `json:"logLevel"`       // 日志级别：off, trace, debug, info, warn, error, fatal
-	Appearance     *conf.Appearance `json:"appearance"`     // 外观
-	Langs          []*conf.Lang     `json:"langs"`          // 界面语言列表
-	Lang           string           `json:"lang"`           // 选择的界面语言，同 Appearance.Lang
-	FileTree       *conf.FileTree   `json:"fileTree"`       // 文档面板
-	Tag            *conf.Tag        `json:"tag"`            // 标签面板
-	Editor         *conf.Editor     `json:"editor"`         // 编辑器配置
-	Export         *conf.Export     `json:"export"`         // 导出配置
-	Graph          *conf.Graph      `json:"graph"`          // 关系图配置
-	UILayout       *conf.UILayout   `json:"uiLayout"`       // 界面布局。不要直接使用，使用 GetUILayout() 和 SetUILayout() 方法
-	UserData       string           `json:"userData"`       // 社区用户信息，对 User 加密存储
-	User           *conf.User       `json:"-"`              // 社区用户内存结构，不持久化。不要直接使用，使用 GetUser() 和 SetUser() 方法
-	Account        *conf.Account    `json:"account"`        // 帐号配置
-	ReadOnly       bool             `json:"readonly"`       // 是否是以只读模式运行
-	ServerAddrs    []string         `json:"serverAddrs"`    // 本地服务器地址列表
-	AccessAuthCode string           `json:"accessAuthCode"` // 访问授权码
-	System         *conf.System     `json:"system"`         // 系统配置
-	Keymap         *conf.Keymap     `json:"keymap"`         // 快捷键配置
-	Sync           *conf.Sync       `json:"sync"`           // 同步配置
-	Search         *conf.Search     `json:"search"`         // 搜索配置
-	Flashcard      *conf.Flashcard  `json:"flashcard"`      // 闪卡配置
-	AI             *conf.AI         `json:"ai"`             // 人工智能配置
-	Bazaar         *conf.Bazaar     `json:"bazaar"`         // 集市配置
-	Stat           *conf.Stat       `json:"stat"`           // 统计
-	Api            *conf.API        `json:"api"`            // API
-	Repo           *conf.Repo       `json:"repo"`           // 数据仓库
-	Publish        *conf.Publish    `json:"publish"`        // 发布服务
-	OpenHelp       bool             `json:"openHelp"`       // 启动后是否需要打开用户指南
-	ShowChangelog  bool             `json:"showChangelog"`  // 是否显示版本更新日志
-	CloudRegion    int              `json:"cloudRegion"`    // 云端区域，0：中国大陆，1：北美
-	Snippet        *conf.Snpt       `json:"snippet"`        // 代码片段
-	DataIndexState int              `json:"dataIndexState"` // 数据索引状态，0：已索引，1：未索引
-	CookieKey      string           `json:"cookieKey"`      // 用于加密 Cookie 的密钥
+	LogLevel       string                `json:"logLevel"`       // 日志级别：off, trace, debug, info, warn, error, fatal
+	Appearance     *conf.Appearance      `json:"appearance"`     // 外观
+	Langs          []*conf.Lang          `json:"langs"`          // 界面语言列表
+	Lang           string                `json:"lang"`           // 选择的界面语言，同 Appearance.Lang
+	FileTree       *conf.FileTree        `json:"fileTree"`       // 文档面板
+	Tag            *conf.Tag             `json:"tag"`            // 标签面板
+	Editor         *conf.Editor          `json:"editor"`         // 编辑器配置
+	Export         *conf.Export          `json:"export"`         // 导出配置
+	Graph          *conf.Graph           `json:"graph"`          // 关系图配置
+	UILayout       *conf.UILayout        `json:"uiLayout"`       // 界面布局。不要直接使用，使用 GetUILayout() 和 SetUILayout() 方法
+	UserData       string                `json:"userData"`       // 社区用户信息，对 User 加密存储
+	User           *conf.User            `json:"-"`              // 社区用户内存结构，不持久化。不要直接使用，使用 GetUser() 和 SetUser() 方法
+	Account        *conf.Account         `json:"account"`        // 帐号配置
+	ReadOnly       bool                  `json:"readonly"`       // 是否是以只读模式运行
+	ServerAddrs    []string              `json:"serverAddrs"`    // 本地服务器地址列表
+	AccessAuthCode string                `json:"accessAuthCode"` // 访问授权码
+	Users          []*conf.WorkspaceUser `json:"users"`          // 伺服时登录工作空间的用户
+	System         *conf.System          `json:"system"`         // 系统配置
+	Keymap         *conf.Keymap          `json:"keymap"`         // 快捷键配置
+	Sync           *conf.Sync            `json:"sync"`           // 同步配置
+	Search         *conf.Search          `json:"search"`         // 搜索配置
+	Flashcard      *conf.Flashcard       `json:"flashcard"`      // 闪卡配置
+	AI             *conf.AI              `json:"ai"`             // 人工智能配置
+	Bazaar         *conf.Bazaar          `json:"bazaar"`         // 集市配置
+	Stat           *conf.Stat            `json:"stat"`           // 统计
+	Api            *conf.API             `json:"api"`            // API
+	Repo           *conf.Repo            `json:"repo"`           // 数据仓库
+	Publish        *conf.Publish         `json:"publish"`        // 发布服务
+	CalDAV         *conf.CalDAV          `json:"caldav"`         // CalDAV 服务
+	Reminder       *conf.Reminder        `json:"reminder"`       // 块提醒
+	OpenHelp       bool                  `json:"openHelp"`       // 启动后是否需要打开用户指南
+	ShowChangelog  bool                  `json:"showChangelog"`  // 是否显示版本更新日志
+	CloudRegion    int                   `json:"cloudRegion"`    // 云端区域，0：中国大陆，1：北美
+	Snippet        *conf.Snpt            `json:"snippet"`        // 代码片段
+	DataIndexState int                   `json:"dataIndexState"` // 数据索引状态，0：已索引，1：未索引
+	CookieKey      string                `json:"cookieKey"`      // 用于加密 Cookie 的密钥
 
 	m        *sync.RWMutex // 配置数据锁
 	userLock *sync.RWMutex // 用户数据独立锁，避免与配置保存操作竞争
@@ -124,6 +127,7 @@ func InitConf() {
 	initLang()
 
 	Conf = NewAppConf()
+	Conf.Search = conf.NewSearch() // 旧版本配置中没有的搜索参数（比如相关度权重）使用默认值
 	confPath := filepath.Join(util.ConfDir, "conf.json")
 	if gulu.File.IsExist(confPath) {
 		if data, err := os.ReadFile(confPath); err != nil {
@@ -435,6 +439,33 @@ func InitConf() {
 	if nil == Conf.Api {
 		Conf.Api = conf.NewAPI()
 	}
+	if nil == Conf.Api.Tokens {
+		Conf.Api.Tokens = []*conf.APIToken{}
+	}
+
+	if nil == Conf.Users {
+		Conf.Users = []*conf.WorkspaceUser{}
+	}
+
+	if nil == Conf.CalDAV {
+		Conf.CalDAV = conf.NewCalDAV()
+	}
+	if nil == Conf.CalDAV.AttributeViews {
+		Conf.CalDAV.AttributeViews = []string{}
+	}
+	if nil == Conf.CalDAV.TaskNotebooks {
+		Conf.CalDAV.TaskNotebooks = []string{}
+	}
+	if nil == Conf.CalDAV.TaskTags {
+		Conf.CalDAV.TaskTags = []string{}
+	}
+	if "" == Conf.CalDAV.TaskDueAttr {
+		Conf.CalDAV.TaskDueAttr = "custom-due"
+	}
+
+	if nil == Conf.Reminder {
+		Conf.Reminder = conf.NewReminder()
+	}
 
 	if nil == Conf.Bazaar {
 		Conf.Bazaar = conf.NewBazaar()
@@ -481,6 +512,13 @@ func InitConf() {
 	if 1 > Conf.Search.BacklinkMentionKeywordsLimit {
 		Conf.Search.BacklinkMentionKeywordsLimit = 512
 	}
+	if 0 >= Conf.Search.SemanticMinScore || 1 < Conf.Search.SemanticMinScore {
+		Conf.Search.SemanticMinScore = 0.3
+	}
+	Conf.Search.FixRank()
+	if !sql.IsValidFTSTokenizer(Conf.Search.Tokenizer) {
+		Conf.Search.Tokenizer = sql.FTSTokenizerSiYuan
+	}
 
 	if nil == Conf.Stat {
 		Conf.Stat = conf.NewStat()
@@ -547,6 +585,9 @@ func InitConf() {
 	if "" == Conf.AI.OpenAI.APIProvider {
 		Conf.AI.OpenAI.APIProvider = "OpenAI"
 	}
+	if "" == Conf.AI.OpenAI.APIEmbeddingModel {
+		Conf.AI.OpenAI.APIEmbeddingModel = string(openai.SmallEmbedding3)
+	}
 	if 0 > Conf.AI.OpenAI.APIMaxTokens {
 		Conf.AI.OpenAI.APIMaxTokens = 0
 	}
@@ -1043,6 +1084,9 @@ func GetMaskedConf() (ret *AppConf, err error) {
 	if "" != ret.AccessAuthCode {
 		ret.AccessAuthCode = MaskedAccessAuthCode
 	}
+	for _, user := range ret.Users {
+		user.Password = ""
+	}
 	return
 }
 
@@ -1051,6 +1095,7 @@ func GetMaskedConf() (ret *AppConf, err error) {
 func HideConfSecret(c *AppConf) {
 	c.AI = &conf.AI{}
 	c.Api = &conf.API{}
+	c.Users = []*conf.WorkspaceUser{}
 	c.Flashcard = &conf.Flashcard{}
 	c.ServerAddrs = []string{}
 	c.Publish = &conf.Publish{}
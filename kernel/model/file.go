@@ -23,6 +23,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -655,8 +656,17 @@ func GetDoc(startID, endID, id string, index int, query string, queryTypes map[s
 		typeFilter := buildTypeFilter(queryTypes)
 		switch queryMethod {
 		case 0:
-			query = stringQuery(query)
-			keywords = highlightByFTS(query, typeFilter, rootID)
+			if isShortNgramQuery(query) {
+				// 和搜索时一样按正则表达式匹配字面值
+				exp := regexp.QuoteMeta(strings.TrimSpace(query))
+				if !Conf.Search.CaseSensitive {
+					exp = "(?i)" + exp
+				}
+				keywords = highlightByRegexp(exp, typeFilter, rootID)
+			} else {
+				query = expandHighlightQuery(query)
+				keywords = highlightByFTS(query, typeFilter, rootID)
+			}
 		case 1:
 			keywords = highlightByFTS(query, typeFilter, rootID)
 		case 3:
@@ -444,6 +444,52 @@ func RollbackAttributeViewHistory(historyPath string) (err error) {
 	return nil
 }
 
+// RollbackReplaceHistory 回滚一次查找替换影响的所有文档，historyDir 为查找替换返回的历史目录名。
+//
+// 某篇文档回滚失败时继续回滚其他文档，failedIDs 返回回滚失败的文档 ID。
+func RollbackReplaceHistory(historyDir string) (failedIDs []string, err error) {
+	if historyDir != filepath.Base(historyDir) || !strings.HasSuffix(historyDir, "-"+HistoryOpReplace) {
+		err = errors.New("invalid replace history [" + historyDir + "]")
+		return
+	}
+
+	historyDir = filepath.Join(util.HistoryDir, historyDir)
+	if !gulu.File.IsDir(historyDir) {
+		logging.LogWarnf("replace history [%s] not exist", historyDir)
+		return
+	}
+
+	boxes, err := os.ReadDir(historyDir)
+	if err != nil {
+		logging.LogErrorf("read replace history [%s] failed: %s", historyDir, err)
+		return
+	}
+
+	for _, box := range boxes {
+		if !box.IsDir() || !ast.IsNodeIDPattern(box.Name()) {
+			continue
+		}
+
+		var historyPaths []string
+		filelock.Walk(filepath.Join(historyDir, box.Name()), func(path string, d fs.DirEntry, err error) error {
+			if nil == err && !d.IsDir() && strings.HasSuffix(d.Name(), ".sy") {
+				historyPaths = append(historyPaths, path)
+			}
+			return nil
+		})
+		for _, historyPath := range historyPaths {
+			if rollbackErr := RollbackDocHistory(box.Name(), historyPath); nil != rollbackErr {
+				logging.LogErrorf("rollback doc history [%s] failed: %s", historyPath, rollbackErr)
+				failedIDs = append(failedIDs, util.GetTreeID(historyPath))
+			}
+		}
+	}
+	if 0 < len(failedIDs) {
+		err = errors.New("rollback replace history failed for docs [" + strings.Join(failedIDs, ", ") + "]")
+	}
+	return
+}
+
 type History struct {
 	HCreated string         `json:"hCreated"`
 	Items    []*HistoryItem `json:"items"`
@@ -458,17 +504,17 @@ type HistoryItem struct {
 
 const fileHistoryPageSize = 32
 
-func FullTextSearchHistory(query, box, op string, typ, page int) (ret []string, pageCount, totalCount int) {
+func FullTextSearchHistory(query, box, op string, typ, page int, filter *HistorySearchFilter) (ret []string, pageCount, totalCount int, err error) {
 	query = util.RemoveInvalid(query)
-	if "" != query && HistoryTypeDocID != typ {
-		query = stringQuery(query)
+	if err = parseHistorySearchQuery(query, filter); err != nil {
+		return
 	}
 
 	offset := (page - 1) * fileHistoryPageSize
 
 	table := "histories_fts_case_insensitive"
 	stmt := "SELECT DISTINCT created FROM " + table + " WHERE "
-	stmt += buildSearchHistoryQueryFilter(query, op, box, table, typ)
+	stmt += buildSearchHistoryQueryFilter(op, box, table, typ, filter)
 	countStmt := strings.ReplaceAll(stmt, "SELECT DISTINCT created", "SELECT COUNT(DISTINCT created) AS total")
 	stmt += " ORDER BY created DESC LIMIT " + strconv.Itoa(fileHistoryPageSize) + " OFFSET " + strconv.Itoa(offset)
 	result, err := sql.QueryHistory(stmt)
@@ -493,15 +539,15 @@ func FullTextSearchHistory(query, box, op string, typ, page int) (ret []string,
 	return
 }
 
-func FullTextSearchHistoryItems(created, query, box, op string, typ int) (ret []*HistoryItem) {
+func FullTextSearchHistoryItems(created, query, box, op string, typ int, filter *HistorySearchFilter) (ret []*HistoryItem, err error) {
 	query = util.RemoveInvalid(query)
-	if "" != query && HistoryTypeDocID != typ {
-		query = stringQuery(query)
+	if err = parseHistorySearchQuery(query, filter); err != nil {
+		return
 	}
 
 	table := "histories_fts_case_insensitive"
 	stmt := "SELECT * FROM " + table + " WHERE "
-	stmt += buildSearchHistoryQueryFilter(query, op, box, table, typ)
+	stmt += buildSearchHistoryQueryFilter(op, box, table, typ, filter)
 
 	_, parseErr := strconv.Atoi(created)
 	if nil != parseErr {
@@ -515,20 +561,21 @@ func FullTextSearchHistoryItems(created, query, box, op string, typ int) (ret []
 	return
 }
 
-func buildSearchHistoryQueryFilter(query, op, box, table string, typ int) (stmt string) {
-	if "" != query {
-		switch typ {
-		case HistoryTypeDocName:
-			stmt += table + " MATCH '{title}:(" + query + ")'"
-		case HistoryTypeDoc:
-			stmt += table + " MATCH '{title content}:(" + query + ")'"
-		case HistoryTypeDocID:
-			stmt += " id = '" + query + "'"
-		case HistoryTypeAsset:
-			stmt += table + " MATCH '{title content}:(" + query + ")'"
-		case HistoryTypeDatabase:
-			stmt += table + " MATCH '{content}:(" + query + ")'"
-		}
+func buildSearchHistoryQueryFilter(op, box, table string, typ int, filter *HistorySearchFilter) (stmt string) {
+	var columns string
+	switch typ {
+	case HistoryTypeDocName:
+		columns = "{title}"
+	case HistoryTypeDoc, HistoryTypeAsset:
+		columns = "{title content}"
+	case HistoryTypeDatabase:
+		columns = "{content}"
+	}
+
+	if HistoryTypeDocID == typ && 0 < len(filter.keywords) {
+		stmt += " id = '" + strings.ReplaceAll(strings.Join(filter.keywords, " "), "'", "''") + "'"
+	} else if query := filter.ftsQuery(); "" != query && "" != columns {
+		stmt += table + " MATCH '" + columns + ":(" + query + ")'"
 	} else {
 		stmt += "1=1"
 	}
@@ -552,6 +599,7 @@ func buildSearchHistoryQueryFilter(query, op, box, table string, typ int) (stmt
 
 	ago := time.Now().Add(-24 * time.Hour * time.Duration(Conf.Editor.HistoryRetentionDays))
 	stmt += " AND CAST(created AS INTEGER) > " + fmt.Sprintf("%d", ago.Unix()) + ""
+	stmt += filter.buildSQLFilter(table, columns)
 	return
 }
 
@@ -969,6 +1017,7 @@ func indexHistoryDir(name string, luteEngine *lute.Lute) {
 			Content: content,
 			Path:    p,
 			Created: created,
+			Attrs:   historyDocAttrs(tree),
 		})
 	}
 
@@ -998,6 +1047,10 @@ func indexHistoryDir(name string, luteEngine *lute.Lute) {
 		p := strings.TrimPrefix(database, util.HistoryDir)
 		p = filepath.ToSlash(p[1:])
 		content := av.GetAttributeViewContentByPath(database)
+		var attrs []*sql.HistoryAttr
+		if attrView, parseErr := av.ParseAttributeViewByPath(database); nil == parseErr {
+			attrs = historyDatabaseAttrs(attrView)
+		}
 		histories = append(histories, &sql.History{
 			ID:      id,
 			Type:    HistoryTypeDatabase,
@@ -1006,6 +1059,7 @@ func indexHistoryDir(name string, luteEngine *lute.Lute) {
 			Content: content,
 			Path:    p,
 			Created: created,
+			Attrs:   attrs,
 		})
 	}
 
@@ -0,0 +1,282 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute/ast"
+	"github.com/88250/lute/parse"
+	"github.com/siyuan-note/siyuan/kernel/av"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+)
+
+// HistorySearchFilter 描述历史和快照搜索的过滤条件。
+//
+// 搜索关键字使用和结构化查询相同的语法，支持关键字、短语、tag:、attr:、created: 字段以及 - 排除，
+// created: 按照历史的创建时间过滤，在快照中搜索时按照文档的更新时间过滤。
+type HistorySearchFilter struct {
+	StartDate string // 开始日期（包含），格式为 2006-01-02
+	EndDate   string // 结束日期（包含），格式为 2006-01-02
+
+	keywords []string
+	excludes []string
+	attrs    []*historyAttrCondition
+	dates    []*historyDateCondition
+}
+
+type historyAttrCondition struct {
+	names    []string // 属性名，标签为 sql.HistoryAttrNameTag
+	value    string
+	hasValue bool // 仅指定属性名时匹配存在该属性的历史
+	isTag    bool // 标签同时匹配子标签
+	negated  bool
+}
+
+type historyDateCondition struct {
+	start, end time.Time // 时间段 [start, end)，零值表示不限制
+	negated    bool
+}
+
+// parseHistorySearchQuery 使用结构化查询的语法解析历史搜索关键字，结果保存到 filter 中。
+func parseHistorySearchQuery(query string, filter *HistorySearchFilter) (err error) {
+	tokens, err := tokenizeSearchQuery(query)
+	if err != nil {
+		return
+	}
+
+	for _, token := range tokens {
+		switch token.field {
+		case "":
+			if token.negated {
+				filter.excludes = append(filter.excludes, token.value)
+			} else {
+				filter.keywords = append(filter.keywords, token.value)
+			}
+		case "tag":
+			if tag := strings.Trim(token.value, "#"); "" != tag {
+				filter.attrs = append(filter.attrs, &historyAttrCondition{names: []string{sql.HistoryAttrNameTag}, value: tag, hasValue: true, isTag: true, negated: token.negated})
+			}
+		case "attr":
+			name, value, hasValue := strings.Cut(token.value, "=")
+			filter.attrs = append(filter.attrs, &historyAttrCondition{names: searchQueryAttrNames(name), value: strings.TrimSpace(value), hasValue: hasValue, negated: token.negated})
+		case "created":
+			start, end, dateErr := parseSearchQueryDateBounds(token)
+			if nil != dateErr {
+				err = dateErr
+				return
+			}
+			filter.dates = append(filter.dates, &historyDateCondition{start: start, end: end, negated: token.negated})
+		default:
+			err = &SearchQueryError{Pos: token.pos, Msg: "unsupported field [" + token.field + "] in history search"}
+			return
+		}
+	}
+	return
+}
+
+// ftsQuery 构建关键字的 FTS 查询，关键字之间是与的关系。
+func (filter *HistorySearchFilter) ftsQuery() string {
+	var terms []string
+	for _, keyword := range filter.keywords {
+		terms = append(terms, quoteSearchQueryKeyword(keyword))
+	}
+	return strings.Join(terms, " ")
+}
+
+// buildSQLFilter 构建关键字以外的过滤条件，columns 是排除关键字匹配的 FTS 列，为空时忽略排除关键字。
+func (filter *HistorySearchFilter) buildSQLFilter(table, columns string) (stmt string) {
+	if nil == filter {
+		return
+	}
+
+	if "" != columns && 0 < len(filter.excludes) {
+		var terms []string
+		for _, exclude := range filter.excludes {
+			terms = append(terms, quoteSearchQueryKeyword(exclude))
+		}
+		stmt += " AND path NOT IN (SELECT path FROM " + table + " WHERE " + table + " MATCH '" + columns + ":(" + strings.Join(terms, " OR ") + ")')"
+	}
+
+	for _, attr := range filter.attrs {
+		condition := "LOWER(name) IN ('" + strings.Join(attr.names, "', '") + "')"
+		if attr.hasValue {
+			value := escapeHistoryAttrValue(attr.value)
+			if attr.isTag {
+				condition += " AND (value = '" + value + "' OR value LIKE '" + strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(value) + "/%' ESCAPE '\\')"
+			} else {
+				condition += " AND value = '" + value + "'"
+			}
+		}
+		stmt += historySQLCondition("path IN (SELECT path FROM history_attrs WHERE "+condition+")", attr.negated)
+	}
+
+	for _, date := range filter.dates {
+		var conditions []string
+		if !date.start.IsZero() {
+			conditions = append(conditions, "CAST(created AS INTEGER) >= "+strconv.FormatInt(date.start.Unix(), 10))
+		}
+		if !date.end.IsZero() {
+			conditions = append(conditions, "CAST(created AS INTEGER) < "+strconv.FormatInt(date.end.Unix(), 10))
+		}
+		stmt += historySQLCondition(strings.Join(conditions, " AND "), date.negated)
+	}
+
+	if start, end := filter.dateRange(); !start.IsZero() || !end.IsZero() {
+		if !start.IsZero() {
+			stmt += " AND CAST(created AS INTEGER) >= " + strconv.FormatInt(start.Unix(), 10)
+		}
+		if !end.IsZero() {
+			stmt += " AND CAST(created AS INTEGER) < " + strconv.FormatInt(end.Unix(), 10)
+		}
+	}
+	return
+}
+
+func historySQLCondition(condition string, negated bool) string {
+	if negated {
+		return " AND NOT (" + condition + ")"
+	}
+	return " AND (" + condition + ")"
+}
+
+// dateRange 返回 StartDate 和 EndDate 表示的时间段 [start, end)，零值表示不限制。
+func (filter *HistorySearchFilter) dateRange() (start, end time.Time) {
+	if "" != filter.StartDate {
+		start, _ = time.ParseInLocation("2006-01-02", filter.StartDate, time.Local)
+	}
+	if "" != filter.EndDate {
+		if t, err := time.ParseInLocation("2006-01-02", filter.EndDate, time.Local); nil == err {
+			end = t.AddDate(0, 0, 1)
+		}
+	}
+	return
+}
+
+// matchTime 判断快照中文档的更新时间是否满足日期过滤条件。
+func (filter *HistorySearchFilter) matchTime(t time.Time) bool {
+	inRange := func(start, end time.Time) bool {
+		return (start.IsZero() || !t.Before(start)) && (end.IsZero() || t.Before(end))
+	}
+
+	for _, date := range filter.dates {
+		if inRange(date.start, date.end) == date.negated {
+			return false
+		}
+	}
+	return inRange(filter.dateRange())
+}
+
+// matchDoc 判断快照中的文档是否满足关键字和属性过滤条件，关键字不区分大小写。
+func (filter *HistorySearchFilter) matchDoc(title, content string, attrs []*sql.HistoryAttr) bool {
+	text := strings.ToLower(title + " " + content)
+	for _, keyword := range filter.keywords {
+		if !strings.Contains(text, strings.ToLower(keyword)) {
+			return false
+		}
+	}
+	for _, exclude := range filter.excludes {
+		if strings.Contains(text, strings.ToLower(exclude)) {
+			return false
+		}
+	}
+
+	for _, condition := range filter.attrs {
+		if condition.match(attrs) == condition.negated {
+			return false
+		}
+	}
+	return true
+}
+
+func (condition *historyAttrCondition) match(attrs []*sql.HistoryAttr) bool {
+	for _, attr := range attrs {
+		// 属性名已经转义了单引号
+		if !gulu.Str.Contains(strings.ReplaceAll(strings.ToLower(attr.Name), "'", "''"), condition.names) {
+			continue
+		}
+		if !condition.hasValue || attr.Value == condition.value || (condition.isTag && strings.HasPrefix(attr.Value, condition.value+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func escapeHistoryAttrValue(value string) string {
+	return strings.ReplaceAll(value, "'", "''")
+}
+
+// historyDocAttrs 收集历史文档中块的自定义属性、命名、别名、备注、书签和标签。
+func historyDocAttrs(tree *parse.Tree) (ret []*sql.HistoryAttr) {
+	ast.Walk(tree.Root, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if !entering {
+			return ast.WalkContinue
+		}
+
+		if n.IsTextMarkType("tag") {
+			if tag := strings.TrimSpace(n.TextMarkTextContent); "" != tag {
+				ret = append(ret, &sql.HistoryAttr{BlockID: n.Parent.ID, Name: sql.HistoryAttrNameTag, Value: tag})
+			}
+			return ast.WalkContinue
+		}
+
+		if !n.IsBlock() {
+			return ast.WalkContinue
+		}
+
+		for _, kv := range n.KramdownIAL {
+			name, value := kv[0], kv[1]
+			switch {
+			case "tags" == name:
+				for _, tag := range strings.Split(value, ",") {
+					if tag = strings.TrimSpace(tag); "" != tag {
+						ret = append(ret, &sql.HistoryAttr{BlockID: n.ID, Name: sql.HistoryAttrNameTag, Value: tag})
+					}
+				}
+			case strings.HasPrefix(name, "custom-"), "name" == name, "alias" == name, "memo" == name, "bookmark" == name:
+				ret = append(ret, &sql.HistoryAttr{BlockID: n.ID, Name: name, Value: value})
+			}
+		}
+		return ast.WalkContinue
+	})
+
+	for _, attr := range ret {
+		if "" == attr.BlockID {
+			attr.BlockID = tree.Root.ID
+		}
+	}
+	return
+}
+
+// historyDatabaseAttrs 收集历史数据库中每个字段的值，属性名为字段名。
+func historyDatabaseAttrs(attrView *av.AttributeView) (ret []*sql.HistoryAttr) {
+	for _, keyValues := range attrView.KeyValues {
+		for _, value := range keyValues.Values {
+			if nil == value {
+				continue
+			}
+
+			if content := strings.TrimSpace(value.String(true)); "" != content {
+				ret = append(ret, &sql.HistoryAttr{BlockID: value.BlockID, Name: keyValues.Key.Name, Value: content})
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,86 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/siyuan-note/siyuan/kernel/sql"
+)
+
+func TestParseHistorySearchQuery(t *testing.T) {
+	filter := &HistorySearchFilter{}
+	err := parseHistorySearchQuery(`思源 "exact phrase" -draft tag:#proj/x -attr:status=done created:2024-01`, filter)
+	if nil != err {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	if got := filter.ftsQuery(); `"思源" "exact phrase"` != got {
+		t.Errorf("expected fts query [\"思源\" \"exact phrase\"], got [%s]", got)
+	}
+
+	start, _ := time.ParseInLocation("2006-01-02", "2024-01-01", time.Local)
+	end := start.AddDate(0, 1, 0)
+	want := ` AND path NOT IN (SELECT path FROM histories WHERE histories MATCH '{title content}:("draft")')` +
+		` AND (path IN (SELECT path FROM history_attrs WHERE LOWER(name) IN ('#tag') AND (value = 'proj/x' OR value LIKE 'proj/x/%' ESCAPE '\')))` +
+		` AND NOT (path IN (SELECT path FROM history_attrs WHERE LOWER(name) IN ('status', 'custom-status') AND value = 'done'))` +
+		` AND (CAST(created AS INTEGER) >= ` + strconv.FormatInt(start.Unix(), 10) + ` AND CAST(created AS INTEGER) < ` + strconv.FormatInt(end.Unix(), 10) + `)`
+	if got := filter.buildSQLFilter("histories", "{title content}"); want != got {
+		t.Errorf("expected sql filter\n%s\ngot\n%s", want, got)
+	}
+
+	attrs := []*sql.HistoryAttr{{Name: sql.HistoryAttrNameTag, Value: "proj/x/y"}, {Name: "custom-status", Value: "doing"}}
+	if !filter.matchDoc("思源", "An exact phrase", attrs) {
+		t.Errorf("expected the doc to match")
+	}
+	if filter.matchDoc("思源", "An exact phrase in a draft", attrs) {
+		t.Errorf("expected the doc with an excluded keyword not to match")
+	}
+	if filter.matchDoc("思源", "An exact phrase", []*sql.HistoryAttr{attrs[0], {Name: "custom-status", Value: "done"}}) {
+		t.Errorf("expected the doc with an excluded attribute not to match")
+	}
+	if !filter.matchTime(start.AddDate(0, 0, 14)) || filter.matchTime(end) {
+		t.Errorf("expected only times in 2024-01 to match")
+	}
+}
+
+func TestParseHistorySearchQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{query: `a type:h2`, pos: 3, msg: "unsupported field [type] in history search"},
+		{query: `created:2024-1`, pos: 1, msg: "invalid date [2024-1]"},
+		{query: `tag:"x`, pos: 5, msg: "unclosed quote"},
+	}
+
+	for _, test := range tests {
+		err := parseHistorySearchQuery(test.query, &HistorySearchFilter{})
+		var queryErr *SearchQueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("parse [%s] expected a syntax error, got [%v]", test.query, err)
+			continue
+		}
+		if test.pos != queryErr.Pos || test.msg != queryErr.Msg {
+			t.Errorf("parse [%s] expected error [%d: %s], got [%d: %s]", test.query, test.pos, test.msg, queryErr.Pos, queryErr.Msg)
+		}
+	}
+}
@@ -782,6 +782,20 @@ func ImportData(zipPath string) (err error) {
 }
 
 func ImportFromLocalPath(boxID, localPath string, toPath string) (err error) {
+	_, err = importFromLocalPath(boxID, localPath, toPath, importFlavorStd)
+	return
+}
+
+// importFlavor 描述导入的 Markdown 方言。
+type importFlavor int
+
+const (
+	importFlavorStd      importFlavor = iota // 标准 Markdown
+	importFlavorObsidian                     // Obsidian 仓库
+)
+
+// importFromLocalPath 导入本地 Markdown 文件或者文件夹，返回导入的 Markdown 文件相对路径（以 / 开头）到文档 ID 的映射。
+func importFromLocalPath(boxID, localPath string, toPath string, flavor importFlavor) (relPathIDs map[string]string, err error) {
 	util.PushEndlessProgress(Conf.Language(73))
 	defer func() {
 		util.PushClearProgress()
@@ -807,13 +821,14 @@ func ImportFromLocalPath(boxID, localPath string, toPath string) (err error) {
 		block := treenode.GetBlockTreeRootByPath(boxID, toPath)
 		if nil == block {
 			logging.LogErrorf("not found block by path [%s]", toPath)
-			return nil
+			return
 		}
 		baseHPath = block.HPath
 		baseTargetPath = strings.TrimSuffix(block.Path, ".sy")
 	}
 	boxLocalPath = filepath.Join(util.DataDir, boxID)
 
+	relPathIDs = map[string]string{}
 	hPathsIDs := map[string]string{}
 	idPaths := map[string]string{}
 	moveIDs := map[string]string{}
@@ -1029,6 +1044,7 @@ func ImportFromLocalPath(boxID, localPath string, toPath string) (err error) {
 			importTrees = append(importTrees, tree)
 
 			hPathsIDs[tree.HPath] = tree.ID
+			relPathIDs[curRelPath] = tree.ID
 			idPaths[tree.ID] = tree.Path
 
 			count++
@@ -1040,7 +1056,7 @@ func ImportFromLocalPath(boxID, localPath string, toPath string) (err error) {
 	} else { // 导入单个文件
 		fileName := filepath.Base(localPath)
 		if !strings.HasSuffix(fileName, ".md") && !strings.HasSuffix(fileName, ".markdown") {
-			return errors.New(Conf.Language(79))
+			return nil, errors.New(Conf.Language(79))
 		}
 
 		title := strings.TrimSuffix(fileName, ".markdown")
@@ -1051,13 +1067,13 @@ func ImportFromLocalPath(boxID, localPath string, toPath string) (err error) {
 		var data []byte
 		data, err = os.ReadFile(localPath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		tree, yfmRootID, yfmTitle, yfmUpdated := parseStdMd(data)
 		if nil == tree {
 			msg := fmt.Sprintf("parse tree [%s] failed", localPath)
 			logging.LogErrorf(msg)
-			return errors.New(msg)
+			return nil, errors.New(msg)
 		}
 
 		if "" != yfmRootID {
@@ -1161,6 +1177,9 @@ func ImportFromLocalPath(boxID, localPath string, toPath string) (err error) {
 		}
 
 		initSearchLinks()
+		if importFlavorObsidian == flavor {
+			convertObsidianSyntax(assetsDone)
+		}
 		convertMdHyperlinks2WikiLinks()
 		convertWikiLinksAndTags()
 		mergeTextAndHandlerNestedInlines()
@@ -1758,3 +1777,12 @@ func mergeTextAndHandlerNestedInlines() {
 		}
 	}
 }
+
+// newImportEmbedNode 新建嵌入 id 对应块的嵌入块，用于将导入文档中的嵌入语法转换为嵌入块。
+func newImportEmbedNode(luteEngine *lute.Lute, id string) *ast.Node {
+	embedTree := parse.Parse("", []byte("{{select * from blocks where id='"+id+"'}}"), luteEngine.ParseOptions)
+	if nil == embedTree || nil == embedTree.Root.FirstChild || ast.NodeBlockQueryEmbed != embedTree.Root.FirstChild.Type {
+		return nil
+	}
+	return embedTree.Root.FirstChild
+}
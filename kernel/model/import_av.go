@@ -0,0 +1,588 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute/ast"
+	"github.com/88250/lute/parse"
+	"github.com/araddon/dateparse"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/av"
+	"github.com/siyuan-note/siyuan/kernel/util"
+	"github.com/xuri/excelize/v2"
+)
+
+// AttributeViewImport 描述导入表格数据到数据库的选项。
+type AttributeViewImport struct {
+	AvID          string            // 导入到的数据库 ID，为空时新建数据库
+	Notebook      string            // 新建数据库所在文档和绑定文档的笔记本
+	ToPath        string            // 新建数据库所在文档和绑定文档的父文档路径
+	Mapping       map[string]string // 表头到字段 ID 或者字段名的映射，映射为空字符串时忽略该列
+	PrimaryColumn string            // 主键列的表头，为空时使用第一列
+	Upsert        bool              // 按主键去重，主键已经存在时更新该项目的字段值
+	BindDocs      bool              // 为新增的项目创建绑定文档，否则创建非绑定块
+	DocID         string            // 新建数据库时将数据库块插入到该文档中，为空时在 ToPath 下新建文档
+	BindDocIDs    map[string]string // 主键内容到已有文档 ID 的映射，新增的项目命中时绑定该文档
+}
+
+// AttributeViewImportResult 描述导入表格数据到数据库的结果。
+type AttributeViewImportResult struct {
+	AvID    string `json:"avID"`
+	BlockID string `json:"blockID"` // 新建数据库时数据库块的 ID
+	Added   int    `json:"added"`
+	Updated int    `json:"updated"`
+}
+
+const (
+	importAvSelectMaxOptions  = 32 // 推断为单选或多选字段时最多的选项数
+	importAvSelectMaxOptLen   = 32 // 推断为单选或多选字段时选项的最大长度
+	importAvMSelectSeparators = ",，;；"
+)
+
+var (
+	importAvNumberRegexp        = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?$`)
+	importAvGroupedNumberRegexp = regexp.MustCompile(`^[+-]?\d{1,3}(,\d{3})+(\.\d+)?$`)
+)
+
+// ImportAttributeView 将 CSV、TSV 或者 XLSX 文件中的表格数据导入到数据库。
+func ImportAttributeView(filePath string, opts *AttributeViewImport) (ret *AttributeViewImportResult, err error) {
+	header, records, err := readTabularFile(filePath)
+	if err != nil {
+		return
+	}
+
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	return importAttributeViewRecords(name, header, records, opts)
+}
+
+func importAttributeViewRecords(name string, header []string, records [][]string, opts *AttributeViewImport) (ret *AttributeViewImportResult, err error) {
+	if 1 > len(header) || 1 > len(records) {
+		err = errors.New(Conf.Language(292))
+		return
+	}
+
+	for i, h := range header {
+		if header[i] = strings.TrimSpace(h); "" == header[i] {
+			header[i] = fmt.Sprintf("Column %d", i+1)
+		}
+	}
+
+	primaryCol := 0
+	for i, h := range header {
+		if h == opts.PrimaryColumn {
+			primaryCol = i
+			break
+		}
+	}
+
+	ret = &AttributeViewImportResult{AvID: opts.AvID}
+	bindPath := ""
+	if "" == ret.AvID {
+		var docPath string
+		if ret.AvID, ret.BlockID, docPath, err = createImportAttributeViewDoc(name, header[primaryCol], opts.Notebook, opts.ToPath, opts.DocID); err != nil {
+			return
+		}
+		bindPath = strings.TrimSuffix(docPath, ".sy")
+	} else if opts.BindDocs {
+		if nil == Conf.Box(opts.Notebook) || "" == opts.ToPath {
+			err = errors.New(Conf.Language(294))
+			return
+		}
+		bindPath = strings.TrimSuffix(opts.ToPath, ".sy")
+	}
+
+	attrView, err := av.ParseAttributeView(ret.AvID)
+	if err != nil {
+		return
+	}
+
+	// 确定每一列对应的字段，不存在的字段按列值推断类型后新建
+	colKeyIDs := make([]string, len(header))
+	colKeyIDs[primaryCol] = attrView.GetBlockKey().ID
+	previousKeyID := colKeyIDs[primaryCol]
+	for i, h := range header {
+		if i == primaryCol {
+			continue
+		}
+
+		target := h
+		if mapped, ok := opts.Mapping[h]; ok {
+			if target = strings.TrimSpace(mapped); "" == target {
+				continue
+			}
+		}
+
+		var key *av.Key
+		for _, keyValues := range attrView.KeyValues {
+			if keyValues.Key.ID == target || keyValues.Key.Name == target {
+				key = keyValues.Key
+				break
+			}
+		}
+		if nil == key {
+			keyID := ast.NewNodeID()
+			keyType := inferImportKeyType(records, i)
+			if err = AddAttributeViewKey(ret.AvID, keyID, target, string(keyType), "", previousKeyID); err != nil {
+				return
+			}
+			colKeyIDs[i], previousKeyID = keyID, keyID
+			continue
+		}
+
+		if !isImportableKeyType(key.Type) {
+			logging.LogWarnf("skip importing column [%s] to key [%s] of type [%s]", h, key.Name, key.Type)
+			continue
+		}
+		colKeyIDs[i] = key.ID
+	}
+
+	if attrView, err = av.ParseAttributeView(ret.AvID); err != nil {
+		return
+	}
+
+	itemIDs := map[string]string{} // 主键内容 -> 项目 ID
+	blockKeyValues := attrView.GetBlockKeyValues()
+	if opts.Upsert {
+		for _, v := range blockKeyValues.Values {
+			if nil != v.Block && "" != strings.TrimSpace(v.Block.Content) {
+				if _, exist := itemIDs[v.Block.Content]; !exist {
+					itemIDs[v.Block.Content] = v.BlockID
+				}
+			}
+		}
+	}
+
+	now := util.CurrentTimeMillis()
+	var boundDocIDs []string
+	for _, record := range records {
+		if isEmptyImportRecord(record) {
+			continue
+		}
+
+		primary := ""
+		if primaryCol < len(record) {
+			primary = strings.TrimSpace(record[primaryCol])
+		}
+
+		// 主键为空的行无法和已有项目对应，总是作为新项目插入
+		var itemID string
+		exist := false
+		if "" != primary {
+			itemID, exist = itemIDs[primary]
+		}
+		if !opts.Upsert || !exist {
+			itemID = ast.NewNodeID()
+			blockValue := &av.Value{ID: ast.NewNodeID(), KeyID: blockKeyValues.Key.ID, BlockID: itemID, Type: av.KeyTypeBlock, IsDetached: true, CreatedAt: now, UpdatedAt: now,
+				Block: &av.ValueBlock{Content: primary, Created: now, Updated: now}}
+			if docID := opts.BindDocIDs[primary]; "" != docID {
+				blockValue.IsDetached = false
+				blockValue.Block.ID = docID
+				boundDocIDs = append(boundDocIDs, docID)
+				delete(opts.BindDocIDs, primary)
+			} else if opts.BindDocs {
+				docID := ast.NewNodeID()
+				if _, err = createDoc(opts.Notebook, path.Join(bindPath, docID+".sy"), primary, ""); err != nil {
+					return
+				}
+				blockValue.IsDetached = false
+				blockValue.Block.ID = docID
+				boundDocIDs = append(boundDocIDs, docID)
+			}
+			blockKeyValues.Values = append(blockKeyValues.Values, blockValue)
+			for _, view := range attrView.Views {
+				view.ItemIDs = append(view.ItemIDs, itemID)
+			}
+			if "" != primary {
+				itemIDs[primary] = itemID
+			}
+			ret.Added++
+		} else {
+			ret.Updated++
+		}
+
+		for i, keyID := range colKeyIDs {
+			if "" == keyID || i == primaryCol || i >= len(record) {
+				continue
+			}
+
+			keyValues, _ := attrView.GetKeyValues(keyID)
+			if nil == keyValues {
+				continue
+			}
+			setImportAttributeViewValue(keyValues, itemID, record[i], now)
+		}
+	}
+
+	regenAttrViewGroups(attrView)
+	if err = av.SaveAttributeView(attrView); err != nil {
+		logging.LogErrorf("save attribute view [%s] failed: %s", ret.AvID, err)
+		return
+	}
+
+	for _, docID := range boundDocIDs {
+		bindBlockAv(nil, ret.AvID, docID)
+	}
+	ReloadAttrView(ret.AvID)
+	return
+}
+
+// createImportAttributeViewDoc 新建数据库，并在 toPath 下新建一篇包含该数据库块的文档。docID 不为空时将数据库块插入到该文档开头。
+func createImportAttributeViewDoc(name, primaryKeyName, boxID, toPath, docID string) (avID, blockID, docPath string, err error) {
+	box := Conf.Box(boxID)
+	if nil == box {
+		err = ErrBoxNotFound
+		return
+	}
+
+	avID = ast.NewNodeID()
+	attrView := av.NewAttributeView(avID)
+	attrView.Name = name
+	keepImportAttributeViewBlockKey(attrView, primaryKeyName)
+	if err = av.SaveAttributeView(attrView); err != nil {
+		logging.LogErrorf("save attribute view [%s] failed: %s", avID, err)
+		return
+	}
+
+	var docTree *parse.Tree
+	if "" != docID {
+		if docTree, err = LoadTreeByBlockID(docID); err != nil {
+			return
+		}
+		docPath = docTree.Path
+	} else {
+		docID = ast.NewNodeID()
+		docPath = path.Join(strings.TrimSuffix(toPath, ".sy"), docID+".sy")
+		if "" == toPath || "/" == toPath {
+			docPath = "/" + docID + ".sy"
+		}
+		if docTree, err = createDoc(box.ID, docPath, name, ""); err != nil {
+			return
+		}
+	}
+
+	blockID = ast.NewNodeID()
+	node := &ast.Node{ID: blockID, Type: ast.NodeAttributeView, AttributeViewID: avID, AttributeViewType: string(av.LayoutTypeTable)}
+	node.SetIALAttr("id", blockID)
+	node.SetIALAttr("updated", util.TimeFromID(blockID))
+	node.SetIALAttr(av.NodeAttrView, attrView.ViewID)
+	docTree.Root.PrependChild(node)
+	if err = indexWriteTreeUpsertQueue(docTree); err != nil {
+		return
+	}
+	av.UpsertBlockRel(avID, blockID)
+	FlushTxQueue()
+	return
+}
+
+// keepImportAttributeViewBlockKey 去掉新建数据库时默认的单选字段，只保留主键并使用 primaryKeyName 作为主键名称。
+func keepImportAttributeViewBlockKey(attrView *av.AttributeView, primaryKeyName string) {
+	blockKeyValues := attrView.GetBlockKeyValues()
+	blockKeyValues.Key.Name = primaryKeyName
+	attrView.KeyValues = []*av.KeyValues{blockKeyValues}
+	for _, view := range attrView.Views {
+		if nil == view.Table {
+			continue
+		}
+		var columns []*av.ViewTableColumn
+		for _, column := range view.Table.Columns {
+			if blockKeyValues.Key.ID == column.ID {
+				columns = append(columns, column)
+			}
+		}
+		view.Table.Columns = columns
+	}
+}
+
+// readTabularFile 读取 CSV、TSV 或者 XLSX 文件，第一行为表头。XLSX 仅读取第一个工作表。
+func readTabularFile(filePath string) (header []string, records [][]string, err error) {
+	var rows [][]string
+	switch ext := strings.ToLower(filepath.Ext(filePath)); ext {
+	case ".csv", ".tsv", ".tab":
+		f, openErr := os.Open(filePath)
+		if nil != openErr {
+			err = openErr
+			logging.LogErrorf("open [%s] failed: %s", filePath, err)
+			return
+		}
+		defer f.Close()
+
+		reader := csv.NewReader(f)
+		if ".csv" != ext {
+			reader.Comma = '\t'
+		}
+		reader.LazyQuotes = true
+		reader.FieldsPerRecord = -1
+		if rows, err = reader.ReadAll(); err != nil {
+			logging.LogErrorf("read [%s] failed: %s", filePath, err)
+			return
+		}
+	case ".xlsx":
+		x, openErr := excelize.OpenFile(filePath)
+		if nil != openErr {
+			err = openErr
+			logging.LogErrorf("open [%s] failed: %s", filePath, err)
+			return
+		}
+		defer x.Close()
+
+		sheets := x.GetSheetList()
+		if 1 > len(sheets) {
+			err = errors.New(Conf.Language(292))
+			return
+		}
+		if rows, err = x.GetRows(sheets[0]); err != nil {
+			logging.LogErrorf("get rows from sheet [%s] failed: %s", sheets[0], err)
+			return
+		}
+	default:
+		err = errors.New(fmt.Sprintf(Conf.Language(293), ext))
+		return
+	}
+
+	if 1 > len(rows) {
+		err = errors.New(Conf.Language(292))
+		return
+	}
+
+	header = rows[0]
+	if 0 < len(header) {
+		header[0] = strings.TrimPrefix(header[0], "\xEF\xBB\xBF")
+	}
+	records = rows[1:]
+	return
+}
+
+func isEmptyImportRecord(record []string) bool {
+	for _, cell := range record {
+		if "" != strings.TrimSpace(cell) {
+			return false
+		}
+	}
+	return true
+}
+
+func isImportableKeyType(keyType av.KeyType) bool {
+	switch keyType {
+	case av.KeyTypeText, av.KeyTypeNumber, av.KeyTypeDate, av.KeyTypeSelect, av.KeyTypeMSelect, av.KeyTypeURL, av.KeyTypeEmail, av.KeyTypePhone, av.KeyTypeCheckbox:
+		return true
+	}
+	return false
+}
+
+// inferImportKeyType 根据列中所有非空的值推断字段类型。
+func inferImportKeyType(records [][]string, col int) av.KeyType {
+	var values []string
+	for _, record := range records {
+		if col < len(record) {
+			if value := strings.TrimSpace(record[col]); "" != value {
+				values = append(values, value)
+			}
+		}
+	}
+	if 1 > len(values) {
+		return av.KeyTypeText
+	}
+
+	allMatch := func(match func(string) bool) bool {
+		for _, value := range values {
+			if !match(value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if allMatch(func(v string) bool { _, ok := parseImportCheckbox(v); return ok }) {
+		return av.KeyTypeCheckbox
+	}
+	// 数字需要在多选之前判断，否则 `1,000` 这样的千分位数字会被拆分为选项；`1,2` 不是合法的千分位数字，仍然作为多选
+	if allMatch(func(v string) bool { _, ok := parseImportNumber(v); return ok }) {
+		return av.KeyTypeNumber
+	}
+	if allMatch(func(v string) bool { _, _, ok := parseImportDate(v); return ok }) {
+		return av.KeyTypeDate
+	}
+	if allMatch(isImportURL) {
+		return av.KeyTypeURL
+	}
+	if allMatch(isImportEmail) {
+		return av.KeyTypeEmail
+	}
+	return inferImportSelectKeyType(values)
+}
+
+// inferImportSelectKeyType 值重复出现较多时作为选项，包含分隔符时作为多选，否则作为文本。
+func inferImportSelectKeyType(values []string) av.KeyType {
+	options, total, multiple := map[string]bool{}, 0, false
+	for _, value := range values {
+		opts := splitImportMSelect(value)
+		if 1 < len(opts) {
+			multiple = true
+		}
+		for _, opt := range opts {
+			if importAvSelectMaxOptLen < utf8.RuneCountInString(opt) {
+				return av.KeyTypeText
+			}
+			options[opt] = true
+			total++
+		}
+	}
+	if len(options) <= importAvSelectMaxOptions && len(options)*2 <= total {
+		if multiple {
+			return av.KeyTypeMSelect
+		}
+		return av.KeyTypeSelect
+	}
+	return av.KeyTypeText
+}
+
+// setImportAttributeViewValue 设置项目的字段值，已经存在值时覆盖，空值和无法解析的值忽略。
+func setImportAttributeViewValue(keyValues *av.KeyValues, itemID, content string, now int64) {
+	if content = strings.TrimSpace(content); "" == content {
+		return
+	}
+
+	key := keyValues.Key
+	val := &av.Value{Type: key.Type}
+	switch key.Type {
+	case av.KeyTypeText:
+		val.Text = &av.ValueText{Content: content}
+	case av.KeyTypeNumber:
+		number, ok := parseImportNumber(content)
+		if !ok {
+			return
+		}
+		val.Number = av.NewFormattedValueNumber(number, key.NumberFormat)
+	case av.KeyTypeDate:
+		date, isNotTime, ok := parseImportDate(content)
+		if !ok {
+			return
+		}
+		val.Date = &av.ValueDate{Content: date.UnixMilli(), IsNotEmpty: true, IsNotTime: isNotTime}
+	case av.KeyTypeCheckbox:
+		checked, _ := parseImportCheckbox(content)
+		val.Checkbox = &av.ValueCheckbox{Checked: checked}
+	case av.KeyTypeSelect, av.KeyTypeMSelect:
+		opts := splitImportMSelect(content)
+		if av.KeyTypeSelect == key.Type {
+			opts = []string{content}
+		}
+		for _, opt := range opts {
+			option := key.GetOption(opt)
+			if nil == option {
+				option = &av.SelectOption{Name: opt, Color: strconv.Itoa(len(key.Options)%14 + 1)}
+				key.Options = append(key.Options, option)
+			}
+			val.MSelect = append(val.MSelect, &av.ValueSelect{Content: option.Name, Color: option.Color})
+		}
+	case av.KeyTypeURL:
+		val.URL = &av.ValueURL{Content: content}
+	case av.KeyTypeEmail:
+		val.Email = &av.ValueEmail{Content: content}
+	case av.KeyTypePhone:
+		val.Phone = &av.ValuePhone{Content: content}
+	default:
+		return
+	}
+
+	if existing := keyValues.GetValue(itemID); nil != existing {
+		val.ID, val.CreatedAt = existing.ID, existing.CreatedAt
+		val.IsDetached = existing.IsDetached
+		*existing = *val
+		existing.KeyID, existing.BlockID, existing.UpdatedAt = key.ID, itemID, now
+		return
+	}
+
+	val.ID = ast.NewNodeID()
+	val.KeyID = key.ID
+	val.BlockID = itemID
+	val.CreatedAt, val.UpdatedAt = now, now
+	keyValues.Values = append(keyValues.Values, val)
+}
+
+// parseImportNumber 解析十进制数字，逗号只能作为千位分隔符，比如 `1,234.5`。
+func parseImportNumber(content string) (ret float64, ok bool) {
+	content = strings.TrimSpace(content)
+	if importAvGroupedNumberRegexp.MatchString(content) {
+		content = strings.ReplaceAll(content, ",", "")
+	}
+	if !importAvNumberRegexp.MatchString(content) {
+		return
+	}
+	ret, err := strconv.ParseFloat(content, 64)
+	ok = nil == err
+	return
+}
+
+func parseImportDate(content string) (ret time.Time, isNotTime, ok bool) {
+	content = strings.TrimSpace(content)
+	if "" == content {
+		return
+	}
+
+	ret, err := dateparse.ParseIn(content, time.Local)
+	if err != nil || 1000 > ret.Year() { // 避免把版本号之类的内容当作日期
+		return
+	}
+	ok = true
+	isNotTime = 0 == ret.Hour() && 0 == ret.Minute() && 0 == ret.Second() && !strings.Contains(content, ":")
+	return
+}
+
+func parseImportCheckbox(content string) (checked, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(content)) {
+	case "true", "yes", "y", "✓", "✔", "☑", "是", "checked":
+		return true, true
+	case "false", "no", "n", "☐", "否", "unchecked":
+		return false, true
+	}
+	return
+}
+
+func isImportURL(content string) bool {
+	u, err := url.Parse(content)
+	return nil == err && ("http" == u.Scheme || "https" == u.Scheme) && "" != u.Host
+}
+
+func isImportEmail(content string) bool {
+	addr, err := mail.ParseAddress(content)
+	return nil == err && addr.Address == content
+}
+
+func splitImportMSelect(content string) (ret []string) {
+	for _, opt := range strings.FieldsFunc(content, func(r rune) bool { return strings.ContainsRune(importAvMSelectSeparators, r) }) {
+		if opt = strings.TrimSpace(opt); "" != opt {
+			ret = append(ret, opt)
+		}
+	}
+	return gulu.Str.RemoveDuplicatedElem(ret)
+}
@@ -0,0 +1,103 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/siyuan-note/siyuan/kernel/av"
+)
+
+func TestParseImportNumber(t *testing.T) {
+	tests := []struct {
+		content string
+		want    float64
+		ok      bool
+	}{
+		{content: "42", want: 42, ok: true},
+		{content: " -3.5 ", want: -3.5, ok: true},
+		{content: "1,234", want: 1234, ok: true},
+		{content: "1,234,567.89", want: 1234567.89, ok: true},
+		{content: "1e3", want: 1000, ok: true},
+		{content: "1,2", ok: false},
+		{content: "12,34", ok: false},
+		{content: "1,2345", ok: false},
+		{content: ",123", ok: false},
+		{content: "Inf", ok: false},
+		{content: "NaN", ok: false},
+		{content: "0x10", ok: false},
+		{content: "", ok: false},
+	}
+
+	for _, test := range tests {
+		got, ok := parseImportNumber(test.content)
+		if test.ok != ok || (ok && test.want != got) {
+			t.Errorf("parse number [%s] expected [%v, %v], got [%v, %v]", test.content, test.want, test.ok, got, ok)
+		}
+	}
+}
+
+func TestInferImportKeyType(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   av.KeyType
+	}{
+		{name: "empty", values: []string{"", " "}, want: av.KeyTypeText},
+		{name: "checkbox", values: []string{"yes", "No", "✓"}, want: av.KeyTypeCheckbox},
+		{name: "number", values: []string{"1", "2.5", "1,234"}, want: av.KeyTypeNumber},
+		{name: "grouped number", values: []string{"1,000", "12,500", "1,000", "12,500"}, want: av.KeyTypeNumber},
+		{name: "not grouped number", values: []string{"1,2", "2,3", "1,3"}, want: av.KeyTypeMSelect},
+		{name: "date", values: []string{"2024-01-02", "2024-03-04 12:00"}, want: av.KeyTypeDate},
+		{name: "url", values: []string{"https://b3log.org", "http://example.com/a"}, want: av.KeyTypeURL},
+		{name: "email", values: []string{"a@example.com", "b@example.com"}, want: av.KeyTypeEmail},
+		{name: "select", values: []string{"Todo", "Done", "Todo", "Done"}, want: av.KeyTypeSelect},
+		{name: "mSelect", values: []string{"a, b", "b；c", "a", "c"}, want: av.KeyTypeMSelect},
+		{name: "text", values: []string{"foo", "bar", "baz"}, want: av.KeyTypeText},
+	}
+
+	for _, test := range tests {
+		var records [][]string
+		for _, value := range test.values {
+			records = append(records, []string{"id", value})
+		}
+		if got := inferImportKeyType(records, 1); test.want != got {
+			t.Errorf("[%s] expected key type [%s], got [%s]", test.name, test.want, got)
+		}
+	}
+}
+
+func TestKeepImportAttributeViewBlockKey(t *testing.T) {
+	// 主键不一定是第一个字段
+	selectKey := av.NewKey("20240101000000-select0", "Select", "", av.KeyTypeSelect)
+	blockKey := av.NewKey("20240101000000-block00", "Block", "", av.KeyTypeBlock)
+	attrView := &av.AttributeView{ID: "20240101000000-avtest1", KeyValues: []*av.KeyValues{{Key: selectKey}, {Key: blockKey}}}
+	attrView.Views = []*av.View{{ID: "20240101000000-view000", Table: &av.LayoutTable{Columns: []*av.ViewTableColumn{
+		{BaseField: &av.BaseField{ID: selectKey.ID}},
+		{BaseField: &av.BaseField{ID: blockKey.ID}},
+	}}}}
+
+	keepImportAttributeViewBlockKey(attrView, "Name")
+	if 1 != len(attrView.KeyValues) || blockKey != attrView.KeyValues[0].Key || "Name" != blockKey.Name {
+		t.Fatalf("expected only the block key to be kept")
+	}
+	for _, view := range attrView.Views {
+		if 1 != len(view.Table.Columns) || blockKey.ID != view.Table.Columns[0].ID {
+			t.Errorf("expected only the block key column to be kept in view [%s]", view.ID)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute/ast"
+	"github.com/88250/lute/parse"
+	"github.com/siyuan-note/filelock"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+// enexNote 描述 ENEX 文件中的一篇笔记。
+type enexNote struct {
+	Title      string         `xml:"title"`
+	Content    string         `xml:"content"`
+	Created    string         `xml:"created"`
+	Updated    string         `xml:"updated"`
+	Tags       []string       `xml:"tag"`
+	Attributes enexAttributes `xml:"note-attributes"`
+	Resources  []enexResource `xml:"resource"`
+}
+
+type enexAttributes struct {
+	Author    string `xml:"author"`
+	SourceURL string `xml:"source-url"`
+}
+
+// enexResource 描述笔记中的附件，正文中通过 `<en-media hash="...">` 引用，hash 为附件数据的 MD5。
+type enexResource struct {
+	Data     string `xml:"data"`
+	Mime     string `xml:"mime"`
+	FileName string `xml:"resource-attributes>file-name"`
+}
+
+var (
+	enexNoteContentRegexp = regexp.MustCompile(`(?is)<en-note[^>]*>(.*)</en-note>`)
+	enexMediaRegexp       = regexp.MustCompile(`(?is)<en-media([^>]*?)/?>(?:\s*</en-media>)?`)
+	enexTodoRegexp        = regexp.MustCompile(`(?is)<en-todo([^>]*?)/?>(?:\s*</en-todo>)?`)
+	enexCryptRegexp       = regexp.MustCompile(`(?is)<en-crypt[^>]*>.*?</en-crypt>`)
+	enexAttrRegexp        = regexp.MustCompile(`(?is)([a-z-]+)\s*=\s*"([^"]*)"`)
+)
+
+// ImportENEX 导入印象笔记/Evernote 导出的 .enex 文件。
+//
+// 一个 .enex 文件对应一个笔记本，导入后在 toPath 下新建以笔记本命名的文档，每篇笔记作为其子文档。笔记的标签转换为文档标签，
+// 创建时间和更新时间保留为文档 ID 和更新时间，附件保存到 assets 文件夹。
+func ImportENEX(enexPath, boxID, toPath string) (err error) {
+	if !strings.EqualFold(".enex", filepath.Ext(enexPath)) {
+		return errors.New(Conf.Language(296))
+	}
+
+	box := Conf.Box(boxID)
+	if nil == box {
+		return ErrBoxNotFound
+	}
+
+	util.PushEndlessProgress(Conf.Language(73))
+	defer func() {
+		util.PushClearProgress()
+
+		if e := recover(); nil != e {
+			stack := debug.Stack()
+			msg := fmt.Sprintf("PANIC RECOVERED: %v\n\t%s\n", e, stack)
+			logging.LogErrorf("import enex failed: %s", msg)
+			err = errors.New("import enex failed, please check kernel log for details")
+		}
+	}()
+
+	f, err := os.Open(enexPath)
+	if err != nil {
+		logging.LogErrorf("open enex [%s] failed: %s", enexPath, err)
+		return
+	}
+	defer f.Close()
+
+	lockSync()
+	defer unlockSync()
+
+	FlushTxQueue()
+
+	baseHPath, baseTargetPath := "/", "/"
+	if "/" != toPath {
+		block := treenode.GetBlockTreeRootByPath(boxID, toPath)
+		if nil == block {
+			logging.LogErrorf("not found block by path [%s]", toPath)
+			return ErrTreeNotFound
+		}
+		baseHPath = block.HPath
+		baseTargetPath = strings.TrimSuffix(block.Path, ".sy")
+	}
+
+	notebookName := strings.TrimSuffix(filepath.Base(enexPath), filepath.Ext(enexPath))
+	notebookID := ast.NewNodeID()
+	notebookTree := treenode.NewTree(boxID, path.Join(baseTargetPath, notebookID+".sy"), path.Join(baseHPath, notebookName), notebookName)
+	trees := []*parse.Tree{notebookTree}
+
+	assetsDirPath := filepath.Join(util.DataDir, "assets")
+	decoder := xml.NewDecoder(f)
+	decoder.Strict = false
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	for {
+		token, tokenErr := decoder.Token()
+		if io.EOF == tokenErr {
+			break
+		}
+		if nil != tokenErr {
+			err = tokenErr
+			logging.LogErrorf("parse enex [%s] failed: %s", enexPath, err)
+			return
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || "note" != start.Name.Local {
+			continue
+		}
+
+		note := &enexNote{}
+		if err = decoder.DecodeElement(note, &start); err != nil {
+			logging.LogErrorf("parse enex note failed: %s", err)
+			return
+		}
+
+		tree := enexNote2Tree(note, notebookTree, assetsDirPath)
+		if nil == tree {
+			continue
+		}
+		trees = append(trees, tree)
+		if 0 == len(trees)%16 {
+			util.PushEndlessProgress(fmt.Sprintf(Conf.language(70), tree.HPath))
+		}
+	}
+
+	sortIDVals := map[string]int{}
+	for i, tree := range trees {
+		indexWriteTreeIndexQueue(tree)
+		sortIDVals[tree.ID] = i
+		if 0 == i%16 {
+			util.PushEndlessProgress(fmt.Sprintf(Conf.Language(66), fmt.Sprintf("%d/%d ", i, len(trees))+tree.HPath))
+		}
+	}
+	box.setSort(sortIDVals)
+
+	IncSync()
+	debug.FreeOSMemory()
+	return
+}
+
+func enexNote2Tree(note *enexNote, notebookTree *parse.Tree, assetsDirPath string) (ret *parse.Tree) {
+	title := strings.TrimSpace(note.Title)
+	if "" == title {
+		title = "Untitled"
+	}
+
+	assets := map[string]string{} // 附件 MD5 -> 资源文件路径
+	for _, res := range note.Resources {
+		hash, assetPath := saveEnexResource(&res, assetsDirPath)
+		if "" != hash {
+			assets[hash] = assetPath
+		}
+	}
+
+	luteEngine := util.NewLute()
+	luteEngine.SetHTMLTag2TextMark(true)
+	ret, _ = HTML2Tree(enml2HTML(note.Content, assets), luteEngine)
+	if nil == ret {
+		logging.LogErrorf("parse enex note [%s] failed", title)
+		return
+	}
+	ast.Walk(ret.Root, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if entering && n.IsBlock() && "" == n.ID {
+			treenode.ResetNodeID(n)
+		}
+		return ast.WalkContinue
+	})
+	parse.TextMarks2Inlines(ret)
+	parse.NestedInlines2FlattedSpansHybrid(ret, false)
+
+	id := ast.NewNodeID()
+	if created, parseErr := time.Parse("20060102T150405Z", note.Created); nil == parseErr {
+		id = created.Local().Format("20060102150405") + "-" + gulu.Rand.String(7)
+	}
+	var updated string
+	if u, parseErr := time.Parse("20060102T150405Z", note.Updated); nil == parseErr {
+		updated = u.Local().Format("20060102150405")
+	}
+
+	ret.ID = id
+	ret.Root.ID = id
+	ret.Root.SetIALAttr("id", id)
+	ret.Root.SetIALAttr("title", title)
+	ret.Box = notebookTree.Box
+	ret.Path = path.Join(strings.TrimSuffix(notebookTree.Path, ".sy"), id+".sy")
+	ret.HPath = path.Join(notebookTree.HPath, title)
+	ret.Root.Spec = treenode.CurrentSpec
+	reassignIDUpdated(ret, id, updated)
+
+	var tags []string
+	for _, tag := range note.Tags {
+		if tag = strings.TrimSpace(tag); "" != tag {
+			tags = append(tags, strings.ReplaceAll(tag, ",", " "))
+		}
+	}
+	if 0 < len(tags) {
+		ret.Root.SetIALAttr("tags", strings.Join(tags, ","))
+	}
+	if author := strings.TrimSpace(note.Attributes.Author); "" != author {
+		ret.Root.SetIALAttr("custom-author", author)
+	}
+	if sourceURL := strings.TrimSpace(note.Attributes.SourceURL); "" != sourceURL {
+		ret.Root.SetIALAttr("custom-source-url", sourceURL)
+	}
+	return
+}
+
+// saveEnexResource 将 base64 编码的附件保存到 assets 文件夹，返回附件数据的 MD5 和资源文件路径。
+func saveEnexResource(res *enexResource, assetsDirPath string) (hash, assetPath string) {
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(res.Data), ""))
+	if err != nil {
+		logging.LogErrorf("decode enex resource [%s] failed: %s", res.FileName, err)
+		return
+	}
+
+	name := strings.TrimSpace(res.FileName)
+	if "" == name || "" == path.Ext(name) {
+		ext := ""
+		if exts, _ := mime.ExtensionsByType(res.Mime); 0 < len(exts) {
+			ext = exts[0]
+		}
+		if "" == name {
+			name = "file"
+			if strings.HasPrefix(res.Mime, "image/") {
+				name = "image"
+			}
+		}
+		name += ext
+	}
+	name = util.FilterUploadFileName(name)
+	name = util.AssetName(name, ast.NewNodeID())
+
+	if err = filelock.WriteFile(filepath.Join(assetsDirPath, name), data); err != nil {
+		logging.LogErrorf("write enex resource [%s] failed: %s", name, err)
+		return
+	}
+
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:]), "assets/" + name
+}
+
+// enml2HTML 将 ENML 转换为 HTML：`<en-media>` 转换为图片或者链接，`<en-todo>` 转换为复选框，加密内容 `<en-crypt>` 无法解密，直接去掉。
+func enml2HTML(enml string, assets map[string]string) string {
+	if m := enexNoteContentRegexp.FindStringSubmatch(enml); nil != m {
+		enml = m[1]
+	}
+
+	enml = enexMediaRegexp.ReplaceAllStringFunc(enml, func(media string) string {
+		attrs := enexAttrs(media)
+		assetPath := assets[strings.ToLower(attrs["hash"])]
+		if "" == assetPath {
+			return ""
+		}
+		if strings.HasPrefix(attrs["type"], "image/") {
+			return "<img src=\"" + assetPath + "\">"
+		}
+		return "<a href=\"" + assetPath + "\">" + html.EscapeString(path.Base(assetPath)) + "</a>"
+	})
+	enml = enexTodoRegexp.ReplaceAllStringFunc(enml, func(todo string) string {
+		if "true" == enexAttrs(todo)["checked"] {
+			return "<input type=\"checkbox\" checked> "
+		}
+		return "<input type=\"checkbox\"> "
+	})
+	enml = enexCryptRegexp.ReplaceAllString(enml, "")
+	return "<div>" + enml + "</div>"
+}
+
+func enexAttrs(tag string) (ret map[string]string) {
+	ret = map[string]string{}
+	for _, m := range enexAttrRegexp.FindAllStringSubmatch(tag, -1) {
+		ret[strings.ToLower(m[1])] = m[2]
+	}
+	return
+}
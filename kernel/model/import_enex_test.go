@@ -0,0 +1,130 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"encoding/xml"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/88250/lute/ast"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+)
+
+func TestENML2HTML(t *testing.T) {
+	assets := map[string]string{
+		"0123456789abcdef0123456789abcdef": "assets/image-20240101000000-abcdefg.png",
+		"fedcba9876543210fedcba9876543210": "assets/file-20240101000000-abcdefg.pdf",
+	}
+
+	tests := []struct {
+		name string
+		enml string
+		want string
+	}{
+		{
+			name: "note content",
+			enml: `<?xml version="1.0" encoding="UTF-8"?><!DOCTYPE en-note SYSTEM "http://xml.evernote.com/pub/enml2.dtd"><en-note style="x"><p>foo</p></en-note>`,
+			want: `<div><p>foo</p></div>`,
+		},
+		{
+			name: "image and file",
+			enml: `<en-note><en-media type="image/png" hash="0123456789ABCDEF0123456789ABCDEF"/><en-media hash="fedcba9876543210fedcba9876543210" type="application/pdf"></en-media></en-note>`,
+			want: `<div><img src="assets/image-20240101000000-abcdefg.png"><a href="assets/file-20240101000000-abcdefg.pdf">file-20240101000000-abcdefg.pdf</a></div>`,
+		},
+		{
+			name: "missing resource",
+			enml: `<en-note>a<en-media type="image/png" hash="00000000000000000000000000000000"/>b</en-note>`,
+			want: `<div>ab</div>`,
+		},
+		{
+			name: "todo",
+			enml: `<en-note><div><en-todo checked="false"></en-todo>todo</div></en-note>`,
+			want: `<div><div><input type="checkbox" checked> done</div><div><input type="checkbox"> todo</div></div>`,
+		},
+		{
+			name: "encrypted",
+			enml: `<en-note>a<en-crypt cipher="AES">secret</en-crypt>b</en-note>`,
+			want: `<div>ab</div>`,
+		},
+	}
+
+	for _, test := range tests {
+		if got := enml2HTML(test.enml, assets); test.want != got {
+			t.Errorf("[%s] expected [%s], got [%s]", test.name, test.want, got)
+		}
+	}
+}
+
+func TestENEXNote2Tree(t *testing.T) {
+	data := `<en-export><note>
+<title>Foo</title>
+<content><![CDATA[<en-note><div><en-media type="image/png" hash="acbd18db4cc2f85cedef654fccc4a4d8"/></div></en-note>]]></content>
+<created>20240102T030405Z</created>
+<tag>a</tag><tag>b,c</tag>
+<note-attributes><author>alice</author><source-url>https://b3log.org</source-url></note-attributes>
+<resource><data encoding="base64">Zm9v</data><mime>image/png</mime><resource-attributes><file-name>foo.png</file-name></resource-attributes></resource>
+</note></en-export>`
+
+	decoder := xml.NewDecoder(strings.NewReader(data))
+	var note *enexNote
+	for nil == note {
+		token, err := decoder.Token()
+		if nil != err {
+			t.Fatalf("note not found: %s", err)
+		}
+		if start, ok := token.(xml.StartElement); ok && "note" == start.Name.Local {
+			note = &enexNote{}
+			if err = decoder.DecodeElement(note, &start); nil != err {
+				t.Fatalf("decode note failed: %s", err)
+			}
+		}
+	}
+
+	assetsDirPath := t.TempDir()
+	notebookTree := treenode.NewTree("20240101000000-abcdefg", "/20240101000000-bcdefgh.sy", "/Notebook", "Notebook")
+	tree := enexNote2Tree(note, notebookTree, assetsDirPath)
+	if nil == tree {
+		t.Fatalf("convert note failed")
+	}
+
+	// 笔记转换为笔记本文档的子文档，创建时间作为文档 ID，标签和笔记属性转换为文档属性
+	if "/Notebook/Foo" != tree.HPath || !strings.HasPrefix(tree.Path, "/20240101000000-bcdefgh/") || !strings.HasPrefix(tree.ID, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Local().Format("20060102150405")) {
+		t.Errorf("unexpected tree [%s, %s, %s]", tree.ID, tree.HPath, tree.Path)
+	}
+	if "a,b c" != tree.Root.IALAttr("tags") || "alice" != tree.Root.IALAttr("custom-author") || "https://b3log.org" != tree.Root.IALAttr("custom-source-url") {
+		t.Errorf("unexpected attrs [%v]", tree.Root.KramdownIAL)
+	}
+
+	// 附件写入 assets 文件夹并在文档中引用
+	entries, _ := os.ReadDir(assetsDirPath)
+	if 1 != len(entries) || !strings.HasPrefix(entries[0].Name(), "foo-") {
+		t.Fatalf("unexpected assets [%v]", entries)
+	}
+	var dest string
+	ast.Walk(tree.Root, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if entering && ast.NodeLinkDest == n.Type {
+			dest = n.TokensStr()
+		}
+		return ast.WalkContinue
+	})
+	if "assets/"+entries[0].Name() != dest {
+		t.Errorf("image dest expected [assets/%s], got [%s]", entries[0].Name(), dest)
+	}
+}
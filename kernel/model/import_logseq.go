@@ -0,0 +1,721 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute"
+	"github.com/88250/lute/ast"
+	"github.com/88250/lute/lex"
+	"github.com/88250/lute/parse"
+	"github.com/siyuan-note/filelock"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/filesys"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+// Logseq 图谱（基于文件）的结构：
+//
+//	pages/页面.md           页面，命名空间 a/b 保存为 a___b.md（旧版为 a%2Fb.md）
+//	journals/2024_01_05.md  日记
+//	assets/                 资源文件
+//
+// 页面内容是大纲形式的 Markdown，每个块是一个列表项，块属性写在块内容下方（`key:: value`），
+// 页面属性写在第一个块中。块通过 `id:: <UUID>` 声明 UUID，其他块通过 `((UUID))` 引用，通过 `{{embed ((UUID))}}` 嵌入。
+
+var (
+	logseqPropertyRegexp    = regexp.MustCompile(`^([A-Za-z0-9_-]+):: ?(.*)$`)
+	logseqMarkerRegexp      = regexp.MustCompile(`(?m)^(\s*)- (TODO|DOING|LATER|NOW|WAIT|WAITING|IN-PROGRESS|DONE) `)
+	logseqBlockRefRegexp    = regexp.MustCompile(`\(\(([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})\)\)`)
+	logseqPageRefRegexp     = regexp.MustCompile(`(#?)\[\[([^\[\]]+)\]\]`)
+	logseqEmbedRegexp       = regexp.MustCompile(`^\{\{embed (\(\([0-9a-f-]{36}\)\)|\[\[[^\[\]]+\]\])\s*\}\}$`)
+	logseqInlineEmbedRegexp = regexp.MustCompile(`\{\{embed (\(\([0-9a-f-]{36}\)\)|\[\[[^\[\]]+\]\])\s*\}\}`)
+	logseqJournalRegexp     = regexp.MustCompile(`^(\d{4})[_-](\d{2})[_-](\d{2})$`)
+)
+
+// LogseqUUIDAttr 用于保存 Logseq 块 UUID 的属性名，导入后 UUID 和块 ID 一一对应。
+const LogseqUUIDAttr = "custom-logseq-uuid"
+
+type logseqPage struct {
+	name     string    // 页面名，命名空间使用 / 分隔
+	journal  time.Time // 日记日期，非日记时为零值
+	filePath string
+	tree     *parse.Tree
+	target   *parse.Tree // 日记对应的日记文档已经存在时合并到该文档中
+}
+
+// ImportLogseqGraph 导入 Logseq 图谱。页面按命名空间导入到 toPath 下，日记导入到笔记本的日记存储路径下，已经存在的日记会追加到日记文档末尾。
+func ImportLogseqGraph(boxID, graphPath, toPath string) (err error) {
+	box := Conf.Box(boxID)
+	if nil == box {
+		return ErrBoxNotFound
+	}
+
+	pagesDir, journalsDir := filepath.Join(graphPath, "pages"), filepath.Join(graphPath, "journals")
+	if !gulu.File.IsDir(pagesDir) && !gulu.File.IsDir(journalsDir) {
+		return errors.New(Conf.Language(297))
+	}
+
+	util.PushEndlessProgress(Conf.Language(73))
+	defer func() {
+		util.PushClearProgress()
+
+		if e := recover(); nil != e {
+			stack := debug.Stack()
+			msg := fmt.Sprintf("PANIC RECOVERED: %v\n\t%s\n", e, stack)
+			logging.LogErrorf("import logseq graph failed: %s", msg)
+			err = errors.New("import logseq graph failed, please check kernel log for details")
+		}
+	}()
+
+	lockSync()
+	defer unlockSync()
+
+	FlushTxQueue()
+
+	baseHPath, baseTargetPath := "/", "/"
+	if "/" != toPath {
+		block := treenode.GetBlockTreeRootByPath(boxID, toPath)
+		if nil == block {
+			logging.LogErrorf("not found block by path [%s]", toPath)
+			return ErrTreeNotFound
+		}
+		baseHPath = block.HPath
+		baseTargetPath = strings.TrimSuffix(block.Path, ".sy")
+	}
+
+	var pages []*logseqPage
+	uuidNodes := map[string]*ast.Node{} // Logseq 块 UUID -> 块
+	assetsDone := map[string]string{}
+	for _, dir := range []string{pagesDir, journalsDir} {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.EqualFold(".md", filepath.Ext(entry.Name())) {
+				continue
+			}
+
+			page := parseLogseqPage(filepath.Join(dir, entry.Name()), dir == journalsDir, uuidNodes, assetsDone)
+			if nil != page {
+				pages = append(pages, page)
+			}
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool { return strings.ToLower(pages[i].name) < strings.ToLower(pages[j].name) })
+
+	// 确定文档路径：页面按命名空间建立层级，日记按日记存储路径建立层级
+	var trees []*parse.Tree
+	hPathTrees := map[string]*parse.Tree{}
+	pageIDs := map[string]string{} // 小写的页面名和别名 -> 文档 ID
+	dailyNoteSavePath := box.GetConf().DailyNoteSavePath
+	if "" == dailyNoteSavePath || "/" == dailyNoteSavePath {
+		dailyNoteSavePath = "/daily note/{{now | date \"2006/01\"}}/{{now | date \"2006-01-02\"}}"
+	}
+	for _, page := range pages {
+		if page.journal.IsZero() {
+			parent := logseqParentTree(boxID, baseHPath, baseTargetPath, path.Dir("/"+page.name), hPathTrees, &trees, false)
+			page.tree.HPath = path.Join(parent.hPath, path.Base("/"+page.name))
+			page.tree.Path = path.Join(parent.dirPath, page.tree.ID+".sy")
+			hPathTrees[strings.ToLower(page.tree.HPath)] = page.tree
+		} else {
+			hPath, renderErr := renderLogseqDailyNotePath(dailyNoteSavePath, page.journal)
+			if nil != renderErr {
+				logging.LogErrorf("render daily note save path [%s] failed: %s", dailyNoteSavePath, renderErr)
+				continue
+			}
+			hPath = util.TrimSpaceInPath(hPath)
+			date := page.journal.Format("20060102")
+			if existRoot := treenode.GetBlockTreeRootByHPath(boxID, hPath); nil != existRoot {
+				if page.target, err = LoadTreeByBlockID(existRoot.RootID); err != nil {
+					return
+				}
+				page.target.Root.SetIALAttr(DailyNoteAttrPrefix+date, date)
+				pageIDs[strings.ToLower(page.name)] = page.target.ID
+				continue
+			}
+
+			parent := logseqParentTree(boxID, "/", "/", path.Dir(hPath), hPathTrees, &trees, true)
+			page.tree.HPath = path.Join(parent.hPath, path.Base(hPath))
+			page.tree.Path = path.Join(parent.dirPath, page.tree.ID+".sy")
+			page.tree.Root.SetIALAttr("title", path.Base(hPath))
+			page.tree.Root.SetIALAttr(DailyNoteAttrPrefix+date, date)
+		}
+		trees = append(trees, page.tree)
+		pageIDs[strings.ToLower(page.name)] = page.tree.ID
+		for _, alias := range strings.Split(page.tree.Root.IALAttr("alias"), ",") {
+			if alias = strings.TrimSpace(alias); "" != alias {
+				if _, ok := pageIDs[strings.ToLower(alias)]; !ok {
+					pageIDs[strings.ToLower(alias)] = page.tree.ID
+				}
+			}
+		}
+	}
+
+	// 记录 UUID，之后单独导入的页面中对这些块的引用可以通过该属性解析
+	for uuid, n := range uuidNodes {
+		n.SetIALAttr(LogseqUUIDAttr, uuid)
+	}
+
+	var refUUIDs []string
+	for _, page := range pages {
+		ast.Walk(page.tree.Root, func(n *ast.Node, entering bool) ast.WalkStatus {
+			if entering && ast.NodeText == n.Type {
+				for _, uuid := range logseqBlockRefUUIDs(n.TokensStr()) {
+					if nil == uuidNodes[uuid] {
+						refUUIDs = append(refUUIDs, uuid)
+					}
+				}
+			}
+			return ast.WalkContinue
+		})
+	}
+	uuidRefs := resolveLogseqBlockRefs(refUUIDs)
+
+	luteEngine := NewLute()
+	for _, page := range pages {
+		convertLogseqRefs(page.tree, luteEngine, uuidNodes, uuidRefs, pageIDs)
+	}
+
+	importTrees = trees
+	for _, page := range pages {
+		if nil != page.target {
+			importTrees = append(importTrees, page.tree)
+		}
+	}
+	mergeTextAndHandlerNestedInlines()
+	importTrees = []*parse.Tree{}
+
+	for i, tree := range trees {
+		indexWriteTreeIndexQueue(tree)
+		if 0 == i%16 {
+			util.PushEndlessProgress(fmt.Sprintf(Conf.Language(66), fmt.Sprintf("%d/%d ", i, len(trees))+tree.HPath))
+		}
+	}
+	for _, page := range pages {
+		if nil == page.target {
+			continue
+		}
+
+		var children []*ast.Node
+		for c := page.tree.Root.FirstChild; nil != c; c = c.Next {
+			children = append(children, c)
+		}
+		for _, c := range children {
+			page.target.Root.AppendChild(c)
+		}
+		page.target.Root.SetIALAttr("updated", util.CurrentTimeSecondsStr())
+		if err = indexWriteTreeUpsertQueue(page.target); err != nil {
+			return
+		}
+	}
+
+	IncSync()
+	debug.FreeOSMemory()
+	return
+}
+
+// parseLogseqPage 解析 Logseq 页面，处理页面属性、块属性、任务标记和资源文件。
+func parseLogseqPage(filePath string, journal bool, uuidNodes map[string]*ast.Node, assetsDone map[string]string) (ret *logseqPage) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		logging.LogErrorf("read logseq page [%s] failed: %s", filePath, err)
+		return
+	}
+
+	ret = &logseqPage{filePath: filePath, name: logseqPageName(filePath)}
+	if journal {
+		m := logseqJournalRegexp.FindStringSubmatch(ret.name)
+		if nil == m {
+			logging.LogWarnf("skip logseq journal [%s] with unknown file name format", filePath)
+			return nil
+		}
+		if ret.journal, err = time.ParseInLocation("2006-01-02", m[1]+"-"+m[2]+"-"+m[3], time.Local); err != nil {
+			return nil
+		}
+		ret.name = logseqJournalTitle(ret.journal)
+	}
+
+	// 任务标记转换为任务列表
+	data = logseqMarkerRegexp.ReplaceAllFunc(data, func(marker []byte) []byte {
+		m := logseqMarkerRegexp.FindSubmatch(marker)
+		if "DONE" == string(m[2]) {
+			return append(m[1], []byte("- [x] ")...)
+		}
+		return append(m[1], []byte("- [ ] ")...)
+	})
+
+	tree, _, _, _ := parseStdMd(data)
+	if nil == tree {
+		logging.LogErrorf("parse logseq page [%s] failed", filePath)
+		return nil
+	}
+	tree.MergeText()
+
+	id := ast.NewNodeID()
+	tree.ID = id
+	tree.Root.ID = id
+	tree.Root.Spec = treenode.CurrentSpec
+	reassignIDUpdated(tree, id, "")
+
+	for k, v := range convertLogseqProperties(tree, uuidNodes) {
+		switch k {
+		case "title":
+			if !journal {
+				ret.name = v
+			}
+		case "alias":
+			tree.Root.SetIALAttr("alias", v)
+		case "tags":
+			tree.Root.SetIALAttr("tags", v)
+		default:
+			tree.Root.SetIALAttr("custom-"+k, v)
+		}
+	}
+	tree.Root.SetIALAttr("title", path.Base("/"+ret.name))
+	ret.tree = tree
+
+	convertLogseqAssets(tree, filepath.Dir(filePath), assetsDone)
+	return
+}
+
+// logseqPageName 返回文件名对应的页面名，命名空间中的 ___ 和 %2F 转换为 /。
+func logseqPageName(filePath string) string {
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	name = strings.ReplaceAll(name, "___", "/")
+	if unescaped, err := url.PathUnescape(name); nil == err {
+		name = unescaped
+	}
+	name = strings.Trim(name, "/ ")
+	if "" == name {
+		name = "Untitled"
+	}
+	return name
+}
+
+// logseqJournalTitle 返回 Logseq 默认格式（MMM do, yyyy）的日记页面名，用于解析日记引用。
+func logseqJournalTitle(date time.Time) string {
+	day := date.Day()
+	suffix := "th"
+	if day < 11 || day > 13 {
+		switch day % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%s %d%s, %d", date.Format("Jan"), day, suffix, date.Year())
+}
+
+// renderLogseqDailyNotePath 使用日记日期渲染日记存储路径模板。
+func renderLogseqDailyNotePath(savePath string, date time.Time) (ret string, err error) {
+	tplFuncMap := filesys.BuiltInTemplateFuncs()
+	tplFuncMap["now"] = func() time.Time { return date }
+	tpl, err := template.New("").Funcs(tplFuncMap).Parse(savePath)
+	if err != nil {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if err = tpl.Execute(buf, nil); err != nil {
+		return
+	}
+	ret = buf.String()
+	return
+}
+
+type logseqParent struct {
+	hPath   string
+	dirPath string // 子文档所在的文件夹路径
+}
+
+// logseqParentTree 返回 hPath 对应的父文档，父文档不存在时新建（包括上级文档）。checkExist 为 true 时优先使用笔记本中已经存在的文档。
+func logseqParentTree(boxID, baseHPath, baseTargetPath, hPath string, hPathTrees map[string]*parse.Tree, trees *[]*parse.Tree, checkExist bool) (ret *logseqParent) {
+	ret = &logseqParent{hPath: baseHPath, dirPath: baseTargetPath}
+	if "/" == hPath || "." == hPath || "" == hPath {
+		return
+	}
+
+	for _, part := range strings.Split(strings.Trim(hPath, "/"), "/") {
+		if "" == part {
+			continue
+		}
+
+		hp := path.Join(ret.hPath, part)
+		if tree := hPathTrees[strings.ToLower(hp)]; nil != tree {
+			ret.hPath, ret.dirPath = tree.HPath, strings.TrimSuffix(tree.Path, ".sy")
+			continue
+		}
+		if checkExist {
+			if bt := treenode.GetBlockTreeRootByHPath(boxID, hp); nil != bt {
+				ret.hPath, ret.dirPath = bt.HPath, strings.TrimSuffix(bt.Path, ".sy")
+				continue
+			}
+		}
+
+		id := ast.NewNodeID()
+		tree := treenode.NewTree(boxID, path.Join(ret.dirPath, id+".sy"), hp, part)
+		hPathTrees[strings.ToLower(hp)] = tree
+		*trees = append(*trees, tree)
+		ret.hPath, ret.dirPath = tree.HPath, strings.TrimSuffix(tree.Path, ".sy")
+	}
+	return
+}
+
+// convertLogseqProperties 将块属性转换为块的 IAL，返回页面属性。
+//
+// 块属性所在段落是列表项的第一个子块时属性设置到列表项上，`id::` 记录到 uuidNodes 中，`collapsed:: true` 转换为折叠。
+// 文档中第一个只包含属性的块为页面属性，转换后移除该块。
+func convertLogseqProperties(tree *parse.Tree, uuidNodes map[string]*ast.Node) (pageProps map[string]string) {
+	pageProps = map[string]string{}
+	var paragraphs []*ast.Node
+	ast.Walk(tree.Root, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if entering && ast.NodeParagraph == n.Type {
+			paragraphs = append(paragraphs, n)
+		}
+		return ast.WalkContinue
+	})
+
+	var unlinks []*ast.Node
+	for i, p := range paragraphs {
+		props := removeLogseqPropertyLines(p)
+		if 1 > len(props) {
+			continue
+		}
+
+		target := p
+		if ast.NodeListItem == p.Parent.Type && (nil == p.Previous || ast.NodeTaskListItemMarker == p.Previous.Type) {
+			target = p.Parent
+		}
+
+		if 0 == i && nil == p.FirstChild && ((target == p && tree.Root.FirstChild == p) || logseqIsFirstListItem(tree, target)) {
+			// 页面属性
+			for _, kv := range props {
+				pageProps[kv[0]] = kv[1]
+			}
+			unlinks = append(unlinks, target)
+			continue
+		}
+
+		for _, kv := range props {
+			switch kv[0] {
+			case "id":
+				uuidNodes[strings.ToLower(kv[1])] = target
+			case "collapsed":
+				if "true" == kv[1] && ast.NodeListItem == target.Type && nil != target.FirstChild && nil != target.FirstChild.Next {
+					target.SetIALAttr("fold", "1")
+				}
+			default:
+				target.SetIALAttr("custom-"+kv[0], kv[1])
+			}
+		}
+	}
+
+	for _, n := range unlinks {
+		if ast.NodeListItem == n.Type {
+			list := n.Parent
+			if next := n.Next; nil != next && ast.NodeKramdownBlockIAL == next.Type {
+				next.Unlink()
+			}
+			n.Unlink()
+			if nil == list.FirstChild || (ast.NodeKramdownBlockIAL == list.FirstChild.Type && nil == list.FirstChild.Next) {
+				n = list
+			} else {
+				continue
+			}
+		}
+		if next := n.Next; nil != next && ast.NodeKramdownBlockIAL == next.Type {
+			next.Unlink()
+		}
+		n.Unlink()
+	}
+	if nil == tree.Root.FirstChild || ast.NodeKramdownBlockIAL == tree.Root.FirstChild.Type {
+		tree.Root.PrependChild(treenode.NewParagraph(ast.NewNodeID()))
+	}
+	return
+}
+
+func logseqIsFirstListItem(tree *parse.Tree, li *ast.Node) bool {
+	list := li.Parent
+	if ast.NodeListItem != li.Type || list.FirstChild != li || list.Parent != tree.Root || tree.Root.FirstChild != list {
+		return false
+	}
+	for c := li.FirstChild.Next; nil != c; c = c.Next {
+		if ast.NodeKramdownBlockIAL != c.Type {
+			return false
+		}
+	}
+	return true
+}
+
+// removeLogseqPropertyLines 移除段落中 `key:: value` 形式的行，返回属性名和属性值。属性名转换为小写，值中的页面引用转换为页面名。
+func removeLogseqPropertyLines(p *ast.Node) (ret [][2]string) {
+	var lines [][]*ast.Node
+	var line []*ast.Node
+	for c := p.FirstChild; nil != c; c = c.Next {
+		line = append(line, c)
+		if ast.NodeSoftBreak == c.Type || ast.NodeHardBreak == c.Type {
+			lines = append(lines, line)
+			line = nil
+		}
+	}
+	if 0 < len(line) {
+		lines = append(lines, line)
+	}
+
+	for i, l := range lines {
+		if ast.NodeText != l[0].Type {
+			continue
+		}
+		m := logseqPropertyRegexp.FindStringSubmatch(l[0].TokensStr())
+		if nil == m {
+			continue
+		}
+
+		key := strings.ToLower(strings.ReplaceAll(m[1], "_", "-"))
+		value := m[2]
+		for _, c := range l[1:] {
+			if ast.NodeSoftBreak != c.Type && ast.NodeHardBreak != c.Type {
+				value += c.Text()
+			}
+		}
+		value = logseqPageRefRegexp.ReplaceAllString(value, "$2")
+		value = strings.TrimSpace(value)
+
+		valid := true
+		for j := 0; j < len(key); j++ {
+			if !lex.IsASCIILetterNumHyphen(key[j]) {
+				valid = false
+				break
+			}
+		}
+		if valid {
+			ret = append(ret, [2]string{key, value})
+		}
+
+		for _, c := range l {
+			c.Unlink()
+		}
+		if i == len(lines)-1 && nil != p.LastChild && (ast.NodeSoftBreak == p.LastChild.Type || ast.NodeHardBreak == p.LastChild.Type) {
+			p.LastChild.Unlink()
+		}
+	}
+	return
+}
+
+// convertLogseqAssets 将链接到图谱中文件的资源文件复制到 assets 文件夹。
+func convertLogseqAssets(tree *parse.Tree, currentDir string, assetsDone map[string]string) {
+	assetsDirPath := filepath.Join(util.DataDir, "assets")
+	ast.Walk(tree.Root, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if !entering || (ast.NodeLinkDest != n.Type && !n.IsTextMarkType("a")) {
+			return ast.WalkContinue
+		}
+
+		dest := n.TokensStr()
+		if ast.NodeTextMark == n.Type {
+			dest = n.TextMarkAHref
+		}
+		if "" == dest || !util.IsRelativePath(dest) {
+			return ast.WalkContinue
+		}
+		if unescaped, err := url.PathUnescape(dest); nil == err {
+			dest = unescaped
+		}
+
+		absolutePath := filepath.Join(currentDir, filepath.FromSlash(dest))
+		if !gulu.File.IsExist(absolutePath) || gulu.File.IsDir(absolutePath) {
+			return ast.WalkContinue
+		}
+
+		name := assetsDone[absolutePath]
+		if "" == name {
+			name = util.AssetName(util.FilterUploadFileName(filepath.Base(absolutePath)), ast.NewNodeID())
+			if err := filelock.Copy(absolutePath, filepath.Join(assetsDirPath, name)); err != nil {
+				logging.LogErrorf("copy asset from [%s] to [%s] failed: %s", absolutePath, assetsDirPath, err)
+				return ast.WalkContinue
+			}
+			assetsDone[absolutePath] = name
+		}
+
+		if ast.NodeLinkDest == n.Type {
+			n.Tokens = []byte("assets/" + name)
+		} else {
+			n.TextMarkAHref = "assets/" + name
+		}
+		return ast.WalkContinue
+	})
+}
+
+// convertLogseqRefs 转换块引用、页面引用和嵌入。
+//
+//   - `((UUID))` 转换为块引用，被引用块不在本次导入中时使用 uuidRefs 中之前导入的块，都找不到时保留原文
+//   - `[[页面]]` 转换为文档引用，`#[[标签]]` 转换为标签
+//   - 单独成段的 `{{embed ((UUID))}}` 和 `{{embed [[页面]]}}` 转换为嵌入块，行内的嵌入转换为引用
+func convertLogseqRefs(tree *parse.Tree, luteEngine *lute.Lute, uuidNodes map[string]*ast.Node, uuidRefs map[string]*logseqBlockRef, pageIDs map[string]string) {
+	var embeds []*ast.Node
+	ast.Walk(tree.Root, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if !entering || ast.NodeText != n.Type {
+			return ast.WalkContinue
+		}
+
+		text := n.TokensStr()
+		if p := n.Parent; ast.NodeParagraph == p.Type && p.FirstChild == n && nil == n.Next {
+			if m := logseqEmbedRegexp.FindStringSubmatch(strings.TrimSpace(text)); nil != m {
+				if id := logseqRefID(m[1], uuidNodes, uuidRefs, pageIDs); "" != id {
+					if embed := newImportEmbedNode(luteEngine, id); nil != embed {
+						embed.ID = p.ID
+						embed.KramdownIAL = p.KramdownIAL
+						embeds = append(embeds, p, embed)
+						return ast.WalkContinue
+					}
+				}
+			}
+		}
+
+		text = logseqInlineEmbedRegexp.ReplaceAllString(text, "$1")
+		text = logseqBlockRefRegexp.ReplaceAllStringFunc(text, func(ref string) string {
+			uuid := strings.ToLower(ref[2 : len(ref)-2])
+			if target := uuidNodes[uuid]; nil != target {
+				return "((" + target.ID + " '" + logseqRefText(target) + "'))"
+			}
+			if target := uuidRefs[uuid]; nil != target {
+				return "((" + target.id + " '" + target.text + "'))"
+			}
+			return ref
+		})
+		text = logseqPageRefRegexp.ReplaceAllStringFunc(text, func(ref string) string {
+			m := logseqPageRefRegexp.FindStringSubmatch(ref)
+			if "#" == m[1] {
+				return "#" + m[2] + "#"
+			}
+			id := pageIDs[strings.ToLower(m[2])]
+			if "" == id {
+				return ref
+			}
+			return "((" + id + " '" + strings.ReplaceAll(m[2], "'", "") + "'))"
+		})
+		n.Tokens = []byte(convertTags(text))
+		return ast.WalkContinue
+	})
+
+	for i := 0; i < len(embeds); i += 2 {
+		embeds[i].InsertBefore(embeds[i+1])
+		embeds[i].Unlink()
+	}
+}
+
+func logseqRefID(ref string, uuidNodes map[string]*ast.Node, uuidRefs map[string]*logseqBlockRef, pageIDs map[string]string) string {
+	if strings.HasPrefix(ref, "((") {
+		uuid := strings.ToLower(ref[2 : len(ref)-2])
+		if target := uuidNodes[uuid]; nil != target {
+			return target.ID
+		}
+		if target := uuidRefs[uuid]; nil != target {
+			return target.id
+		}
+		return ""
+	}
+	return pageIDs[strings.ToLower(ref[2:len(ref)-2])]
+}
+
+// logseqBlockRef 描述不在本次导入中的被引用块。
+type logseqBlockRef struct {
+	id   string
+	text string // 锚文本
+}
+
+// resolveLogseqBlockRefs 解析不在本次导入中的块 UUID。
+//
+// 之前导入过的块通过 custom-logseq-uuid 属性查找，没有找到的 UUID 不在结果中。
+func resolveLogseqBlockRefs(uuids []string) (ret map[string]*logseqBlockRef) {
+	ret = map[string]*logseqBlockRef{}
+	uuids = gulu.Str.RemoveDuplicatedElem(uuids)
+	for i := 0; i < len(uuids); i += 256 {
+		batch := uuids[i:min(i+256, len(uuids))]
+		stmt := "SELECT block_id, value FROM attributes WHERE name = '" + LogseqUUIDAttr + "' AND value IN ('" + strings.Join(batch, "', '") + "')"
+		rows, err := sql.QueryNoLimit(stmt)
+		if err != nil {
+			logging.LogErrorf("query logseq block uuids failed: %s", err)
+			continue
+		}
+
+		var ids []string
+		idUUIDs := map[string]string{}
+		for _, row := range rows {
+			id := row["block_id"].(string)
+			ids = append(ids, id)
+			idUUIDs[id] = row["value"].(string)
+		}
+		for _, block := range sql.GetBlocks(ids) {
+			if nil == block {
+				continue
+			}
+
+			text := strings.ReplaceAll(strings.TrimSpace(block.Content), "'", "")
+			text = gulu.Str.SubStr(strings.ReplaceAll(text, "\n", " "), 64)
+			if "" == text {
+				text = block.ID
+			}
+			ret[idUUIDs[block.ID]] = &logseqBlockRef{id: block.ID, text: text}
+		}
+	}
+
+	return
+}
+
+// logseqBlockRefUUIDs 返回文本中块引用和嵌入的小写 UUID。
+func logseqBlockRefUUIDs(text string) (ret []string) {
+	for _, m := range logseqBlockRefRegexp.FindAllStringSubmatch(strings.ToLower(text), -1) {
+		ret = append(ret, m[1])
+	}
+	return
+}
+
+// logseqRefText 返回块引用的锚文本，使用被引用块第一个段落的内容。
+func logseqRefText(target *ast.Node) (ret string) {
+	p := target
+	if ast.NodeListItem == target.Type {
+		p = target.ChildByType(ast.NodeParagraph)
+	}
+	if nil != p {
+		ret = p.Text()
+	}
+	ret = strings.ReplaceAll(strings.TrimSpace(ret), "'", "")
+	ret = strings.ReplaceAll(ret, "\n", " ")
+	ret = gulu.Str.SubStr(ret, 64)
+	if "" == ret {
+		ret = target.ID
+	}
+	return
+}
@@ -0,0 +1,124 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute/ast"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+func TestLogseqGraphPages(t *testing.T) {
+	const uuid = "65a1b2c3-0000-4000-8000-000000000001"
+
+	graph := t.TempDir()
+	files := map[string]string{
+		"pages/proj___sub.md": "title:: Project Sub\nalias:: PS\n\n" +
+			"- DONE First block\n  id:: " + uuid + "\n  status:: active\n" +
+			"- Pic ![pic](../assets/pic.png)\n",
+		"journals/2024_01_02.md": "- See ((" + uuid + ")) and [[ps]]\n" +
+			"- {{embed ((" + uuid + "))}}\n" +
+			"- Missing ((65a1b2c3-0000-4000-8000-00000000000f))\n",
+		"assets/pic.png": "png",
+	}
+	for relPath, content := range files {
+		p := filepath.Join(graph, filepath.FromSlash(relPath))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); nil != err {
+			t.Fatalf("write [%s] failed: %s", p, err)
+		}
+	}
+
+	dataDir := util.DataDir
+	util.DataDir = t.TempDir()
+	defer func() { util.DataDir = dataDir }()
+	os.MkdirAll(filepath.Join(util.DataDir, "assets"), 0755)
+
+	uuidNodes := map[string]*ast.Node{}
+	assetsDone := map[string]string{}
+	page := parseLogseqPage(filepath.Join(graph, "pages", "proj___sub.md"), false, uuidNodes, assetsDone)
+	journal := parseLogseqPage(filepath.Join(graph, "journals", "2024_01_02.md"), true, uuidNodes, assetsDone)
+	if nil == page || nil == journal {
+		t.Fatalf("parse logseq pages failed")
+	}
+
+	// 页面属性转换为文档属性，第一行的页面属性不保留为块
+	if "Project Sub" != page.name || "PS" != page.tree.Root.IALAttr("alias") || "Project Sub" != page.tree.Root.IALAttr("title") {
+		t.Errorf("unexpected page [%s], alias [%s]", page.name, page.tree.Root.IALAttr("alias"))
+	}
+	if strings.Contains(page.tree.Root.Text(), "title::") {
+		t.Errorf("page properties are not removed from [%s]", page.tree.Root.Text())
+	}
+	if "Jan 2nd, 2024" != journal.name || "2024-01-02" != journal.journal.Format("2006-01-02") {
+		t.Errorf("unexpected journal [%s]", journal.name)
+	}
+
+	// 块属性转换为列表项属性，任务标记转换为任务列表，资源文件复制到 assets 下
+	li := uuidNodes[uuid]
+	if nil == li || ast.NodeListItem != li.Type || "active" != li.IALAttr("custom-status") {
+		t.Fatalf("block with uuid [%s] is not converted to a list item with properties", uuid)
+	}
+	if marker := li.ChildByType(ast.NodeTaskListItemMarker); nil == marker || !marker.TaskListItemChecked || strings.Contains(li.Text(), "id::") {
+		t.Errorf("unexpected task list item [%s]", li.Text())
+	}
+	name := assetsDone[filepath.Join(graph, "assets", "pic.png")]
+	if "" == name || !gulu.File.IsExist(filepath.Join(util.DataDir, "assets", name)) {
+		t.Errorf("asset is not copied, got [%s]", name)
+	}
+	var dest string
+	ast.Walk(page.tree.Root, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if entering && ast.NodeLinkDest == n.Type {
+			dest = n.TokensStr()
+		}
+		return ast.WalkContinue
+	})
+	if "assets/"+name != dest {
+		t.Errorf("asset link expected [assets/%s], got [%s]", name, dest)
+	}
+
+	// 块引用、别名引用和嵌入转换为思源的引用和嵌入块，找不到的块引用保留原文
+	pageIDs := map[string]string{"project sub": page.tree.ID, "ps": page.tree.ID}
+	convertLogseqRefs(journal.tree, util.NewLute(), uuidNodes, map[string]*logseqBlockRef{}, pageIDs)
+	var embed *ast.Node
+	var texts []string
+	ast.Walk(journal.tree.Root, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if !entering {
+			return ast.WalkContinue
+		}
+		if ast.NodeBlockQueryEmbed == n.Type {
+			embed = n
+		} else if ast.NodeText == n.Type {
+			texts = append(texts, n.TokensStr())
+		}
+		return ast.WalkContinue
+	})
+	text := strings.Join(texts, "\n")
+	if !strings.Contains(text, "See (("+li.ID+" 'First block')) and (("+page.tree.ID+" 'ps'))") {
+		t.Errorf("refs are not converted in [%s]", text)
+	}
+	if !strings.Contains(text, "Missing ((65a1b2c3-0000-4000-8000-00000000000f))") {
+		t.Errorf("unresolved ref is not kept in [%s]", text)
+	}
+	if nil == embed || !strings.Contains(string(embed.ChildByType(ast.NodeBlockQueryEmbedScript).Tokens), li.ID) {
+		t.Errorf("embed is not converted to an embed block")
+	}
+}
@@ -0,0 +1,363 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/88250/gulu"
+	"github.com/siyuan-note/filelock"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+// Notion 导出（Markdown & CSV 格式）的结构：
+//
+//	页面 <ID>.md          页面内容，第一行是页面标题
+//	页面 <ID>/            子页面、数据库和资源文件
+//	数据库 <ID>.csv       数据库当前视图中的项目
+//	数据库 <ID>_all.csv   数据库中所有的项目（新版导出才有）
+//	数据库 <ID>/          数据库中每个项目对应的页面
+//
+// 导入时先将导出包规范化为普通的 Markdown 文件夹（去掉 ID 后缀、重写链接），再通过 importFromLocalPath 导入，
+// 最后将 CSV 数据库转换为数据库块插入到同名文档中，并绑定对应的项目页面。
+
+var (
+	notionNameIDRegexp = regexp.MustCompile(`^(.*?) ?([0-9a-f]{32})(_all)?$`)
+	notionURLIDRegexp  = regexp.MustCompile(`^https?://(?:www\.)?notion\.so/[^?#]*?([0-9a-f]{32}|[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})(?:[?#].*)?$`)
+	notionLinkRegexp   = regexp.MustCompile(`\]\(([^()\s]+)\)`)
+	notionRelRegexp    = regexp.MustCompile(` ?\([^()]*?[0-9a-f]{32}\.md\)`)
+)
+
+type notionExport struct {
+	srcDir    string            // 解压后的导出文件夹
+	destDir   string            // 规范化后的 Markdown 文件夹
+	paths     map[string]string // 导出文件相对路径 -> 规范化后的相对路径
+	ids       map[string]string // Notion 页面 ID -> 规范化后的相对路径
+	titles    map[string]string // 规范化后的 Markdown 相对路径 -> 页面标题
+	names     map[string]string // 去掉 _all 后的导出文件名（不含扩展名） -> 规范化后的名称，用于对应同名的页面、文件夹和数据库
+	used      map[string]bool   // 已经使用的规范化后的相对路径，用于处理同一层级下标题重复
+	databases []*notionDatabase
+}
+
+type notionDatabase struct {
+	title   string // 数据库名称
+	csvPath string // 导出的 CSV 文件路径
+	mdPath  string // 数据库所在文档规范化后的相对路径
+	all     bool   // 是否是 _all.csv
+}
+
+// ImportNotion 导入 Notion 导出的 Markdown & CSV 压缩包。
+func ImportNotion(zipPath, boxID, toPath string) (err error) {
+	if !strings.EqualFold(".zip", filepath.Ext(zipPath)) {
+		return errors.New(Conf.Language(295))
+	}
+
+	baseName := strings.TrimSuffix(filepath.Base(zipPath), filepath.Ext(zipPath))
+	workDir := filepath.Join(util.TempDir, "import", "notion-"+gulu.Rand.String(7))
+	defer os.RemoveAll(workDir)
+
+	srcDir := filepath.Join(workDir, "export")
+	if err = unzipNotionExport(zipPath, srcDir); err != nil {
+		return
+	}
+
+	export := &notionExport{
+		srcDir:  srcDir,
+		destDir: filepath.Join(workDir, "md", notionExportName(baseName)),
+		paths:   map[string]string{},
+		ids:     map[string]string{},
+		titles:  map[string]string{},
+		names:   map[string]string{},
+		used:    map[string]bool{},
+	}
+	if err = export.normalize(); err != nil {
+		return
+	}
+
+	relPathIDs, err := importFromLocalPath(boxID, export.destDir, toPath, importFlavorStd)
+	if err != nil {
+		return
+	}
+
+	for _, db := range export.databases {
+		if err = importNotionDatabase(boxID, db, export, relPathIDs); err != nil {
+			logging.LogErrorf("import notion database [%s] failed: %s", db.csvPath, err)
+			err = nil
+		}
+	}
+	return
+}
+
+// unzipNotionExport 解压 Notion 导出包。导出内容较多时 Notion 会将导出包拆分为多个压缩包再打包，这里一并解压。
+func unzipNotionExport(zipPath, unzipPath string) (err error) {
+	if err = gulu.Zip.Unzip(zipPath, unzipPath); err != nil {
+		logging.LogErrorf("unzip notion export [%s] failed: %s", zipPath, err)
+		return
+	}
+
+	entries, err := os.ReadDir(unzipPath)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(".zip", filepath.Ext(entry.Name())) {
+			continue
+		}
+
+		partPath := filepath.Join(unzipPath, entry.Name())
+		if err = gulu.Zip.Unzip(partPath, unzipPath); err != nil {
+			logging.LogErrorf("unzip notion export part [%s] failed: %s", partPath, err)
+			return
+		}
+		if err = os.Remove(partPath); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func notionExportName(baseName string) string {
+	if strings.HasPrefix(baseName, "Export-") || notionNameIDRegexp.MatchString(baseName) {
+		return "Notion"
+	}
+	return baseName
+}
+
+// normalize 将导出文件夹规范化到 destDir：去掉文件名中的 ID 后缀，将 CSV 数据库转换为同名文档，并重写页面之间的链接。
+func (export *notionExport) normalize() (err error) {
+	var files []string
+	err = filepath.WalkDir(export.srcDir, func(currentPath string, d fs.DirEntry, walkErr error) error {
+		if nil != walkErr {
+			return walkErr
+		}
+
+		relPath := filepath.ToSlash(strings.TrimPrefix(currentPath, export.srcDir))
+		relPath = strings.TrimPrefix(relPath, "/")
+		if "" == relPath {
+			return nil
+		}
+
+		parent, name := path.Dir(relPath), d.Name()
+		if "." == parent {
+			parent = ""
+		}
+		ext := ""
+		if !d.IsDir() {
+			ext = filepath.Ext(name)
+		}
+		newPath := path.Join(export.paths[parent], export.normalizeName(parent, strings.TrimSuffix(name, ext), ext))
+		export.paths[relPath] = newPath
+		if d.IsDir() {
+			return nil
+		}
+
+		stem := strings.TrimSuffix(name, ext)
+		switch strings.ToLower(ext) {
+		case ".md":
+			if m := notionNameIDRegexp.FindStringSubmatch(stem); nil != m {
+				export.ids[m[2]] = newPath
+			}
+			export.titles[newPath] = notionTitle(stem)
+		case ".csv":
+			if m := notionNameIDRegexp.FindStringSubmatch(stem); nil != m {
+				export.addDatabase(notionTitle(stem), currentPath, newPath, "" != m[3])
+				if _, ok := export.ids[m[2]]; !ok {
+					export.ids[m[2]] = newPath
+				}
+				return nil
+			}
+		}
+		files = append(files, relPath)
+		return nil
+	})
+	if err != nil {
+		logging.LogErrorf("walk notion export [%s] failed: %s", export.srcDir, err)
+		return
+	}
+
+	for _, db := range export.databases {
+		export.titles[db.mdPath] = db.title
+		mdPath := filepath.Join(export.destDir, filepath.FromSlash(db.mdPath))
+		if gulu.File.IsExist(mdPath) {
+			continue
+		}
+		if err = os.MkdirAll(filepath.Dir(mdPath), 0755); err != nil {
+			return
+		}
+		if err = os.WriteFile(mdPath, nil, 0644); err != nil {
+			return
+		}
+	}
+
+	for _, relPath := range files {
+		srcPath := filepath.Join(export.srcDir, filepath.FromSlash(relPath))
+		destPath := filepath.Join(export.destDir, filepath.FromSlash(export.paths[relPath]))
+		if err = os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return
+		}
+
+		if !strings.EqualFold(".md", filepath.Ext(relPath)) {
+			if err = filelock.Copy(srcPath, destPath); err != nil {
+				logging.LogErrorf("copy [%s] to [%s] failed: %s", srcPath, destPath, err)
+				return
+			}
+			continue
+		}
+
+		data, readErr := os.ReadFile(srcPath)
+		if nil != readErr {
+			err = readErr
+			return
+		}
+		content := export.rewriteMarkdown(relPath, string(data))
+		if err = os.WriteFile(destPath, []byte(content), 0644); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// normalizeName 返回去掉 ID 后缀后的名称，同一层级下名称重复时加上序号。同名的页面、文件夹和数据库使用相同的名称。
+func (export *notionExport) normalizeName(parent, stem, ext string) string {
+	m := notionNameIDRegexp.FindStringSubmatch(stem)
+	if nil == m {
+		return stem + ext
+	}
+
+	key := path.Join(parent, strings.TrimSuffix(stem, m[3]))
+	if strings.EqualFold(".csv", ext) {
+		ext = ".md" // 数据库转换为同名文档
+	}
+	if name, ok := export.names[key]; ok {
+		return name + ext
+	}
+
+	title := notionTitle(stem)
+	if "" == title {
+		title = "Untitled"
+	}
+	name := title
+	newParent := export.paths[parent]
+	for i := 2; export.used[strings.ToLower(path.Join(newParent, name))]; i++ {
+		name = fmt.Sprintf("%s (%d)", title, i)
+	}
+	export.used[strings.ToLower(path.Join(newParent, name))] = true
+	export.names[key] = name
+	return name + ext
+}
+
+// addDatabase 记录导出的数据库，同时存在 .csv 和 _all.csv 时使用 _all.csv。
+func (export *notionExport) addDatabase(title, csvPath, mdPath string, all bool) {
+	for _, db := range export.databases {
+		if db.mdPath == mdPath {
+			if all && !db.all {
+				db.csvPath, db.all = csvPath, all
+			}
+			return
+		}
+	}
+	export.databases = append(export.databases, &notionDatabase{title: title, csvPath: csvPath, mdPath: mdPath, all: all})
+}
+
+// rewriteMarkdown 去掉页面开头重复的标题，并将链接到导出中其他文件的链接改为规范化后的相对路径。
+func (export *notionExport) rewriteMarkdown(relPath, content string) string {
+	content = strings.TrimPrefix(content, "\xEF\xBB\xBF")
+	title := export.titles[export.paths[relPath]]
+	if first, rest, _ := strings.Cut(content, "\n"); "# "+title == strings.TrimSpace(first) {
+		content = strings.TrimLeft(rest, "\r\n")
+	}
+
+	dir := path.Dir(export.paths[relPath])
+	return notionLinkRegexp.ReplaceAllStringFunc(content, func(link string) string {
+		dest := link[2 : len(link)-1]
+		target := export.resolveLink(relPath, dest)
+		if "" == target {
+			return link
+		}
+
+		rel, relErr := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(target))
+		if nil != relErr {
+			return link
+		}
+		return "](<" + filepath.ToSlash(rel) + ">)"
+	})
+}
+
+// resolveLink 返回链接指向的规范化后的相对路径，链接不指向导出中的文件时返回空字符串。
+func (export *notionExport) resolveLink(relPath, dest string) string {
+	if m := notionURLIDRegexp.FindStringSubmatch(dest); nil != m {
+		return export.ids[strings.ReplaceAll(m[1], "-", "")]
+	}
+	if !util.IsRelativePath(dest) || strings.HasPrefix(dest, "#") {
+		return ""
+	}
+
+	dest, _, _ = strings.Cut(dest, "#")
+	if unescaped, err := url.PathUnescape(dest); nil == err {
+		dest = unescaped
+	}
+	return export.paths[path.Join(path.Dir(relPath), dest)]
+}
+
+func notionTitle(stem string) string {
+	if m := notionNameIDRegexp.FindStringSubmatch(stem); nil != m {
+		stem = m[1]
+	}
+	return strings.TrimSpace(stem)
+}
+
+// importNotionDatabase 将 CSV 数据库导入为数据库块插入到同名文档中，项目绑定数据库文档下的同名页面。
+func importNotionDatabase(boxID string, db *notionDatabase, export *notionExport, relPathIDs map[string]string) (err error) {
+	docID := relPathIDs["/"+db.mdPath]
+	if "" == docID {
+		return
+	}
+
+	header, records, err := readTabularFile(db.csvPath)
+	if err != nil {
+		return
+	}
+	for _, record := range records {
+		for i, cell := range record {
+			// 关系字段的值形如 `页面 (页面%20<ID>.md)`，只保留页面标题
+			record[i] = notionRelRegexp.ReplaceAllString(cell, "")
+		}
+	}
+
+	bindDocIDs := map[string]string{}
+	rowsDir := "/" + strings.TrimSuffix(db.mdPath, ".md") + "/"
+	for relPath, id := range relPathIDs {
+		if !strings.HasPrefix(relPath, rowsDir) || strings.Contains(strings.TrimPrefix(relPath, rowsDir), "/") {
+			continue
+		}
+		if title := export.titles[strings.TrimPrefix(relPath, "/")]; "" != title {
+			bindDocIDs[title] = id
+		}
+	}
+
+	_, err = importAttributeViewRecords(db.title, header, records, &AttributeViewImport{Notebook: boxID, DocID: docID, BindDocIDs: bindDocIDs})
+	return
+}
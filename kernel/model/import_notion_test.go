@@ -0,0 +1,115 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNotionExport(t *testing.T) {
+	const (
+		homeID  = "11111111111111111111111111111111"
+		childID = "22222222222222222222222222222222"
+		dupID   = "33333333333333333333333333333333"
+		dbID    = "44444444444444444444444444444444"
+	)
+
+	// 导出内容较多时 Notion 会将导出包拆分为多个压缩包再打包
+	part := writeTestZip(t, map[string]string{
+		"Home " + homeID + ".md": "# Home\n\n[Child](Home%20" + homeID + "/Child%20" + childID + ".md) " +
+			"[URL](https://www.notion.so/Child-" + childID + "?pvs=4) [Web](https://b3log.org)\n",
+		"Home " + homeID + "/Child " + childID + ".md":            "# Child\n\n[Back](../Home%20" + homeID + ".md#top)\n",
+		"Home " + homeID + "/Child " + dupID + ".md":              "# Child\n",
+		"Home " + homeID + "/Tasks " + dbID + ".csv":              "Name,Status\nA,Done\n",
+		"Home " + homeID + "/Tasks " + dbID + "_all.csv":          "Name,Status\nA,Done\nB,Todo\n",
+		"Home " + homeID + "/Tasks " + dbID + "/A " + dupID + "/": "",
+		"Home " + homeID + "/image.png":                           "png",
+	})
+	zipPath := filepath.Join(t.TempDir(), "Export-0123.zip")
+	if err := os.WriteFile(zipPath, writeTestZip(t, map[string]string{"Export-0123-Part-1.zip": string(part)}), 0644); nil != err {
+		t.Fatalf("write [%s] failed: %s", zipPath, err)
+	}
+
+	srcDir := filepath.Join(t.TempDir(), "export")
+	if err := unzipNotionExport(zipPath, srcDir); nil != err {
+		t.Fatalf("unzip failed: %s", err)
+	}
+	export := &notionExport{
+		srcDir:  srcDir,
+		destDir: filepath.Join(t.TempDir(), notionExportName("Export-0123")),
+		paths:   map[string]string{},
+		ids:     map[string]string{},
+		titles:  map[string]string{},
+		names:   map[string]string{},
+		used:    map[string]bool{},
+	}
+	if err := export.normalize(); nil != err {
+		t.Fatalf("normalize failed: %s", err)
+	}
+	if "Notion" != filepath.Base(export.destDir) {
+		t.Errorf("unexpected export name [%s]", filepath.Base(export.destDir))
+	}
+
+	// 页面去掉 ID 后缀，同名页面追加序号，页面之间的链接改为相对路径，数据库转换为同名文档
+	wants := map[string]string{
+		"Home.md":           "[Child](<Home/Child.md>) [URL](<Home/Child.md>) [Web](https://b3log.org)\n",
+		"Home/Child.md":     "[Back](<../Home.md>)\n",
+		"Home/Child (2).md": "",
+		"Home/Tasks.md":     "",
+		"Home/image.png":    "png",
+	}
+	for relPath, want := range wants {
+		data, err := os.ReadFile(filepath.Join(export.destDir, filepath.FromSlash(relPath)))
+		if nil != err {
+			t.Errorf("read [%s] failed: %s", relPath, err)
+			continue
+		}
+		if want != string(data) {
+			t.Errorf("content of [%s] expected [%q], got [%q]", relPath, want, string(data))
+		}
+	}
+	if "Child" != export.titles["Home/Child (2).md"] {
+		t.Errorf("title of duplicated page expected [Child], got [%s]", export.titles["Home/Child (2).md"])
+	}
+
+	if 1 != len(export.databases) {
+		t.Fatalf("expected 1 database, got %d", len(export.databases))
+	}
+	if db := export.databases[0]; "Tasks" != db.title || "Home/Tasks.md" != db.mdPath || !db.all {
+		t.Errorf("unexpected database [%+v]", db)
+	}
+}
+
+func writeTestZip(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if nil != err {
+			t.Fatalf("create zip entry [%s] failed: %s", name, err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); nil != err {
+		t.Fatalf("close zip failed: %s", err)
+	}
+	return buf.Bytes()
+}
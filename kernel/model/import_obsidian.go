@@ -0,0 +1,357 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute/ast"
+	"github.com/88250/lute/parse"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+	"github.com/siyuan-note/siyuan/kernel/util"
+	"gopkg.in/yaml.v3"
+)
+
+// ImportObsidianVault 导入 Obsidian 仓库。
+//
+// 在导入 Markdown 文件夹的基础上额外处理 Obsidian 的语法：
+//   - YAML Front Matter 转换为文档属性，aliases 转换为文档别名
+//   - `> [!type] 标题` 转换为提示块
+//   - `^id` 块标识转换为块引用和嵌入块的目标
+//   - `![[文档]]`、`![[文档#标题]]`、`![[文档#^id]]` 转换为嵌入块，`![[附件]]` 转换为资源文件链接
+func ImportObsidianVault(boxID, localPath, toPath string) (err error) {
+	_, err = importFromLocalPath(boxID, localPath, toPath, importFlavorObsidian)
+	return
+}
+
+var (
+	obsidianBlockAnchorRegexp = regexp.MustCompile(`(?:^|\s)\^([A-Za-z0-9-]+)\s*$`)
+	obsidianCalloutRegexp     = regexp.MustCompile(`^\[!([A-Za-z0-9_-]+)\][+-]?\s*`)
+	obsidianEmbedRegexp       = regexp.MustCompile(`!\[\[([^\[\]]+)\]\]`)
+)
+
+// obsidianCalloutTypes 将 Obsidian 的提示类型映射为提示块类型。
+var obsidianCalloutTypes = map[string]string{
+	"note": "NOTE", "abstract": "NOTE", "summary": "NOTE", "tldr": "NOTE", "info": "NOTE", "todo": "NOTE",
+	"question": "NOTE", "help": "NOTE", "faq": "NOTE", "example": "NOTE", "quote": "NOTE", "cite": "NOTE",
+	"tip": "TIP", "hint": "TIP", "success": "TIP", "check": "TIP", "done": "TIP",
+	"important": "IMPORTANT",
+	"warning":   "WARNING", "attention": "WARNING",
+	"caution": "CAUTION", "danger": "CAUTION", "error": "CAUTION", "failure": "CAUTION", "fail": "CAUTION", "missing": "CAUTION", "bug": "CAUTION",
+}
+
+var obsidianCalloutIcons = map[string]string{
+	"NOTE": "✏️", "TIP": "💡", "IMPORTANT": "❗", "WARNING": "⚠️", "CAUTION": "🚨",
+}
+
+// convertObsidianSyntax 转换导入文档中的 Obsidian 语法，需要在 initSearchLinks 之后、convertWikiLinksAndTags 之前调用。
+func convertObsidianSyntax(assetsDone map[string]string) {
+	for _, tree := range importTrees {
+		tree.MergeText()
+		convertObsidianFrontMatter(tree)
+		convertObsidianCallouts(tree)
+		for anchor, id := range convertObsidianBlockAnchors(tree) {
+			searchLinks[tree.HPath+"#^"+anchor] = id
+		}
+	}
+
+	for _, tree := range importTrees {
+		convertObsidianEmbeds(tree, assetsDone)
+	}
+}
+
+// convertObsidianFrontMatter 将 YAML Front Matter 转换为文档属性，并移除 normalizeTree 中生成的 YAML 代码块。
+func convertObsidianFrontMatter(tree *parse.Tree) {
+	codeBlock := tree.Root.FirstChild
+	if nil == codeBlock || ast.NodeCodeBlock != codeBlock.Type {
+		return
+	}
+	info := codeBlock.ChildByType(ast.NodeCodeBlockFenceInfoMarker)
+	code := codeBlock.ChildByType(ast.NodeCodeBlockCode)
+	if nil == info || "yaml" != string(info.CodeBlockInfo) || nil == code {
+		return
+	}
+	content := code.TokensStr()
+	if !strings.HasPrefix(content, "---\n") || !strings.HasSuffix(content, "\n---") {
+		return
+	}
+
+	attrs := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(content[len("---\n"):len(content)-len("\n---")]), &attrs); err != nil {
+		logging.LogWarnf("parse obsidian front matter of [%s] failed: %s", tree.HPath, err)
+		return
+	}
+
+	for k, v := range attrs {
+		switch k {
+		case "title", "date", "lastmod", "tags":
+			// 已经在 normalizeTree 中处理
+		case "aliases", "alias":
+			if aliases := obsidianFrontMatterValues(v); 0 < len(aliases) {
+				tree.Root.SetIALAttr("alias", strings.Join(aliases, ","))
+			}
+			tree.Root.RemoveIALAttr("custom-" + k)
+		default:
+			if _, isList := v.([]interface{}); !isList || "" == tree.Root.IALAttr("custom-"+k) {
+				continue
+			}
+			// normalizeTree 中列表值会被格式化为 `[a b]`，这里改为逗号分隔
+			tree.Root.SetIALAttr("custom-"+k, strings.Join(obsidianFrontMatterValues(v), ","))
+		}
+	}
+
+	if next := codeBlock.Next; nil != next && ast.NodeKramdownBlockIAL == next.Type {
+		next.Unlink()
+	}
+	codeBlock.Unlink()
+	if nil == tree.Root.FirstChild || ast.NodeKramdownBlockIAL == tree.Root.FirstChild.Type {
+		tree.Root.PrependChild(treenode.NewParagraph(""))
+	}
+}
+
+func obsidianFrontMatterValues(v interface{}) (ret []string) {
+	switch val := v.(type) {
+	case []interface{}:
+		for _, item := range val {
+			if s := strings.TrimSpace(fmt.Sprint(item)); "" != s {
+				ret = append(ret, s)
+			}
+		}
+	case nil:
+	default:
+		for _, s := range strings.Split(fmt.Sprint(val), ",") {
+			if s = strings.TrimSpace(s); "" != s {
+				ret = append(ret, s)
+			}
+		}
+	}
+	return
+}
+
+// convertObsidianCallouts 将第一行为 `[!type] 标题` 的引述块转换为提示块。
+func convertObsidianCallouts(tree *parse.Tree) {
+	var blockquotes []*ast.Node
+	ast.Walk(tree.Root, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if entering && ast.NodeBlockquote == n.Type {
+			blockquotes = append(blockquotes, n)
+		}
+		return ast.WalkContinue
+	})
+
+	for _, bq := range blockquotes {
+		p := bq.FirstChild
+		for nil != p && ast.NodeBlockquoteMarker == p.Type {
+			p = p.Next
+		}
+		if nil == p || ast.NodeParagraph != p.Type || nil == p.FirstChild || ast.NodeText != p.FirstChild.Type {
+			continue
+		}
+
+		first := p.FirstChild
+		m := obsidianCalloutRegexp.FindStringSubmatch(first.TokensStr())
+		if nil == m {
+			continue
+		}
+
+		// 第一行剩余的内容作为标题
+		first.Tokens = first.Tokens[len(m[0]):]
+		var title strings.Builder
+		var titleNodes []*ast.Node
+		for c := first; nil != c; c = c.Next {
+			if ast.NodeSoftBreak == c.Type || ast.NodeHardBreak == c.Type {
+				titleNodes = append(titleNodes, c)
+				break
+			}
+			title.WriteString(c.Text())
+			titleNodes = append(titleNodes, c)
+		}
+		for _, c := range titleNodes {
+			c.Unlink()
+		}
+		if nil == p.FirstChild {
+			if next := p.Next; nil != next && ast.NodeKramdownBlockIAL == next.Type {
+				next.Unlink()
+			}
+			p.Unlink()
+		}
+
+		calloutType := obsidianCalloutTypes[strings.ToLower(m[1])]
+		calloutTitle := strings.TrimSpace(title.String())
+		if "" == calloutType {
+			calloutType = strings.ToUpper(m[1])
+		} else if "" == calloutTitle && !strings.EqualFold(calloutType, m[1]) {
+			// 类型映射后保留原来的类型名作为标题
+			calloutTitle = strings.ToUpper(m[1][:1]) + strings.ToLower(m[1][1:])
+		}
+
+		callout := &ast.Node{Type: ast.NodeCallout, ID: bq.ID, KramdownIAL: bq.KramdownIAL,
+			CalloutType: calloutType, CalloutTitle: calloutTitle, CalloutIcon: obsidianCalloutIcons[calloutType]}
+		var children []*ast.Node
+		for c := bq.FirstChild; nil != c; c = c.Next {
+			if ast.NodeBlockquoteMarker != c.Type {
+				children = append(children, c)
+			}
+		}
+		for _, c := range children {
+			callout.AppendChild(c)
+		}
+		if nil == callout.FirstChild {
+			callout.AppendChild(treenode.NewParagraph(""))
+		}
+		bq.InsertBefore(callout)
+		bq.Unlink()
+	}
+}
+
+// convertObsidianBlockAnchors 移除块末尾的 `^id` 块标识，返回块标识到块 ID 的映射。
+//
+// 单独成段的块标识指向前一个块（比如表格和列表），列表项中段落的块标识指向列表项。
+func convertObsidianBlockAnchors(tree *parse.Tree) (ret map[string]string) {
+	ret = map[string]string{}
+	var unlinks []*ast.Node
+	ast.Walk(tree.Root, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if !entering || (ast.NodeParagraph != n.Type && ast.NodeHeading != n.Type) {
+			return ast.WalkContinue
+		}
+
+		last := n.LastChild
+		if nil == last || ast.NodeText != last.Type {
+			return ast.WalkContinue
+		}
+		m := obsidianBlockAnchorRegexp.FindStringSubmatchIndex(last.TokensStr())
+		if nil == m {
+			return ast.WalkContinue
+		}
+		anchor := last.TokensStr()[m[2]:m[3]]
+		last.Tokens = last.Tokens[:m[0]]
+
+		target := n
+		if nil == n.FirstChild.Next && 1 > len(strings.TrimSpace(last.TokensStr())) {
+			// 单独成段的块标识
+			if prev := obsidianPreviousBlock(n); nil != prev {
+				target = prev
+				unlinks = append(unlinks, n)
+			}
+		} else if ast.NodeListItem == n.Parent.Type && (nil == n.Previous || ast.NodeTaskListItemMarker == n.Previous.Type) {
+			target = n.Parent
+		}
+		ret[anchor] = target.ID
+		return ast.WalkSkipChildren
+	})
+
+	for _, n := range unlinks {
+		if next := n.Next; nil != next && ast.NodeKramdownBlockIAL == next.Type {
+			next.Unlink()
+		}
+		n.Unlink()
+	}
+	return
+}
+
+func obsidianPreviousBlock(n *ast.Node) *ast.Node {
+	for prev := n.Previous; nil != prev; prev = prev.Previous {
+		if ast.NodeKramdownBlockIAL != prev.Type && "" != prev.ID {
+			return prev
+		}
+	}
+	return nil
+}
+
+// convertObsidianEmbeds 转换 `![[...]]`：单独成段的文档、标题或块嵌入转换为嵌入块，附件嵌入转换为资源文件链接，其余的作为块引用处理。
+func convertObsidianEmbeds(tree *parse.Tree, assetsDone map[string]string) {
+	luteEngine := NewLute()
+	var paragraphs []*ast.Node
+	ast.Walk(tree.Root, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if !entering || ast.NodeText != n.Type || !strings.Contains(n.TokensStr(), "![[") {
+			return ast.WalkContinue
+		}
+
+		text := obsidianEmbedRegexp.ReplaceAllStringFunc(n.TokensStr(), func(embed string) string {
+			target := embed[3 : len(embed)-2]
+			name, _, _ := strings.Cut(target, "|")
+			name, _, _ = strings.Cut(name, "#")
+			if ext := strings.ToLower(path.Ext(name)); "" != ext && ".md" != ext {
+				return obsidianAssetLink(name, assetsDone, embed)
+			}
+			return embed
+		})
+		n.Tokens = []byte(text)
+
+		if p := n.Parent; ast.NodeParagraph == p.Type && p.FirstChild == n && nil == n.Next &&
+			obsidianEmbedRegexp.MatchString(text) && "" == strings.TrimSpace(obsidianEmbedRegexp.ReplaceAllString(text, "")) &&
+			1 == len(obsidianEmbedRegexp.FindAllString(text, -1)) {
+			paragraphs = append(paragraphs, p)
+			return ast.WalkContinue
+		}
+
+		// 行内的文档嵌入按块引用处理
+		n.Tokens = []byte(obsidianEmbedRegexp.ReplaceAllString(text, "[[$1]]"))
+		return ast.WalkContinue
+	})
+
+	for _, p := range paragraphs {
+		text := strings.TrimSpace(p.FirstChild.TokensStr())
+		target := text[3 : len(text)-2]
+		target, _, _ = strings.Cut(target, "|")
+		target = strings.TrimSuffix(strings.TrimSpace(target), ".md")
+		link := path.Join(path.Dir(tree.HPath), target)
+		if !strings.Contains(link, "#") {
+			link += "#"
+		}
+
+		id := searchLinkID(link)
+		if "" == id {
+			p.FirstChild.Tokens = []byte(text[1:])
+			continue
+		}
+
+		embed := newImportEmbedNode(luteEngine, id)
+		if nil == embed {
+			p.FirstChild.Tokens = []byte(text[1:])
+			continue
+		}
+
+		embed.ID = p.ID
+		embed.KramdownIAL = p.KramdownIAL
+		p.InsertBefore(embed)
+		p.Unlink()
+	}
+}
+
+// obsidianAssetLink 按文件名查找已经导入的附件，返回指向资源文件的 Markdown 链接。
+func obsidianAssetLink(name string, assetsDone map[string]string, embed string) string {
+	name = strings.TrimSpace(name)
+	for srcPath, assetName := range assetsDone {
+		srcPath = filepath.ToSlash(srcPath)
+		if path.Base(srcPath) != path.Base(name) || !strings.HasSuffix(srcPath, "/"+strings.TrimPrefix(name, "/")) {
+			continue
+		}
+
+		dest := "assets/" + assetName
+		if gulu.Str.Contains(strings.ToLower(path.Ext(name)), util.SiYuanAssetsImage) {
+			return "![](" + dest + ")"
+		}
+		return "[" + path.Base(name) + "](" + dest + ")"
+	}
+	return embed
+}
@@ -0,0 +1,122 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/88250/lute/ast"
+	"github.com/siyuan-note/siyuan/kernel/conf"
+)
+
+func TestObsidianSyntax(t *testing.T) {
+	if nil == Conf {
+		Conf = NewAppConf()
+		Conf.Editor = conf.NewEditor()
+		Conf.Export = conf.NewExport()
+		defer func() { Conf = nil }()
+	}
+	const otherID = "20240101000000-abcdefg"
+	searchLinks = map[string]string{"/Vault/Other#": otherID}
+	defer func() { searchLinks = map[string]string{} }()
+
+	md := "---\naliases: [Foo, Bar]\nsources:\n  - a\n  - b\n---\n\n" +
+		"> [!tip]\n> Body\n\n" +
+		"Paragraph ^para\n\n" +
+		"- Item ^item\n\n" +
+		"![[Other]]\n\n" +
+		"See ![[Other#^para]] and ![[attachments/pic.png]]\n"
+	tree, _, _, _ := parseStdMd([]byte(md))
+	tree.HPath = "/Vault/Note"
+
+	// 按导入时的顺序转换
+	tree.MergeText()
+	convertObsidianFrontMatter(tree)
+	convertObsidianCallouts(tree)
+	anchors := convertObsidianBlockAnchors(tree)
+	var embedParagraphID string
+	ast.Walk(tree.Root, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if entering && ast.NodeParagraph == n.Type && "![[Other]]" == strings.TrimSpace(n.Text()) {
+			embedParagraphID = n.ID
+		}
+		return ast.WalkContinue
+	})
+	convertObsidianEmbeds(tree, map[string]string{"/vault/attachments/pic.png": "pic-20240101000000-abcdefg.png"})
+
+	// Front Matter 转换为文档属性，YAML 代码块被移除
+	if "Foo,Bar" != tree.Root.IALAttr("alias") || "" != tree.Root.IALAttr("custom-aliases") {
+		t.Errorf("unexpected alias [%s], custom-aliases [%s]", tree.Root.IALAttr("alias"), tree.Root.IALAttr("custom-aliases"))
+	}
+	if "a,b" != tree.Root.IALAttr("custom-sources") {
+		t.Errorf("custom-sources expected [a,b], got [%s]", tree.Root.IALAttr("custom-sources"))
+	}
+
+	var callout, para, item, embed *ast.Node
+	var texts []string
+	ast.Walk(tree.Root, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if !entering {
+			return ast.WalkContinue
+		}
+		switch n.Type {
+		case ast.NodeCodeBlock:
+			t.Errorf("front matter code block is not removed")
+		case ast.NodeCallout:
+			callout = n
+		case ast.NodeListItem:
+			item = n
+		case ast.NodeBlockQueryEmbed:
+			embed = n
+		case ast.NodeParagraph:
+			if strings.HasPrefix(n.Text(), "Paragraph") {
+				para = n
+			}
+		case ast.NodeText:
+			texts = append(texts, n.TokensStr())
+		}
+		return ast.WalkContinue
+	})
+
+	// 引述块转换为提示块
+	if nil == callout || "TIP" != callout.CalloutType || !strings.Contains(callout.Text(), "Body") {
+		t.Errorf("blockquote is not converted to a tip callout")
+	}
+
+	// 块标识从内容中移除，段落的块标识指向段落，列表项中的块标识指向列表项
+	if nil == para || anchors["para"] != para.ID {
+		t.Errorf("anchor [para] expected to point to the paragraph, got [%s]", anchors["para"])
+	}
+	if nil == item || anchors["item"] != item.ID {
+		t.Errorf("anchor [item] expected to point to the list item, got [%s]", anchors["item"])
+	}
+	if nil != para && "Paragraph" != strings.TrimSpace(para.Text()) {
+		t.Errorf("block anchor is not removed from [%s]", para.Text())
+	}
+	if nil != item && "Item" != strings.TrimSpace(item.Text()) {
+		t.Errorf("block anchor is not removed from [%s]", item.Text())
+	}
+
+	// 单独成段的文档嵌入转换为嵌入块，行内的嵌入转换为块引用，附件嵌入转换为资源文件链接
+	if nil == embed || "" == embedParagraphID || embedParagraphID != embed.ID {
+		t.Errorf("document embed is not converted to an embed block")
+	} else if !strings.Contains(string(embed.ChildByType(ast.NodeBlockQueryEmbedScript).Tokens), otherID) {
+		t.Errorf("embed block does not query [%s]", otherID)
+	}
+	if text := strings.Join(texts, ""); !strings.Contains(text, "See [[Other#^para]] and ![](assets/pic-20240101000000-abcdefg.png)") {
+		t.Errorf("inline embeds are not converted in [%s]", text)
+	}
+}
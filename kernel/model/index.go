@@ -324,11 +324,14 @@ func autoIndexEmbedBlock() {
 			continue
 		}
 
-		if !strings.Contains(strings.ToLower(stmt), "select") {
-			continue
-		}
+		queryResultBlocks, isCriterion := selectCriterionEmbedBlocks(stmt, nil, 102400)
+		if !isCriterion {
+			if !strings.Contains(strings.ToLower(stmt), "select") {
+				continue
+			}
 
-		queryResultBlocks := sql.SelectBlocksRawStmtNoParse(stmt, 102400)
+			queryResultBlocks = sql.SelectBlocksReadOnlyRawStmtNoParse(stmt, 102400)
+		}
 		for _, block := range queryResultBlocks {
 			embedBlock.Content += block.Content
 		}
@@ -442,5 +445,8 @@ func subscribeSQLEvents() {
 	eventbus.Subscribe(eventbus.EvtSQLIndexFlushed, func() {
 		Conf.DataIndexState = 0
 		Conf.Save()
+		clearCriterionResultCache()
+		// 索引变化后智能文件夹中的块数也可能变化
+		util.BroadcastByType("filetree", "reloadSmartFolders", 0, "", nil)
 	})
 }
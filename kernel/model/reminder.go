@@ -0,0 +1,323 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute/editor"
+	"github.com/araddon/dateparse"
+	"github.com/siyuan-note/filelock"
+	"github.com/siyuan-note/httpclient"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+	"github.com/siyuan-note/siyuan/kernel/util"
+	"github.com/teambition/rrule-go"
+)
+
+const (
+	reminderRepeatAttrName = "custom-reminder-repeat" // 提醒重复规则，使用 RRULE 格式，比如 FREQ=WEEKLY;BYDAY=MO
+
+	reminderTimeLayout = "20060102150405"
+
+	// reminderMissedDuration 内错过的提醒会在内核启动后补发，更早的提醒视为已过期
+	reminderMissedDuration = 24 * time.Hour
+)
+
+// SnoozeBlockReminder 稍后提醒，在 minutes 分钟后再次提醒，不影响提醒的重复规则。
+func SnoozeBlockReminder(id string, minutes int) (err error) {
+	if 1 > minutes {
+		return errors.New("invalid snooze minutes")
+	}
+
+	attrs := sql.GetBlockAttrs(id)
+	if "" == attrs[reminderAttrName] {
+		return errors.New(Conf.Language(286))
+	}
+
+	reminderStateLock.Lock()
+	defer reminderStateLock.Unlock()
+
+	states := getReminderStates()
+	state := states[id]
+	if nil == state {
+		state = &reminderState{}
+		states[id] = state
+	}
+	state.Snooze = time.Now().Add(time.Duration(minutes) * time.Minute).Format(reminderTimeLayout)
+	err = setReminderStates(states)
+	return
+}
+
+// ReminderJob 检查到期的块提醒，通过 websocket 推送通知，并按需调用 Webhook。
+func ReminderJob() {
+	if !Conf.Reminder.Local || util.ReadOnly {
+		return
+	}
+
+	rows, err := sql.QueryNoLimit("SELECT block_id FROM attributes WHERE name = '" + reminderAttrName + "'")
+	if err != nil {
+		logging.LogErrorf("query reminders failed: %s", err)
+		return
+	}
+
+	var ids []string
+	for _, row := range rows {
+		ids = append(ids, row["block_id"].(string))
+	}
+	if 1 > len(ids) {
+		return
+	}
+
+	reminderStateLock.Lock()
+	defer reminderStateLock.Unlock()
+
+	reminderIDs := map[string]bool{}
+	for _, id := range ids {
+		reminderIDs[id] = true
+	}
+	states := getReminderStates()
+	changed := false
+	// 移除已经取消提醒的块的状态
+	for id := range states {
+		if !reminderIDs[id] {
+			delete(states, id)
+			changed = true
+		}
+	}
+
+	now := time.Now()
+	attrs := sql.BatchGetBlockAttrs(ids)
+	for _, id := range ids {
+		blockAttrs := attrs[id]
+		if nil == blockAttrs {
+			continue
+		}
+
+		state := states[id]
+		if nil == state {
+			state = &reminderState{}
+		}
+		due, snoozed, first := getReminderDueTime(id, blockAttrs, state, now)
+		if due.IsZero() {
+			continue
+		}
+
+		// 订阅用户的首次提醒已经由云端通知，本地只负责重复提醒和稍后提醒，避免重复通知
+		if !first || !IsSubscriber() {
+			block := sql.GetBlock(id)
+			if nil == block {
+				continue
+			}
+
+			fireReminder(block, due, blockAttrs[reminderRepeatAttrName])
+		}
+
+		// 提醒状态保存在内核的存储文件中，不修改块属性，避免每次提醒都产生文档变更和同步
+		state.Fired = due.Format(reminderTimeLayout)
+		if snoozed {
+			state.Snooze = ""
+		}
+		states[id] = state
+		changed = true
+	}
+
+	if changed {
+		setReminderStates(states)
+	}
+}
+
+// getReminderDueTime 返回需要提醒的时间，没有到期的提醒时返回零值。first 表示是否为设置的提醒时间（而不是重复或者稍后提醒）。
+func getReminderDueTime(id string, attrs map[string]string, state *reminderState, now time.Time) (ret time.Time, snoozed, first bool) {
+	if snooze := state.Snooze; "" != snooze {
+		snoozeTime, err := time.ParseInLocation(reminderTimeLayout, snooze, time.Local)
+		if err != nil {
+			logging.LogWarnf("parse reminder snooze [%s] of block [%s] failed: %s", snooze, id, err)
+			return
+		}
+		if !snoozeTime.After(now) {
+			ret, snoozed = snoozeTime, true
+		}
+		return
+	}
+
+	timed, err := dateparse.ParseIn(attrs[reminderAttrName], time.Local)
+	if err != nil {
+		logging.LogWarnf("parse reminder [%s] of block [%s] failed: %s", attrs[reminderAttrName], id, err)
+		return
+	}
+
+	// 找到最近一次不晚于当前时间的提醒
+	occurrence := timed
+	if repeat := attrs[reminderRepeatAttrName]; "" != repeat {
+		option, parseErr := rrule.StrToROptionInLocation(repeat, time.Local)
+		if parseErr != nil {
+			logging.LogWarnf("parse reminder repeat [%s] of block [%s] failed: %s", repeat, id, parseErr)
+			return
+		}
+		option.Dtstart = timed
+		rule, ruleErr := rrule.NewRRule(*option)
+		if ruleErr != nil {
+			logging.LogWarnf("build reminder repeat [%s] of block [%s] failed: %s", repeat, id, ruleErr)
+			return
+		}
+		occurrence = rule.Before(now, true)
+	}
+	if occurrence.IsZero() || occurrence.After(now) || reminderMissedDuration < now.Sub(occurrence) {
+		return
+	}
+
+	if fired := state.Fired; "" != fired {
+		firedTime, parseErr := time.ParseInLocation(reminderTimeLayout, fired, time.Local)
+		if nil == parseErr && !occurrence.After(firedTime) {
+			return
+		}
+	}
+	ret = occurrence
+	first = occurrence.Equal(timed)
+	return
+}
+
+// reminderState 记录块提醒的提醒状态，时间格式为 yyyyMMddHHmmss。
+type reminderState struct {
+	Fired  string `json:"fired,omitempty"`  // 最近一次已经提醒的时间
+	Snooze string `json:"snooze,omitempty"` // 稍后提醒时间
+}
+
+var (
+	reminderStates    map[string]*reminderState // 块 ID -> 提醒状态
+	reminderStateLock = sync.Mutex{}
+)
+
+// removeReminderState 在重新设置或者取消提醒后清空稍后提醒和已提醒时间。
+func removeReminderState(id string) {
+	reminderStateLock.Lock()
+	defer reminderStateLock.Unlock()
+
+	states := getReminderStates()
+	if _, ok := states[id]; !ok {
+		return
+	}
+	delete(states, id)
+	setReminderStates(states)
+}
+
+func getReminderStates() map[string]*reminderState {
+	if nil != reminderStates {
+		return reminderStates
+	}
+
+	reminderStates = map[string]*reminderState{}
+	dataPath := filepath.Join(util.DataDir, "storage/reminders.json")
+	if !filelock.IsExist(dataPath) {
+		return reminderStates
+	}
+
+	data, err := filelock.ReadFile(dataPath)
+	if err != nil {
+		logging.LogErrorf("read storage [reminders] failed: %s", err)
+		return reminderStates
+	}
+
+	if err = gulu.JSON.UnmarshalJSON(data, &reminderStates); err != nil {
+		logging.LogErrorf("unmarshal storage [reminders] failed: %s", err)
+		reminderStates = map[string]*reminderState{}
+	}
+	return reminderStates
+}
+
+func setReminderStates(states map[string]*reminderState) (err error) {
+	dirPath := filepath.Join(util.DataDir, "storage")
+	if err = os.MkdirAll(dirPath, 0755); err != nil {
+		logging.LogErrorf("create storage [reminders] dir failed: %s", err)
+		return
+	}
+
+	data, err := gulu.JSON.MarshalIndentJSON(states, "", "  ")
+	if err != nil {
+		logging.LogErrorf("marshal storage [reminders] failed: %s", err)
+		return
+	}
+
+	if err = filelock.WriteFile(filepath.Join(dirPath, "reminders.json"), data); err != nil {
+		logging.LogErrorf("write storage [reminders] failed: %s", err)
+		return
+	}
+	return
+}
+
+func fireReminder(block *sql.Block, due time.Time, repeat string) {
+	content := block.Content
+	if "" == content {
+		content = block.HPath
+	}
+	content = strings.ReplaceAll(gulu.Str.SubStr(content, 128), editor.Zwsp, "")
+
+	util.PushMsg(fmt.Sprintf(Conf.Language(284), content), 0)
+	data := map[string]interface{}{
+		"id":      block.ID,
+		"rootID":  block.RootID,
+		"box":     block.Box,
+		"hPath":   block.HPath,
+		"content": content,
+		"time":    due.UnixMilli(),
+		"repeat":  repeat,
+	}
+	util.BroadcastByType("main", "reminder", 0, "", data)
+
+	webhook := strings.TrimSpace(Conf.Reminder.Webhook)
+	if "" == webhook {
+		return
+	}
+
+	go func() {
+		resp, err := httpclient.NewBrowserRequest().SetBody(data).Post(webhook)
+		if err != nil {
+			logging.LogErrorf("post reminder [%s] to webhook [%s] failed: %s", block.ID, webhook, err)
+			return
+		}
+		if 200 > resp.StatusCode || 300 <= resp.StatusCode {
+			logging.LogErrorf("post reminder [%s] to webhook [%s] failed: %d", block.ID, webhook, resp.StatusCode)
+		}
+	}()
+}
+
+// normalizeReminderRepeat 将 daily、weekly、monthly、yearly 转换为 RRULE，并校验 RRULE 是否有效。
+func normalizeReminderRepeat(repeat string) (ret string, err error) {
+	ret = strings.TrimSpace(repeat)
+	switch strings.ToLower(ret) {
+	case "", "none":
+		ret = ""
+		return
+	case "daily", "weekly", "monthly", "yearly":
+		ret = "FREQ=" + strings.ToUpper(ret)
+	}
+
+	ret = strings.TrimPrefix(ret, "RRULE:")
+	if _, err = rrule.StrToROption(ret); err != nil {
+		err = errors.New(fmt.Sprintf(Conf.Language(285), repeat))
+		ret = ""
+	}
+	return
+}
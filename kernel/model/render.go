@@ -268,7 +268,7 @@ func resolveEmbedR(n *ast.Node, blockEmbedMode int, luteEngine *lute.Lute, resol
 			stmt := n.ChildByType(ast.NodeBlockQueryEmbedScript).TokensStr()
 			stmt = html.UnescapeString(stmt)
 			stmt = strings.ReplaceAll(stmt, editor.IALValEscNewLine, "\n")
-			sqlBlocks := sql.SelectBlocksRawStmt(stmt, 1, Conf.Search.Limit)
+			sqlBlocks := sql.SelectBlocksReadOnlyRawStmt(stmt, 1, Conf.Search.Limit)
 			for _, sqlBlock := range sqlBlocks {
 				if "query_embed" == sqlBlock.Type {
 					continue
@@ -469,7 +469,7 @@ func renderBlockMarkdownR(id string, rendered *[]string) (ret []*ast.Node) {
 				stmt := n.ChildByType(ast.NodeBlockQueryEmbedScript).TokensStr()
 				stmt = html.UnescapeString(stmt)
 				stmt = strings.ReplaceAll(stmt, editor.IALValEscNewLine, "\n")
-				sqlBlocks := sql.SelectBlocksRawStmt(stmt, 1, Conf.Search.Limit)
+				sqlBlocks := sql.SelectBlocksReadOnlyRawStmt(stmt, 1, Conf.Search.Limit)
 				for _, sqlBlock := range sqlBlocks {
 					subNodes := renderBlockMarkdownR(sqlBlock.ID, rendered)
 					for _, subNode := range subNodes {
@@ -45,6 +45,7 @@ import (
 	"github.com/88250/lute/parse"
 	"github.com/88250/lute/render"
 	"github.com/emirpasic/gods/sets/hashset"
+	gcache "github.com/patrickmn/go-cache"
 	"github.com/siyuan-note/dataparser"
 	"github.com/siyuan-note/dejavu"
 	"github.com/siyuan-note/dejavu/cloud"
@@ -54,6 +55,7 @@ import (
 	"github.com/siyuan-note/httpclient"
 	"github.com/siyuan-note/logging"
 	"github.com/siyuan-note/siyuan/kernel/conf"
+	"github.com/siyuan-note/siyuan/kernel/sql"
 	"github.com/siyuan-note/siyuan/kernel/task"
 	"github.com/siyuan-note/siyuan/kernel/treenode"
 	"github.com/siyuan-note/siyuan/kernel/util"
@@ -408,6 +410,97 @@ func DiffRepoSnapshots(left, right string) (ret *LeftRightDiff, err error) {
 	return
 }
 
+// SearchRepoSnapshotDocs 在快照中搜索文档，query 使用和历史搜索相同的语法，最多返回 Conf.Search.Limit 个文档。
+func SearchRepoSnapshotDocs(id, query string, filter *HistorySearchFilter) (ret []*DiffFile, err error) {
+	ret = []*DiffFile{}
+	if 1 > len(Conf.Repo.Key) {
+		err = errors.New(Conf.Language(26))
+		return
+	}
+
+	if err = parseHistorySearchQuery(util.RemoveInvalid(query), filter); err != nil {
+		return
+	}
+
+	repo, err := newRepository()
+	if err != nil {
+		return
+	}
+
+	index, err := repo.GetIndex(id)
+	if err != nil {
+		return
+	}
+
+	files, err := repo.GetFiles(index)
+	if err != nil {
+		return
+	}
+
+	util.PushEndlessProgress(fmt.Sprintf(Conf.Language(298), 0, len(files)))
+	defer util.PushClearProgress()
+
+	luteEngine := NewLute()
+	for i, file := range files {
+		if 0 == i%64 {
+			util.PushEndlessProgress(fmt.Sprintf(Conf.Language(298), i, len(files)))
+		}
+
+		if !strings.HasSuffix(file.Path, ".sy") || !filter.matchTime(time.UnixMilli(file.Updated)) {
+			continue
+		}
+
+		doc := getSnapshotSearchDoc(repo, file, luteEngine)
+		if nil == doc || !filter.matchDoc(doc.title, doc.content, doc.attrs) {
+			continue
+		}
+
+		ret = append(ret, &DiffFile{
+			FileID:  file.ID,
+			Title:   doc.title,
+			Path:    file.Path,
+			HSize:   humanize.BytesCustomCeil(uint64(file.Size), 2),
+			Updated: file.Updated,
+		})
+		if Conf.Search.Limit <= len(ret) {
+			break
+		}
+	}
+	return
+}
+
+// snapshotSearchDoc 快照中文档的搜索索引。
+type snapshotSearchDoc struct {
+	title   string
+	content string
+	attrs   []*sql.HistoryAttr
+}
+
+// snapshotSearchDocCache 缓存快照中文档的搜索索引。快照中的文件不会变化，文件 ID 相同时内容也相同，所以按文件 ID 缓存，多个快照之间共用。
+var snapshotSearchDocCache = gcache.New(30*time.Minute, 5*time.Minute) // [fileID]*snapshotSearchDoc
+
+func getSnapshotSearchDoc(repo *dejavu.Repo, file *entity.File, luteEngine *lute.Lute) (ret *snapshotSearchDoc) {
+	if val, ok := snapshotSearchDocCache.Get(file.ID); ok {
+		return val.(*snapshotSearchDoc)
+	}
+
+	data, err := repo.OpenFile(file)
+	if err != nil {
+		logging.LogErrorf("open file [%s] failed: %s", file.ID, err)
+		return
+	}
+
+	_, tree, err := parseTreeInSnapshot(data, luteEngine)
+	if err != nil {
+		logging.LogErrorf("parse file [%s] failed: %s", file.ID, err)
+		return
+	}
+
+	ret = &snapshotSearchDoc{title: tree.Root.IALAttr("title"), content: tree.Root.Content(), attrs: historyDocAttrs(tree)}
+	snapshotSearchDocCache.SetDefault(file.ID, ret)
+	return
+}
+
 func parseTitleInSnapshot(fileID string, repo *dejavu.Repo, luteEngine *lute.Lute) (title string, err error) {
 	file, err := repo.GetFile(fileID)
 	if err != nil {
@@ -21,7 +21,8 @@ import "github.com/gin-gonic/gin"
 type Role uint
 
 const (
-	RoleContextKey = "role"
+	RoleContextKey      = "role"
+	NotebooksContextKey = "notebooks" // 当前请求允许访问的笔记本 ID 列表，不存在时不限制
 )
 
 const (
@@ -58,3 +59,26 @@ func GetGinContextRole(c *gin.Context) Role {
 func IsAdminRoleContext(c *gin.Context) bool {
 	return GetGinContextRole(c) == RoleAdministrator
 }
+
+// GetGinContextNotebooks 返回当前请求允许访问的笔记本 ID 列表，scoped 为 false 时不限制。
+func GetGinContextNotebooks(c *gin.Context) (ret []string, scoped bool) {
+	if notebooks, exists := c.Get(NotebooksContextKey); exists {
+		return notebooks.([]string), true
+	}
+	return
+}
+
+// IsNotebookAllowedContext 判断当前请求是否允许访问指定的笔记本。
+func IsNotebookAllowedContext(c *gin.Context, boxID string) bool {
+	notebooks, scoped := GetGinContextNotebooks(c)
+	if !scoped {
+		return true
+	}
+
+	for _, notebook := range notebooks {
+		if notebook == boxID {
+			return true
+		}
+	}
+	return false
+}
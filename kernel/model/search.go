@@ -239,12 +239,16 @@ func getEmbedBlock(embedBlockID string, includeIDs []string, headingMode int, br
 	return
 }
 
-func SearchEmbedBlock(embedBlockID, stmt string, excludeIDs []string, headingMode int, breadcrumb bool) (ret []*EmbedBlock) {
-	return searchEmbedBlock(embedBlockID, stmt, excludeIDs, headingMode, breadcrumb)
+// SearchEmbedBlock 查询嵌入块的内容，scopedBoxes 不为空时已保存搜索仅在这些笔记本中搜索。
+func SearchEmbedBlock(embedBlockID, stmt string, excludeIDs []string, headingMode int, breadcrumb bool, scopedBoxes []string) (ret []*EmbedBlock) {
+	return searchEmbedBlock(embedBlockID, stmt, excludeIDs, headingMode, breadcrumb, scopedBoxes)
 }
 
-func searchEmbedBlock(embedBlockID, stmt string, excludeIDs []string, headingMode int, breadcrumb bool) (ret []*EmbedBlock) {
-	sqlBlocks := sql.SelectBlocksRawStmtNoParse(stmt, Conf.Search.Limit)
+func searchEmbedBlock(embedBlockID, stmt string, excludeIDs []string, headingMode int, breadcrumb bool, scopedBoxes []string) (ret []*EmbedBlock) {
+	sqlBlocks, isCriterion := selectCriterionEmbedBlocks(stmt, scopedBoxes, Conf.Search.Limit)
+	if !isCriterion {
+		sqlBlocks = sql.SelectBlocksReadOnlyRawStmtNoParse(stmt, Conf.Search.Limit)
+	}
 	ret = buildEmbedBlock(embedBlockID, excludeIDs, headingMode, breadcrumb, sqlBlocks)
 	return
 }
@@ -479,13 +483,47 @@ func prependNotebookNameInHPath(blocks []*Block) {
 	}
 }
 
-func FindReplace(keyword, replacement string, replaceTypes map[string]bool, ids []string, paths, boxes []string, types map[string]bool, method, orderBy, groupBy int) (err error) {
-	// method：0：文本，1：查询语法，2：SQL，3：正则表达式
+// FindReplaceResult 描述查找替换的结果。
+type FindReplaceResult struct {
+	Blocks     int                `json:"blocks"`          // 内容有变化的块数
+	Docs       int                `json:"docs"`            // 内容有变化的文档数
+	Diffs      []*FindReplaceDiff `json:"diffs,omitempty"` // 各个块替换前后的内容，仅在预览或者请求时返回
+	HistoryDir string             `json:"historyDir"`      // 替换前的文档历史目录名，用于整体回滚，预览时为空
+}
+
+// FindReplaceDiff 描述一个块替换前后的 Markdown，文档块为替换前后的标题和标签。
+type FindReplaceDiff struct {
+	ID     string `json:"id"`
+	RootID string `json:"rootID"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// FindReplace 查找替换，ids 不为空时仅替换这些块。
+//
+// dryRun 为 true 时仅预览，返回各个块替换前后的内容，不修改文档。实际替换时仅返回数量，withDiffs 为 true 时才返回各个块替换前后的内容。
+func FindReplace(keyword, replacement string, replaceTypes map[string]bool, ids []string, paths, boxes []string, types map[string]bool, method, orderBy, groupBy int, dryRun, withDiffs bool) (ret *FindReplaceResult, err error) {
+	ret = &FindReplaceResult{}
+	withDiffs = dryRun || withDiffs
+	if withDiffs {
+		ret.Diffs = []*FindReplaceDiff{}
+	}
+	// method：0：文本，1：查询语法，2：SQL，3：正则表达式，4：语义，5：结构化查询
 	if 2 == method {
 		err = errors.New(Conf.Language(132))
 		return
 	}
 
+	if 4 == method {
+		err = errors.New(Conf.Language(289))
+		return
+	}
+
+	if 5 == method {
+		err = errors.New(Conf.Language(291))
+		return
+	}
+
 	if 1 == method {
 		// 将查询语法等价于关键字，因为 keyword 参数已经是结果关键字了
 		// Find and replace supports query syntax https://github.com/siyuan-note/siyuan/issues/14937
@@ -514,15 +552,19 @@ func FindReplace(keyword, replacement string, replaceTypes map[string]bool, ids
 	renameRootTitles := map[string]string{}
 	cachedTrees := map[string]*parse.Tree{}
 
-	historyDir, err := getHistoryDir(HistoryOpReplace, time.Now())
-	if err != nil {
-		logging.LogErrorf("get history dir failed: %s", err)
-		return
+	var historyDir string
+	if !dryRun {
+		// 所有受影响的文档都保存在同一个历史目录中，以便整体回滚
+		if historyDir, err = getHistoryDir(HistoryOpReplace, time.Now()); err != nil {
+			logging.LogErrorf("get history dir failed: %s", err)
+			return
+		}
+		ret.HistoryDir = filepath.Base(historyDir)
 	}
 
 	if 1 > len(ids) {
 		// `Replace All` is no longer affected by pagination https://github.com/siyuan-note/siyuan/issues/8265
-		blocks, _, _, _, _ := FullTextSearchBlock(keyword, boxes, paths, types, method, orderBy, groupBy, 1, math.MaxInt)
+		blocks, _, _, _, _, _ := FullTextSearchBlock(keyword, boxes, paths, types, method, orderBy, groupBy, 1, math.MaxInt, false)
 		for _, block := range blocks {
 			ids = append(ids, block.ID)
 		}
@@ -544,6 +586,11 @@ func FindReplace(keyword, replacement string, replaceTypes map[string]bool, ids
 			continue
 		}
 
+		cachedTrees[bt.RootID] = tree
+		if dryRun {
+			continue
+		}
+
 		historyPath := filepath.Join(historyDir, tree.Box, tree.Path)
 		if err = os.MkdirAll(filepath.Dir(historyPath), 0755); err != nil {
 			logging.LogErrorf("generate history failed: %s", err)
@@ -560,14 +607,16 @@ func FindReplace(keyword, replacement string, replaceTypes map[string]bool, ids
 			logging.LogErrorf("generate history failed: %s", err)
 			return
 		}
-
-		cachedTrees[bt.RootID] = tree
 	}
-	indexHistoryDir(filepath.Base(historyDir), util.NewLute())
+	if !dryRun {
+		indexHistoryDir(filepath.Base(historyDir), util.NewLute())
+	}
 
 	luteEngine := util.NewLute()
 	var reloadTreeIDs []string
 	updateNodes := map[string]*ast.Node{}
+	changedDocs := map[string]bool{}
+	reloadTag := false
 	for i, id := range ids {
 		bt := treenode.GetBlockTree(id)
 		if nil == bt {
@@ -585,6 +634,7 @@ func FindReplace(keyword, replacement string, replaceTypes map[string]bool, ids
 		}
 
 		reloadTreeIDs = append(reloadTreeIDs, tree.ID)
+		before := findReplaceNodeContent(node, luteEngine)
 		if ast.NodeDocument == node.Type {
 			if !replaceTypes["docTitle"] {
 				continue
@@ -605,7 +655,7 @@ func FindReplace(keyword, replacement string, replaceTypes map[string]bool, ids
 					tags = strings.ReplaceAll(tags, keyword, replacement)
 					tags = strings.ReplaceAll(tags, editor.Zwsp, "")
 					node.SetIALAttr("tags", tags)
-					ReloadTag()
+					reloadTag = true
 				}
 			} else if 3 == method {
 				if nil != r && r.MatchString(title) {
@@ -620,7 +670,7 @@ func FindReplace(keyword, replacement string, replaceTypes map[string]bool, ids
 					tags = r.ReplaceAllString(tags, replacement)
 					tags = strings.ReplaceAll(tags, editor.Zwsp, "")
 					node.SetIALAttr("tags", tags)
-					ReloadTag()
+					reloadTag = true
 				}
 			}
 		} else {
@@ -849,7 +899,7 @@ func FindReplace(keyword, replacement string, replaceTypes map[string]bool, ids
 							unlinks = append(unlinks, n)
 						}
 
-						ReloadTag()
+						reloadTag = true
 					} else if n.IsTextMarkType("u") {
 						if !replaceTypes["u"] {
 							return ast.WalkContinue
@@ -956,6 +1006,21 @@ func FindReplace(keyword, replacement string, replaceTypes map[string]bool, ids
 			}
 		}
 
+		after := findReplaceNodeContent(node, luteEngine)
+		if newTitle, ok := renameRootTitles[node.ID]; ok && ast.NodeDocument == node.Type {
+			after = strings.Replace(after, node.IALAttr("title"), newTitle, 1)
+		}
+		if before != after {
+			ret.Blocks++
+			changedDocs[tree.ID] = true
+			if withDiffs {
+				ret.Diffs = append(ret.Diffs, &FindReplaceDiff{ID: node.ID, RootID: tree.ID, Before: before, After: after})
+			}
+		}
+		if dryRun {
+			continue
+		}
+
 		if err = writeTreeUpsertQueue(tree); err != nil {
 			return
 		}
@@ -963,6 +1028,14 @@ func FindReplace(keyword, replacement string, replaceTypes map[string]bool, ids
 		util.PushEndlessProgress(fmt.Sprintf(Conf.Language(206), i+1, len(ids)))
 	}
 
+	ret.Docs = len(changedDocs)
+	if dryRun {
+		return
+	}
+
+	if reloadTag {
+		ReloadTag()
+	}
 	for i, renameRoot := range renameRoots {
 		newTitle := renameRootTitles[renameRoot.ID]
 		RenameDoc(renameRoot.Box, renameRoot.Path, newTitle)
@@ -984,6 +1057,18 @@ func FindReplace(keyword, replacement string, replaceTypes map[string]bool, ids
 	return
 }
 
+// findReplaceNodeContent 返回用于查找替换预览的块内容，文档块返回标题和标签。
+func findReplaceNodeContent(node *ast.Node, luteEngine *lute.Lute) string {
+	if ast.NodeDocument == node.Type {
+		ret := node.IALAttr("title")
+		if tags := node.IALAttr("tags"); "" != tags {
+			ret += "\n" + tags
+		}
+		return ret
+	}
+	return strings.TrimSpace(treenode.ExportNodeStdMd(node, luteEngine))
+}
+
 func replaceNodeTextMarkTextContent(n *ast.Node, method int, keyword, escapedKey string, replacement string, r *regexp.Regexp, typ string, luteEngine *lute.Lute) {
 	if 0 == method {
 		if strings.Contains(typ, "tag") {
@@ -1171,7 +1256,8 @@ func mergeSamePreNext(n *ast.Node) {
 // method：0：关键字，1：查询语法，2：SQL，3：正则表达式
 // orderBy: 0：按块类型（默认），1：按创建时间升序，2：按创建时间降序，3：按更新时间升序，4：按更新时间降序，5：按内容顺序（仅在按文档分组时），6：按相关度升序，7：按相关度降序
 // groupBy：0：不分组，1：按文档分组
-func FullTextSearchBlock(query string, boxes, paths []string, types map[string]bool, method, orderBy, groupBy, page, pageSize int) (ret []*Block, matchedBlockCount, matchedRootCount, pageCount int, docMode bool) {
+// withFacets：是否同时返回按笔记本、块类型、标签和文档分组的命中数
+func FullTextSearchBlock(query string, boxes, paths []string, types map[string]bool, method, orderBy, groupBy, page, pageSize int, withFacets bool) (ret []*Block, matchedBlockCount, matchedRootCount, pageCount int, docMode bool, facets *SearchFacets) {
 	ret = []*Block{}
 	if "" == query {
 		return
@@ -1191,6 +1277,7 @@ func FullTextSearchBlock(query string, boxes, paths []string, types map[string]b
 
 	beforeLen := 36
 	var blocks []*Block
+	var facetFrom string // 命中块的 FROM 和 WHERE 子句，用于统计分面
 	orderByClause := buildOrderBy(query, method, orderBy)
 	switch method {
 	case 1: // 查询语法
@@ -1199,33 +1286,64 @@ func FullTextSearchBlock(query string, boxes, paths []string, types map[string]b
 		pathFilter := buildPathsFilter(paths)
 		if ast.IsNodeIDPattern(query) {
 			blocks, matchedBlockCount, matchedRootCount = searchBySQL("SELECT * FROM `blocks` WHERE `id` = '"+query+"'", beforeLen, page, pageSize)
+			facetFrom = " FROM `blocks` WHERE `id` = '" + query + "'"
 		} else {
 			blocks, matchedBlockCount, matchedRootCount = fullTextSearchByFTS(query, boxFilter, pathFilter, typeFilter, ignoreFilter, orderByClause, beforeLen, page, pageSize)
+			facetFrom = ftsFromClause(query, boxFilter, pathFilter, typeFilter, ignoreFilter)
 		}
 	case 2: // SQL
 		blocks, matchedBlockCount, matchedRootCount = searchBySQL(query, beforeLen, page, pageSize)
+		// SQL 搜索只统计返回的块
+		facetFrom = idsFromClause(blocks)
 	case 3: // 正则表达式
 		typeFilter := buildTypeFilter(types)
 		boxFilter := buildBoxesFilter(boxes)
 		pathFilter := buildPathsFilter(paths)
 		blocks, matchedBlockCount, matchedRootCount = fullTextSearchByRegexp(query, boxFilter, pathFilter, typeFilter, ignoreFilter, orderByClause, beforeLen, page, pageSize)
+		facetFrom = regexpFromClause(query, boxFilter, pathFilter, typeFilter, ignoreFilter)
+	case 4: // 语义
+		typeFilter := buildTypeFilter(types)
+		boxFilter := buildBoxesFilter(boxes)
+		pathFilter := buildPathsFilter(paths)
+		var matchedIDs []string
+		blocks, matchedBlockCount, matchedRootCount, matchedIDs = semanticSearchBlock(query, boxFilter, pathFilter, typeFilter, ignoreFilter, beforeLen, page, pageSize)
+		if 0 < len(matchedIDs) {
+			facetFrom = " FROM `blocks` WHERE id IN ('" + strings.Join(matchedIDs, "','") + "')"
+		}
+	case 5: // 结构化查询
+		blocks, matchedBlockCount, matchedRootCount = structuredSearchBlock(query, boxes, paths, types, ignoreFilter, orderByClause, beforeLen, page, pageSize)
+		facetFrom = structuredSearchFromClause(query, boxes, paths, types, ignoreFilter)
 	default: // 关键字
 		typeFilter := buildTypeFilter(types)
 		boxFilter := buildBoxesFilter(boxes)
 		pathFilter := buildPathsFilter(paths)
 		if ast.IsNodeIDPattern(query) {
 			blocks, matchedBlockCount, matchedRootCount = searchBySQL("SELECT * FROM `blocks` WHERE `id` = '"+query+"'", beforeLen, page, pageSize)
+			facetFrom = " FROM `blocks` WHERE `id` = '" + query + "'"
 		} else {
-			if 2 > len(strings.Split(strings.TrimSpace(query), " ")) {
-				query = stringQuery(query)
+			if isShortNgramQuery(query) {
+				// n-gram 分词无法匹配少于 3 个字符的关键字，改为按正则表达式匹配字面值
+				exp := regexp.QuoteMeta(strings.TrimSpace(query))
+				if !Conf.Search.CaseSensitive {
+					exp = "(?i)" + exp
+				}
+				blocks, matchedBlockCount, matchedRootCount = fullTextSearchByRegexp(exp, boxFilter, pathFilter, typeFilter, ignoreFilter, orderByClause, beforeLen, page, pageSize)
+				facetFrom = regexpFromClause(exp, boxFilter, pathFilter, typeFilter, ignoreFilter)
+			} else if 2 > len(strings.Split(strings.TrimSpace(query), " ")) {
+				query = expandStringQuery(query)
 				blocks, matchedBlockCount, matchedRootCount = fullTextSearchByFTS(query, boxFilter, pathFilter, typeFilter, ignoreFilter, orderByClause, beforeLen, page, pageSize)
+				facetFrom = ftsFromClause(query, boxFilter, pathFilter, typeFilter, ignoreFilter)
 			} else {
 				docMode = true // 文档全文搜索模式 https://github.com/siyuan-note/siyuan/issues/10584
 				blocks, matchedBlockCount, matchedRootCount = fullTextSearchByLikeWithRoot(query, boxFilter, pathFilter, typeFilter, ignoreFilter, orderByClause, beforeLen, page, pageSize)
+				facetFrom = likeWithRootFromClause(query, boxFilter, pathFilter, typeFilter, ignoreFilter)
 			}
 		}
 	}
 	pageCount = (matchedBlockCount + pageSize - 1) / pageSize
+	if withFacets && "" != facetFrom && 0 < matchedBlockCount {
+		facets = searchFacets(facetFrom)
+	}
 
 	switch groupBy {
 	case 0: // 不分组
@@ -1361,6 +1479,23 @@ func buildBoxesFilter(boxes []string) string {
 	return builder.String()
 }
 
+// SplitSearchPaths 将搜索范围 `笔记本 ID/文档路径` 拆分为文档路径和笔记本 ID。
+func SplitSearchPaths(idPaths []string) (paths, boxes []string) {
+	for _, p := range idPaths {
+		box := strings.TrimSpace(strings.Split(p, "/")[0])
+		if "" != box {
+			boxes = append(boxes, box)
+		}
+		p = strings.TrimSpace(strings.TrimPrefix(p, box))
+		if "" != p {
+			paths = append(paths, p)
+		}
+	}
+	paths = gulu.Str.RemoveDuplicatedElem(paths)
+	boxes = gulu.Str.RemoveDuplicatedElem(boxes)
+	return
+}
+
 func buildPathsFilter(paths []string) string {
 	if 0 == len(paths) {
 		return ""
@@ -1410,6 +1545,55 @@ func buildOrderBy(query string, method, orderBy int) string {
 	}
 }
 
+// buildFTSRankOrderBy 使用配置的相关度参数替换排序子句中的 rank 列，默认排序时在名称和别名匹配程度之后按相关度排序。
+//
+// columnWeights 是 FTS 表各列的 BM25 权重，recencyAge 是计算距上次更新天数的表达式，boostFilter 是需要加权的块的条件。
+func buildFTSRankOrderBy(orderBy, table string, columnWeights []float64, recencyAge, boostFilter string) string {
+	if isDefaultSearchRank() {
+		return orderBy
+	}
+
+	byRank := strings.Contains(orderBy, "ORDER BY rank")
+	// 默认排序先按名称和别名匹配程度排序，然后按相关度排序
+	byDefault := strings.HasPrefix(orderBy, "ORDER BY CASE ") && strings.Contains(orderBy, " END ASC, sort ASC")
+	if !byRank && !byDefault {
+		return orderBy
+	}
+
+	var weights []string
+	for _, weight := range columnWeights {
+		weights = append(weights, strconv.FormatFloat(weight, 'f', -1, 64))
+	}
+	// bm25() 返回负数，值越小越相关，所以加权时乘以大于 1 的系数
+	rank := "(bm25(" + table + ", " + strings.Join(weights, ", ") + ")"
+	if 0 < Conf.Search.RankRecency && "" != recencyAge {
+		halfLife := strconv.Itoa(Conf.Search.RankRecencyHalfLife)
+		rank += " * (1 + " + strconv.FormatFloat(Conf.Search.RankRecency, 'f', -1, 64) + " * " + halfLife + ".0 / (" + halfLife + " + MAX(" + recencyAge + ", 0)))"
+	}
+	if 1 < Conf.Search.RankDocBoost && "" != boostFilter {
+		rank += " * (CASE WHEN " + boostFilter + " THEN " + strconv.FormatFloat(Conf.Search.RankDocBoost, 'f', -1, 64) + " ELSE 1 END)"
+	}
+	rank += ")"
+	if byDefault {
+		return strings.Replace(orderBy, " END ASC, sort ASC", " END ASC, "+rank+" ASC, sort ASC", 1)
+	}
+	return strings.Replace(orderBy, "ORDER BY rank", "ORDER BY "+rank, 1)
+}
+
+func buildBlockFTSRankOrderBy(orderBy, table string) string {
+	s := Conf.Search
+	// id, parent_id, root_id, hash, box, path, hpath, name, alias, memo, tag, content, fcontent, markdown, length, type, subtype, ial, sort, created, updated
+	columnWeights := []float64{0, 0, 0, 0, 0, 0, s.RankHPath, s.RankName, s.RankAlias, s.RankMemo, s.RankTag, s.RankContent, s.RankContent, 0, 0, 0, 0, 1, 0, 0, 0}
+	recencyAge := "julianday('now', 'localtime') - julianday(substr(updated, 1, 4) || '-' || substr(updated, 5, 2) || '-' || substr(updated, 7, 2))"
+	return buildFTSRankOrderBy(orderBy, table, columnWeights, recencyAge, "type IN ('d', 'h')")
+}
+
+func isDefaultSearchRank() bool {
+	s := Conf.Search
+	return 1 == s.RankContent && 1 == s.RankName && 1 == s.RankAlias && 1 == s.RankMemo && 1 == s.RankTag && 1 == s.RankHPath &&
+		0 == s.RankRecency && 1 == s.RankDocBoost
+}
+
 func buildTypeFilter(types map[string]bool) string {
 	s := conf.NewSearch()
 	if err := copier.Copy(s, Conf.Search); err != nil {
@@ -1459,7 +1643,7 @@ func buildTypeFilter(types map[string]bool) string {
 
 func searchBySQL(stmt string, beforeLen, page, pageSize int) (ret []*Block, matchedBlockCount, matchedRootCount int) {
 	stmt = strings.TrimSpace(stmt)
-	blocks := sql.SelectBlocksRawStmt(stmt, page, pageSize)
+	blocks := sql.SelectBlocksReadOnlyRawStmt(stmt, page, pageSize)
 	ret = fromSQLBlocks(&blocks, "", beforeLen)
 	if 1 > len(ret) {
 		ret = []*Block{}
@@ -1476,7 +1660,7 @@ func searchBySQL(stmt string, beforeLen, page, pageSize int) (ret []*Block, matc
 		}
 	}
 	stmt = removeLimitClause(stmt)
-	result, _ := sql.QueryNoLimit(stmt)
+	result, _ := sql.QueryReadOnlyNoLimit(stmt)
 	if 1 > len(result) {
 		return
 	}
@@ -1523,7 +1707,7 @@ func fullTextSearchRefBlock(keyword string, beforeLen int, onlyDoc bool) (ret []
 		return
 	}
 
-	quotedKeyword := stringQuery(keyword)
+	quotedKeyword := expandStringQuery(keyword)
 	table := "blocks_fts" // 大小写敏感
 	if !Conf.Search.CaseSensitive {
 		table = "blocks_fts_case_insensitive"
@@ -1537,7 +1721,7 @@ func fullTextSearchRefBlock(keyword string, beforeLen int, onlyDoc bool) (ret []
 		"snippet(" + table + ", 10, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 64) AS tag, " +
 		"snippet(" + table + ", 11, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 64) AS content, " +
 		"fcontent, markdown, length, type, subtype, ial, sort, created, updated"
-	stmt := "SELECT " + projections + " FROM " + table + " WHERE " + table + " MATCH '" + columnFilter() + ":(" + quotedKeyword + ")' AND type"
+	stmt := "SELECT " + projections + " FROM " + table + " WHERE " + table + " MATCH '" + columnFilter() + ":(" + sql.SegmentFTSQuery(quotedKeyword) + ")' AND type"
 	if onlyDoc {
 		stmt += " = 'd'"
 	} else {
@@ -1617,9 +1801,7 @@ func fullTextSearchByRegexp(exp, boxFilter, pathFilter, typeFilter, ignoreFilter
 }
 
 func fullTextSearchCountByRegexp(exp, boxFilter, pathFilter, typeFilter, ignoreFilter string) (matchedBlockCount, matchedRootCount int) {
-	fieldFilter := fieldRegexp(exp)
-	stmt := "SELECT COUNT(id) AS `matches`, COUNT(DISTINCT(root_id)) AS `docs` FROM `blocks` WHERE " + fieldFilter + " AND type IN " + typeFilter + ignoreFilter
-	stmt += boxFilter + pathFilter
+	stmt := "SELECT COUNT(id) AS `matches`, COUNT(DISTINCT(root_id)) AS `docs`" + regexpFromClause(exp, boxFilter, pathFilter, typeFilter, ignoreFilter)
 	result, _ := sql.QueryNoLimit(stmt)
 	if 1 > len(result) {
 		return
@@ -1643,9 +1825,9 @@ func fullTextSearchByFTS(query, boxFilter, pathFilter, typeFilter, ignoreFilter,
 		"snippet(" + table + ", 10, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 64) AS tag, " +
 		"snippet(" + table + ", 11, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 512) AS content, " +
 		"fcontent, markdown, length, type, subtype, ial, sort, created, updated"
-	stmt := "SELECT " + projections + " FROM " + table + " WHERE (`" + table + "` MATCH '" + columnFilter() + ":(" + query + ")'"
+	stmt := "SELECT " + projections + " FROM " + table + " WHERE (`" + table + "` MATCH '" + columnFilter() + ":(" + sql.SegmentFTSQuery(query) + ")'"
 	stmt += ") AND type IN " + typeFilter
-	stmt += boxFilter + pathFilter + ignoreFilter + " " + orderBy
+	stmt += boxFilter + pathFilter + ignoreFilter + " " + buildBlockFTSRankOrderBy(orderBy, table)
 	stmt += " LIMIT " + strconv.Itoa(pageSize) + " OFFSET " + strconv.Itoa((page-1)*pageSize)
 	blocks := sql.SelectBlocksRawStmt(stmt, page, pageSize)
 	ret = fromSQLBlocks(&blocks, "", beforeLen)
@@ -1658,14 +1840,7 @@ func fullTextSearchByFTS(query, boxFilter, pathFilter, typeFilter, ignoreFilter,
 }
 
 func fullTextSearchCountByFTS(query, boxFilter, pathFilter, typeFilter, ignoreFilter string) (matchedBlockCount, matchedRootCount int) {
-	table := "blocks_fts" // 大小写敏感
-	if !Conf.Search.CaseSensitive {
-		table = "blocks_fts_case_insensitive"
-	}
-
-	stmt := "SELECT COUNT(id) AS `matches`, COUNT(DISTINCT(root_id)) AS `docs` FROM `" + table + "` WHERE (`" + table + "` MATCH '" + columnFilter() + ":(" + query + ")'"
-	stmt += ") AND type IN " + typeFilter
-	stmt += boxFilter + pathFilter + ignoreFilter
+	stmt := "SELECT COUNT(id) AS `matches`, COUNT(DISTINCT(root_id)) AS `docs`" + ftsFromClause(query, boxFilter, pathFilter, typeFilter, ignoreFilter)
 	result, _ := sql.QueryNoLimit(stmt)
 	if 1 > len(result) {
 		return
@@ -1675,16 +1850,36 @@ func fullTextSearchCountByFTS(query, boxFilter, pathFilter, typeFilter, ignoreFi
 	return
 }
 
+// ftsFromClause 构建全文搜索命中块的 FROM 和 WHERE 子句，计数和分面统计共用。
+func ftsFromClause(query, boxFilter, pathFilter, typeFilter, ignoreFilter string) string {
+	table := "blocks_fts" // 大小写敏感
+	if !Conf.Search.CaseSensitive {
+		table = "blocks_fts_case_insensitive"
+	}
+
+	ret := " FROM `" + table + "` WHERE (`" + table + "` MATCH '" + columnFilter() + ":(" + sql.SegmentFTSQuery(query) + ")'"
+	ret += ") AND type IN " + typeFilter
+	ret += boxFilter + pathFilter + ignoreFilter
+	return ret
+}
+
+// regexpFromClause 构建正则表达式搜索命中块的 FROM 和 WHERE 子句，计数和分面统计共用。
+func regexpFromClause(exp, boxFilter, pathFilter, typeFilter, ignoreFilter string) string {
+	return " FROM `blocks` WHERE " + fieldRegexp(exp) + " AND type IN " + typeFilter + ignoreFilter + boxFilter + pathFilter
+}
+
 func fullTextSearchByLikeWithRoot(query, boxFilter, pathFilter, typeFilter, ignoreFilter, orderBy string, beforeLen, page, pageSize int) (ret []*Block, matchedBlockCount, matchedRootCount int) {
+	expandedKeywords := expandKeywords(query)
 	query = strings.ReplaceAll(query, "'", "''") // 不需要转义双引号，因为条件都是通过单引号包裹的，只需要转义单引号即可
-	keywords := strings.Split(query, " ")
 	contentField := columnConcat()
 	var likeFilter string
+	var keywords []string
 	orderByLike := "("
-	for i, keyword := range keywords {
-		likeFilter += "GROUP_CONCAT(" + contentField + ") LIKE '%" + keyword + "%'"
-		orderByLike += "(docContent LIKE '%" + keyword + "%')"
-		if i < len(keywords)-1 {
+	for i, alternatives := range expandedKeywords {
+		keywords = append(keywords, alternatives...)
+		likeFilter += likeAnyFilter("GROUP_CONCAT("+contentField+")", alternatives)
+		orderByLike += "(" + likeAnyFilter("docContent", alternatives) + ")"
+		if i < len(expandedKeywords)-1 {
 			likeFilter += " AND "
 			orderByLike += " + "
 		}
@@ -1723,7 +1918,6 @@ func fullTextSearchByLikeWithRoot(query, boxFilter, pathFilter, typeFilter, igno
 
 	keywords = gulu.Str.RemoveDuplicatedElem(keywords)
 	terms := strings.Join(keywords, search.TermSep)
-	terms = strings.ReplaceAll(terms, "''", "'")
 	ret = fromSQLBlocks(&resultBlocks, terms, beforeLen)
 	if 1 > len(ret) {
 		ret = []*Block{}
@@ -1748,7 +1942,7 @@ func highlightByFTS(query, typeFilter, id string) (ret []string) {
 		"fcontent, markdown, length, type, subtype, " +
 		"highlight(" + table + ", 17, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "') AS ial, " +
 		"sort, created, updated"
-	stmt := "SELECT " + projections + " FROM " + table + " WHERE (`" + table + "` MATCH '" + columnFilter() + ":(" + query + ")'"
+	stmt := "SELECT " + projections + " FROM " + table + " WHERE (`" + table + "` MATCH '" + columnFilter() + ":(" + sql.SegmentFTSQuery(query) + ")'"
 	stmt += ") AND type IN " + typeFilter
 	stmt += " AND root_id = '" + id + "'"
 	stmt += " LIMIT " + strconv.Itoa(limit)
@@ -2027,6 +2221,11 @@ func columnConcat() string {
 	return buf.String()
 }
 
+// isShortNgramQuery 判断使用 n-gram 分词时关键字是否太短，无法通过全文索引匹配。
+func isShortNgramQuery(query string) bool {
+	return sql.FTSTokenizerNgram == sql.GetFTSTokenizer() && 3 > utf8.RuneCountInString(strings.TrimSpace(query))
+}
+
 func stringQuery(query string) string {
 	trimmedQuery := strings.TrimSpace(query)
 	if "" == trimmedQuery {
@@ -0,0 +1,187 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/88250/gulu"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+)
+
+// embedCriterionPrefix 是嵌入块引用已保存搜索的标记，比如 `{{//!criterion 待解决问题}}`。
+const embedCriterionPrefix = "//!criterion"
+
+var (
+	ErrCriterionNotFound  = errors.New("criterion not found")
+	ErrCriterionScopedSQL = errors.New("SQL search is not allowed for notebook-scoped requests")
+)
+
+// CriterionResult 描述已保存搜索当前的搜索结果。
+type CriterionResult struct {
+	Blocks            []*Block `json:"blocks"`
+	MatchedBlockCount int      `json:"matchedBlockCount"`
+	MatchedRootCount  int      `json:"matchedRootCount"`
+	PageCount         int      `json:"pageCount"`
+}
+
+// SmartFolder 是已保存搜索在文档树中的只读虚拟节点。
+type SmartFolder struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"` // 当前命中的块数
+}
+
+var (
+	criterionResultCache     = map[string]*CriterionResult{}
+	criterionResultCacheLock = sync.Mutex{}
+)
+
+// ListSmartFolders 列出需要在文档树中显示的已保存搜索。
+func ListSmartFolders(scopedBoxes []string) (ret []*SmartFolder) {
+	ret = []*SmartFolder{}
+	for _, criterion := range GetCriteria() {
+		if !criterion.SmartFolder {
+			continue
+		}
+
+		result, err := RunCriterion(criterion.Name, scopedBoxes, 1, 1)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, &SmartFolder{Name: criterion.Name, Count: result.MatchedBlockCount})
+	}
+	return
+}
+
+// RunCriterion 执行已保存的搜索。搜索结果会被缓存，数据库索引提交后失效。
+//
+// scopedBoxes 不为空时仅在这些笔记本中搜索。
+func RunCriterion(name string, scopedBoxes []string, page, pageSize int) (ret *CriterionResult, err error) {
+	var criterion *Criterion
+	for _, c := range GetCriteria() {
+		if c.Name == name {
+			criterion = c
+			break
+		}
+	}
+	if nil == criterion {
+		err = ErrCriterionNotFound
+		return
+	}
+
+	cacheKey := name + "\n" + strings.Join(scopedBoxes, ",") + "\n" + strconv.Itoa(page) + "\n" + strconv.Itoa(pageSize)
+	criterionResultCacheLock.Lock()
+	ret = criterionResultCache[cacheKey]
+	criterionResultCacheLock.Unlock()
+	if nil != ret {
+		return
+	}
+
+	paths, boxes := SplitSearchPaths(criterion.IDPath)
+	if 0 < len(scopedBoxes) {
+		if 2 == criterion.Method {
+			// SQL 搜索无法限制笔记本范围
+			err = ErrCriterionScopedSQL
+			return
+		}
+
+		if 1 > len(boxes) {
+			boxes = scopedBoxes
+		} else {
+			var tmp []string
+			for _, box := range boxes {
+				if gulu.Str.Contains(box, scopedBoxes) {
+					tmp = append(tmp, box)
+				}
+			}
+			if 1 > len(tmp) {
+				ret = &CriterionResult{Blocks: []*Block{}}
+				return
+			}
+			boxes = tmp
+		}
+	}
+
+	var types map[string]bool
+	if nil != criterion.Types {
+		data, _ := gulu.JSON.MarshalJSON(criterion.Types)
+		if err = gulu.JSON.UnmarshalJSON(data, &types); err != nil {
+			logging.LogErrorf("unmarshal criterion [%s] types failed: %s", name, err)
+			return
+		}
+	}
+
+	ret = &CriterionResult{}
+	ret.Blocks, ret.MatchedBlockCount, ret.MatchedRootCount, ret.PageCount, _, _ = FullTextSearchBlock(criterion.K, boxes, paths, types, criterion.Method, criterion.Sort, criterion.Group, page, pageSize, false)
+
+	criterionResultCacheLock.Lock()
+	criterionResultCache[cacheKey] = ret
+	criterionResultCacheLock.Unlock()
+	return
+}
+
+func clearCriterionResultCache() {
+	criterionResultCacheLock.Lock()
+	criterionResultCache = map[string]*CriterionResult{}
+	criterionResultCacheLock.Unlock()
+}
+
+// selectCriterionEmbedBlocks 查询嵌入块引用的已保存搜索的结果，stmt 不是 `//!criterion 名称` 形式时返回 false。
+//
+// scopedBoxes 不为空时仅在这些笔记本中搜索，内部索引嵌入块时传入 nil。
+func selectCriterionEmbedBlocks(stmt string, scopedBoxes []string, limit int) (ret []*sql.Block, ok bool) {
+	stmt = strings.TrimSpace(stmt)
+	if !strings.HasPrefix(stmt, embedCriterionPrefix) {
+		return
+	}
+
+	ok = true
+	name := strings.TrimSpace(strings.TrimPrefix(stmt, embedCriterionPrefix))
+	result, err := RunCriterion(name, scopedBoxes, 1, limit)
+	if err != nil {
+		logging.LogWarnf("run criterion [%s] for embed block failed: %s", name, err)
+		return
+	}
+
+	var ids []string
+	for _, block := range result.Blocks {
+		if nil != block.Children { // 按文档分组时取子块
+			for _, child := range block.Children {
+				ids = append(ids, child.ID)
+			}
+			continue
+		}
+		ids = append(ids, block.ID)
+	}
+
+	blocks := map[string]*sql.Block{}
+	for _, block := range sql.GetBlocks(ids) {
+		if nil != block {
+			blocks[block.ID] = block
+		}
+	}
+	for _, id := range ids {
+		if block := blocks[id]; nil != block {
+			ret = append(ret, block)
+		}
+	}
+	return
+}
@@ -0,0 +1,269 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/88250/gulu"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+const (
+	fuzzyTermMinLen = 4 // 小于该长度的词不做模糊匹配，避免匹配到过多无关的词
+	fuzzyTermsLimit = 8 // 每个词最多扩展的模糊匹配词数
+)
+
+// expandStringQuery 使用同义词和模糊匹配词扩展关键字，然后构建 FTS 查询。
+//
+// 每个关键字扩展为 ("关键字" OR "同义词" OR "相近词")，关键字之间仍然是与的关系。
+func expandStringQuery(query string) string {
+	if "" == strings.TrimSpace(query) || !isSearchExpandEnabled() {
+		return stringQuery(query)
+	}
+
+	var parts []string
+	for _, alternatives := range expandKeywords(query) {
+		parts = append(parts, ftsAnyTerm(alternatives))
+	}
+	return strings.Join(parts, " ")
+}
+
+// expandHighlightQuery 使用同义词和模糊匹配词扩展关键字，构建文档内高亮使用的 FTS 查询。
+//
+// highlightByFTS 会将空格替换为 OR，所以这里将扩展后的词拆分为单个的词，使用空格分隔。
+func expandHighlightQuery(query string) string {
+	if "" == strings.TrimSpace(query) || !isSearchExpandEnabled() {
+		return stringQuery(query)
+	}
+
+	var terms []string
+	for _, alternatives := range expandKeywords(query) {
+		for _, alternative := range alternatives {
+			for _, term := range strings.Fields(alternative) {
+				terms = append(terms, quoteSearchQueryKeyword(term))
+			}
+		}
+	}
+	return strings.Join(gulu.Str.RemoveDuplicatedElem(terms), " ")
+}
+
+// expandKeywords 将空格分隔的关键字分别扩展，返回的每一项第一个元素是关键字本身。
+func expandKeywords(query string) (ret [][]string) {
+	synonyms := getSearchSynonyms()
+	fuzzy := isFuzzySearchEnabled()
+	for _, keyword := range strings.Split(strings.TrimSpace(query), " ") {
+		if keyword = strings.TrimSpace(keyword); "" == keyword {
+			continue
+		}
+		ret = append(ret, expandKeyword(keyword, synonyms, fuzzy))
+	}
+	return
+}
+
+// expandKeyword 返回关键字、关键字的同义词和模糊匹配词。
+func expandKeyword(keyword string, synonyms map[string][]string, fuzzy bool) (ret []string) {
+	ret = []string{keyword}
+	ret = append(ret, synonyms[strings.ToLower(keyword)]...)
+	if fuzzy {
+		ret = append(ret, fuzzyTerms(keyword)...)
+	}
+	return gulu.Str.RemoveDuplicatedElem(ret)
+}
+
+// ftsAnyTerm 构建匹配任一扩展词的 FTS 查询。
+func ftsAnyTerm(alternatives []string) string {
+	var quoted []string
+	for _, alternative := range alternatives {
+		quoted = append(quoted, quoteSearchQueryKeyword(alternative))
+	}
+	if 1 == len(quoted) {
+		return quoted[0]
+	}
+	return "(" + strings.Join(quoted, " OR ") + ")"
+}
+
+// likeAnyFilter 构建 field 包含任一扩展词的 LIKE 条件。
+func likeAnyFilter(field string, alternatives []string) string {
+	var filters []string
+	for _, alternative := range alternatives {
+		filters = append(filters, field+" LIKE '%"+strings.ReplaceAll(alternative, "'", "''")+"%'")
+	}
+	if 1 == len(filters) {
+		return filters[0]
+	}
+	return "(" + strings.Join(filters, " OR ") + ")"
+}
+
+func isSearchExpandEnabled() bool {
+	return 0 < len(getSearchSynonyms()) || isFuzzySearchEnabled()
+}
+
+// isFuzzySearchEnabled 判断是否启用模糊匹配。
+//
+// n-gram 分词的词表中都是三元组，无法通过编辑距离查找相近的词，所以不启用模糊匹配。
+func isFuzzySearchEnabled() bool {
+	return Conf.Search.Fuzzy && sql.FTSTokenizerNgram != sql.GetFTSTokenizer()
+}
+
+// fuzzyTerms 从全文索引词表中查找和 keyword 编辑距离相近的词。
+//
+// 仅处理由字母和数字组成的非中日韩关键字，并且认为首字符没有拼错以缩小候选词范围。
+func fuzzyTerms(keyword string) (ret []string) {
+	if !Conf.Search.CaseSensitive {
+		keyword = strings.ToLower(keyword)
+	}
+
+	runes := []rune(keyword)
+	if fuzzyTermMinLen > len(runes) {
+		return
+	}
+	for _, r := range runes {
+		if (!unicode.IsLetter(r) && !unicode.IsDigit(r)) || unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return
+		}
+	}
+
+	if sql.ExistFTSVocabTerm(keyword) {
+		// 关键字本身可以命中时不需要模糊匹配
+		return
+	}
+
+	maxDistance := 1
+	if 8 <= len(runes) {
+		maxDistance = 2
+	}
+
+	type candidate struct {
+		term     string
+		distance int
+		docs     int
+	}
+	var candidates []*candidate
+	for _, term := range sql.GetFTSVocabTerms(runes[0], len(runes)-maxDistance, len(runes)+maxDistance) {
+		if distance := levenshteinDistance(runes, []rune(term.Term)); distance <= maxDistance {
+			candidates = append(candidates, &candidate{term: term.Term, distance: distance, docs: term.Docs})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].docs > candidates[j].docs
+	})
+
+	for _, c := range candidates {
+		ret = append(ret, c.term)
+		if fuzzyTermsLimit <= len(ret) {
+			break
+		}
+	}
+	return
+}
+
+func levenshteinDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+var (
+	searchSynonymsLastModified int64
+	searchSynonyms             map[string][]string
+	searchSynonymsLock         = sync.Mutex{}
+)
+
+// getSearchSynonyms 读取同义词词典 data/.siyuan/searchsynonyms。
+//
+// 每行是一组同义词，使用逗号分隔，比如 `JS, JavaScript`。返回的键为小写的词，值为同组的其他词。
+func getSearchSynonyms() (ret map[string][]string) {
+	searchSynonymsLock.Lock()
+	defer searchSynonymsLock.Unlock()
+
+	ret = searchSynonyms
+	now := time.Now().UnixMilli()
+	if now-searchSynonymsLastModified < 30*1000 {
+		return
+	}
+	searchSynonymsLastModified = now
+
+	searchSynonymsPath := filepath.Join(util.DataDir, ".siyuan", "searchsynonyms")
+	err := os.MkdirAll(filepath.Dir(searchSynonymsPath), 0755)
+	if err != nil {
+		return
+	}
+	if !gulu.File.IsExist(searchSynonymsPath) {
+		if err = gulu.File.WriteFileSafer(searchSynonymsPath, nil, 0644); err != nil {
+			logging.LogErrorf("create searchsynonyms [%s] failed: %s", searchSynonymsPath, err)
+			return
+		}
+	}
+	data, err := os.ReadFile(searchSynonymsPath)
+	if err != nil {
+		logging.LogErrorf("read searchsynonyms [%s] failed: %s", searchSynonymsPath, err)
+		return
+	}
+
+	ret = map[string][]string{}
+	dataStr := strings.ReplaceAll(string(data), "\r\n", "\n")
+	for _, line := range strings.Split(dataStr, "\n") {
+		line = strings.ReplaceAll(line, "，", ",")
+		var group []string
+		for _, word := range strings.Split(line, ",") {
+			if word = strings.TrimSpace(word); "" != word {
+				group = append(group, word)
+			}
+		}
+		group = gulu.Str.RemoveDuplicatedElem(group)
+		if 2 > len(group) {
+			continue
+		}
+
+		for _, word := range group {
+			key := strings.ToLower(word)
+			for _, synonym := range group {
+				if synonym != word {
+					ret[key] = append(ret[key], synonym)
+				}
+			}
+		}
+	}
+	searchSynonyms = ret
+	return
+}
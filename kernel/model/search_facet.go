@@ -0,0 +1,168 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/88250/gulu"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+)
+
+const (
+	searchFacetTagLimit = 32 // 标签分面最多返回的标签数
+	searchFacetDocLimit = 16 // 文档分面最多返回的文档数
+)
+
+// SearchFacets 描述搜索命中块按笔记本、块类型、标签和文档分组后的命中数。
+type SearchFacets struct {
+	Boxes []*SearchFacet `json:"boxes"`
+	Types []*SearchFacet `json:"types"`
+	Tags  []*SearchFacet `json:"tags"`
+	Docs  []*SearchFacet `json:"docs"`
+}
+
+type SearchFacet struct {
+	Value   string `json:"value"`             // 笔记本 ID、块类型、标签或者文档 ID
+	SubType string `json:"subType,omitempty"` // 块子类型，仅用于块类型分面
+	Label   string `json:"label"`             // 笔记本名称、标签或者文档路径
+	Count   int    `json:"count"`
+}
+
+// searchFacets 使用和命中数统计相同的 FROM 和 WHERE 子句分组统计分面。
+func searchFacets(fromClause string) (ret *SearchFacets) {
+	ret = &SearchFacets{Boxes: []*SearchFacet{}, Types: []*SearchFacet{}, Tags: []*SearchFacet{}, Docs: []*SearchFacet{}}
+
+	for _, row := range querySearchFacet("SELECT box, COUNT(id) AS `count`" + fromClause + " GROUP BY box ORDER BY `count` DESC") {
+		boxID := facetString(row["box"])
+		facet := &SearchFacet{Value: boxID, Label: boxID, Count: facetCount(row["count"])}
+		if box := Conf.GetBox(boxID); nil != box {
+			facet.Label = box.Name
+		}
+		ret.Boxes = append(ret.Boxes, facet)
+	}
+
+	for _, row := range querySearchFacet("SELECT type, subtype, COUNT(id) AS `count`" + fromClause + " GROUP BY type, subtype ORDER BY `count` DESC") {
+		typ := facetString(row["type"])
+		ret.Types = append(ret.Types, &SearchFacet{Value: typ, SubType: facetString(row["subtype"]), Label: typ, Count: facetCount(row["count"])})
+	}
+
+	// 标签字段形如 `#foo# #bar/baz#`，需要拆分后再合计
+	tagCounts := map[string]int{}
+	for _, row := range querySearchFacet("SELECT tag, COUNT(id) AS `count`" + fromClause + " GROUP BY tag") {
+		tags := strings.TrimSpace(facetString(row["tag"]))
+		if "" == tags {
+			continue
+		}
+
+		count := facetCount(row["count"])
+		for _, tag := range strings.Split(strings.Trim(tags, "#"), "# #") {
+			if tag = strings.TrimSpace(tag); "" != tag {
+				tagCounts[tag] += count
+			}
+		}
+	}
+	for tag, count := range tagCounts {
+		ret.Tags = append(ret.Tags, &SearchFacet{Value: tag, Label: tag, Count: count})
+	}
+	sortSearchFacets(ret.Tags)
+	if searchFacetTagLimit < len(ret.Tags) {
+		ret.Tags = ret.Tags[:searchFacetTagLimit]
+	}
+
+	var rootIDs []string
+	for _, row := range querySearchFacet("SELECT root_id, COUNT(id) AS `count`" + fromClause + " GROUP BY root_id ORDER BY `count` DESC LIMIT " + strconv.Itoa(searchFacetDocLimit)) {
+		rootID := facetString(row["root_id"])
+		rootIDs = append(rootIDs, rootID)
+		ret.Docs = append(ret.Docs, &SearchFacet{Value: rootID, Label: rootID, Count: facetCount(row["count"])})
+	}
+	hPaths := map[string]string{}
+	for _, root := range sql.GetBlocks(rootIDs) {
+		if nil != root {
+			hPaths[root.ID] = root.HPath
+		}
+	}
+	for _, doc := range ret.Docs {
+		if hPath := hPaths[doc.Value]; "" != hPath {
+			doc.Label = hPath
+		}
+	}
+	return
+}
+
+// likeWithRootFromClause 构建文档全文搜索模式下命中块的 FROM 和 WHERE 子句。
+//
+// 该模式按文档返回结果，分面统计的是命中文档中包含任一关键字的块，和其他搜索模式一样按块计数。
+func likeWithRootFromClause(query, boxFilter, pathFilter, typeFilter, ignoreFilter string) string {
+	contentField := columnConcat()
+	var docFilters, keywords []string
+	for _, alternatives := range expandKeywords(query) {
+		docFilters = append(docFilters, likeAnyFilter("GROUP_CONCAT("+contentField+")", alternatives))
+		keywords = append(keywords, alternatives...)
+	}
+	return " FROM `blocks` WHERE type IN " + typeFilter + boxFilter + pathFilter + ignoreFilter +
+		" AND " + likeAnyFilter(contentField, gulu.Str.RemoveDuplicatedElem(keywords)) +
+		" AND root_id IN (SELECT root_id FROM blocks WHERE type IN " + typeFilter + boxFilter + pathFilter + ignoreFilter +
+		" GROUP BY root_id HAVING " + strings.Join(docFilters, " AND ") + ")"
+}
+
+// idsFromClause 构建指定块的 FROM 和 WHERE 子句，用于统计 SQL 搜索返回的块，避免再次执行用户编写的语句。
+func idsFromClause(blocks []*Block) string {
+	if 1 > len(blocks) {
+		return ""
+	}
+
+	var ids []string
+	for _, block := range blocks {
+		ids = append(ids, block.ID)
+	}
+	return " FROM `blocks` WHERE id IN ('" + strings.Join(ids, "','") + "')"
+}
+
+func querySearchFacet(stmt string) (ret []map[string]interface{}) {
+	ret, err := sql.QueryNoLimit(stmt)
+	if err != nil {
+		logging.LogWarnf("query search facet [%s] failed: %s", stmt, err)
+	}
+	return
+}
+
+func sortSearchFacets(facets []*SearchFacet) {
+	sort.SliceStable(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+}
+
+func facetString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return ""
+}
+
+func facetCount(value interface{}) int {
+	if count, ok := value.(int64); ok {
+		return int(count)
+	}
+	return 0
+}
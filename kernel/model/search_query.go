@@ -0,0 +1,515 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute/ast"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+)
+
+// 结构化查询语法，关键字、短语和字段过滤之间使用空格分隔，比如：
+//
+//	tag:#proj/x type:h2 updated:>2024-01 box:Work attr:status=done "exact phrase" -excluded
+//
+// 支持的字段：
+//
+//   - tag：标签，#proj/x 会同时匹配 #proj/x/y
+//   - type：块类型，比如 h、h2、p、d、code、task
+//   - updated、created：日期，支持 2024、2024-01、2024-01-15 以及 >、>=、<、<= 前缀和 2024-01..2024-03 区间
+//   - box：笔记本名称或者 ID
+//   - attr：块属性，attr:status 表示存在该属性，attr:status=done 表示属性值等于 done，属性名可以省略 custom- 前缀
+//
+// 字段和关键字前加 - 表示排除。不支持的字段（比如 http://example.com）和没有值的字段（比如 note:）作为普通关键字。
+
+// SearchQueryError 描述结构化查询的语法错误，Pos 是错误在查询中的位置（从 1 开始，按字符计算）。
+type SearchQueryError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SearchQueryError) Error() string {
+	return fmt.Sprintf(Conf.Language(290), e.Pos, e.Msg)
+}
+
+type searchQuery struct {
+	keywords []string            // 需要包含的关键字和短语
+	excludes []string            // 需要排除的关键字和短语
+	types    map[string]bool     // 块类型过滤，为 nil 时使用搜索参数中的块类型
+	subTypes map[string][]string // 块子类型过滤，比如 h -> [h2, h3]，值为 nil 时表示不限制该类型的子类型
+	boxes    []string            // 笔记本过滤
+	filters  []string            // 其他过滤条件，比如 " AND (tag LIKE '%#x#%')"
+}
+
+// searchQueryFields 是结构化查询支持的字段。
+var searchQueryFields = []string{"tag", "type", "updated", "created", "box", "attr"}
+
+type searchQueryToken struct {
+	pos     int
+	negated bool
+	field   string
+	value   string
+}
+
+// searchQueryBlockTypes 是 type: 字段支持的块类型，值为搜索类型过滤选项名和节点类型。
+var searchQueryBlockTypes = map[string]struct {
+	typ      string
+	nodeType ast.NodeType
+}{
+	"d":          {"document", ast.NodeDocument},
+	"doc":        {"document", ast.NodeDocument},
+	"document":   {"document", ast.NodeDocument},
+	"h":          {"heading", ast.NodeHeading},
+	"heading":    {"heading", ast.NodeHeading},
+	"p":          {"paragraph", ast.NodeParagraph},
+	"paragraph":  {"paragraph", ast.NodeParagraph},
+	"l":          {"list", ast.NodeList},
+	"list":       {"list", ast.NodeList},
+	"i":          {"listItem", ast.NodeListItem},
+	"li":         {"listItem", ast.NodeListItem},
+	"listitem":   {"listItem", ast.NodeListItem},
+	"task":       {"listItem", ast.NodeListItem},
+	"c":          {"codeBlock", ast.NodeCodeBlock},
+	"code":       {"codeBlock", ast.NodeCodeBlock},
+	"m":          {"mathBlock", ast.NodeMathBlock},
+	"math":       {"mathBlock", ast.NodeMathBlock},
+	"t":          {"table", ast.NodeTable},
+	"table":      {"table", ast.NodeTable},
+	"b":          {"blockquote", ast.NodeBlockquote},
+	"quote":      {"blockquote", ast.NodeBlockquote},
+	"blockquote": {"blockquote", ast.NodeBlockquote},
+	"s":          {"superBlock", ast.NodeSuperBlock},
+	"superblock": {"superBlock", ast.NodeSuperBlock},
+	"html":       {"htmlBlock", ast.NodeHTMLBlock},
+	"embed":      {"embedBlock", ast.NodeBlockQueryEmbed},
+	"av":         {"databaseBlock", ast.NodeAttributeView},
+	"database":   {"databaseBlock", ast.NodeAttributeView},
+	"audio":      {"audioBlock", ast.NodeAudio},
+	"video":      {"videoBlock", ast.NodeVideo},
+	"iframe":     {"iframeBlock", ast.NodeIFrame},
+	"widget":     {"widgetBlock", ast.NodeWidget},
+	"callout":    {"callout", ast.NodeCallout},
+}
+
+func parseSearchQuery(query string) (ret *searchQuery, err error) {
+	tokens, err := tokenizeSearchQuery(query)
+	if err != nil {
+		return
+	}
+
+	ret = &searchQuery{}
+	for _, token := range tokens {
+		if "" == token.field {
+			if token.negated {
+				ret.excludes = append(ret.excludes, token.value)
+			} else {
+				ret.keywords = append(ret.keywords, token.value)
+			}
+			continue
+		}
+
+		var filter string
+		switch token.field {
+		case "tag":
+			tag := strings.ReplaceAll(strings.Trim(token.value, "#"), "'", "''")
+			filter = "tag LIKE '%#" + tag + "#%' OR tag LIKE '%#" + tag + "/%'"
+		case "type":
+			filter, err = ret.parseType(token)
+		case "updated", "created":
+			filter, err = parseSearchQueryDate(token)
+		case "box":
+			filter, err = ret.parseBox(token)
+		case "attr":
+			filter = parseSearchQueryAttr(token)
+		}
+		if err != nil {
+			return
+		}
+
+		if "" == filter {
+			continue
+		}
+		if token.negated {
+			ret.filters = append(ret.filters, " AND NOT ("+filter+")")
+		} else {
+			ret.filters = append(ret.filters, " AND ("+filter+")")
+		}
+	}
+	ret.filters = append(ret.filters, ret.subTypesFilter()...)
+	return
+}
+
+// subTypesFilter 将同一块类型的多个子类型合并为一个过滤条件，比如 type:h2 type:h3 表示二级或者三级标题。
+func (q *searchQuery) subTypesFilter() (ret []string) {
+	var typeAbbrs []string
+	for typeAbbr, subTypes := range q.subTypes {
+		if 0 < len(subTypes) {
+			typeAbbrs = append(typeAbbrs, typeAbbr)
+		}
+	}
+	sort.Strings(typeAbbrs)
+
+	for _, typeAbbr := range typeAbbrs {
+		ret = append(ret, " AND (type != '"+typeAbbr+"' OR subtype IN ('"+strings.Join(q.subTypes[typeAbbr], "', '")+"'))")
+	}
+	return
+}
+
+func (q *searchQuery) parseType(token *searchQueryToken) (filter string, err error) {
+	value := strings.ToLower(token.value)
+	var subType string
+	if 2 == len(value) && 'h' == value[0] && '1' <= value[1] && '6' >= value[1] {
+		subType = value
+		value = "h"
+	}
+	if "task" == value {
+		subType = "t"
+	}
+
+	blockType, ok := searchQueryBlockTypes[value]
+	if !ok {
+		err = &SearchQueryError{Pos: token.pos, Msg: "unknown block type [" + token.value + "]"}
+		return
+	}
+
+	typeAbbr := treenode.TypeAbbr(blockType.nodeType.String())
+	if token.negated {
+		filter = "type = '" + typeAbbr + "'"
+		if "" != subType {
+			filter += " AND subtype = '" + subType + "'"
+		}
+		return
+	}
+
+	// 多个 type: 字段之间是或的关系，同一类型的子类型在解析完成后合并过滤
+	if nil == q.types {
+		q.types = map[string]bool{}
+		q.subTypes = map[string][]string{}
+	}
+	q.types[blockType.typ] = true
+	subTypes, exists := q.subTypes[typeAbbr]
+	if "" == subType {
+		q.subTypes[typeAbbr] = nil
+	} else if !exists || nil != subTypes {
+		if !gulu.Str.Contains(subType, subTypes) {
+			q.subTypes[typeAbbr] = append(subTypes, subType)
+		}
+	}
+	return
+}
+
+func (q *searchQuery) parseBox(token *searchQueryToken) (filter string, err error) {
+	var boxID string
+	for _, box := range Conf.GetBoxes() {
+		if box.ID == token.value || strings.EqualFold(box.Name, token.value) {
+			boxID = box.ID
+			break
+		}
+	}
+	if "" == boxID {
+		err = &SearchQueryError{Pos: token.pos, Msg: "notebook not found [" + token.value + "]"}
+		return
+	}
+
+	if token.negated {
+		filter = "box = '" + boxID + "'"
+		return
+	}
+	q.boxes = append(q.boxes, boxID)
+	return
+}
+
+func parseSearchQueryAttr(token *searchQueryToken) (filter string) {
+	name, value, hasValue := strings.Cut(token.value, "=")
+	filter = "id IN (SELECT block_id FROM attributes WHERE name IN ('" + strings.Join(searchQueryAttrNames(name), "', '") + "')"
+	if hasValue {
+		filter += " AND value = '" + strings.ReplaceAll(strings.TrimSpace(value), "'", "''") + "'"
+	}
+	filter += ")"
+	return
+}
+
+// searchQueryAttrNames 返回 attr: 字段匹配的属性名（已经转义单引号），省略 custom- 前缀时同时匹配带前缀的属性名。
+func searchQueryAttrNames(name string) (ret []string) {
+	name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "'", "''")
+	ret = append(ret, name)
+	if !strings.HasPrefix(name, "custom-") {
+		ret = append(ret, "custom-"+name)
+	}
+	return
+}
+
+func parseSearchQueryDate(token *searchQueryToken) (filter string, err error) {
+	start, end, err := parseSearchQueryDateBounds(token)
+	if err != nil {
+		return
+	}
+
+	const layout = "20060102150405"
+	var conditions []string
+	if !start.IsZero() {
+		conditions = append(conditions, token.field+" >= '"+start.Format(layout)+"'")
+	}
+	if !end.IsZero() {
+		conditions = append(conditions, token.field+" < '"+end.Format(layout)+"'")
+	}
+	filter = strings.Join(conditions, " AND ")
+	return
+}
+
+// parseSearchQueryDateBounds 解析日期字段，返回时间段 [start, end)，零值表示不限制。
+func parseSearchQueryDateBounds(token *searchQueryToken) (start, end time.Time, err error) {
+	value := token.value
+	var op string
+	for _, prefix := range []string{">=", "<=", ">", "<"} {
+		if strings.HasPrefix(value, prefix) {
+			op = prefix
+			value = value[len(prefix):]
+			break
+		}
+	}
+
+	if from, to, isRange := strings.Cut(value, ".."); isRange && "" == op {
+		fromStart, _, fromErr := parseSearchQueryDatePeriod(from)
+		_, toEnd, toErr := parseSearchQueryDatePeriod(to)
+		if nil != fromErr || nil != toErr {
+			err = &SearchQueryError{Pos: token.pos, Msg: "invalid date range [" + token.value + "]"}
+			return
+		}
+		start, end = fromStart, toEnd
+		return
+	}
+
+	periodStart, periodEnd, parseErr := parseSearchQueryDatePeriod(value)
+	if nil != parseErr {
+		err = &SearchQueryError{Pos: token.pos, Msg: "invalid date [" + token.value + "]"}
+		return
+	}
+
+	switch op {
+	case ">":
+		start = periodEnd
+	case ">=":
+		start = periodStart
+	case "<":
+		end = periodStart
+	case "<=":
+		end = periodEnd
+	default:
+		start, end = periodStart, periodEnd
+	}
+	return
+}
+
+// parseSearchQueryDatePeriod 解析 2024、2024-01、2024-01-15 这样的日期，返回日期表示的时间段 [start, end)。
+func parseSearchQueryDatePeriod(value string) (start, end time.Time, err error) {
+	value = strings.TrimSpace(value)
+	switch len(value) {
+	case 4:
+		if start, err = time.ParseInLocation("2006", value, time.Local); nil == err {
+			end = start.AddDate(1, 0, 0)
+		}
+	case 7:
+		if start, err = time.ParseInLocation("2006-01", value, time.Local); nil == err {
+			end = start.AddDate(0, 1, 0)
+		}
+	case 10:
+		if start, err = time.ParseInLocation("2006-01-02", value, time.Local); nil == err {
+			end = start.AddDate(0, 0, 1)
+		}
+	default:
+		err = fmt.Errorf("invalid date [%s]", value)
+	}
+	return
+}
+
+func tokenizeSearchQuery(query string) (ret []*searchQueryToken, err error) {
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		token := &searchQueryToken{pos: i + 1}
+		if '-' == runes[i] && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			token.negated = true
+			i++
+		}
+
+		// 字段名只包含字母，后面紧跟冒号
+		start := i
+		j := i
+		for j < len(runes) && unicode.IsLetter(runes[j]) {
+			j++
+		}
+		if j > i && j < len(runes) && ':' == runes[j] {
+			if field := strings.ToLower(string(runes[i:j])); gulu.Str.Contains(field, searchQueryFields) {
+				token.field = field
+				i = j + 1
+			}
+		}
+
+		if i < len(runes) && '"' == runes[i] {
+			end := i + 1
+			for end < len(runes) && '"' != runes[end] {
+				end++
+			}
+			if end >= len(runes) {
+				err = &SearchQueryError{Pos: i + 1, Msg: "unclosed quote"}
+				return
+			}
+			token.value = string(runes[i+1 : end])
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) {
+				end++
+			}
+			token.value = string(runes[i:end])
+			i = end
+		}
+
+		if "" != token.field && "" == token.value {
+			// 没有值的字段作为普通关键字
+			token.field = ""
+			token.value = string(runes[start:i])
+		}
+
+		if "" == token.field && "" == token.value {
+			err = &SearchQueryError{Pos: token.pos, Msg: "empty keyword"}
+			return
+		}
+		ret = append(ret, token)
+	}
+	return
+}
+
+// ftsQuery 将关键字和短语转换为 FTS MATCH 表达式。
+func (q *searchQuery) ftsQuery() string {
+	synonyms := getSearchSynonyms()
+	fuzzy := isFuzzySearchEnabled()
+	buf := bytes.Buffer{}
+	for i, keyword := range q.keywords {
+		if 0 < i {
+			buf.WriteString(" AND ")
+		}
+		buf.WriteString(ftsAnyTerm(expandKeyword(keyword, synonyms, fuzzy)))
+	}
+	for _, exclude := range q.excludes {
+		buf.WriteString(" NOT ")
+		buf.WriteString(quoteSearchQueryKeyword(exclude))
+	}
+	return buf.String()
+}
+
+func quoteSearchQueryKeyword(keyword string) string {
+	keyword = strings.ReplaceAll(keyword, "\"", "\"\"")
+	keyword = strings.ReplaceAll(keyword, "'", "''")
+	return "\"" + keyword + "\""
+}
+
+// CheckSearchQuery 检查结构化查询的语法，存在语法错误时返回错误描述和位置。
+func CheckSearchQuery(query string) *SearchQueryError {
+	_, err := parseSearchQuery(filterQueryInvisibleChars(query))
+	if nil == err {
+		return nil
+	}
+
+	var queryErr *SearchQueryError
+	if errors.As(err, &queryErr) {
+		return queryErr
+	}
+	return &SearchQueryError{Pos: 1, Msg: err.Error()}
+}
+
+// structuredSearchBlock 使用结构化查询搜索块，调用方需要先通过 CheckSearchQuery 检查语法，存在语法错误时返回空结果。
+func structuredSearchBlock(query string, boxes, paths []string, types map[string]bool, ignoreFilter, orderBy string, beforeLen, page, pageSize int) (ret []*Block, matchedBlockCount, matchedRootCount int) {
+	ret = []*Block{}
+	q, err := parseSearchQuery(query)
+	if err != nil {
+		return
+	}
+
+	typeFilter, boxFilter, pathFilter, filter := q.buildFilters(boxes, paths, types, ignoreFilter)
+	if 0 < len(q.keywords) {
+		return fullTextSearchByFTS(q.ftsQuery(), boxFilter, pathFilter, typeFilter, filter, orderBy, beforeLen, page, pageSize)
+	}
+
+	// 没有需要包含的关键字时无法使用 FTS，只按照字段过滤
+	filter += q.excludesFilter()
+	stmt := "SELECT * FROM `blocks` WHERE type IN " + typeFilter + boxFilter + pathFilter + filter + " " + orderBy
+	stmt += " LIMIT " + strconv.Itoa(pageSize) + " OFFSET " + strconv.Itoa((page-1)*pageSize)
+	blocks := sql.SelectBlocksRawStmt(stmt, page, pageSize)
+	ret = fromSQLBlocks(&blocks, "", beforeLen)
+	if 1 > len(ret) {
+		ret = []*Block{}
+	}
+
+	countStmt := "SELECT COUNT(id) AS `matches`, COUNT(DISTINCT(root_id)) AS `docs` FROM `blocks` WHERE type IN " + typeFilter + boxFilter + pathFilter + filter
+	result, _ := sql.QueryNoLimit(countStmt)
+	if 1 > len(result) {
+		return
+	}
+	matchedBlockCount = int(result[0]["matches"].(int64))
+	matchedRootCount = int(result[0]["docs"].(int64))
+	return
+}
+
+// structuredSearchFromClause 构建结构化查询命中块的 FROM 和 WHERE 子句，用于分面统计。
+func structuredSearchFromClause(query string, boxes, paths []string, types map[string]bool, ignoreFilter string) string {
+	q, err := parseSearchQuery(query)
+	if err != nil {
+		return ""
+	}
+
+	typeFilter, boxFilter, pathFilter, filter := q.buildFilters(boxes, paths, types, ignoreFilter)
+	if 0 < len(q.keywords) {
+		return ftsFromClause(q.ftsQuery(), boxFilter, pathFilter, typeFilter, filter)
+	}
+	return " FROM `blocks` WHERE type IN " + typeFilter + boxFilter + pathFilter + filter + q.excludesFilter()
+}
+
+// buildFilters 合并查询中的字段过滤和搜索参数中的过滤，查询中指定的类型优先。
+// 查询中指定的笔记本和搜索参数中的笔记本取交集，避免绕过按照笔记本限制的搜索范围。
+func (q *searchQuery) buildFilters(boxes, paths []string, types map[string]bool, ignoreFilter string) (typeFilter, boxFilter, pathFilter, filter string) {
+	if nil != q.types {
+		types = q.types
+	}
+	typeFilter = buildTypeFilter(types)
+	boxFilter = buildBoxesFilter(boxes) + buildBoxesFilter(q.boxes)
+	pathFilter = buildPathsFilter(paths)
+	filter = strings.Join(q.filters, "") + ignoreFilter
+	return
+}
+
+func (q *searchQuery) excludesFilter() (ret string) {
+	for _, exclude := range q.excludes {
+		ret += " AND " + columnConcat() + " NOT LIKE '%" + strings.ReplaceAll(exclude, "'", "''") + "%'"
+	}
+	return
+}
@@ -0,0 +1,160 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTokenizeSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string // 词法单元之间使用 | 分隔，字段和值之间使用 : 分隔，排除的词法单元以 - 开头
+	}{
+		{query: `tag:#proj/x "exact phrase" -excluded`, want: `tag:#proj/x|exact phrase|-excluded`},
+		{query: `  思源  笔记 `, want: `思源|笔记`},
+		{query: `box:"My Notes" TYPE:h2`, want: `box:My Notes|type:h2`},
+		{query: `http://example.com note: foo`, want: `http://example.com|note:|foo`},
+		{query: `type: -tag: -`, want: `type:|-tag:|-`},
+		{query: `unknown:value -updated:>2024-01`, want: `unknown:value|-updated:>2024-01`},
+	}
+
+	for _, test := range tests {
+		tokens, err := tokenizeSearchQuery(test.query)
+		if nil != err {
+			t.Errorf("tokenize [%s] failed: %s", test.query, err.(*SearchQueryError).Msg)
+			continue
+		}
+
+		var got []string
+		for _, token := range tokens {
+			var s string
+			if token.negated {
+				s = "-"
+			}
+			if "" != token.field {
+				s += token.field + ":"
+			}
+			got = append(got, s+token.value)
+		}
+		if test.want != strings.Join(got, "|") {
+			t.Errorf("tokenize [%s] expected [%s], got [%s]", test.query, test.want, strings.Join(got, "|"))
+		}
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		keywords string
+		excludes string
+		types    string
+		filters  string
+	}{
+		{
+			query:    `思源 "exact phrase" -excluded`,
+			keywords: `思源|exact phrase`,
+			excludes: `excluded`,
+		},
+		{
+			query:   `type:h2 type:h3`,
+			types:   `heading`,
+			filters: ` AND (type != 'h' OR subtype IN ('h2', 'h3'))`,
+		},
+		{
+			query: `type:h2 type:heading type:h3`,
+			types: `heading`,
+		},
+		{
+			query:   `type:task type:p -type:h1`,
+			types:   `listItem|paragraph`,
+			filters: ` AND NOT (type = 'h' AND subtype = 'h1') AND (type != 'i' OR subtype IN ('t'))`,
+		},
+		{
+			query:   `tag:#proj/x -attr:status=done`,
+			filters: ` AND (tag LIKE '%#proj/x#%' OR tag LIKE '%#proj/x/%') AND NOT (id IN (SELECT block_id FROM attributes WHERE name IN ('status', 'custom-status') AND value = 'done'))`,
+		},
+		{
+			query:   `updated:2024-01 created:<2024`,
+			filters: ` AND (updated >= '20240101000000' AND updated < '20240201000000') AND (created < '20240101000000')`,
+		},
+		{
+			query:   `created:2024-01..2024-03-15`,
+			filters: ` AND (created >= '20240101000000' AND created < '20240316000000')`,
+		},
+		{
+			query:    `note: http://example.com`,
+			keywords: `note:|http://example.com`,
+		},
+	}
+
+	for _, test := range tests {
+		q, err := parseSearchQuery(test.query)
+		if nil != err {
+			t.Errorf("parse [%s] failed: %s", test.query, err.(*SearchQueryError).Msg)
+			continue
+		}
+
+		var types []string
+		for _, typ := range []string{"document", "heading", "listItem", "paragraph"} {
+			if q.types[typ] {
+				types = append(types, typ)
+			}
+		}
+
+		if got := strings.Join(q.keywords, "|"); test.keywords != got {
+			t.Errorf("parse [%s] keywords expected [%s], got [%s]", test.query, test.keywords, got)
+		}
+		if got := strings.Join(q.excludes, "|"); test.excludes != got {
+			t.Errorf("parse [%s] excludes expected [%s], got [%s]", test.query, test.excludes, got)
+		}
+		if got := strings.Join(types, "|"); test.types != got {
+			t.Errorf("parse [%s] types expected [%s], got [%s]", test.query, test.types, got)
+		}
+		if got := strings.Join(q.filters, ""); test.filters != got {
+			t.Errorf("parse [%s] filters expected [%s], got [%s]", test.query, test.filters, got)
+		}
+	}
+}
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{query: `"unclosed`, pos: 1, msg: "unclosed quote"},
+		{query: `思源 tag:"x`, pos: 8, msg: "unclosed quote"},
+		{query: `type:foo`, pos: 1, msg: "unknown block type [foo]"},
+		{query: `a -updated:2024-1`, pos: 3, msg: "invalid date [2024-1]"},
+		{query: `created:2024..x`, pos: 1, msg: "invalid date range [2024..x]"},
+	}
+
+	for _, test := range tests {
+		_, err := parseSearchQuery(test.query)
+		var queryErr *SearchQueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("parse [%s] expected a syntax error, got [%v]", test.query, err)
+			continue
+		}
+		if test.pos != queryErr.Pos || test.msg != queryErr.Msg {
+			t.Errorf("parse [%s] expected error [%d: %s], got [%d: %s]", test.query, test.pos, test.msg, queryErr.Pos, queryErr.Msg)
+		}
+	}
+}
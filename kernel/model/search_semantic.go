@@ -0,0 +1,246 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute/editor"
+	"github.com/sashabaranov/go-openai"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+const (
+	blockVectorBatchSize     = 32   // 每次请求嵌入接口的块数
+	blockVectorContentMaxLen = 2048 // 生成向量的块内容最大长度
+	blockVectorRootMaxBlocks = 102400
+)
+
+// blockVectorLock 避免重建块向量数据库时同时生成向量。
+var blockVectorLock = sync.Mutex{}
+
+// IndexBlockVectorsJob 为内容变化的文档生成块向量，嵌入接口不可用时下次重试。
+func IndexBlockVectorsJob() {
+	if !Conf.Search.Semantic || !isEmbeddingEnabled() {
+		return
+	}
+
+	blockVectorLock.Lock()
+	defer blockVectorLock.Unlock()
+
+	rootIDs, prune := sql.PopBlockVectorDirty()
+	if prune {
+		pruneBlockVectors()
+	}
+
+	for i, rootID := range rootIDs {
+		if err := indexBlockVectors(rootID); err != nil {
+			sql.MarkBlockVectorRootIDs(rootIDs[i:]...)
+			return
+		}
+	}
+}
+
+// ReindexBlockVectors 清空块向量数据库并重新为所有文档生成块向量。
+func ReindexBlockVectors() {
+	blockVectorLock.Lock()
+	defer blockVectorLock.Unlock()
+
+	sql.InitBlockVectorDatabase(true)
+	if !Conf.Search.Semantic {
+		return
+	}
+
+	rows, err := sql.QueryNoLimit("SELECT DISTINCT root_id FROM blocks")
+	if err != nil {
+		logging.LogErrorf("query root ids failed: %s", err)
+		return
+	}
+
+	var rootIDs []string
+	for _, row := range rows {
+		rootIDs = append(rootIDs, row["root_id"].(string))
+	}
+	sql.MarkBlockVectorRootIDs(rootIDs...)
+	logging.LogInfof("marked [%d] docs to reindex block vectors", len(rootIDs))
+}
+
+func indexBlockVectors(rootID string) (err error) {
+	blocks := sql.GetAllChildBlocks([]string{rootID}, "", blockVectorRootMaxBlocks)
+	if 1 > len(blocks) {
+		err = sql.DeleteBlockVectorsByRootIDs([]string{rootID})
+		return
+	}
+
+	hashes := sql.GetBlockVectorHashes(rootID)
+	existing := map[string]bool{}
+	var changed []*sql.BlockVector
+	var contents []string
+	for _, block := range blocks {
+		content := getBlockVectorContent(block)
+		if "" == content {
+			continue
+		}
+
+		existing[block.ID] = true
+		hash := hashBlockVectorContent(content)
+		if hashes[block.ID] == hash {
+			continue
+		}
+
+		changed = append(changed, &sql.BlockVector{ID: block.ID, RootID: block.RootID, Box: block.Box, Path: block.Path, Type: block.Type, Hash: hash})
+		contents = append(contents, content)
+	}
+
+	var removed []string
+	for id := range hashes {
+		if !existing[id] {
+			removed = append(removed, id)
+		}
+	}
+	if err = sql.DeleteBlockVectors(removed); err != nil {
+		return
+	}
+
+	client := newEmbeddingClient()
+	for i := 0; i < len(changed); i += blockVectorBatchSize {
+		end := min(i+blockVectorBatchSize, len(changed))
+		var vectors [][]float32
+		vectors, err = util.Embeddings(contents[i:end], client, Conf.AI.OpenAI.APIEmbeddingModel, Conf.AI.OpenAI.APITimeout)
+		if err != nil {
+			return
+		}
+
+		batch := changed[i:end]
+		for j, vector := range vectors {
+			batch[j].Vector = vector
+		}
+		if err = sql.UpsertBlockVectors(batch); err != nil {
+			return
+		}
+	}
+
+	// 移动文档后块内容不变，只需要更新路径
+	err = sql.UpdateBlockVectorsPath(rootID, blocks[0].Box, blocks[0].Path)
+	return
+}
+
+func pruneBlockVectors() {
+	rows, err := sql.QueryNoLimit("SELECT DISTINCT root_id FROM blocks")
+	if err != nil {
+		logging.LogErrorf("query root ids failed: %s", err)
+		return
+	}
+
+	rootIDs := map[string]bool{}
+	for _, row := range rows {
+		rootIDs[row["root_id"].(string)] = true
+	}
+
+	var removed []string
+	for _, rootID := range sql.GetBlockVectorRootIDs() {
+		if !rootIDs[rootID] {
+			removed = append(removed, rootID)
+		}
+	}
+	sql.DeleteBlockVectorsByRootIDs(removed)
+}
+
+func semanticSearchBlock(query, boxFilter, pathFilter, typeFilter, ignoreFilter string, beforeLen, page, pageSize int) (ret []*Block, matchedBlockCount, matchedRootCount int, matchedIDs []string) {
+	ret = []*Block{}
+	if !Conf.Search.Semantic || !isEmbeddingEnabled() {
+		util.PushMsg(Conf.Language(287), 5000)
+		return
+	}
+
+	vectors, err := util.Embeddings([]string{query}, newEmbeddingClient(), Conf.AI.OpenAI.APIEmbeddingModel, Conf.AI.OpenAI.APITimeout)
+	if err != nil || 1 > len(vectors) || 1 > len(vectors[0]) {
+		util.PushErrMsg(Conf.Language(288), 5000)
+		return
+	}
+
+	scores := sql.SearchBlockVectors(vectors[0], " AND type IN "+typeFilter+boxFilter+pathFilter, Conf.Search.SemanticMinScore, Conf.Search.Limit)
+	if 1 > len(scores) {
+		return
+	}
+
+	var ids []string
+	for _, score := range scores {
+		ids = append(ids, score.ID)
+	}
+	stmt := "SELECT * FROM `blocks` WHERE id IN ('" + strings.Join(ids, "','") + "')" + ignoreFilter
+	blocks := map[string]*sql.Block{}
+	for _, block := range sql.SelectBlocksRawStmtNoParse(stmt, len(ids)) {
+		blocks[block.ID] = block
+	}
+
+	// 按照相似度排序
+	var sqlBlocks []*sql.Block
+	rootIDs := map[string]bool{}
+	for _, score := range scores {
+		if block := blocks[score.ID]; nil != block {
+			sqlBlocks = append(sqlBlocks, block)
+			rootIDs[block.RootID] = true
+			matchedIDs = append(matchedIDs, block.ID)
+		}
+	}
+	matchedBlockCount = len(sqlBlocks)
+	matchedRootCount = len(rootIDs)
+
+	start := (page - 1) * pageSize
+	if start >= len(sqlBlocks) {
+		return
+	}
+	end := min(start+pageSize, len(sqlBlocks))
+	sqlBlocks = sqlBlocks[start:end]
+	ret = fromSQLBlocks(&sqlBlocks, "", beforeLen)
+	return
+}
+
+func getBlockVectorContent(block *sql.Block) (ret string) {
+	switch block.Type {
+	case "l", "s": // 列表和超级块的内容由子块组成，不需要单独生成向量
+		return
+	}
+
+	ret = block.Content
+	if "" != block.Memo {
+		ret += "\n" + block.Memo
+	}
+	ret = strings.TrimSpace(strings.ReplaceAll(ret, editor.Zwsp, ""))
+	ret = gulu.Str.SubStr(ret, blockVectorContentMaxLen)
+	return
+}
+
+// hashBlockVectorContent 计算块内容和嵌入模型的哈希，更换嵌入模型后需要重新生成向量。
+func hashBlockVectorContent(content string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(Conf.AI.OpenAI.APIEmbeddingModel+"\n"+content)))
+}
+
+func isEmbeddingEnabled() bool {
+	return "" != Conf.AI.OpenAI.APIEmbeddingModel && "" != Conf.AI.OpenAI.APIBaseURL
+}
+
+func newEmbeddingClient() *openai.Client {
+	return util.NewOpenAIClient(Conf.AI.OpenAI.APIKey, Conf.AI.OpenAI.APIProxy, Conf.AI.OpenAI.APIBaseURL, Conf.AI.OpenAI.APIUserAgent, Conf.AI.OpenAI.APIVersion, Conf.AI.OpenAI.APIProvider)
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/conf"
 	"github.com/siyuan-note/siyuan/kernel/util"
 	"github.com/steambap/captcha"
 )
@@ -107,7 +108,30 @@ func LoginAuth(c *gin.Context) {
 	authCode = strings.TrimSpace(authCode)
 	authCode = util.RemoveInvalid(authCode)
 
-	if Conf.AccessAuthCode != authCode {
+	var user *conf.WorkspaceUser
+	if username, _ := arg["username"].(string); "" != strings.TrimSpace(username) {
+		// 使用用户名和密码登录
+		if user = authWorkspaceUser(username, authCode); nil == user {
+			ret.Code = -1
+			ret.Msg = Conf.Language(283)
+			logging.LogWarnf("invalid username or password [ip=%s]", util.GetRemoteAddr(c.Request))
+
+			util.WrongAuthCount++
+			workspaceSession.Captcha = gulu.Rand.String(7)
+			if util.NeedCaptcha() {
+				ret.Code = 1 // 需要渲染验证码
+			}
+
+			if err := session.Save(c); err != nil {
+				logging.LogErrorf("save session failed: " + err.Error())
+				session.Clear(c)
+				ret.Code = 1
+				ret.Msg = Conf.Language(258)
+				return
+			}
+			return
+		}
+	} else if Conf.AccessAuthCode != authCode {
 		ret.Code = -1
 		ret.Msg = Conf.Language(83)
 		logging.LogWarnf("invalid auth code [ip=%s]", util.GetRemoteAddr(c.Request))
@@ -128,7 +152,13 @@ func LoginAuth(c *gin.Context) {
 		return
 	}
 
-	workspaceSession.AccessAuthCode = authCode
+	if nil != user {
+		workspaceSession.AccessAuthCode = ""
+		workspaceSession.UserID = user.ID
+	} else {
+		workspaceSession.AccessAuthCode = authCode
+		workspaceSession.UserID = ""
+	}
 	util.WrongAuthCount = 0
 	workspaceSession.Captcha = gulu.Rand.String(7)
 
@@ -144,7 +174,11 @@ func LoginAuth(c *gin.Context) {
 		HttpOnly: true,
 	})
 
-	logging.LogInfof("auth success [ip=%s, maxAge=%d]", util.GetRemoteAddr(c.Request), maxAge)
+	if nil != user {
+		logging.LogInfof("auth success [ip=%s, user=%s, maxAge=%d]", util.GetRemoteAddr(c.Request), user.Name, maxAge)
+	} else {
+		logging.LogInfof("auth success [ip=%s, maxAge=%d]", util.GetRemoteAddr(c.Request), maxAge)
+	}
 	if err := session.Save(c); err != nil {
 		logging.LogErrorf("save session failed: " + err.Error())
 		c.Status(http.StatusInternalServerError)
@@ -219,28 +253,18 @@ func CheckAuth(c *gin.Context) {
 		}
 
 		if "" != token {
-			if Conf.Api.Token == token {
-				c.Set(RoleContextKey, RoleAdministrator)
+			if checkAPIToken(c, token, "header: Authorization") {
 				c.Next()
-				return
 			}
-
-			c.JSON(http.StatusUnauthorized, map[string]interface{}{"code": -1, "msg": "Auth failed [header: Authorization]"})
-			c.Abort()
 			return
 		}
 	}
 
 	// 通过 API token (query-params: token)
 	if token := c.Query("token"); "" != token {
-		if Conf.Api.Token == token {
-			c.Set(RoleContextKey, RoleAdministrator)
+		if checkAPIToken(c, token, "query: token") {
 			c.Next()
-			return
 		}
-
-		c.JSON(http.StatusUnauthorized, map[string]interface{}{"code": -1, "msg": "Auth failed [query: token]"})
-		c.Abort()
 		return
 	}
 
@@ -313,6 +337,12 @@ func CheckAuth(c *gin.Context) {
 	// 通过 Cookie
 	session := util.GetSession(c)
 	workspaceSession := util.GetWorkspaceSession(session)
+	if "" != workspaceSession.UserID {
+		if checkWorkspaceUser(c, workspaceSession.UserID) {
+			c.Next()
+		}
+		return
+	}
 	if workspaceSession.AccessAuthCode == Conf.AccessAuthCode {
 		c.Set(RoleContextKey, RoleAdministrator)
 		c.Next()
@@ -321,11 +351,33 @@ func CheckAuth(c *gin.Context) {
 
 	// 通过 BasicAuth (header: Authorization)
 	if username, password, ok := c.Request.BasicAuth(); ok {
-		// 使用访问授权码作为密码
-		if util.WorkspaceName == username && Conf.AccessAuthCode == password {
-			c.Set(RoleContextKey, RoleAdministrator)
-			c.Next()
-			return
+		if util.NeedCaptcha() {
+			// BasicAuth 无法输入验证码，连续认证失败后只接受最近已经认证通过的用户，其他情况需要先在登录页输入验证码登录
+			if userID := authWorkspaceUserByBasicAuth(username, password, false); "" != userID {
+				if checkWorkspaceUser(c, userID) {
+					c.Next()
+				}
+				return
+			}
+			logging.LogWarnf("too many auth failures, reject basic auth [ip=%s]", util.GetRemoteAddr(c.Request))
+		} else {
+			// 使用访问授权码作为密码
+			if util.WorkspaceName == username && Conf.AccessAuthCode == password {
+				c.Set(RoleContextKey, RoleAdministrator)
+				c.Next()
+				return
+			}
+
+			// 使用用户名和密码
+			if userID := authWorkspaceUserByBasicAuth(username, password, true); "" != userID {
+				if checkWorkspaceUser(c, userID) {
+					c.Next()
+				}
+				return
+			}
+
+			logging.LogWarnf("invalid basic auth [ip=%s]", util.GetRemoteAddr(c.Request))
+			util.WrongAuthCount++
 		}
 	}
 
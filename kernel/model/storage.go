@@ -475,13 +475,14 @@ type Criterion struct {
 	Sort         int                    `json:"sort"`       // 0：按块类型（默认），1：按创建时间升序，2：按创建时间降序，3：按更新时间升序，4：按更新时间降序，5：按内容顺序（仅在按文档分组时）
 	Group        int                    `json:"group"`      // 0：不分组，1：按文档分组
 	HasReplace   bool                   `json:"hasReplace"` // 是否有替换
-	Method       int                    `json:"method"`     // 0：文本，1：查询语法，2：SQL，3：正则表达式
+	Method       int                    `json:"method"`     // 0：文本，1：查询语法，2：SQL，3：正则表达式，4：语义，5：结构化查询
 	HPath        string                 `json:"hPath"`
 	IDPath       []string               `json:"idPath"`
 	K            string                 `json:"k"`            // 搜索关键字
 	R            string                 `json:"r"`            // 替换关键字
 	Types        *CriterionTypes        `json:"types"`        // 类型过滤选项
 	ReplaceTypes *CriterionReplaceTypes `json:"replaceTypes"` // 替换类型过滤选项
+	SmartFolder  bool                   `json:"smartFolder"`  // 是否作为智能文件夹显示在文档树中
 }
 
 type CriterionTypes struct {
@@ -552,6 +553,8 @@ func RemoveCriterion(name string) (err error) {
 	}
 
 	err = setCriteria(criteria)
+	clearCriterionResultCache()
+	util.BroadcastByType("filetree", "reloadSmartFolders", 0, "", nil)
 	return
 }
 
@@ -581,6 +584,8 @@ func SetCriterion(criterion *Criterion) (err error) {
 	}
 
 	err = setCriteria(criteria)
+	clearCriterionResultCache()
+	util.BroadcastByType("filetree", "reloadSmartFolders", 0, "", nil)
 	return
 }
 
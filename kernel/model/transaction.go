@@ -255,6 +255,8 @@ func performTx(tx *Transaction) (ret *TxErr) {
 				ret = tx.doReplaceAttrViewBlock(op)
 			case "updateAttrViewColTemplate":
 				ret = tx.doUpdateAttrViewColTemplate(op)
+			case "updateAttrViewColFormula":
+				ret = tx.doUpdateAttrViewColFormula(op)
 			case "addAttrViewView":
 				ret = tx.doAddAttrViewView(op)
 			case "removeAttrViewView":
@@ -307,6 +309,18 @@ func performTx(tx *Transaction) (ret *TxErr) {
 				ret = tx.doSetAttrViewBlockView(op)
 			case "setAttrViewCardAspectRatio":
 				ret = tx.doSetAttrViewCardAspectRatio(op)
+			case "setAttrViewCalendarDateField":
+				ret = tx.doSetAttrViewCalendarDateField(op)
+			case "setAttrViewCalendarMode":
+				ret = tx.doSetAttrViewCalendarMode(op)
+			case "setAttrViewCalendarStartWeekday":
+				ret = tx.doSetAttrViewCalendarStartWeekday(op)
+			case "setAttrViewCalendarAnchor":
+				ret = tx.doSetAttrViewCalendarAnchor(op)
+			case "setAttrViewTimelineDependencyField":
+				ret = tx.doSetAttrViewTimelineDependencyField(op)
+			case "setAttrViewTimelineScale":
+				ret = tx.doSetAttrViewTimelineScale(op)
 			case "setAttrViewGroup":
 				ret = tx.doSetAttrViewGroup(op)
 			case "hideAttrViewGroup":
@@ -0,0 +1,407 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/88250/lute/ast"
+	"github.com/gin-gonic/gin"
+	gcache "github.com/patrickmn/go-cache"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/conf"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+var (
+	ErrWorkspaceUserNotFound     = errors.New("user not found")
+	ErrWorkspaceUserExists       = errors.New("user already exists")
+	ErrWorkspaceUserInvalidName  = errors.New("invalid user name")
+	ErrWorkspaceUserInvalidPwd   = errors.New("password must not be empty")
+	ErrWorkspaceUserInvalidRole  = errors.New("invalid user role")
+	ErrWorkspaceUserInvalidScope = errors.New("invalid notebook role")
+)
+
+const userPasswordIterations = 210000
+
+var workspaceUserLock = sync.Mutex{}
+
+// GetWorkspaceUsers 返回所有工作空间用户，返回的用户中不包含密码哈希值。
+func GetWorkspaceUsers() (ret []*conf.WorkspaceUser) {
+	workspaceUserLock.Lock()
+	defer workspaceUserLock.Unlock()
+
+	ret = []*conf.WorkspaceUser{}
+	for _, user := range Conf.Users {
+		ret = append(ret, cloneWorkspaceUser(user))
+	}
+	return
+}
+
+// CreateWorkspaceUser 创建工作空间用户。
+func CreateWorkspaceUser(name, password string, role conf.AccessRole, notebooks map[string]conf.AccessRole) (ret *conf.WorkspaceUser, err error) {
+	name = strings.TrimSpace(name)
+	if err = checkWorkspaceUserArgs(name, role, notebooks); nil != err {
+		return
+	}
+	if "" == password {
+		err = ErrWorkspaceUserInvalidPwd
+		return
+	}
+
+	workspaceUserLock.Lock()
+	defer workspaceUserLock.Unlock()
+
+	if nil != getWorkspaceUserByName(name) {
+		err = ErrWorkspaceUserExists
+		return
+	}
+
+	now := util.CurrentTimeMillis()
+	user := &conf.WorkspaceUser{
+		ID:        ast.NewNodeID(),
+		Name:      name,
+		Password:  hashUserPassword(password),
+		Role:      role,
+		Notebooks: notebooks,
+		Created:   now,
+		Updated:   now,
+	}
+	if nil == user.Notebooks {
+		user.Notebooks = map[string]conf.AccessRole{}
+	}
+	Conf.Users = append(Conf.Users, user)
+	Conf.Save()
+	logging.LogInfof("created user [%s, %s]", user.ID, user.Name)
+
+	ret = cloneWorkspaceUser(user)
+	return
+}
+
+// UpdateWorkspaceUser 更新工作空间用户，password 为空时不修改密码。
+func UpdateWorkspaceUser(id, name, password string, role conf.AccessRole, notebooks map[string]conf.AccessRole, disabled bool) (err error) {
+	name = strings.TrimSpace(name)
+	if err = checkWorkspaceUserArgs(name, role, notebooks); nil != err {
+		return
+	}
+
+	workspaceUserLock.Lock()
+	defer workspaceUserLock.Unlock()
+
+	user := getWorkspaceUserByID(id)
+	if nil == user {
+		err = ErrWorkspaceUserNotFound
+		return
+	}
+	if existUser := getWorkspaceUserByName(name); nil != existUser && existUser.ID != id {
+		err = ErrWorkspaceUserExists
+		return
+	}
+
+	user.Name = name
+	if "" != password {
+		user.Password = hashUserPassword(password)
+	}
+	user.Role = role
+	user.Notebooks = notebooks
+	if nil == user.Notebooks {
+		user.Notebooks = map[string]conf.AccessRole{}
+	}
+	user.Disabled = disabled
+	user.Updated = util.CurrentTimeMillis()
+	Conf.Save()
+	basicAuthCache.Flush()
+	util.CloseUserSessions(id)
+	return
+}
+
+// RemoveWorkspaceUser 删除工作空间用户，该用户已经登录的会话会立即失效。
+func RemoveWorkspaceUser(id string) (err error) {
+	workspaceUserLock.Lock()
+	defer workspaceUserLock.Unlock()
+
+	for i, user := range Conf.Users {
+		if user.ID == id {
+			Conf.Users = append(Conf.Users[:i], Conf.Users[i+1:]...)
+			Conf.Save()
+			logging.LogInfof("removed user [%s, %s]", user.ID, user.Name)
+			basicAuthCache.Flush()
+			util.CloseUserSessions(id)
+			return
+		}
+	}
+	err = ErrWorkspaceUserNotFound
+	return
+}
+
+// IsValidWorkspaceUser 判断会话中的用户是否存在且没有被禁用。
+func IsValidWorkspaceUser(id string) bool {
+	if "" == id {
+		return false
+	}
+
+	workspaceUserLock.Lock()
+	defer workspaceUserLock.Unlock()
+
+	user := getWorkspaceUserByID(id)
+	return nil != user && !user.Disabled
+}
+
+// IsNotebookScopedWorkspaceUser 判断用户是否只能访问部分笔记本。
+func IsNotebookScopedWorkspaceUser(id string) bool {
+	workspaceUserLock.Lock()
+	defer workspaceUserLock.Unlock()
+
+	user := getWorkspaceUserByID(id)
+	return nil != user && conf.AccessRoleAdmin != user.Role && 0 < len(user.Notebooks)
+}
+
+// authWorkspaceUser 使用用户名和密码认证用户，认证失败时返回 nil。
+func authWorkspaceUser(name, password string) *conf.WorkspaceUser {
+	workspaceUserLock.Lock()
+	user := getWorkspaceUserByName(strings.TrimSpace(name))
+	var hashed string
+	if nil != user {
+		hashed = user.Password
+		user = cloneWorkspaceUser(user)
+	}
+	workspaceUserLock.Unlock()
+
+	// 口令校验耗时较长，不能持有锁，否则会阻塞其他请求的用户查询
+	if nil == user || user.Disabled || !checkUserPassword(hashed, password) {
+		return nil
+	}
+	return user
+}
+
+// basicAuthCache 缓存最近通过 BasicAuth 认证的用户，避免 WebDAV 等客户端的每个请求都重新计算 PBKDF2。
+var basicAuthCache = gcache.New(5*time.Minute, 10*time.Minute) // [basicAuthCacheKey]userID
+
+// authWorkspaceUserByBasicAuth 使用 BasicAuth 的用户名和密码认证用户，认证成功的结果会短暂缓存。
+// verify 为 false 时只查找缓存，不校验口令。
+func authWorkspaceUserByBasicAuth(name, password string, verify bool) (userID string) {
+	key := basicAuthCacheKey(name, password)
+	if val, ok := basicAuthCache.Get(key); ok {
+		return val.(string)
+	}
+	if !verify {
+		return
+	}
+
+	user := authWorkspaceUser(name, password)
+	if nil == user {
+		return
+	}
+	basicAuthCache.SetDefault(key, user.ID)
+	userID = user.ID
+	return
+}
+
+func basicAuthCacheKey(name, password string) string {
+	hash := sha256.Sum256([]byte(strings.TrimSpace(name) + "\x00" + password))
+	return hex.EncodeToString(hash[:])
+}
+
+// checkWorkspaceUser 根据用户在请求涉及的笔记本上的角色设置当前请求的角色，失败时已经写入响应。
+func checkWorkspaceUser(c *gin.Context, id string) bool {
+	workspaceUserLock.Lock()
+	user := getWorkspaceUserByID(id)
+	if nil != user {
+		user = cloneWorkspaceUser(user)
+	}
+	workspaceUserLock.Unlock()
+
+	if nil == user || user.Disabled {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"code": -1, "msg": "Auth failed [user]"})
+		c.Abort()
+		return false
+	}
+
+	// 管理员不受笔记本限制
+	if conf.AccessRoleAdmin == user.Role || 1 > len(user.Notebooks) {
+		c.Set(RoleContextKey, getAccessRole(user.Role))
+		return true
+	}
+
+	var notebooks []string
+	for notebook := range user.Notebooks {
+		notebooks = append(notebooks, notebook)
+	}
+
+	boxIDs, ok := getNotebookScopedRequestBoxIDs(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, map[string]interface{}{"code": -1, "msg": "Access denied: API not allowed for user [" + user.Name + "]"})
+		c.Abort()
+		return false
+	}
+
+	role := getAccessRole(user.Role)
+	if 0 < len(boxIDs) {
+		// 请求涉及多个笔记本时使用其中权限最低的角色
+		role = RoleAdministrator
+		for _, boxID := range boxIDs {
+			notebookRole, exists := user.Notebooks[boxID]
+			if !exists {
+				c.JSON(http.StatusForbidden, map[string]interface{}{"code": -1, "msg": "Access denied: notebook not allowed for user [" + user.Name + "]"})
+				c.Abort()
+				return false
+			}
+
+			if r := getAccessRole(notebookRole); r > role {
+				role = r
+			}
+		}
+	}
+
+	c.Set(RoleContextKey, role)
+	c.Set(NotebooksContextKey, notebooks)
+	return true
+}
+
+// FilterBlocksByContextNotebooks 过滤掉当前请求不允许访问的笔记本中的块。
+func FilterBlocksByContextNotebooks(c *gin.Context, blocks []*Block) (ret []*Block) {
+	if _, scoped := GetGinContextNotebooks(c); !scoped {
+		return blocks
+	}
+
+	ret = []*Block{}
+	for _, block := range blocks {
+		if IsNotebookAllowedContext(c, block.Box) {
+			ret = append(ret, block)
+		}
+	}
+	return
+}
+
+// FilterRecentDocsByContextNotebooks 过滤掉当前请求不允许访问的笔记本中的最近文档。
+func FilterRecentDocsByContextNotebooks(c *gin.Context, docs []*RecentDoc) (ret []*RecentDoc) {
+	if _, scoped := GetGinContextNotebooks(c); !scoped {
+		return docs
+	}
+
+	ret = []*RecentDoc{}
+	for _, doc := range docs {
+		if bt := treenode.GetBlockTree(doc.RootID); nil != bt && IsNotebookAllowedContext(c, bt.BoxID) {
+			ret = append(ret, doc)
+		}
+	}
+	return
+}
+
+func getAccessRole(role conf.AccessRole) Role {
+	switch role {
+	case conf.AccessRoleAdmin:
+		return RoleAdministrator
+	case conf.AccessRoleEditor:
+		return RoleEditor
+	default:
+		return RoleReader
+	}
+}
+
+func checkWorkspaceUserArgs(name string, role conf.AccessRole, notebooks map[string]conf.AccessRole) error {
+	if "" == name || util.WorkspaceName == name {
+		// 工作空间名称用于 BasicAuth 访问授权码登录，不能作为用户名
+		return ErrWorkspaceUserInvalidName
+	}
+	if !role.IsValid() {
+		return ErrWorkspaceUserInvalidRole
+	}
+	for _, notebookRole := range notebooks {
+		if !notebookRole.IsValid() || conf.AccessRoleAdmin == notebookRole {
+			return ErrWorkspaceUserInvalidScope
+		}
+	}
+	return nil
+}
+
+func getWorkspaceUserByID(id string) *conf.WorkspaceUser {
+	for _, user := range Conf.Users {
+		if user.ID == id {
+			return user
+		}
+	}
+	return nil
+}
+
+func getWorkspaceUserByName(name string) *conf.WorkspaceUser {
+	for _, user := range Conf.Users {
+		if user.Name == name {
+			return user
+		}
+	}
+	return nil
+}
+
+func cloneWorkspaceUser(user *conf.WorkspaceUser) (ret *conf.WorkspaceUser) {
+	cloned := *user
+	cloned.Password = ""
+	cloned.Notebooks = map[string]conf.AccessRole{}
+	for notebook, role := range user.Notebooks {
+		cloned.Notebooks[notebook] = role
+	}
+	return &cloned
+}
+
+func hashUserPassword(password string) string {
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	key, err := pbkdf2.Key(sha256.New, password, salt, userPasswordIterations, 32)
+	if nil != err {
+		logging.LogErrorf("hash password failed: %s", err)
+		return ""
+	}
+	return "pbkdf2-sha256$" + strconv.Itoa(userPasswordIterations) + "$" + base64.RawStdEncoding.EncodeToString(salt) + "$" + base64.RawStdEncoding.EncodeToString(key)
+}
+
+func checkUserPassword(hashed, password string) bool {
+	parts := strings.Split(hashed, "$")
+	if 4 != len(parts) || "pbkdf2-sha256" != parts[0] {
+		return false
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if nil != err || 1 > iterations {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if nil != err {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if nil != err {
+		return false
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(expected))
+	if nil != err {
+		return false
+	}
+	return 1 == subtle.ConstantTimeCompare(key, expected)
+}
@@ -520,6 +520,7 @@ func serveAuthPage(c *gin.Context) {
 		"l8":                     model.Conf.Language(95),
 		"l9":                     model.Conf.Language(83),
 		"l10":                    model.Conf.Language(257),
+		"l11":                    model.Conf.Language(282),
 		"appearanceMode":         model.Conf.Appearance.Mode,
 		"appearanceModeOS":       model.Conf.Appearance.ModeOS,
 		"workspace":              util.WorkspaceName,
@@ -663,6 +664,7 @@ func serveWebSocket(ginServer *gin.Engine) {
 	util.WebSocketServer.HandleConnect(func(s *melody.Session) {
 		//logging.LogInfof("ws check auth for [%s]", s.Request.RequestURI)
 		authOk := true
+		var workspaceUserID string
 
 		if "" != model.Conf.AccessAuthCode {
 			session, err := sessionStore.Get(s.Request, "siyuan")
@@ -682,6 +684,10 @@ func serveWebSocket(ginServer *gin.Engine) {
 					} else {
 						workspaceSess := util.GetWorkspaceSession(sess)
 						authOk = workspaceSess.AccessAuthCode == model.Conf.AccessAuthCode
+						if !authOk && model.IsValidWorkspaceUser(workspaceSess.UserID) {
+							authOk = true
+							workspaceUserID = workspaceSess.UserID
+						}
 					}
 				}
 			}
@@ -716,6 +722,14 @@ func serveWebSocket(ginServer *gin.Engine) {
 			}
 		}
 
+		// 标记工作空间用户的连接，限制了笔记本的用户不接收广播消息
+		if "" != workspaceUserID {
+			s.Set("userID", workspaceUserID)
+			if model.IsNotebookScopedWorkspaceUser(workspaceUserID) {
+				s.Set("notebookScoped", true)
+			}
+		}
+
 		util.AddPushChan(s)
 		//sessionId, _ := s.Get("id")
 		//logging.LogInfof("ws [%s] connected", sessionId)
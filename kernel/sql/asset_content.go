@@ -70,12 +70,12 @@ func insertAssetContents0(tx *sql.Tx, bulk []*AssetContent, context map[string]i
 	for _, b := range bulk {
 		valueStrings = append(valueStrings, AssetContentsPlaceholder)
 		valueArgs = append(valueArgs, b.ID)
-		valueArgs = append(valueArgs, b.Name)
+		valueArgs = append(valueArgs, segmentFTSValue(b.Name))
 		valueArgs = append(valueArgs, b.Ext)
 		valueArgs = append(valueArgs, b.Path)
 		valueArgs = append(valueArgs, b.Size)
 		valueArgs = append(valueArgs, b.Updated)
-		valueArgs = append(valueArgs, b.Content)
+		valueArgs = append(valueArgs, segmentFTSValue(b.Content))
 	}
 
 	stmt := fmt.Sprintf(AssetContentsFTSCaseInsensitiveInsert, strings.Join(valueStrings, ","))
@@ -176,6 +176,7 @@ func scanAssetContentRows(rows *sql.Rows) (ret *AssetContent) {
 		logging.LogErrorf("query scan field failed: %s\n%s", err, logging.ShortStack())
 		return
 	}
+	ac.Name, ac.Content = unsegmentFTSValue(ac.Name), unsegmentFTSValue(ac.Content)
 	ret = &ac
 	return
 }
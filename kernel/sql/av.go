@@ -65,6 +65,25 @@ func RenderGroupView(attrView *av.AttributeView, view, groupView *av.View, query
 		groupView.Kanban.FitImage = view.Kanban.FitImage
 		groupView.Kanban.DisplayFieldName = view.Kanban.DisplayFieldName
 		groupView.Kanban.FillColBackgroundColor = view.Kanban.FillColBackgroundColor
+	case av.LayoutTypeCalendar:
+		err = copier.CopyWithOption(&groupView.Calendar.Fields, &view.Calendar.Fields, copier.Option{DeepCopy: true})
+		groupView.Calendar.ShowIcon = view.Calendar.ShowIcon
+		groupView.Calendar.WrapField = view.Calendar.WrapField
+
+		groupView.Calendar.DateFieldID = view.Calendar.DateFieldID
+		groupView.Calendar.Mode = view.Calendar.Mode
+		groupView.Calendar.StartWeekday = view.Calendar.StartWeekday
+		groupView.Calendar.Anchor = view.Calendar.Anchor
+		groupView.Calendar.DisplayFieldName = view.Calendar.DisplayFieldName
+	case av.LayoutTypeTimeline:
+		err = copier.CopyWithOption(&groupView.Timeline.Fields, &view.Timeline.Fields, copier.Option{DeepCopy: true})
+		groupView.Timeline.ShowIcon = view.Timeline.ShowIcon
+		groupView.Timeline.WrapField = view.Timeline.WrapField
+
+		groupView.Timeline.DateFieldID = view.Timeline.DateFieldID
+		groupView.Timeline.DependencyFieldID = view.Timeline.DependencyFieldID
+		groupView.Timeline.Scale = view.Timeline.Scale
+		groupView.Timeline.DisplayFieldName = view.Timeline.DisplayFieldName
 	}
 	if nil != err {
 		logging.LogErrorf("copy view fields [%s] to group [%s] failed: %s", view.ID, groupView.ID, err)
@@ -75,6 +94,10 @@ func RenderGroupView(attrView *av.AttributeView, view, groupView *av.View, query
 			groupView.Gallery.CardFields = view.Gallery.CardFields
 		case av.LayoutTypeKanban:
 			groupView.Kanban.Fields = view.Kanban.Fields
+		case av.LayoutTypeCalendar:
+			groupView.Calendar.Fields = view.Calendar.Fields
+		case av.LayoutTypeTimeline:
+			groupView.Timeline.Fields = view.Timeline.Fields
 		}
 	}
 
@@ -107,6 +130,10 @@ func renderView(attrView *av.AttributeView, view *av.View, query string, depth *
 		ret = RenderAttributeViewGallery(attrView, view, query, depth, cachedAttrViews)
 	case av.LayoutTypeKanban:
 		ret = RenderAttributeViewKanban(attrView, view, query, depth, cachedAttrViews)
+	case av.LayoutTypeCalendar:
+		ret = RenderAttributeViewCalendar(attrView, view, query, depth, cachedAttrViews)
+	case av.LayoutTypeTimeline:
+		ret = RenderAttributeViewTimeline(attrView, view, query, depth, cachedAttrViews)
 	}
 	return
 }
@@ -348,6 +375,8 @@ func fillAttributeViewBaseValue(baseValue *av.BaseValue, fieldID, itemID string,
 		baseValue.Value = &av.Value{ID: baseValue.ID, KeyID: fieldID, BlockID: itemID, Type: av.KeyTypeCreated}
 	case av.KeyTypeUpdated: // 填充更新时间字段值，后面再渲染
 		baseValue.Value = &av.Value{ID: baseValue.ID, KeyID: fieldID, BlockID: itemID, Type: av.KeyTypeUpdated}
+	case av.KeyTypeFormula: // 填充公式字段值，后面再计算
+		baseValue.Value = &av.Value{ID: baseValue.ID, KeyID: fieldID, BlockID: itemID, Type: av.KeyTypeFormula, Formula: &av.ValueFormula{}}
 	}
 
 	if nil == baseValue.Value {
@@ -375,60 +404,9 @@ func fillAttributeViewAutoGeneratedValues(attrView *av.AttributeView, collection
 					}
 				}
 			case av.KeyTypeCreated: // 渲染创建时间
-				key, _ := attrView.GetKey(value.KeyID)
-				isNotTime := false
-				if nil != key && nil != key.Created {
-					isNotTime = !key.Created.IncludeTime
-				}
-
-				ial := map[string]string{}
-				block := item.GetBlockValue()
-				if nil != block {
-					ial = ials[block.Block.ID]
-				}
-				if nil == ial {
-					ial = map[string]string{}
-				}
-				id := itemID
-				if "" != ial["id"] {
-					id = ial["id"]
-				}
-				createdStr := id[:len("20060102150405")]
-				created, parseErr := time.ParseInLocation("20060102150405", createdStr, time.Local)
-				if nil == parseErr {
-					value.Created = av.NewFormattedValueCreated(created.UnixMilli(), 0, av.CreatedFormatNone, isNotTime)
-					value.Created.IsNotEmpty = true
-				} else {
-					value.Created = av.NewFormattedValueCreated(time.Now().UnixMilli(), 0, av.CreatedFormatNone, isNotTime)
-				}
+				fillAttributeViewCreatedValue(attrView, value, itemID, item.GetBlockValue(), ials)
 			case av.KeyTypeUpdated: // 渲染更新时间
-				key, _ := attrView.GetKey(value.KeyID)
-				isNotTime := false
-				if nil != key && nil != key.Updated {
-					isNotTime = !key.Updated.IncludeTime
-				}
-
-				ial := map[string]string{}
-				block := item.GetBlockValue()
-				if nil != block {
-					ial = ials[block.Block.ID]
-				}
-				if nil == ial {
-					ial = map[string]string{}
-				}
-				updatedStr := ial["updated"]
-				if "" == updatedStr && nil != block {
-					value.Updated = av.NewFormattedValueUpdated(block.Block.Updated, 0, av.UpdatedFormatNone, isNotTime)
-					value.Updated.IsNotEmpty = true
-				} else {
-					updated, parseErr := time.ParseInLocation("20060102150405", updatedStr, time.Local)
-					if nil == parseErr {
-						value.Updated = av.NewFormattedValueUpdated(updated.UnixMilli(), 0, av.UpdatedFormatNone, isNotTime)
-						value.Updated.IsNotEmpty = true
-					} else {
-						value.Updated = av.NewFormattedValueUpdated(time.Now().UnixMilli(), 0, av.UpdatedFormatNone, isNotTime)
-					}
-				}
+				fillAttributeViewUpdatedValue(attrView, value, item.GetBlockValue(), ials)
 			}
 		}
 	}
@@ -503,12 +481,13 @@ func fillAttributeViewAutoGeneratedValues(attrView *av.AttributeView, collection
 
 		isSameAv := destAv.ID == attrView.ID
 		var furtherCollection av.Collection
-		if av.KeyTypeTemplate == destKey.Type || (!isSameAv && (av.KeyTypeUpdated == destKey.Type || av.KeyTypeCreated == destKey.Type || av.KeyTypeRelation == destKey.Type)) {
+		if av.KeyTypeTemplate == destKey.Type || av.KeyTypeFormula == destKey.Type || (!isSameAv && (av.KeyTypeUpdated == destKey.Type || av.KeyTypeCreated == destKey.Type || av.KeyTypeRelation == destKey.Type)) {
 			viewable := renderView(destAv, destAv.Views[0], "", depth, cachedAttrViews)
 			if nil != viewable {
 				furtherCollection = viewable.(av.Collection)
 			} else {
 				fillAttributeViewTemplateValues(destAv, destAv.Views[0], collection, ials)
+				fillAttributeViewFormulaValues(destAv, collection)
 				furtherCollection = collection
 			}
 		}
@@ -590,7 +569,7 @@ func GetFurtherCollections(attrView *av.AttributeView, cachedAttrViews map[strin
 		isSameAv := destAv.ID == attrView.ID
 
 		var furtherCollection av.Collection
-		if av.KeyTypeTemplate == destKey.Type || (!isSameAv && (av.KeyTypeUpdated == destKey.Type || av.KeyTypeCreated == destKey.Type || av.KeyTypeRelation == destKey.Type)) {
+		if av.KeyTypeTemplate == destKey.Type || av.KeyTypeFormula == destKey.Type || (!isSameAv && (av.KeyTypeUpdated == destKey.Type || av.KeyTypeCreated == destKey.Type || av.KeyTypeRelation == destKey.Type)) {
 			viewable := RenderView(destAv, destAv.Views[0], "")
 			if nil != viewable {
 				furtherCollection = viewable.(av.Collection)
@@ -649,6 +628,39 @@ func fillAttributeViewTemplateValues(attrView *av.AttributeView, view *av.View,
 	return
 }
 
+func fillAttributeViewFormulaValues(attrView *av.AttributeView, collection av.Collection) {
+	formulaKeys, cycleKeys := av.GetFormulaKeysByResolutionOrder(attrView)
+	if 1 > len(formulaKeys) && 1 > len(cycleKeys) {
+		return
+	}
+
+	for _, cycleKey := range cycleKeys {
+		for _, item := range collection.GetItems() {
+			if value := item.GetValue(cycleKey.ID); nil != value {
+				value.Formula = &av.ValueFormula{Err: av.ErrFormulaCycle.Error()}
+			}
+		}
+	}
+
+	// 按依赖顺序计算，这样公式就可以引用其他公式字段的计算结果了
+	now := time.Now()
+	for _, formulaKey := range formulaKeys {
+		expr, parseErr := av.ParseFormula(formulaKey.Formula.Expression)
+		for _, item := range collection.GetItems() {
+			value := item.GetValue(formulaKey.ID)
+			if nil == value {
+				continue
+			}
+
+			if nil != parseErr {
+				value.Formula = &av.ValueFormula{Err: parseErr.Error()}
+				continue
+			}
+			value.Formula = expr.Eval(attrView, item, formulaKey.NumberFormat, now)
+		}
+	}
+}
+
 func fillAttributeViewKeyValues(attrView *av.AttributeView, collection av.Collection) {
 	fieldValues := map[string][]*av.Value{}
 	for _, item := range collection.GetItems() {
@@ -738,6 +750,10 @@ func FillAttributeViewNilValue(value *av.Value, typ av.KeyType) {
 		if nil == value.Rollup {
 			value.Rollup = &av.ValueRollup{}
 		}
+	case av.KeyTypeFormula:
+		if nil == value.Formula {
+			value.Formula = &av.ValueFormula{}
+		}
 	}
 }
 
@@ -793,6 +809,26 @@ func removeMissingField(attrView *av.AttributeView, view *av.View, missingKeyID
 		}
 	}
 
+	if nil != view.Calendar {
+		for i, calendarField := range view.Calendar.Fields {
+			if calendarField.ID == missingKeyID {
+				view.Calendar.Fields = append(view.Calendar.Fields[:i], view.Calendar.Fields[i+1:]...)
+				changed = true
+				break
+			}
+		}
+	}
+
+	if nil != view.Timeline {
+		for i, timelineField := range view.Timeline.Fields {
+			if timelineField.ID == missingKeyID {
+				view.Timeline.Fields = append(view.Timeline.Fields[:i], view.Timeline.Fields[i+1:]...)
+				changed = true
+				break
+			}
+		}
+	}
+
 	if changed {
 		av.SaveAttributeView(attrView)
 	}
@@ -990,3 +1026,81 @@ func collectVars(node parse.Node, vars map[string]struct{}) {
 		}
 	}
 }
+
+func fillAttributeViewCreatedValue(attrView *av.AttributeView, value *av.Value, itemID string, block *av.Value, ials map[string]map[string]string) {
+	key, _ := attrView.GetKey(value.KeyID)
+	isNotTime := false
+	if nil != key && nil != key.Created {
+		isNotTime = !key.Created.IncludeTime
+	}
+
+	ial := map[string]string{}
+	if nil != block {
+		ial = ials[block.Block.ID]
+	}
+	if nil == ial {
+		ial = map[string]string{}
+	}
+	id := itemID
+	if "" != ial["id"] {
+		id = ial["id"]
+	}
+	createdStr := id[:len("20060102150405")]
+	created, parseErr := time.ParseInLocation("20060102150405", createdStr, time.Local)
+	if nil == parseErr {
+		value.Created = av.NewFormattedValueCreated(created.UnixMilli(), 0, av.CreatedFormatNone, isNotTime)
+		value.Created.IsNotEmpty = true
+	} else {
+		value.Created = av.NewFormattedValueCreated(time.Now().UnixMilli(), 0, av.CreatedFormatNone, isNotTime)
+	}
+}
+
+func fillAttributeViewUpdatedValue(attrView *av.AttributeView, value *av.Value, block *av.Value, ials map[string]map[string]string) {
+	key, _ := attrView.GetKey(value.KeyID)
+	isNotTime := false
+	if nil != key && nil != key.Updated {
+		isNotTime = !key.Updated.IncludeTime
+	}
+
+	ial := map[string]string{}
+	if nil != block {
+		ial = ials[block.Block.ID]
+	}
+	if nil == ial {
+		ial = map[string]string{}
+	}
+	updatedStr := ial["updated"]
+	if "" == updatedStr && nil != block {
+		value.Updated = av.NewFormattedValueUpdated(block.Block.Updated, 0, av.UpdatedFormatNone, isNotTime)
+		value.Updated.IsNotEmpty = true
+	} else {
+		updated, parseErr := time.ParseInLocation("20060102150405", updatedStr, time.Local)
+		if nil == parseErr {
+			value.Updated = av.NewFormattedValueUpdated(updated.UnixMilli(), 0, av.UpdatedFormatNone, isNotTime)
+			value.Updated.IsNotEmpty = true
+		} else {
+			value.Updated = av.NewFormattedValueUpdated(time.Now().UnixMilli(), 0, av.UpdatedFormatNone, isNotTime)
+		}
+	}
+}
+
+// getAttributeViewItemDateValue 获取条目的日期字段值，用于日期字段没有在视图中显示的情况。
+// 创建时间和更新时间字段没有存储值，需要和显示的字段一样根据绑定块的属性计算。
+func getAttributeViewItemDateValue(attrView *av.AttributeView, keyID, itemID string, ials map[string]map[string]string) (ret *av.Value) {
+	key, _ := attrView.GetKey(keyID)
+	if nil == key {
+		return
+	}
+
+	switch key.Type {
+	case av.KeyTypeCreated:
+		ret = &av.Value{KeyID: keyID, BlockID: itemID, Type: av.KeyTypeCreated}
+		fillAttributeViewCreatedValue(attrView, ret, itemID, attrView.GetBlockValue(itemID), ials)
+	case av.KeyTypeUpdated:
+		ret = &av.Value{KeyID: keyID, BlockID: itemID, Type: av.KeyTypeUpdated}
+		fillAttributeViewUpdatedValue(attrView, ret, attrView.GetBlockValue(itemID), ials)
+	default:
+		ret = attrView.GetValue(keyID, itemID)
+	}
+	return
+}
@@ -0,0 +1,162 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sql
+
+import (
+	"fmt"
+
+	"github.com/88250/lute/ast"
+	"github.com/siyuan-note/siyuan/kernel/av"
+	"github.com/siyuan-note/siyuan/kernel/filesys"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+func RenderAttributeViewCalendar(attrView *av.AttributeView, view *av.View, query string, depth *int, cachedAttrViews map[string]*av.AttributeView) (ret *av.Calendar) {
+	viewable := attrView.RenderedViewables[view.ID]
+	if nil != viewable {
+		ret = viewable.(*av.Calendar)
+		return
+	}
+
+	ret = &av.Calendar{
+		BaseInstance:     av.NewViewBaseInstance(view),
+		DateFieldID:      view.Calendar.DateFieldID,
+		Mode:             view.Calendar.Mode,
+		StartWeekday:     view.Calendar.StartWeekday,
+		Anchor:           view.Calendar.Anchor,
+		DisplayFieldName: view.Calendar.DisplayFieldName,
+		Fields:           []*av.CalendarField{},
+		Events:           []*av.CalendarEvent{},
+		Days:             []*av.CalendarDay{},
+	}
+
+	// 组装字段
+	for _, field := range view.Calendar.Fields {
+		key, getErr := attrView.GetKey(field.ID)
+		if nil != getErr {
+			// 找不到字段则在视图中删除
+			removeMissingField(attrView, view, field.ID)
+			continue
+		}
+
+		ret.Fields = append(ret.Fields, &av.CalendarField{
+			BaseInstanceField: &av.BaseInstanceField{
+				ID:           key.ID,
+				Name:         key.Name,
+				Type:         key.Type,
+				Icon:         key.Icon,
+				Wrap:         field.Wrap,
+				Hidden:       field.Hidden,
+				Desc:         key.Desc,
+				Calc:         field.Calc,
+				Options:      key.Options,
+				NumberFormat: key.NumberFormat,
+				Template:     key.Template,
+				Relation:     key.Relation,
+				Rollup:       key.Rollup,
+				Formula:      key.Formula,
+				Date:         key.Date,
+				Created:      key.Created,
+				Updated:      key.Updated,
+			},
+		})
+	}
+
+	eventsValues := generateAttrViewItems(attrView, view) // 生成事项
+	filterNotFoundAttrViewItems(eventsValues)             // 过滤掉不存在的事项
+
+	// 批量加载绑定块对应的树
+	var ialIDs []string
+	for _, keyValues := range eventsValues {
+		for _, kValues := range keyValues {
+			blockVal := kValues.GetBlockValue()
+			if nil != blockVal && !blockVal.IsDetached {
+				ialIDs = append(ialIDs, blockVal.Block.ID)
+			}
+		}
+	}
+	boundTrees := filesys.LoadTrees(ialIDs)
+
+	// 生成事项字段值
+	for eventID, eventValues := range eventsValues {
+		var calendarEvent av.CalendarEvent
+		for _, field := range ret.Fields {
+			var fieldValue *av.CalendarFieldValue
+			for _, keyValues := range eventValues {
+				if keyValues.Key.ID == field.ID {
+					fieldValue = &av.CalendarFieldValue{
+						BaseValue: &av.BaseValue{
+							ID:        keyValues.Values[0].ID,
+							Value:     keyValues.Values[0],
+							ValueType: field.Type,
+						},
+					}
+					break
+				}
+			}
+			if nil == fieldValue {
+				fieldValue = &av.CalendarFieldValue{
+					BaseValue: &av.BaseValue{
+						ID:        eventID[:14] + ast.NewNodeID()[14:],
+						ValueType: field.Type,
+					},
+				}
+			}
+			calendarEvent.ID = eventID
+
+			filedDateIsTime := false
+			if nil != field.Date {
+				filedDateIsTime = field.Date.FillSpecificTime
+			}
+			fillAttributeViewBaseValue(fieldValue.BaseValue, field.ID, eventID, field.NumberFormat, field.Template, filedDateIsTime)
+			calendarEvent.Values = append(calendarEvent.Values, fieldValue)
+		}
+		ret.Events = append(ret.Events, &calendarEvent)
+	}
+
+	// 回填补全数据
+	fillAttributeViewKeyValues(attrView, ret)
+
+	// 批量获取块属性以提升性能
+	ials := BatchGetBlockAttrsWitTrees(ialIDs, boundTrees)
+
+	// 渲染自动生成的字段值，比如关联、汇总、创建时间和更新时间
+	fillAttributeViewAutoGeneratedValues(attrView, ret, ials, depth, cachedAttrViews)
+
+	// 最后渲染模板字段，这样模板就可以使用汇总、关联、创建时间和更新时间的值了
+	renderTemplateErr := fillAttributeViewTemplateValues(attrView, view, ret, ials)
+	if nil != renderTemplateErr {
+		util.PushErrMsg(fmt.Sprintf(util.Langs[util.Lang][44], util.EscapeHTML(renderTemplateErr.Error())), 30000)
+	}
+
+	// 渲染公式字段，公式可以引用包括模板在内的所有字段值
+	fillAttributeViewFormulaValues(attrView, ret)
+
+	// 根据日期字段计算事项的起止时间
+	for _, event := range ret.Events {
+		dateValue := event.GetValue(ret.DateFieldID)
+		if nil == dateValue {
+			// 日期字段没有在视图中显示时从属性视图中获取或者计算
+			dateValue = getAttributeViewItemDateValue(attrView, ret.DateFieldID, event.ID, ials)
+		}
+		event.SetTime(dateValue)
+	}
+
+	filterByQuery(query, ret)
+	manualSort(view, ret)
+	return
+}
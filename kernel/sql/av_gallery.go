@@ -61,6 +61,7 @@ func RenderAttributeViewGallery(attrView *av.AttributeView, view *av.View, query
 				Template:     key.Template,
 				Relation:     key.Relation,
 				Rollup:       key.Rollup,
+				Formula:      key.Formula,
 				Date:         key.Date,
 				Created:      key.Created,
 				Updated:      key.Updated,
@@ -137,6 +138,9 @@ func RenderAttributeViewGallery(attrView *av.AttributeView, view *av.View, query
 		util.PushErrMsg(fmt.Sprintf(util.Langs[util.Lang][44], util.EscapeHTML(renderTemplateErr.Error())), 30000)
 	}
 
+	// 渲染公式字段，公式可以引用包括模板在内的所有字段值
+	fillAttributeViewFormulaValues(attrView, ret)
+
 	filterByQuery(query, ret)
 	manualSort(view, ret)
 	return
@@ -59,6 +59,7 @@ func RenderAttributeViewTable(attrView *av.AttributeView, view *av.View, query s
 				Template:     key.Template,
 				Relation:     key.Relation,
 				Rollup:       key.Rollup,
+				Formula:      key.Formula,
 				Date:         key.Date,
 				Created:      key.Created,
 				Updated:      key.Updated,
@@ -130,6 +131,9 @@ func RenderAttributeViewTable(attrView *av.AttributeView, view *av.View, query s
 		util.PushErrMsg(fmt.Sprintf(util.Langs[util.Lang][44], util.EscapeHTML(renderTemplateErr.Error())), 30000)
 	}
 
+	// 渲染公式字段，公式可以引用包括模板在内的所有字段值
+	fillAttributeViewFormulaValues(attrView, ret)
+
 	filterByQuery(query, ret)
 	manualSort(view, ret)
 	return
@@ -63,13 +63,14 @@ func updateRootContent(tx *sql.Tx, content, updated, id string) (err error) {
 	if err = execStmtTx(tx, stmt, content, content, updated, id); err != nil {
 		return
 	}
+	ftsContent := segmentFTSValue(content)
 	stmt = "UPDATE blocks_fts SET content = ?, fcontent = ?, updated = ? WHERE id = ?"
-	if err = execStmtTx(tx, stmt, content, content, updated, id); err != nil {
+	if err = execStmtTx(tx, stmt, ftsContent, ftsContent, updated, id); err != nil {
 		return
 	}
 	if !caseSensitive {
 		stmt = "UPDATE blocks_fts_case_insensitive SET content = ?, fcontent = ?, updated = ? WHERE id = ?"
-		if err = execStmtTx(tx, stmt, content, content, updated, id); err != nil {
+		if err = execStmtTx(tx, stmt, ftsContent, ftsContent, updated, id); err != nil {
 			return
 		}
 	}
@@ -84,14 +85,15 @@ func updateBlockContent(tx *sql.Tx, block *Block) (err error) {
 		tx.Rollback()
 		return
 	}
+	ftsContent := segmentFTSValue(block.Content)
 	stmt = "UPDATE blocks_fts SET content = ? WHERE id = ?"
-	if err = execStmtTx(tx, stmt, block.Content, block.ID); err != nil {
+	if err = execStmtTx(tx, stmt, ftsContent, block.ID); err != nil {
 		tx.Rollback()
 		return
 	}
 	if !caseSensitive {
 		stmt = "UPDATE blocks_fts_case_insensitive SET content = ? WHERE id = ?"
-		if err = execStmtTx(tx, stmt, block.Content, block.ID); err != nil {
+		if err = execStmtTx(tx, stmt, ftsContent, block.ID); err != nil {
 			tx.Rollback()
 			return
 		}
@@ -124,14 +126,15 @@ func indexNode(tx *sql.Tx, id string) (err error) {
 		tx.Rollback()
 		return
 	}
+	ftsContent := segmentFTSValue(content)
 	stmt = "UPDATE blocks_fts SET content = ? WHERE id = ?"
-	if err = execStmtTx(tx, stmt, content, id); err != nil {
+	if err = execStmtTx(tx, stmt, ftsContent, id); err != nil {
 		tx.Rollback()
 		return
 	}
 	if !caseSensitive {
 		stmt = "UPDATE blocks_fts_case_insensitive SET content = ? WHERE id = ?"
-		if err = execStmtTx(tx, stmt, content, id); err != nil {
+		if err = execStmtTx(tx, stmt, ftsContent, id); err != nil {
 			tx.Rollback()
 			return
 		}
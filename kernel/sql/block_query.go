@@ -18,12 +18,15 @@ package sql
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
+	"errors"
 	"math"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/88250/lute/ast"
 	"github.com/88250/vitess-sqlparser/sqlparser"
@@ -378,7 +381,16 @@ func QueryNoLimit(stmt string) (ret []map[string]interface{}, err error) {
 	return queryRawStmt(stmt, math.MaxInt)
 }
 
-func Query(stmt string, limit int) (ret []map[string]interface{}, err error) {
+// Query 在只读连接上执行 SELECT 或者 WITH 语句，执行时间超过 timeout 时中断查询并返回 ErrQueryTimeout。
+func Query(stmt string, limit int, timeout time.Duration) (ret []map[string]interface{}, err error) {
+	// Read-only SQL guard and per-query timeout for `/api/query/sql`
+	if err = CheckReadOnlyStmt(stmt); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fixQueryTimeout(timeout))
+	defer cancel()
+
 	originalStmt := stmt
 	// Kernel API `/api/query/sql` support `||` operator https://github.com/siyuan-note/siyuan/issues/9662
 	// 这里为了支持 || 操作符，使用了另一个 sql 解析器，但是这个解析器无法处理 UNION https://github.com/siyuan-note/siyuan/issues/8226
@@ -390,7 +402,7 @@ func Query(stmt string, limit int) (ret []map[string]interface{}, err error) {
 			// 这个解析器无法处理 || 连接字符串操作符
 			parsedStmt, err2 := sqlparser.Parse(stmt)
 			if nil != err2 {
-				return queryRawStmt(stmt, limit)
+				return queryReadOnlyRawStmt(ctx, stmt, limit)
 			}
 
 			switch parsedStmt.(type) {
@@ -406,10 +418,10 @@ func Query(stmt string, limit int) (ret []map[string]interface{}, err error) {
 				union.Limit = limitClause
 				stmt = sqlparser.String(union)
 			default:
-				return queryRawStmt(stmt, limit)
+				return queryReadOnlyRawStmt(ctx, stmt, limit)
 			}
 		} else {
-			return queryRawStmt(stmt, limit)
+			return queryReadOnlyRawStmt(ctx, stmt, limit)
 		}
 	} else {
 		switch parsedStmt2.(type) {
@@ -420,14 +432,18 @@ func Query(stmt string, limit int) (ret []map[string]interface{}, err error) {
 			}
 			stmt = slct.String()
 		default:
-			return queryRawStmt(stmt, limit)
+			return queryReadOnlyRawStmt(ctx, stmt, limit)
 		}
 	}
 
 	ret = []map[string]interface{}{}
-	rows, err := query(stmt)
+	rows, err := queryReadOnly(ctx, stmt)
 	if err != nil {
-		rows, err = query(originalStmt + " LIMIT " + strconv.Itoa(limit))
+		if errors.Is(err, ErrQueryTimeout) {
+			return
+		}
+
+		rows, err = queryReadOnly(ctx, originalStmt+" LIMIT "+strconv.Itoa(limit))
 		if err != nil {
 			logging.LogWarnf("sql query [%s] failed: %s", stmt, err)
 			return
@@ -458,6 +474,7 @@ func Query(stmt string, limit int) (ret []map[string]interface{}, err error) {
 		}
 		ret = append(ret, m)
 	}
+	err = readOnlyQueryErr(ctx, rows.Err())
 	return
 }
 
@@ -486,6 +503,7 @@ func ToBlocks(result []map[string]interface{}) (ret []*Block) {
 			Created:  row["created"].(string),
 			Updated:  row["updated"].(string),
 		}
+		unsegmentFTSBlock(b)
 		ret = append(ret, b)
 	}
 	return
@@ -519,13 +537,26 @@ func getLimitClause(parsedStmt sqlparser.Statement, limit int) (ret *sqlparser.L
 func queryRawStmt(stmt string, limit int) (ret []map[string]interface{}, err error) {
 	rows, err := query(stmt)
 	if err != nil {
-		if strings.Contains(err.Error(), "syntax error") {
-			return
-		}
 		return
 	}
 	defer rows.Close()
+	return scanRawRows(rows, stmt, limit)
+}
 
+func queryReadOnlyRawStmt(ctx context.Context, stmt string, limit int) (ret []map[string]interface{}, err error) {
+	rows, err := queryReadOnly(ctx, stmt)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	if ret, err = scanRawRows(rows, stmt, limit); err != nil {
+		return
+	}
+	err = readOnlyQueryErr(ctx, rows.Err())
+	return
+}
+
+func scanRawRows(rows *sql.Rows, stmt string, limit int) (ret []map[string]interface{}, err error) {
 	cols, err := rows.Columns()
 	if err != nil || nil == cols {
 		return
@@ -560,13 +591,27 @@ func queryRawStmt(stmt string, limit int) (ret []map[string]interface{}, err err
 }
 
 func SelectBlocksRawStmtNoParse(stmt string, limit int) (ret []*Block) {
-	return selectBlocksRawStmt(stmt, limit)
+	return selectBlocksRawStmt(stmt, limit, false)
 }
 
 func SelectBlocksRawStmt(stmt string, page, limit int) (ret []*Block) {
+	return selectBlocksRawStmtPage(stmt, page, limit, false)
+}
+
+// SelectBlocksReadOnlyRawStmtNoParse 在只读连接上执行用户编写的查询，比如嵌入块的查询语句。
+func SelectBlocksReadOnlyRawStmtNoParse(stmt string, limit int) (ret []*Block) {
+	return selectBlocksRawStmt(stmt, limit, true)
+}
+
+// SelectBlocksReadOnlyRawStmt 在只读连接上执行用户编写的查询，比如嵌入块和 SQL 搜索的查询语句。
+func SelectBlocksReadOnlyRawStmt(stmt string, page, limit int) (ret []*Block) {
+	return selectBlocksRawStmtPage(stmt, page, limit, true)
+}
+
+func selectBlocksRawStmtPage(stmt string, page, limit int, readOnly bool) (ret []*Block) {
 	parsedStmt, err := sqlparser.Parse(stmt)
 	if err != nil {
-		return selectBlocksRawStmt(stmt, limit)
+		return selectBlocksRawStmt(stmt, limit, readOnly)
 	}
 
 	switch parsedStmt.(type) {
@@ -642,7 +687,8 @@ func SelectBlocksRawStmt(stmt string, page, limit int) (ret []*Block) {
 	stmt = strings.ReplaceAll(stmt, "\\\"", "\"")
 	stmt = strings.ReplaceAll(stmt, "\\\\*", "\\*")
 	stmt = strings.ReplaceAll(stmt, "from dual", "")
-	rows, err := query(stmt)
+	rows, cancel, err := queryBlockRows(stmt, readOnly)
+	defer cancel()
 	if err != nil {
 		if strings.Contains(err.Error(), "syntax error") {
 			return
@@ -710,11 +756,12 @@ func SelectBlocksRegex(stmt string, exp *regexp.Regexp, name, alias, memo, ial b
 	return
 }
 
-func selectBlocksRawStmt(stmt string, limit int) (ret []*Block) {
-	rows, err := query(stmt)
+func selectBlocksRawStmt(stmt string, limit int, readOnly bool) (ret []*Block) {
+	rows, cancel, err := queryBlockRows(stmt, readOnly)
+	defer cancel()
 	if err != nil {
-		if strings.Contains(err.Error(), "syntax error") {
-			return
+		if readOnly && !strings.Contains(err.Error(), "syntax error") {
+			logging.LogWarnf("raw sql query [%s] failed: %s", stmt, err)
 		}
 		return
 	}
@@ -738,12 +785,30 @@ func selectBlocksRawStmt(stmt string, limit int) (ret []*Block) {
 	return
 }
 
+// queryBlockRows 执行查询块的语句，readOnly 为 true 时语句由用户编写，需要在只读连接上执行并且限制执行时间。
+func queryBlockRows(stmt string, readOnly bool) (rows *sql.Rows, cancel context.CancelFunc, err error) {
+	if !readOnly {
+		cancel = func() {}
+		rows, err = query(stmt)
+		return
+	}
+
+	var ctx context.Context
+	ctx, cancel = context.WithTimeout(context.Background(), QueryTimeout)
+	if err = CheckReadOnlyStmt(stmt); err != nil {
+		return
+	}
+	rows, err = queryReadOnly(ctx, stmt)
+	return
+}
+
 func scanBlockRows(rows *sql.Rows) (ret *Block) {
 	var block Block
 	if err := rows.Scan(&block.ID, &block.ParentID, &block.RootID, &block.Hash, &block.Box, &block.Path, &block.HPath, &block.Name, &block.Alias, &block.Memo, &block.Tag, &block.Content, &block.FContent, &block.Markdown, &block.Length, &block.Type, &block.SubType, &block.IAL, &block.Sort, &block.Created, &block.Updated); err != nil {
 		logging.LogErrorf("query scan field failed: %s\n%s", err, logging.ShortStack())
 		return
 	}
+	unsegmentFTSBlock(&block)
 	ret = &block
 	putBlockCache(ret)
 	return
@@ -757,6 +822,7 @@ func scanBlockRow(row *sql.Row) (ret *Block) {
 		}
 		return
 	}
+	unsegmentFTSBlock(&block)
 	ret = &block
 	putBlockCache(ret)
 	return
@@ -0,0 +1,468 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sql
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/88250/gulu"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+// BlockVector 描述块内容的嵌入向量，保存在独立的块向量数据库中。
+type BlockVector struct {
+	ID     string
+	RootID string
+	Box    string
+	Path   string
+	Type   string
+	Hash   string // 块内容和嵌入模型的哈希，用于判断是否需要重新生成向量
+	Vector []float32
+}
+
+// BlockVectorScore 描述语义搜索命中的块和相似度。
+type BlockVectorScore struct {
+	ID     string
+	RootID string
+	Score  float64
+}
+
+var (
+	blockVectorDB *sql.DB
+
+	indexBlockVector        bool
+	blockVectorDirtyRootIDs = map[string]bool{}
+	blockVectorPrune        bool
+	blockVectorDirtyLock    = sync.Mutex{}
+)
+
+// blockVectorCache 缓存解码后的块向量，避免每次语义搜索都读取和解码所有向量，为 nil 时表示尚未加载。
+var (
+	blockVectorCache     map[string]*cachedBlockVector // 块 ID -> 块向量
+	blockVectorCacheLock = sync.RWMutex{}
+)
+
+type cachedBlockVector struct {
+	rootID string
+	vector []float32
+	norm   float64
+}
+
+var initBlockVectorDatabaseLock = sync.Mutex{}
+
+func InitBlockVectorDatabase(forceRebuild bool) {
+	initBlockVectorDatabaseLock.Lock()
+	defer initBlockVectorDatabaseLock.Unlock()
+
+	clearBlockVectorCache()
+	initBlockVectorDBConnection()
+
+	if !forceRebuild && gulu.File.IsExist(util.BlockVectorDBPath) {
+		return
+	}
+
+	blockVectorDB.Close()
+	blockVectorDB = nil
+	runtime.GC()
+	if err := os.RemoveAll(util.BlockVectorDBPath); err != nil {
+		logging.LogErrorf("remove block vector database file [%s] failed: %s", util.BlockVectorDBPath, err)
+		return
+	}
+
+	initBlockVectorDBConnection()
+	initBlockVectorDBTables()
+}
+
+func initBlockVectorDBConnection() {
+	if nil != blockVectorDB {
+		blockVectorDB.Close()
+		blockVectorDB = nil
+		runtime.GC()
+	}
+
+	util.LogDatabaseSize(util.BlockVectorDBPath)
+	dsn := util.BlockVectorDBPath + "?_journal_mode=WAL" +
+		"&_synchronous=OFF" +
+		"&_mmap_size=2684354560" +
+		"&_secure_delete=OFF" +
+		"&_cache_size=-20480" +
+		"&_page_size=32768" +
+		"&_busy_timeout=7000" +
+		"&_ignore_check_constraints=ON" +
+		"&_temp_store=MEMORY" +
+		"&_case_sensitive_like=OFF"
+	var err error
+	blockVectorDB, err = sql.Open("sqlite3_extended", dsn)
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeUnavailableDatabase, "create block vector database failed: %s", err)
+	}
+	blockVectorDB.SetMaxIdleConns(3)
+	blockVectorDB.SetMaxOpenConns(3)
+	blockVectorDB.SetConnMaxLifetime(365 * 24 * time.Hour)
+}
+
+func initBlockVectorDBTables() {
+	blockVectorDB.Exec("DROP TABLE block_vectors")
+	_, err := blockVectorDB.Exec("CREATE TABLE block_vectors (id TEXT PRIMARY KEY, root_id TEXT, box TEXT, path TEXT, type TEXT, hash TEXT, vector BLOB)")
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeUnavailableDatabase, "create table [block_vectors] failed: %s", err)
+	}
+	_, err = blockVectorDB.Exec("CREATE INDEX idx_block_vectors_root_id ON block_vectors(root_id)")
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeUnavailableDatabase, "create index [idx_block_vectors_root_id] failed: %s", err)
+	}
+}
+
+// SetIndexBlockVector 设置是否跟踪需要更新向量的文档，关闭语义搜索时不跟踪。
+func SetIndexBlockVector(b bool) {
+	indexBlockVector = b
+}
+
+// MarkBlockVectorRootIDs 标记需要更新向量的文档。
+func MarkBlockVectorRootIDs(rootIDs ...string) {
+	if !indexBlockVector {
+		return
+	}
+
+	blockVectorDirtyLock.Lock()
+	defer blockVectorDirtyLock.Unlock()
+	for _, rootID := range rootIDs {
+		blockVectorDirtyRootIDs[rootID] = true
+	}
+}
+
+// markBlockVectorOp 根据数据库队列操作标记需要更新向量的文档。
+func markBlockVectorOp(op *dbQueueOperation) {
+	if !indexBlockVector {
+		return
+	}
+
+	switch op.action {
+	case "index":
+		MarkBlockVectorRootIDs(op.indexTree.ID)
+	case "upsert":
+		MarkBlockVectorRootIDs(op.upsertTree.ID)
+	case "rename", "rename_sub_tree":
+		MarkBlockVectorRootIDs(op.renameTree.ID)
+	case "delete_id":
+		MarkBlockVectorRootIDs(op.removeTreeID)
+	case "delete_ids":
+		MarkBlockVectorRootIDs(op.removeTreeIDs...)
+	case "update_block_content":
+		MarkBlockVectorRootIDs(op.block.RootID)
+	case "index_node":
+		if bt := treenode.GetBlockTree(op.id); nil != bt {
+			MarkBlockVectorRootIDs(bt.RootID)
+		}
+	case "delete", "delete_box":
+		markBlockVectorPrune()
+	}
+}
+
+// markBlockVectorPrune 标记需要清理已经删除的文档的向量，用于无法确定文档 ID 的批量删除。
+func markBlockVectorPrune() {
+	if !indexBlockVector {
+		return
+	}
+
+	blockVectorDirtyLock.Lock()
+	defer blockVectorDirtyLock.Unlock()
+	blockVectorPrune = true
+}
+
+// PopBlockVectorDirty 返回并清空需要更新向量的文档以及是否需要清理已删除文档的向量。
+func PopBlockVectorDirty() (rootIDs []string, prune bool) {
+	blockVectorDirtyLock.Lock()
+	defer blockVectorDirtyLock.Unlock()
+
+	for rootID := range blockVectorDirtyRootIDs {
+		rootIDs = append(rootIDs, rootID)
+	}
+	blockVectorDirtyRootIDs = map[string]bool{}
+	prune = blockVectorPrune
+	blockVectorPrune = false
+	return
+}
+
+func GetBlockVectorHashes(rootID string) (ret map[string]string) {
+	ret = map[string]string{}
+	rows, err := blockVectorDB.Query("SELECT id, hash FROM block_vectors WHERE root_id = ?", rootID)
+	if err != nil {
+		logging.LogErrorf("query block vectors failed: %s", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, hash string
+		if err = rows.Scan(&id, &hash); err != nil {
+			logging.LogErrorf("scan block vector failed: %s", err)
+			return
+		}
+		ret[id] = hash
+	}
+	return
+}
+
+func GetBlockVectorRootIDs() (ret []string) {
+	rows, err := blockVectorDB.Query("SELECT DISTINCT root_id FROM block_vectors")
+	if err != nil {
+		logging.LogErrorf("query block vectors failed: %s", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rootID string
+		if err = rows.Scan(&rootID); err != nil {
+			logging.LogErrorf("scan block vector failed: %s", err)
+			return
+		}
+		ret = append(ret, rootID)
+	}
+	return
+}
+
+func UpsertBlockVectors(vectors []*BlockVector) (err error) {
+	if 1 > len(vectors) {
+		return
+	}
+
+	tx, err := blockVectorDB.Begin()
+	if err != nil {
+		logging.LogErrorf("begin block vector tx failed: %s", err)
+		return
+	}
+
+	stmt := "INSERT OR REPLACE INTO block_vectors (id, root_id, box, path, type, hash, vector) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	for _, v := range vectors {
+		if _, err = tx.Exec(stmt, v.ID, v.RootID, v.Box, v.Path, v.Type, v.Hash, encodeBlockVector(v.Vector)); err != nil {
+			tx.Rollback()
+			logging.LogErrorf("upsert block vector [%s] failed: %s", v.ID, err)
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logging.LogErrorf("commit block vector tx failed: %s", err)
+		return
+	}
+
+	blockVectorCacheLock.Lock()
+	defer blockVectorCacheLock.Unlock()
+	if nil != blockVectorCache {
+		for _, v := range vectors {
+			blockVectorCache[v.ID] = newCachedBlockVector(v.RootID, v.Vector)
+		}
+	}
+	return
+}
+
+func DeleteBlockVectors(ids []string) (err error) {
+	if 1 > len(ids) {
+		return
+	}
+
+	stmt := "DELETE FROM block_vectors WHERE id IN ('" + strings.Join(ids, "','") + "')"
+	if _, err = blockVectorDB.Exec(stmt); err != nil {
+		logging.LogErrorf("delete block vectors failed: %s", err)
+		return
+	}
+
+	blockVectorCacheLock.Lock()
+	defer blockVectorCacheLock.Unlock()
+	for _, id := range ids {
+		delete(blockVectorCache, id)
+	}
+	return
+}
+
+func DeleteBlockVectorsByRootIDs(rootIDs []string) (err error) {
+	if 1 > len(rootIDs) {
+		return
+	}
+
+	stmt := "DELETE FROM block_vectors WHERE root_id IN ('" + strings.Join(rootIDs, "','") + "')"
+	if _, err = blockVectorDB.Exec(stmt); err != nil {
+		logging.LogErrorf("delete block vectors failed: %s", err)
+		return
+	}
+
+	removed := map[string]bool{}
+	for _, rootID := range rootIDs {
+		removed[rootID] = true
+	}
+	blockVectorCacheLock.Lock()
+	defer blockVectorCacheLock.Unlock()
+	for id, cached := range blockVectorCache {
+		if removed[cached.rootID] {
+			delete(blockVectorCache, id)
+		}
+	}
+	return
+}
+
+// SearchBlockVectors 按照余弦相似度返回和 query 最相似的块，filter 是附加在 WHERE 后的过滤条件，比如 " AND (box = 'xxx')"。
+//
+// 先通过 filter 在数据库中筛选出块 ID，然后使用内存中缓存的向量计算相似度。
+func SearchBlockVectors(query []float32, filter string, minScore float64, limit int) (ret []*BlockVectorScore) {
+	ret = []*BlockVectorScore{}
+	rows, err := blockVectorDB.Query("SELECT id FROM block_vectors WHERE 1 = 1" + filter)
+	if err != nil {
+		logging.LogErrorf("query block vectors failed: %s", err)
+		return
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			logging.LogErrorf("scan block vector failed: %s", err)
+			rows.Close()
+			return
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if 1 > len(ids) {
+		return
+	}
+
+	loadBlockVectorCache()
+	blockVectorCacheLock.RLock()
+	defer blockVectorCacheLock.RUnlock()
+
+	queryNorm := blockVectorNorm(query)
+	for _, id := range ids {
+		cached := blockVectorCache[id]
+		if nil == cached {
+			continue
+		}
+
+		score := cosineSimilarity(query, queryNorm, cached.vector, cached.norm)
+		if score < minScore {
+			continue
+		}
+		ret = append(ret, &BlockVectorScore{ID: id, RootID: cached.rootID, Score: score})
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].Score > ret[j].Score })
+	if 0 < limit && limit < len(ret) {
+		ret = ret[:limit]
+	}
+	return
+}
+
+// loadBlockVectorCache 第一次搜索时读取并解码所有块向量，之后通过更新和删除块向量维护缓存。
+func loadBlockVectorCache() {
+	blockVectorCacheLock.Lock()
+	defer blockVectorCacheLock.Unlock()
+
+	if nil != blockVectorCache {
+		return
+	}
+
+	rows, err := blockVectorDB.Query("SELECT id, root_id, vector FROM block_vectors")
+	if err != nil {
+		logging.LogErrorf("query block vectors failed: %s", err)
+		return
+	}
+	defer rows.Close()
+
+	cache := map[string]*cachedBlockVector{}
+	for rows.Next() {
+		var id, rootID string
+		var data []byte
+		if err = rows.Scan(&id, &rootID, &data); err != nil {
+			logging.LogErrorf("scan block vector failed: %s", err)
+			return
+		}
+		cache[id] = newCachedBlockVector(rootID, decodeBlockVector(data))
+	}
+	blockVectorCache = cache
+}
+
+func clearBlockVectorCache() {
+	blockVectorCacheLock.Lock()
+	defer blockVectorCacheLock.Unlock()
+	blockVectorCache = nil
+}
+
+func newCachedBlockVector(rootID string, vector []float32) *cachedBlockVector {
+	return &cachedBlockVector{rootID: rootID, vector: vector, norm: blockVectorNorm(vector)}
+}
+
+// UpdateBlockVectorsPath 更新文档下所有块向量的笔记本和路径，移动文档时不需要重新生成向量。
+func UpdateBlockVectorsPath(rootID, box, p string) (err error) {
+	if _, err = blockVectorDB.Exec("UPDATE block_vectors SET box = ?, path = ? WHERE root_id = ?", box, p, rootID); err != nil {
+		logging.LogErrorf("update block vectors path failed: %s", err)
+	}
+	return
+}
+
+func closeBlockVectorDatabase() {
+	if nil == blockVectorDB {
+		return
+	}
+
+	if err := blockVectorDB.Close(); err != nil {
+		logging.LogErrorf("close block vector database failed: %s", err)
+	}
+}
+
+func encodeBlockVector(vector []float32) (ret []byte) {
+	ret = make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(ret[i*4:], math.Float32bits(v))
+	}
+	return
+}
+
+func decodeBlockVector(data []byte) (ret []float32) {
+	ret = make([]float32, len(data)/4)
+	for i := range ret {
+		ret[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return
+}
+
+func blockVectorNorm(vector []float32) float64 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum)
+}
+
+func cosineSimilarity(a []float32, normA float64, b []float32, normB float64) float64 {
+	if len(a) != len(b) || 1 > len(a) || 0 == normA || 0 == normB {
+		return 0
+	}
+
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot / (normA * normB)
+}
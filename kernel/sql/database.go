@@ -88,9 +88,14 @@ func InitDatabase(forceRebuild bool) (err error) {
 	if !forceRebuild {
 		// 检查数据库结构版本，如果版本不一致的话说明改过表结构，需要重建
 		if util.DatabaseVer == getDatabaseVer() {
-			return
+			if ftsTokenizer == getFTSTokenizer() {
+				initFTSVocabTables()
+				return
+			}
+			logging.LogInfof("the full-text search tokenizer is changed to [%s], rebuilding database...", ftsTokenizer)
+		} else {
+			logging.LogInfof("the database structure is changed, rebuilding database...")
 		}
-		logging.LogInfof("the database structure is changed, rebuilding database...")
 	}
 
 	// 不存在库或者版本不一致都会走到这里
@@ -105,6 +110,7 @@ func InitDatabase(forceRebuild bool) (err error) {
 
 	initDBConnection()
 	initDBTables()
+	initFTSVocabTables()
 
 	logging.LogInfof("reinitialized database [%s]", util.DBPath)
 	return
@@ -120,6 +126,7 @@ func initDBTables() {
 		logging.LogFatalf(logging.ExitCodeUnavailableDatabase, "create table [stat] failed: %s", err)
 	}
 	setDatabaseVer()
+	setFTSTokenizer()
 
 	_, err = db.Exec("DROP TABLE IF EXISTS blocks")
 	if err != nil {
@@ -154,7 +161,7 @@ func initDBTables() {
 	if err != nil {
 		logging.LogFatalf(logging.ExitCodeUnavailableDatabase, "drop table [blocks_fts] failed: %s", err)
 	}
-	_, err = db.Exec("CREATE VIRTUAL TABLE blocks_fts USING fts5(id UNINDEXED, parent_id UNINDEXED, root_id UNINDEXED, hash UNINDEXED, box UNINDEXED, path UNINDEXED, hpath, name, alias, memo, tag, content, fcontent, markdown UNINDEXED, length UNINDEXED, type UNINDEXED, subtype UNINDEXED, ial, sort UNINDEXED, created UNINDEXED, updated UNINDEXED, tokenize=\"siyuan\")")
+	_, err = db.Exec("CREATE VIRTUAL TABLE blocks_fts USING fts5(id UNINDEXED, parent_id UNINDEXED, root_id UNINDEXED, hash UNINDEXED, box UNINDEXED, path UNINDEXED, hpath, name, alias, memo, tag, content, fcontent, markdown UNINDEXED, length UNINDEXED, type UNINDEXED, subtype UNINDEXED, ial, sort UNINDEXED, created UNINDEXED, updated UNINDEXED, tokenize=\"" + ftsTokenize(false) + "\")")
 	if err != nil {
 		logging.LogFatalf(logging.ExitCodeUnavailableDatabase, "create table [blocks_fts] failed: %s", err)
 	}
@@ -163,7 +170,7 @@ func initDBTables() {
 	if err != nil {
 		logging.LogFatalf(logging.ExitCodeUnavailableDatabase, "drop table [blocks_fts_case_insensitive] failed: %s", err)
 	}
-	_, err = db.Exec("CREATE VIRTUAL TABLE blocks_fts_case_insensitive USING fts5(id UNINDEXED, parent_id UNINDEXED, root_id UNINDEXED, hash UNINDEXED, box UNINDEXED, path UNINDEXED, hpath, name, alias, memo, tag, content, fcontent, markdown UNINDEXED, length UNINDEXED, type UNINDEXED, subtype UNINDEXED, ial, sort UNINDEXED, created UNINDEXED, updated UNINDEXED, tokenize=\"siyuan case_insensitive\")")
+	_, err = db.Exec("CREATE VIRTUAL TABLE blocks_fts_case_insensitive USING fts5(id UNINDEXED, parent_id UNINDEXED, root_id UNINDEXED, hash UNINDEXED, box UNINDEXED, path UNINDEXED, hpath, name, alias, memo, tag, content, fcontent, markdown UNINDEXED, length UNINDEXED, type UNINDEXED, subtype UNINDEXED, ial, sort UNINDEXED, created UNINDEXED, updated UNINDEXED, tokenize=\"" + ftsTokenize(true) + "\")")
 	if err != nil {
 		logging.LogFatalf(logging.ExitCodeUnavailableDatabase, "create table [blocks_fts_case_insensitive] failed: %s", err)
 	}
@@ -254,6 +261,8 @@ func initDBConnection() {
 	db.SetMaxIdleConns(20)
 	db.SetMaxOpenConns(20)
 	db.SetConnMaxLifetime(365 * 24 * time.Hour)
+
+	initReadOnlyDBConnection()
 }
 
 var initHistoryDatabaseLock = sync.Mutex{}
@@ -265,6 +274,11 @@ func InitHistoryDatabase(forceRebuild bool) {
 	initHistoryDBConnection()
 
 	if !forceRebuild && gulu.File.IsExist(util.HistoryDBPath) {
+		if !existHistoryAttrsTable() {
+			// 旧版本的历史数据库没有属性表，创建后重建历史索引
+			initHistoryAttrsTable()
+			eventbus.Publish(util.EvtSQLHistoryRebuild)
+		}
 		return
 	}
 
@@ -314,6 +328,32 @@ func initHistoryDBTables() {
 	if err != nil {
 		logging.LogFatalf(logging.ExitCodeUnavailableDatabase, "create table [histories_fts_case_insensitive] failed: %s", err)
 	}
+
+	historyDB.Exec("DROP TABLE history_attrs")
+	initHistoryAttrsTable()
+}
+
+func initHistoryAttrsTable() {
+	_, err := historyDB.Exec("CREATE TABLE history_attrs (block_id, root_id, name, value, path, created)")
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeUnavailableDatabase, "create table [history_attrs] failed: %s", err)
+	}
+	_, err = historyDB.Exec("CREATE INDEX idx_history_attrs_name_value ON history_attrs(name, value)")
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeUnavailableDatabase, "create index [idx_history_attrs_name_value] failed: %s", err)
+	}
+	_, err = historyDB.Exec("CREATE INDEX idx_history_attrs_path ON history_attrs(path)")
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeUnavailableDatabase, "create index [idx_history_attrs_path] failed: %s", err)
+	}
+}
+
+func existHistoryAttrsTable() bool {
+	var name string
+	if err := historyDB.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'history_attrs'").Scan(&name); err != nil {
+		return false
+	}
+	return "history_attrs" == name
 }
 
 var initAssetContentDatabaseLock = sync.Mutex{}
@@ -370,7 +410,7 @@ func initAssetContentDBConnection() {
 
 func initAssetContentDBTables() {
 	assetContentDB.Exec("DROP TABLE asset_contents_fts_case_insensitive")
-	_, err := assetContentDB.Exec("CREATE VIRTUAL TABLE asset_contents_fts_case_insensitive USING fts5(id UNINDEXED, name, ext, path, size UNINDEXED, updated UNINDEXED, content, tokenize=\"siyuan case_insensitive\")")
+	_, err := assetContentDB.Exec("CREATE VIRTUAL TABLE asset_contents_fts_case_insensitive USING fts5(id UNINDEXED, name, ext, path, size UNINDEXED, updated UNINDEXED, content, tokenize=\"" + ftsTokenize(true) + "\")")
 	if err != nil {
 		logging.LogFatalf(logging.ExitCodeUnavailableDatabase, "create table [asset_contents_fts_case_insensitive] failed: %s", err)
 	}
@@ -379,8 +419,55 @@ func initAssetContentDBTables() {
 var (
 	caseSensitive  bool
 	indexAssetPath bool
+	ftsTokenizer   = FTSTokenizerSiYuan
 )
 
+const (
+	FTSTokenizerSiYuan = "siyuan"  // 中日韩单字分词，其他语言按单词分词
+	FTSTokenizerPorter = "porter"  // 在 siyuan 分词的基础上对英文单词做 Porter 词干提取
+	FTSTokenizerNgram  = "trigram" // 三元组 n-gram 分词，支持任意子串匹配，但是关键字至少需要 3 个字符
+	FTSTokenizerDict   = "dict"    // 基于词典的中日韩分词（gse），其他语言按单词分词，不区分大小写
+)
+
+func IsValidFTSTokenizer(tokenizer string) bool {
+	return FTSTokenizerSiYuan == tokenizer || FTSTokenizerPorter == tokenizer || FTSTokenizerNgram == tokenizer || FTSTokenizerDict == tokenizer
+}
+
+// SetFTSTokenizer 设置全文索引使用的分词器，需要在初始化数据库之前调用，分词器变化后初始化数据库时会重建。
+func SetFTSTokenizer(tokenizer string) {
+	if !IsValidFTSTokenizer(tokenizer) {
+		tokenizer = FTSTokenizerSiYuan
+	}
+	ftsTokenizer = tokenizer
+}
+
+func GetFTSTokenizer() string {
+	return ftsTokenizer
+}
+
+func ftsTokenize(caseInsensitive bool) string {
+	switch ftsTokenizer {
+	case FTSTokenizerPorter:
+		if caseInsensitive {
+			return "porter siyuan case_insensitive"
+		}
+		return "porter siyuan"
+	case FTSTokenizerNgram:
+		if caseInsensitive {
+			return "trigram case_sensitive 0"
+		}
+		return "trigram case_sensitive 1"
+	case FTSTokenizerDict:
+		// 写入时已经在中日韩词语之间插入了分隔符，unicode61 按分隔符切分即可
+		return "unicode61 remove_diacritics 0"
+	default:
+		if caseInsensitive {
+			return "siyuan case_insensitive"
+		}
+		return "siyuan"
+	}
+}
+
 func SetCaseSensitive(b bool) {
 	caseSensitive = b
 	if b {
@@ -1249,13 +1336,14 @@ func batchUpdatePath(tx *sql.Tx, tree *parse.Tree, context map[string]interface{
 	if err = execStmtTx(tx, stmt, tree.Box, tree.Path, tree.HPath, ialContent, tree.ID); err != nil {
 		return
 	}
+	ftsHPath, ftsIAL := segmentFTSValue(tree.HPath), segmentFTSValue(ialContent)
 	stmt = "UPDATE blocks_fts SET box = ?, path = ?, hpath = ?, ial = ? WHERE root_id = ?"
-	if err = execStmtTx(tx, stmt, tree.Box, tree.Path, tree.HPath, ialContent, tree.ID); err != nil {
+	if err = execStmtTx(tx, stmt, tree.Box, tree.Path, ftsHPath, ftsIAL, tree.ID); err != nil {
 		return
 	}
 	if !caseSensitive {
 		stmt = "UPDATE blocks_fts_case_insensitive SET box = ?, path = ?, hpath = ?, ial = ? WHERE root_id = ?"
-		if err = execStmtTx(tx, stmt, tree.Box, tree.Path, tree.HPath, ialContent, tree.ID); err != nil {
+		if err = execStmtTx(tx, stmt, tree.Box, tree.Path, ftsHPath, ftsIAL, tree.ID); err != nil {
 			return
 		}
 	}
@@ -1271,13 +1359,14 @@ func batchUpdateHPath(tx *sql.Tx, tree *parse.Tree, context map[string]interface
 	if err = execStmtTx(tx, stmt, tree.HPath, ialContent, tree.ID); err != nil {
 		return
 	}
+	ftsHPath, ftsIAL := segmentFTSValue(tree.HPath), segmentFTSValue(ialContent)
 	stmt = "UPDATE blocks_fts SET hpath = ?, ial = ? WHERE root_id = ?"
-	if err = execStmtTx(tx, stmt, tree.HPath, ialContent, tree.ID); err != nil {
+	if err = execStmtTx(tx, stmt, ftsHPath, ftsIAL, tree.ID); err != nil {
 		return
 	}
 	if !caseSensitive {
 		stmt = "UPDATE blocks_fts_case_insensitive SET hpath = ?, ial = ? WHERE root_id = ?"
-		if err = execStmtTx(tx, stmt, tree.HPath, ialContent, tree.ID); err != nil {
+		if err = execStmtTx(tx, stmt, ftsHPath, ftsIAL, tree.ID); err != nil {
 			return
 		}
 	}
@@ -1288,6 +1377,7 @@ func batchUpdateHPath(tx *sql.Tx, tree *parse.Tree, context map[string]interface
 }
 
 func CloseDatabase() {
+	closeReadOnlyDatabase()
 	if err := db.Close(); err != nil {
 		logging.LogErrorf("close database failed: %s", err)
 	}
@@ -1297,6 +1387,7 @@ func CloseDatabase() {
 	if err := assetContentDB.Close(); err != nil {
 		logging.LogErrorf("close asset content database failed: %s", err)
 	}
+	closeBlockVectorDatabase()
 	treenode.CloseDatabase()
 	logging.LogInfof("closed database")
 }
@@ -1552,6 +1643,7 @@ func closeDatabase() {
 		return
 	}
 
+	closeReadOnlyDatabase()
 	db.Close()
 	debug.FreeOSMemory()
 	db = nil
@@ -1564,7 +1656,7 @@ func SQLTemplateFuncs(templateFuncMap *template.FuncMap) {
 		for _, arg := range args {
 			stmt = strings.Replace(stmt, "?", arg, 1)
 		}
-		retBlocks = SelectBlocksRawStmt(stmt, 1, 512)
+		retBlocks = SelectBlocksReadOnlyRawStmt(stmt, 1, 512)
 		return
 	}
 	(*templateFuncMap)["getBlock"] = func(arg any) (retBlock *Block) {
@@ -1586,7 +1678,7 @@ func SQLTemplateFuncs(templateFuncMap *template.FuncMap) {
 		return
 	}
 	(*templateFuncMap)["querySQL"] = func(stmt string) (ret []map[string]interface{}) {
-		ret, _ = Query(stmt, 1024)
+		ret, _ = Query(stmt, 1024, QueryTimeout)
 		return
 	}
 }
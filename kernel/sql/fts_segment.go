@@ -0,0 +1,131 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sql
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/88250/lute/editor"
+	"github.com/go-ego/gse"
+	"github.com/siyuan-note/logging"
+)
+
+// 使用词典分词时，写入全文索引表的文本在中日韩词语之间插入零宽空格，unicode61 分词器会将其作为分隔符，
+// 这样中日韩文本按照词语而不是单字建立索引。读取全文索引表时再移除这些零宽空格。
+const ftsSegmentSep = editor.Zwsp
+
+var (
+	ftsSegmenter     gse.Segmenter
+	ftsSegmenterOnce sync.Once
+)
+
+// getFTSSegmenter 返回词典分词器，第一次使用时才加载词典。
+func getFTSSegmenter() *gse.Segmenter {
+	ftsSegmenterOnce.Do(func() {
+		ftsSegmenter.SkipLog = true
+		if err := ftsSegmenter.LoadDict(); err != nil {
+			logging.LogErrorf("load segment dictionary failed: %s", err)
+		}
+	})
+	return &ftsSegmenter
+}
+
+// SegmentFTSQuery 使用词典分词时将 FTS 查询中连续的中日韩文本按词语使用空格分开，和索引时的分词保持一致。
+func SegmentFTSQuery(query string) string {
+	return segmentFTSText(query, " ")
+}
+
+// segmentFTSValue 使用词典分词时对写入全文索引表的文本分词。
+func segmentFTSValue(text string) string {
+	return segmentFTSText(text, ftsSegmentSep)
+}
+
+// unsegmentFTSValue 移除写入全文索引表时插入的分隔符。
+func unsegmentFTSValue(text string) string {
+	if FTSTokenizerDict != ftsTokenizer {
+		return text
+	}
+	return strings.ReplaceAll(text, ftsSegmentSep, "")
+}
+
+// segmentFTSBlockValueArgs 对插入全文索引表的块字段分词，参数顺序和 BlocksFTSInsert 一致。
+func segmentFTSBlockValueArgs(valueArgs []interface{}) []interface{} {
+	if FTSTokenizerDict != ftsTokenizer {
+		return valueArgs
+	}
+
+	ret := make([]interface{}, len(valueArgs))
+	copy(ret, valueArgs)
+	for i := range ret {
+		switch i % 21 {
+		case 6, 7, 8, 9, 10, 11, 12, 17: // hpath, name, alias, memo, tag, content, fcontent, ial
+			ret[i] = segmentFTSValue(ret[i].(string))
+		}
+	}
+	return ret
+}
+
+func unsegmentFTSBlock(block *Block) {
+	if FTSTokenizerDict != ftsTokenizer {
+		return
+	}
+
+	block.HPath = unsegmentFTSValue(block.HPath)
+	block.Name = unsegmentFTSValue(block.Name)
+	block.Alias = unsegmentFTSValue(block.Alias)
+	block.Memo = unsegmentFTSValue(block.Memo)
+	block.Tag = unsegmentFTSValue(block.Tag)
+	block.Content = unsegmentFTSValue(block.Content)
+	block.FContent = unsegmentFTSValue(block.FContent)
+	block.IAL = unsegmentFTSValue(block.IAL)
+}
+
+func segmentFTSText(text, sep string) string {
+	if FTSTokenizerDict != ftsTokenizer || !strings.ContainsFunc(text, isFTSSegmentRune) {
+		return text
+	}
+
+	buf := strings.Builder{}
+	buf.Grow(len(text) + len(text)/4)
+	last, pos := utf8.RuneError, 0
+	for _, word := range getFTSSegmenter().Cut(text, true) {
+		if "" == word {
+			continue
+		}
+		if !strings.HasPrefix(text[pos:], word) {
+			// 分词结果和原文对不上时不分词，避免修改原文
+			return text
+		}
+
+		first, _ := utf8.DecodeRuneInString(word)
+		if isFTSSegmentRune(last) && isFTSSegmentRune(first) {
+			buf.WriteString(sep)
+		}
+		buf.WriteString(word)
+		last, _ = utf8.DecodeLastRuneInString(word)
+		pos += len(word)
+	}
+	buf.WriteString(text[pos:])
+	return buf.String()
+}
+
+func isFTSSegmentRune(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
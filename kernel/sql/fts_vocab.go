@@ -0,0 +1,79 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sql
+
+import (
+	"github.com/siyuan-note/logging"
+)
+
+// FTSVocabTerm 描述全文索引词表中的一个词。
+type FTSVocabTerm struct {
+	Term string // 分词后的词
+	Docs int    // 包含该词的块数
+}
+
+// initFTSVocabTables 创建全文索引的词表。
+//
+// fts5vocab 虚拟表直接读取 FTS 索引，索引块时会自动维护，不需要单独写入，所以也不需要修改数据库版本重建。
+func initFTSVocabTables() {
+	for _, table := range []string{"blocks_fts", "blocks_fts_case_insensitive"} {
+		vocabTable := table + "_vocab"
+		if _, err := db.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS " + vocabTable + " USING fts5vocab(" + table + ", row)"); err != nil {
+			logging.LogErrorf("create table [%s] failed: %s", vocabTable, err)
+		}
+	}
+}
+
+// GetFTSVocabTerms 获取词表中首字符为 first 且长度在 [minLen, maxLen] 之间的词，用于计算模糊搜索候选词。
+func GetFTSVocabTerms(first rune, minLen, maxLen int) (ret []*FTSVocabTerm) {
+	table := "blocks_fts_case_insensitive_vocab"
+	if caseSensitive {
+		table = "blocks_fts_vocab"
+	}
+
+	// 按照词的范围过滤可以利用索引，不需要扫描整个词表
+	stmt := "SELECT term, doc FROM " + table + " WHERE term >= ? AND term < ? AND length(term) BETWEEN ? AND ?"
+	rows, err := query(stmt, string(first), string(first+1), minLen, maxLen)
+	if err != nil {
+		logging.LogErrorf("sql query [%s] failed: %s", stmt, err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		term := &FTSVocabTerm{}
+		if err = rows.Scan(&term.Term, &term.Docs); err != nil {
+			logging.LogErrorf("query scan field failed: %s", err)
+			return
+		}
+		ret = append(ret, term)
+	}
+	return
+}
+
+// ExistFTSVocabTerm 判断词表中是否存在 term。
+func ExistFTSVocabTerm(term string) bool {
+	table := "blocks_fts_case_insensitive_vocab"
+	if caseSensitive {
+		table = "blocks_fts_vocab"
+	}
+
+	var count int
+	if err := queryRow("SELECT COUNT(*) FROM "+table+" WHERE term = ?", term).Scan(&count); err != nil {
+		return false
+	}
+	return 0 < count
+}
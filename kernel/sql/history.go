@@ -34,8 +34,20 @@ type History struct {
 	Content string
 	Created string
 	Path    string
+
+	Attrs []*HistoryAttr // 历史中块的属性、标签和数据库值，仅用于索引
+}
+
+// HistoryAttr 描述历史中的一个块属性、标签或者数据库值。
+type HistoryAttr struct {
+	BlockID string
+	Name    string // 属性名，标签为 HistoryAttrNameTag，数据库值为字段名
+	Value   string
 }
 
+// HistoryAttrNameTag 是历史属性表中标签的属性名。
+const HistoryAttrNameTag = "#tag"
+
 func QueryHistory(stmt string) (ret []map[string]interface{}, err error) {
 	ret = []map[string]interface{}{}
 	rows, err := queryHistory(stmt)
@@ -115,12 +127,18 @@ func deleteOutdatedHistories(tx *sql.Tx, before int64, context map[string]interf
 	if err = execStmtTx(tx, stmt, before); err != nil {
 		return
 	}
+	stmt = "DELETE FROM history_attrs WHERE CAST(created AS INTEGER) < ?"
+	if err = execStmtTx(tx, stmt, before); err != nil {
+		return
+	}
 	return
 }
 
 const (
 	HistoriesFTSCaseInsensitiveInsert = "INSERT INTO histories_fts_case_insensitive (id, type, op, title, content, path, created) VALUES %s"
 	HistoriesPlaceholder              = "(?, ?, ?, ?, ?, ?, ?)"
+	HistoryAttrsInsert                = "INSERT INTO history_attrs (block_id, root_id, name, value, path, created) VALUES %s"
+	HistoryAttrsPlaceholder           = "(?, ?, ?, ?, ?, ?)"
 )
 
 func insertHistories(tx *sql.Tx, histories []*History, context map[string]interface{}) (err error) {
@@ -167,6 +185,35 @@ func insertHistories0(tx *sql.Tx, bulk []*History, context map[string]interface{
 		return
 	}
 
+	for _, b := range bulk {
+		if err = insertHistoryAttrs(tx, b); err != nil {
+			return
+		}
+	}
+
 	eventbus.Publish(eventbus.EvtSQLInsertHistory, context)
 	return
 }
+
+func insertHistoryAttrs(tx *sql.Tx, history *History) (err error) {
+	for i := 0; i < len(history.Attrs); i += 512 {
+		bulk := history.Attrs[i:min(i+512, len(history.Attrs))]
+		valueStrings := make([]string, 0, len(bulk))
+		valueArgs := make([]interface{}, 0, len(bulk)*strings.Count(HistoryAttrsPlaceholder, "?"))
+		for _, attr := range bulk {
+			valueStrings = append(valueStrings, HistoryAttrsPlaceholder)
+			valueArgs = append(valueArgs, attr.BlockID)
+			valueArgs = append(valueArgs, history.ID)
+			valueArgs = append(valueArgs, attr.Name)
+			valueArgs = append(valueArgs, attr.Value)
+			valueArgs = append(valueArgs, history.Path)
+			valueArgs = append(valueArgs, history.Created)
+		}
+
+		stmt := fmt.Sprintf(HistoryAttrsInsert, strings.Join(valueStrings, ","))
+		if err = prepareExecInsertTx(tx, stmt, valueArgs); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,228 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+// 用户编写的 SQL（`/api/query/sql`、嵌入块、模板和 SQL 搜索）通过只读连接执行，避免误改数据库或者长时间占用数据库
+
+const (
+	QueryTimeout    = 7 * time.Second  // 只读查询的默认超时时间
+	MaxQueryTimeout = 60 * time.Second // 只读查询允许设置的最长超时时间
+
+	sqliteRecursive = 33 // SQLITE_RECURSIVE，go-sqlite3 中没有导出该常量
+)
+
+var (
+	ErrNotReadOnlyStmt = errors.New("only SELECT and WITH statements are allowed")
+	ErrQueryTimeout    = errors.New("query timeout")
+)
+
+var readOnlyDB *sql.DB
+
+func init() {
+	regex := func(re, s string) (bool, error) {
+		re = strings.ReplaceAll(re, "\\\\", "\\")
+		return regexp.MatchString(re, s)
+	}
+
+	sql.Register("sqlite3_readonly", &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			conn.RegisterAuthorizer(readOnlyAuthorizer)
+			return conn.RegisterFunc("regexp", regex, true)
+		},
+	})
+}
+
+// readOnlyAuthorizer 仅允许读取表和调用函数，拒绝写入、PRAGMA、ATTACH 和事务等操作。
+func readOnlyAuthorizer(op int, arg1, arg2, arg3 string) int {
+	switch op {
+	case sqlite3.SQLITE_SELECT, sqlite3.SQLITE_READ, sqlite3.SQLITE_FUNCTION, sqliteRecursive:
+		return sqlite3.SQLITE_OK
+	case sqlite3.SQLITE_PRAGMA:
+		// FTS5 虚拟表内部会读取 data_version
+		if "data_version" == arg1 {
+			return sqlite3.SQLITE_OK
+		}
+	case sqlite3.SQLITE_UPDATE:
+		// 连接 FTS5 虚拟表时声明表结构会检查 sqlite_master，实际修改会被 SQLite 和 query_only 拒绝
+		if "sqlite_master" == arg1 {
+			return sqlite3.SQLITE_OK
+		}
+	}
+	return sqlite3.SQLITE_DENY
+}
+
+func initReadOnlyDBConnection() {
+	closeReadOnlyDatabase()
+
+	dsn := util.DBPath + "?_query_only=true" +
+		"&_mmap_size=2684354560" +
+		"&_cache_size=-20480" +
+		"&_busy_timeout=7000" +
+		"&_temp_store=MEMORY" +
+		"&_case_sensitive_like=OFF"
+	var err error
+	readOnlyDB, err = sql.Open("sqlite3_readonly", dsn)
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeUnavailableDatabase, "create read-only database connection failed: %s", err)
+	}
+	readOnlyDB.SetMaxIdleConns(8)
+	readOnlyDB.SetMaxOpenConns(8)
+	readOnlyDB.SetConnMaxLifetime(365 * 24 * time.Hour)
+}
+
+func closeReadOnlyDatabase() {
+	if nil == readOnlyDB {
+		return
+	}
+
+	if err := readOnlyDB.Close(); err != nil {
+		logging.LogErrorf("close read-only database connection failed: %s", err)
+	}
+	readOnlyDB = nil
+}
+
+// CheckReadOnlyStmt 检查语句是否是 SELECT 或者 WITH 查询语句。
+//
+// 这里仅做快速检查以便返回明确的错误，实际执行时只读连接上的授权回调会拒绝所有写操作。
+func CheckReadOnlyStmt(stmt string) error {
+	stmt = trimStmtLeading(stmt)
+	keyword := stmt
+	if i := strings.IndexFunc(stmt, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z')
+	}); 0 <= i {
+		keyword = stmt[:i]
+	}
+
+	switch strings.ToUpper(keyword) {
+	case "SELECT", "WITH":
+		return nil
+	}
+	return ErrNotReadOnlyStmt
+}
+
+// QueryReadOnlyNoLimit 在只读连接上执行查询并返回全部结果。
+func QueryReadOnlyNoLimit(stmt string) (ret []map[string]interface{}, err error) {
+	if err = CheckReadOnlyStmt(stmt); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), QueryTimeout)
+	defer cancel()
+	return queryReadOnlyRawStmt(ctx, stmt, math.MaxInt)
+}
+
+// ExplainQueryPlan 返回 EXPLAIN QUERY PLAN 的结果，用于分析查询是否使用了索引。
+func ExplainQueryPlan(stmt string, timeout time.Duration) (ret []map[string]interface{}, err error) {
+	if err = CheckReadOnlyStmt(stmt); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fixQueryTimeout(timeout))
+	defer cancel()
+
+	rows, err := queryReadOnly(ctx, "EXPLAIN QUERY PLAN "+strings.TrimSpace(stmt))
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	ret = []map[string]interface{}{}
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		if err = rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			err = readOnlyQueryErr(ctx, err)
+			return
+		}
+		ret = append(ret, map[string]interface{}{"id": id, "parent": parent, "detail": detail})
+	}
+	err = readOnlyQueryErr(ctx, rows.Err())
+	return
+}
+
+func queryReadOnly(ctx context.Context, stmt string) (rows *sql.Rows, err error) {
+	stmt = strings.TrimSpace(stmt)
+	if "" == stmt {
+		return nil, errors.New("statement is empty")
+	}
+	if nil == readOnlyDB {
+		return nil, errors.New("database is nil")
+	}
+
+	rows, err = readOnlyDB.QueryContext(ctx, stmt)
+	err = readOnlyQueryErr(ctx, err)
+	return
+}
+
+// readOnlyQueryErr 将超时中断的错误转换为 ErrQueryTimeout。
+func readOnlyQueryErr(ctx context.Context, err error) error {
+	if nil == err {
+		return nil
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		logging.LogWarnf("read-only query is interrupted: %s", err)
+		return ErrQueryTimeout
+	}
+	return err
+}
+
+func fixQueryTimeout(timeout time.Duration) time.Duration {
+	if 0 >= timeout {
+		return QueryTimeout
+	}
+	if MaxQueryTimeout < timeout {
+		return MaxQueryTimeout
+	}
+	return timeout
+}
+
+// trimStmtLeading 去掉语句开头的空白、注释和左括号。
+func trimStmtLeading(stmt string) string {
+	for {
+		stmt = strings.TrimLeft(stmt, " \t\r\n(")
+		if strings.HasPrefix(stmt, "--") {
+			if i := strings.Index(stmt, "\n"); 0 <= i {
+				stmt = stmt[i+1:]
+				continue
+			}
+			return ""
+		}
+		if strings.HasPrefix(stmt, "/*") {
+			if i := strings.Index(stmt, "*/"); 0 <= i {
+				stmt = stmt[i+2:]
+				continue
+			}
+			return ""
+		}
+		return stmt
+	}
+}
@@ -244,6 +244,10 @@ func execOp(op *dbQueueOperation, tx *sql.Tx, context map[string]interface{}) (e
 		logging.LogErrorf(msg)
 		err = errors.New(msg)
 	}
+
+	if nil == err {
+		markBlockVectorOp(op)
+	}
 	return
 }
 
@@ -53,6 +53,29 @@ func setDatabaseVer() {
 	commitTx(tx)
 }
 
+func getFTSTokenizer() (ret string) {
+	key := "siyuan_fts_tokenizer"
+	stmt := "SELECT value FROM stat WHERE `key` = ?"
+	row := db.QueryRow(stmt, key)
+	if err := row.Scan(&ret); err != nil {
+		// 旧版本数据库没有记录分词器，使用的是默认分词器
+		ret = FTSTokenizerSiYuan
+	}
+	return
+}
+
+func setFTSTokenizer() {
+	key := "siyuan_fts_tokenizer"
+	tx, err := beginTx()
+	if err != nil {
+		return
+	}
+	if err = putStat(tx, key, ftsTokenizer); err != nil {
+		return
+	}
+	commitTx(tx)
+}
+
 func putStat(tx *sql.Tx, key, value string) (err error) {
 	stmt := "DELETE FROM stat WHERE `key` = '" + key + "'"
 	if err = execStmtTx(tx, stmt); err != nil {
@@ -123,14 +123,15 @@ func insertBlocks0(tx *sql.Tx, bulk []*Block, context map[string]interface{}) (e
 	// 使用下面的 EvtSQLInsertBlocksFTS 就可以了
 	//eventbus.Publish(eventbus.EvtSQLInsertBlocks, context, current, total, len(bulk), evtHash)
 
+	ftsValueArgs := segmentFTSBlockValueArgs(valueArgs)
 	stmt = fmt.Sprintf(BlocksFTSInsert, strings.Join(valueStrings, ","))
-	if err = prepareExecInsertTx(tx, stmt, valueArgs); err != nil {
+	if err = prepareExecInsertTx(tx, stmt, ftsValueArgs); err != nil {
 		return
 	}
 
 	if !caseSensitive {
 		stmt = fmt.Sprintf(BlocksFTSCaseInsensitiveInsert, strings.Join(valueStrings, ","))
-		if err = prepareExecInsertTx(tx, stmt, valueArgs); err != nil {
+		if err = prepareExecInsertTx(tx, stmt, ftsValueArgs); err != nil {
 			return
 		}
 	}
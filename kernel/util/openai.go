@@ -88,6 +88,26 @@ func ChatGPT(msg string, contextMsgs []string, c *openai.Client, model string, m
 	return
 }
 
+// Embeddings 调用 OpenAI 兼容的嵌入接口，返回的向量和 inputs 一一对应。
+func Embeddings(inputs []string, c *openai.Client, model string, timeout int) (ret [][]float32, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	resp, err := c.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{Input: inputs, Model: openai.EmbeddingModel(model)})
+	if err != nil {
+		logging.LogErrorf("create embeddings failed: %s", err)
+		return
+	}
+
+	ret = make([][]float32, len(inputs))
+	for _, data := range resp.Data {
+		if 0 <= data.Index && data.Index < len(ret) {
+			ret[data.Index] = data.Embedding
+		}
+	}
+	return
+}
+
 func NewOpenAIClient(apiKey, apiProxy, apiBaseURL, apiUserAgent, apiVersion, apiProvider string) *openai.Client {
 	config := openai.DefaultConfig(apiKey)
 	if "Azure" == apiProvider {
@@ -36,6 +36,7 @@ type SessionData struct {
 
 type WorkspaceSession struct {
 	AccessAuthCode string
+	UserID         string // 通过用户名和密码登录时的用户 ID
 	Captcha        string
 }
 
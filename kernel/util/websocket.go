@@ -32,6 +32,12 @@ var (
 	sessions = sync.Map{} // {appId, {sessionId, session}}
 )
 
+// isNotebookScopedSession 判断会话是否属于限制了笔记本的工作空间用户，这些会话不接收广播消息，避免泄露其他笔记本的内容。
+func isNotebookScopedSession(session *melody.Session) bool {
+	scoped, ok := session.Get("notebookScoped")
+	return ok && true == scoped
+}
+
 func BroadcastByTypeAndExcludeApp(excludeApp, typ, cmd string, code int, msg string, data interface{}) {
 	sessions.Range(func(key, value interface{}) bool {
 		appSessions := value.(*sync.Map)
@@ -41,7 +47,7 @@ func BroadcastByTypeAndExcludeApp(excludeApp, typ, cmd string, code int, msg str
 
 		appSessions.Range(func(key, value interface{}) bool {
 			session := value.(*melody.Session)
-			if t, ok := session.Get("type"); ok && typ == t {
+			if t, ok := session.Get("type"); ok && typ == t && !isNotebookScopedSession(session) {
 				event := NewResult()
 				event.Cmd = cmd
 				event.Code = code
@@ -79,6 +85,10 @@ func BroadcastByTypeAndApp(typ, app, cmd string, code int, msg string, data inte
 func BroadcastByType(typ, cmd string, code int, msg string, data interface{}) {
 	typeSessions := SessionsByType(typ)
 	for _, sess := range typeSessions {
+		if isNotebookScopedSession(sess) {
+			continue
+		}
+
 		event := NewResult()
 		event.Cmd = cmd
 		event.Code = code
@@ -374,6 +384,9 @@ func Broadcast(msg []byte) {
 		appSessions := value.(*sync.Map)
 		appSessions.Range(func(key, value interface{}) bool {
 			session := value.(*melody.Session)
+			if isNotebookScopedSession(session) {
+				return true
+			}
 			session.Write(msg)
 			return true
 		})
@@ -386,7 +399,7 @@ func broadcastOtherApps(msg []byte, excludeApp string) {
 		appSessions := value.(*sync.Map)
 		appSessions.Range(func(key, value interface{}) bool {
 			session := value.(*melody.Session)
-			if app, _ := session.Get("app"); app == excludeApp {
+			if app, _ := session.Get("app"); app == excludeApp || isNotebookScopedSession(session) {
 				return true
 			}
 			session.Write(msg)
@@ -401,7 +414,7 @@ func broadcastOtherAppMains(msg []byte, excludeApp string) {
 		appSessions := value.(*sync.Map)
 		appSessions.Range(func(key, value interface{}) bool {
 			session := value.(*melody.Session)
-			if app, _ := session.Get("app"); app == excludeApp {
+			if app, _ := session.Get("app"); app == excludeApp || isNotebookScopedSession(session) {
 				return true
 			}
 
@@ -436,7 +449,7 @@ func broadcastOthers(msg []byte, excludeSID string) {
 		appSessions := value.(*sync.Map)
 		appSessions.Range(func(key, value interface{}) bool {
 			session := value.(*melody.Session)
-			if id, _ := session.Get("id"); id == excludeSID {
+			if id, _ := session.Get("id"); id == excludeSID || isNotebookScopedSession(session) {
 				return true
 			}
 			session.Write(msg)
@@ -496,3 +509,24 @@ func ClosePublishServiceSessions() {
 		RemovePushChan(session)
 	}
 }
+
+// CloseUserSessions 关闭工作空间用户的 WebSocket 连接，客户端重连时会按照用户最新的配置重新鉴权。
+func CloseUserSessions(userID string) {
+	var userSessions []*melody.Session
+	sessions.Range(func(key, value interface{}) bool {
+		appSessions := value.(*sync.Map)
+		appSessions.Range(func(key, value interface{}) bool {
+			session := value.(*melody.Session)
+			if id, ok := session.Get("userID"); ok && userID == id {
+				userSessions = append(userSessions, session)
+			}
+			return true
+		})
+		return true
+	})
+
+	for _, session := range userSessions {
+		session.Close()
+		RemovePushChan(session)
+	}
+}